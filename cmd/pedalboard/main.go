@@ -0,0 +1,205 @@
+// Command pedalboard processes, inspects and converts audio files from the command line.
+//
+// Usage:
+//
+//	pedalboard process -template chain.json [-out dir] files...
+//	pedalboard scan [-timeout d] [-depth n] dir
+//	pedalboard probe files...
+//	pedalboard convert [-rate hz] [-bits n] input output
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Br1an6/go-pedalboard/pkg/pedalboard"
+)
+
+const usage = `Usage: pedalboard <command> [flags] [args]
+
+Commands:
+  process   Apply a processor chain from a JSON template to audio files
+  scan      List VST3 and Audio Unit plugins in a directory
+  probe     Show audio file metadata
+  convert   Change an audio file's format, sample rate or bit depth
+
+Run "pedalboard <command> -h" for command flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func([]string) error{
+		"process": runProcess,
+		"scan":    runScan,
+		"probe":   runProbe,
+		"convert": runConvert,
+		// Not listed in usage: started by pedalboard.NewSandboxedPlugin
+		"sandbox-host": pedalboard.RunSandboxHost,
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "pedalboard %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func runProcess(args []string) error {
+	flags := flag.NewFlagSet("process", flag.ExitOnError)
+	templatePath := flags.String("template", "", "JSON chain template (required)")
+	outDir := flags.String("out", "", "output directory (default: next to each input with a _processed suffix)")
+	bitDepth := flags.Int("bits", 16, "output bit depth (8, 16, 24 or 32)")
+	flags.Parse(args)
+
+	if *templatePath == "" || flags.NArg() == 0 {
+		return fmt.Errorf("usage: pedalboard process -template chain.json [-out dir] files...")
+	}
+	tmpl, err := loadChainTemplate(*templatePath)
+	if err != nil {
+		return err
+	}
+	if *outDir != "" {
+		if err := os.MkdirAll(*outDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	for _, input := range flags.Args() {
+		// Build a fresh chain per file so effect tails don't carry over
+		chain, err := tmpl.build()
+		if err != nil {
+			return err
+		}
+		buffer, err := pedalboard.LoadAudioFile(input)
+		if err != nil {
+			return err
+		}
+		result, err := chain.ProcessToNewBuffer(buffer)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		output := processedPath(input, *outDir)
+		if err := pedalboard.SaveAudioFileWithBitDepth(output, result, *bitDepth); err != nil {
+			return fmt.Errorf("%s: %w", output, err)
+		}
+		fmt.Printf("%s -> %s\n", input, output)
+	}
+	return nil
+}
+
+// processedPath returns where the processed version of input is written.
+func processedPath(input, outDir string) string {
+	if outDir != "" {
+		return filepath.Join(outDir, filepath.Base(input))
+	}
+	ext := filepath.Ext(input)
+	return strings.TrimSuffix(input, ext) + "_processed" + ext
+}
+
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 30*time.Second, "give up on a plugin that takes longer to load")
+	depth := flags.Int("depth", 0, "maximum directory depth to search (0 = no limit)")
+	cachePath := flags.String("cache", "", "reuse and update the scan cache in this JSON file")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: pedalboard scan [-timeout d] [-depth n] [-cache file] dir")
+	}
+
+	var cached []pedalboard.PluginInfo
+	if *cachePath != "" {
+		// A missing or unreadable cache just means a full scan
+		cached, _ = pedalboard.LoadScanCache(*cachePath)
+	}
+	plugins, err := pedalboard.ScanPlugins(flags.Arg(0), pedalboard.ScanOptions{
+		Timeout:  *timeout,
+		MaxDepth: *depth,
+		OnError: func(path string, err error) {
+			fmt.Fprintf(os.Stderr, "skipped %s: %v\n", path, err)
+		},
+		Cache: cached,
+	})
+	if err != nil {
+		return err
+	}
+	if *cachePath != "" {
+		if err := pedalboard.SaveScanCache(plugins, *cachePath); err != nil {
+			return err
+		}
+	}
+	for _, p := range plugins {
+		fmt.Printf("%-10s %-30s %-20s %s\n", p.Format, p.Name, p.Vendor, p.Path)
+	}
+	return nil
+}
+
+func runProbe(args []string) error {
+	flags := flag.NewFlagSet("probe", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: pedalboard probe files...")
+	}
+
+	for _, path := range flags.Args() {
+		buffer, err := pedalboard.LoadAudioFile(path)
+		if err != nil {
+			return err
+		}
+		numSamples := 0
+		if len(buffer.Data) > 0 {
+			numSamples = len(buffer.Data[0])
+		}
+		duration := time.Duration(float64(numSamples) / buffer.SampleRate * float64(time.Second))
+
+		fmt.Printf("%s\n", path)
+		fmt.Printf("  Channels:    %d\n", len(buffer.Data))
+		fmt.Printf("  Sample rate: %.0f Hz\n", buffer.SampleRate)
+		fmt.Printf("  Bit depth:   %d\n", buffer.BitDepth)
+		fmt.Printf("  Samples:     %d\n", numSamples)
+		fmt.Printf("  Duration:    %v\n", duration.Round(time.Millisecond))
+		for _, m := range buffer.Markers {
+			fmt.Printf("  Marker:      %v %q\n", m.Position.Round(time.Millisecond), m.Name)
+		}
+	}
+	return nil
+}
+
+func runConvert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	sampleRate := flags.Float64("rate", 0, "output sample rate in Hz (default: keep)")
+	bitDepth := flags.Int("bits", 0, "output bit depth (8, 16, 24 or 32; default: keep, or 16 if unknown)")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: pedalboard convert [-rate hz] [-bits n] input output")
+	}
+
+	buffer, err := pedalboard.LoadAudioFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *bitDepth == 0 {
+		*bitDepth = buffer.BitDepth
+		if *bitDepth == 0 {
+			*bitDepth = 16
+		}
+	}
+	if *sampleRate > 0 && *sampleRate != buffer.SampleRate {
+		if buffer, err = buffer.Resample(*sampleRate); err != nil {
+			return err
+		}
+	}
+	// The output format follows the output file's extension
+	return pedalboard.SaveAudioFileWithBitDepth(flags.Arg(1), buffer, *bitDepth)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Br1an6/go-pedalboard/pkg/pedalboard"
+)
+
+// writeSine writes one second of a 440 Hz mono sine to path.
+func writeSine(t *testing.T, path string, sampleRate float64, bitDepth int) {
+	t.Helper()
+	data := make([]float32, int(sampleRate))
+	for i := range data {
+		data[i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+	}
+	buffer := &pedalboard.AudioBuffer{Data: [][]float32{data}, SampleRate: sampleRate}
+	if err := pedalboard.SaveAudioFileWithBitDepth(path, buffer, bitDepth); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.wav")
+	writeSine(t, input, 44100, 24)
+
+	// Without flags the sample rate and bit depth are kept
+	kept := filepath.Join(dir, "kept.aiff")
+	if err := runConvert([]string{input, kept}); err != nil {
+		t.Fatalf("runConvert failed: %v", err)
+	}
+	buffer, err := pedalboard.LoadAudioFile(kept)
+	if err != nil {
+		t.Fatalf("LoadAudioFile failed: %v", err)
+	}
+	if buffer.SampleRate != 44100 || buffer.BitDepth != 24 {
+		t.Errorf("Expected 44100 Hz 24-bit, got %v Hz %d-bit", buffer.SampleRate, buffer.BitDepth)
+	}
+
+	converted := filepath.Join(dir, "converted.wav")
+	if err := runConvert([]string{"-rate", "48000", "-bits", "16", input, converted}); err != nil {
+		t.Fatalf("runConvert failed: %v", err)
+	}
+	buffer, err = pedalboard.LoadAudioFile(converted)
+	if err != nil {
+		t.Fatalf("LoadAudioFile failed: %v", err)
+	}
+	if buffer.SampleRate != 48000 || buffer.BitDepth != 16 || len(buffer.Data[0]) != 48000 {
+		t.Errorf("Expected 48000 16-bit samples at 48000 Hz, got %d %d-bit samples at %v Hz",
+			len(buffer.Data[0]), buffer.BitDepth, buffer.SampleRate)
+	}
+
+	if err := runConvert([]string{input}); err == nil {
+		t.Error("Expected usage error without an output")
+	}
+	if err := runConvert([]string{"-bits", "12", input, converted}); err == nil {
+		t.Error("Expected error for an unsupported bit depth")
+	}
+	if err := runConvert([]string{filepath.Join(dir, "missing.wav"), converted}); err == nil {
+		t.Error("Expected error for a missing input")
+	}
+}
+
+func TestRunProcess(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "take.wav")
+	writeSine(t, input, 44100, 16)
+	template := filepath.Join(dir, "chain.json")
+	if err := os.WriteFile(template, []byte(`{"processors": [{"name": "Gain"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := filepath.Join(dir, "out")
+	if err := runProcess([]string{"-template", template, "-out", outDir, input}); err != nil {
+		t.Fatalf("runProcess failed: %v", err)
+	}
+	buffer, err := pedalboard.LoadAudioFile(filepath.Join(outDir, "take.wav"))
+	if err != nil {
+		t.Fatalf("Expected a processed file: %v", err)
+	}
+	if len(buffer.Data) != 1 || len(buffer.Data[0]) != 44100 {
+		t.Errorf("Expected 1 channel of 44100 samples, got %d channels", len(buffer.Data))
+	}
+
+	if err := runProcess([]string{input}); err == nil {
+		t.Error("Expected usage error without a template")
+	}
+	if err := runProcess([]string{"-template", filepath.Join(dir, "missing.json"), input}); err == nil {
+		t.Error("Expected error for a missing template")
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.wav")
+	writeSine(t, input, 44100, 16)
+
+	if err := runProbe([]string{input}); err != nil {
+		t.Errorf("runProbe failed: %v", err)
+	}
+	if err := runProbe(nil); err == nil {
+		t.Error("Expected usage error without files")
+	}
+	if err := runProbe([]string{filepath.Join(dir, "missing.wav")}); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Br1an6/go-pedalboard/pkg/pedalboard"
+)
+
+// chainTemplate describes a processor chain as stored in a JSON template file:
+//
+//	{
+//	  "processors": [
+//	    {"name": "Compressor", "parameters": {"0": 0.4, "1": 0.3}},
+//	    {"plugin": "/Library/Audio/Plug-Ins/VST3/Example.vst3"}
+//	  ]
+//	}
+type chainTemplate struct {
+	Processors []processorTemplate `json:"processors"`
+}
+
+// processorTemplate describes one stage of a chain. Exactly one of Name
+// (an internal processor, as passed to NewInternalProcessor) or Plugin
+// (a plugin path, as passed to LoadPlugin) must be set.
+type processorTemplate struct {
+	Name   string `json:"name,omitempty"`
+	Plugin string `json:"plugin,omitempty"`
+	// Parameters maps parameter indexes to normalized values for SetParameter.
+	Parameters map[int]float32 `json:"parameters,omitempty"`
+}
+
+// loadChainTemplate reads and validates a chain template from a JSON file.
+func loadChainTemplate(path string) (*chainTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	var tmpl chainTemplate
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	if len(tmpl.Processors) == 0 {
+		return nil, fmt.Errorf("template %s has no processors", path)
+	}
+	for i, p := range tmpl.Processors {
+		if (p.Name == "") == (p.Plugin == "") {
+			return nil, fmt.Errorf("processor %d must set exactly one of name or plugin", i)
+		}
+	}
+	return &tmpl, nil
+}
+
+// build creates the processors described by the template and joins them into a chain.
+func (t *chainTemplate) build() (*pedalboard.ProcessorChain, error) {
+	processors := make([]*pedalboard.Processor, 0, len(t.Processors))
+	for i, pt := range t.Processors {
+		var p *pedalboard.Processor
+		var err error
+		if pt.Plugin != "" {
+			p, err = pedalboard.LoadPlugin(pt.Plugin)
+		} else {
+			p, err = pedalboard.NewInternalProcessor(pt.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("processor %d: %w", i, err)
+		}
+		for index, value := range pt.Parameters {
+			if index < 0 || index >= p.NumParameters() {
+				return nil, fmt.Errorf("processor %d: parameter index %d out of range (0-%d)", i, index, p.NumParameters()-1)
+			}
+			p.SetParameter(index, value)
+		}
+		processors = append(processors, p)
+	}
+	return pedalboard.NewProcessorChain(processors...)
+}
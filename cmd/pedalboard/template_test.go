@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadChainTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chain.json")
+	data := `{"processors": [{"name": "Gain", "parameters": {"0": 0.5}}, {"plugin": "/tmp/Example.vst3"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadChainTemplate(path)
+	if err != nil {
+		t.Fatalf("loadChainTemplate failed: %v", err)
+	}
+	if len(tmpl.Processors) != 2 {
+		t.Fatalf("Expected 2 processors, got %d", len(tmpl.Processors))
+	}
+	if tmpl.Processors[0].Name != "Gain" || tmpl.Processors[0].Parameters[0] != 0.5 {
+		t.Errorf("Unexpected first processor: %+v", tmpl.Processors[0])
+	}
+	if tmpl.Processors[1].Plugin != "/tmp/Example.vst3" {
+		t.Errorf("Unexpected second processor: %+v", tmpl.Processors[1])
+	}
+
+	invalid := map[string]string{
+		"empty.json": `{"processors": []}`,
+		"both.json":  `{"processors": [{"name": "Gain", "plugin": "/tmp/Example.vst3"}]}`,
+		"none.json":  `{"processors": [{"parameters": {"0": 1}}]}`,
+		"bad.json":   `{"processors": [`,
+	}
+	for name, contents := range invalid {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadChainTemplate(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestProcessedPath(t *testing.T) {
+	if got := processedPath("/music/take.wav", ""); got != "/music/take_processed.wav" {
+		t.Errorf("Unexpected path without output dir: %s", got)
+	}
+	if got := processedPath("/music/take.wav", "/out"); got != "/out/take.wav" {
+		t.Errorf("Unexpected path with output dir: %s", got)
+	}
+}
@@ -5,9 +5,17 @@
 #include <juce_events/juce_events.h>
 #include <juce_audio_basics/juce_audio_basics.h>
 #include <juce_dsp/juce_dsp.h>
+#if JUCE_MAC
+#include <AudioToolbox/AudioToolbox.h>
+#endif
 
 extern "C" {
 
+// Implemented in Go (xrun.go); invoked from the audio thread.
+void goPedalboardXrun(uintptr_t context, int xrun_type, long long unix_nanos);
+// Implemented in Go (sandbox.go); processes a block for an external processor.
+void goPedalboardProcessExternal(uintptr_t context, float** samples, int num_channels, int num_samples, double sample_rate);
+
 // --- Helper Functions ---
 float mapRange(float input, float min, float max) {
     return min + input * (max - min);
@@ -17,6 +25,47 @@ float mapRangeLog(float input, float min, float max) {
     return min * std::pow(max / min, input);
 }
 
+// How an internal processor turns a normalized parameter value (0 to 1) into the value it
+// uses: linearly or logarithmically from at0 (for 0) to at1 (for 1), in unit. passThrough
+// parameters take the value itself, anywhere from at0 to at1. The default is 0 to 1
+// without a unit.
+struct ParamMapping {
+    float at0 = 0.0f;
+    float at1 = 1.0f;
+    bool logarithmic = false;
+    const char* unit = "";
+    bool passThrough = false;
+
+    float toValue(float normalized) const {
+        if (passThrough) return normalized;
+        return logarithmic ? mapRangeLog(normalized, at0, at1) : mapRange(normalized, at0, at1);
+    }
+    float toNormalized(float value) const {
+        if (passThrough) return value;
+        return logarithmic ? std::log(value / at0) / std::log(at1 / at0) : (value - at0) / (at1 - at0);
+    }
+};
+
+// Returns a malloc'd UTF-8 copy of str; the caller frees it with free().
+static char* copyString(const juce::String& str) {
+    auto utf8 = str.toRawUTF8();
+    auto* result = (char*)malloc(strlen(utf8) + 1);
+    strcpy(result, utf8);
+    return result;
+}
+
+// Returns a list of copies of items; the caller frees it with pedalboard_string_list_free.
+static PedalboardStringList* newStringList(const juce::StringArray& items) {
+    auto* list = new PedalboardStringList();
+    list->count = items.size();
+    list->items = nullptr;
+    if (list->count > 0) {
+        list->items = (char**)malloc(sizeof(char*) * (size_t)list->count);
+        for (int i = 0; i < list->count; ++i) list->items[i] = copyString(items[i]);
+    }
+    return list;
+}
+
 class PedalboardInternal {
 public:
     PedalboardInternal() {
@@ -26,6 +75,9 @@ public:
         pluginFormatManager.addFormat(std::make_unique<juce::AudioUnitPluginFormat>());
 #endif
         pluginFormatManager.addFormat(std::make_unique<juce::VST3PluginFormat>());
+#if JUCE_PLUGINHOST_LV2
+        pluginFormatManager.addFormat(std::make_unique<juce::LV2PluginFormat>());
+#endif
     }
 
     juce::AudioFormatManager formatManager;
@@ -40,10 +92,42 @@ void pedalboard_init() {
     }
 }
 
+// Sub-block length, in samples, at which ramps are applied to plugins
+static constexpr int rampSubBlockSamples = 32;
+
+// A linear parameter ramp scheduled with pedalboard_processor_ramp_parameter
+struct ParameterRamp {
+    int index;
+    float start;
+    float target;
+    int totalSamples;
+    int elapsedSamples = 0;
+};
+
 struct ProcessorWrapper {
     std::unique_ptr<juce::AudioProcessor> processor;
     juce::AudioBuffer<float> buffer;
     juce::MidiBuffer midiBuffer;
+    juce::MidiBuffer midiOutput; // MIDI produced by the last processBlock call
+
+    // Pending ramps, guarded by rampLock since they may be set while a stream runs
+    juce::SpinLock rampLock;
+    std::vector<ParameterRamp> ramps;
+    juce::MidiBuffer rampMidiIn, rampMidiOut; // Scratch for splitting MIDI into sub-blocks
+
+    // Serializes pedalboard_processor_process calls made from several threads
+    std::mutex processLock;
+
+    // Samples processed since creation, read from other threads to time parameter events
+    std::atomic<int64_t> processedSamples { 0 };
+
+    // While bypassed the processor is not run; audio is delayed by its reported latency
+    // instead, through a circular buffer sized by prepareWrapper for the most latency the
+    // processor can report and otherwise only touched by the processing thread
+    std::atomic<bool> bypassed { false };
+    juce::AudioBuffer<float> bypassDelay;
+    int bypassDelayLength = 0; // The latency the delay currently runs at
+    int bypassDelayPos = 0;
 };
 
 // --- Base Processor Class ---
@@ -61,11 +145,16 @@ public:
         spec.sampleRate = sampleRate;
         spec.maximumBlockSize = (uint32_t)samplesPerBlock;
         spec.numChannels = (uint32_t)getTotalNumOutputChannels();
+        setRateAndBufferSizeDetails(sampleRate, samplesPerBlock);
         prepare(spec);
     }
     
     virtual void prepare(const juce::dsp::ProcessSpec& spec) {}
 
+    // The most latency the processor can report whatever its parameters, so delays that
+    // compensate for it can be sized before processing starts.
+    virtual int getMaxLatencySamples() { return getLatencySamples(); }
+
     void releaseResources() override { reset(); }
     virtual void reset() {}
 
@@ -79,8 +168,46 @@ public:
     void setCurrentProgram(int) override {}
     const juce::String getProgramName(int) override { return {}; }
     void changeProgramName(int, const juce::String&) override {}
-    void getStateInformation(juce::MemoryBlock&) override {}
-    void setStateInformation(const void*, int) override {}
+
+    // State is the processor name and every parameter value, stored as XML in JUCE's
+    // binary wrapper. Processors with state beyond their parameters add it with
+    // saveExtraState and restore it with loadExtraState.
+    void getStateInformation(juce::MemoryBlock& destData) override {
+        juce::XmlElement xml("PedalboardPreset");
+        xml.setAttribute("processor", procName);
+        for (int i = 0; i < getNumParams(); ++i) {
+            auto* param = xml.createNewChildElement("Param");
+            param->setAttribute("index", i);
+            param->setAttribute("value", (double)getParam(i));
+        }
+        saveExtraState(xml);
+        copyXmlToBinary(xml, destData);
+    }
+    void setStateInformation(const void* data, int sizeInBytes) override { loadState(data, sizeInBytes); }
+
+    // Restores state written by getStateInformation. Returns false, leaving the
+    // parameters unchanged, if data is not state of this kind of processor.
+    bool loadState(const void* data, int sizeInBytes) {
+        auto xml = getXmlFromBinary(data, sizeInBytes);
+        if (!xml || !xml->hasTagName("PedalboardPreset") || xml->getStringAttribute("processor") != procName) return false;
+
+        std::vector<std::pair<int, float>> values;
+        for (auto* param : xml->getChildWithTagNameIterator("Param")) {
+            int index = param->getIntAttribute("index", -1);
+            double value = param->getDoubleAttribute("value", std::nan(""));
+            if (!juce::isPositiveAndBelow(index, getNumParams()) || !std::isfinite(value)) return false;
+            values.emplace_back(index, (float)value);
+        }
+        if (!loadExtraState(*xml)) return false;
+        for (auto& [index, value] : values) setParam(index, value);
+        return true;
+    }
+
+    // Adds state that parameters don't cover to the preset XML.
+    virtual void saveExtraState(juce::XmlElement&) {}
+    // Restores what saveExtraState added. Returns false, changing nothing, if it is
+    // missing or invalid.
+    virtual bool loadExtraState(const juce::XmlElement&) { return true; }
     juce::AudioProcessorEditor* createEditor() override { return nullptr; }
     bool hasEditor() const override { return false; }
 
@@ -88,10 +215,208 @@ public:
     virtual float getParam(int index) = 0;
     virtual int getNumParams() = 0;
 
+    // How the processor maps a normalized parameter value; see ParamMapping.
+    virtual ParamMapping getParamMapping(int) { return {}; }
+
+    // Momentary action for processors that support one (e.g., Freeze). Returns false if unsupported.
+    virtual bool trigger(bool) { return false; }
+
+    // Labels of an enumerated parameter, in order of increasing value; empty if continuous.
+    // Choice i of n is selected by the normalized value i / (n - 1).
+    virtual juce::StringArray getParamChoices(int) { return {}; }
+
+    // Current envelope level of a channel for processors that track one (e.g.,
+    // WaveformFollower). Returns false if unsupported or channel is out of range.
+    virtual bool getEnvelopeLevel(int, float&) { return false; }
+
+    // Records the current parameter values as the defaults reported by getParamDefault.
+    // Called by the factory once the processor is constructed.
+    void captureParamDefaults() {
+        paramDefaults.clear();
+        for (int i = 0; i < getNumParams(); ++i) paramDefaults.push_back(getParam(i));
+    }
+
+    // Normalized value of a parameter when the processor was created.
+    float getParamDefault(int index) const {
+        return juce::isPositiveAndBelow(index, (int)paramDefaults.size()) ? paramDefaults[(size_t)index] : 0.0f;
+    }
+
 private:
     juce::String procName;
+    std::vector<float> paramDefaults;
 };
 
+// --- Parameter Helpers ---
+static void setWrapperParameter(ProcessorWrapper* wrapper, int index, float value) {
+    // Check if it's our internal base class
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        internal->setParam(index, value);
+        return;
+    }
+
+    // External plugin
+    auto& params = wrapper->processor->getParameters();
+    if (index >= 0 && index < params.size()) {
+        params[index]->setValueNotifyingHost(value);
+    }
+}
+
+static float getWrapperParameter(ProcessorWrapper* wrapper, int index) {
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        return internal->getParam(index);
+    }
+
+    auto& params = wrapper->processor->getParameters();
+    if (index >= 0 && index < params.size()) {
+        return params[index]->getValue();
+    }
+    return 0.0f;
+}
+
+// Text of a parameter's current value. Internal processors report the choice label of
+// enumerated parameters and the mapped value with its unit otherwise (e.g., "-6.00 dB");
+// plugins report their own text.
+static juce::String getWrapperParameterText(ProcessorWrapper* wrapper, int index) {
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        float value = internal->getParam(index);
+        auto choices = internal->getParamChoices(index);
+        if (choices.size() > 1) return choices[juce::roundToInt(value * (float)(choices.size() - 1))];
+        auto mapping = internal->getParamMapping(index);
+        juce::String text(mapping.toValue(value), 2);
+        if (*mapping.unit == '\0') return text;
+        // Ratios read "4.00:1"; other units are separated by a space
+        return text + (*mapping.unit == ':' ? "" : " ") + mapping.unit;
+    }
+    return wrapper->processor->getParameters()[index]->getCurrentValueAsText();
+}
+
+// Parses text into a normalized value for a parameter. Enumerated parameters accept one
+// of their labels (ignoring case); internal continuous parameters accept a value in their
+// mapped range, optionally followed by their unit (e.g., "-6 dB", "440 Hz"), and plugin
+// parameters anything their own parser reads as a number. Returns false if text is not
+// a valid value.
+static bool getWrapperParameterValueForText(ProcessorWrapper* wrapper, int index, const juce::String& text, float& value) {
+    auto trimmed = text.trim();
+    auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get());
+    juce::AudioProcessorParameter* param = internal ? nullptr : wrapper->processor->getParameters()[index];
+
+    juce::StringArray choices;
+    if (internal) choices = internal->getParamChoices(index);
+    else if (param->isDiscrete()) choices = param->getAllValueStrings();
+    if (choices.size() > 0) {
+        int choice = choices.indexOf(trimmed, true);
+        if (choice < 0) return false;
+        if (param) value = param->getValueForText(choices[choice]);
+        else value = choices.size() > 1 ? (float)choice / (float)(choices.size() - 1) : 0.0f;
+        return true;
+    }
+
+    if (!trimmed.containsAnyOf("0123456789")) return false;
+    if (param) {
+        value = param->getValueForText(trimmed);
+        return true;
+    }
+    auto mapping = internal->getParamMapping(index);
+    if (*mapping.unit != '\0' && trimmed.endsWithIgnoreCase(mapping.unit)) {
+        trimmed = trimmed.dropLastCharacters((int)strlen(mapping.unit)).trimEnd();
+    }
+    if (!trimmed.containsOnly("0123456789.-+eE")) return false;
+    float mapped = trimmed.getFloatValue();
+    // Allow for the rounding of displayed values at the ends of the range
+    float lo = juce::jmin(mapping.at0, mapping.at1), hi = juce::jmax(mapping.at0, mapping.at1);
+    float tolerance = std::isfinite(hi - lo) ? (hi - lo) * 1e-4f : 0.0f;
+    if (!std::isfinite(mapped) || mapped < lo - tolerance || mapped > hi + tolerance) return false;
+    value = mapping.passThrough ? juce::jlimit(lo, hi, mapped) : juce::jlimit(0.0f, 1.0f, mapping.toNormalized(juce::jlimit(lo, hi, mapped)));
+    return true;
+}
+
+static int wrapperLatency(ProcessorWrapper* wrapper);
+static void prepareWrapper(ProcessorWrapper* wrapper, double sampleRate, int blockSize, int numChannels);
+
+// Passes buffer through a bypassed processor: the audio is delayed by the processor's
+// latency so switching bypass does not shift the signal in time, and MIDI is left as is.
+// Latency beyond what the delay was prepared for, and channels beyond the prepared count,
+// pass through undelayed rather than allocating on the audio thread.
+static void bypassWrapper(ProcessorWrapper* wrapper, juce::AudioBuffer<float>& buffer) {
+    int latency = wrapperLatency(wrapper);
+    auto& delay = wrapper->bypassDelay;
+    if (latency <= 0 || latency > delay.getNumSamples()) return;
+    if (latency != wrapper->bypassDelayLength) {
+        delay.clear();
+        wrapper->bypassDelayLength = latency;
+        wrapper->bypassDelayPos = 0;
+    }
+
+    int numSamples = buffer.getNumSamples();
+    int pos = wrapper->bypassDelayPos;
+    for (int ch = 0; ch < juce::jmin(buffer.getNumChannels(), delay.getNumChannels()); ++ch) {
+        float* data = buffer.getWritePointer(ch);
+        float* line = delay.getWritePointer(ch);
+        int p = pos;
+        for (int i = 0; i < numSamples; ++i) {
+            std::swap(data[i], line[p]);
+            if (++p == latency) p = 0;
+        }
+    }
+    wrapper->bypassDelayPos = (pos + numSamples) % latency;
+}
+
+// Runs the wrapped processor on buffer, applying pending parameter ramps. While any ramp
+// is running the buffer is processed in sub-blocks, each with the ramp values reached at
+// its end: single samples for internal processors, which are cheap to call, and
+// rampSubBlockSamples for plugins. MIDI events are passed to the sub-block they fall in.
+// rampLock is only held while the ramps are advanced and their values set, not while the
+// processor runs, so scheduling or cancelling a ramp never waits for a block.
+static void processWrapper(ProcessorWrapper* wrapper, juce::AudioBuffer<float>& buffer, juce::MidiBuffer& midi) {
+    wrapper->processedSamples += buffer.getNumSamples();
+    if (wrapper->bypassed.load()) {
+        bypassWrapper(wrapper, buffer);
+        return;
+    }
+    bool ramping;
+    {
+        const juce::SpinLock::ScopedLockType sl(wrapper->rampLock);
+        ramping = !wrapper->ramps.empty();
+    }
+    if (!ramping) {
+        wrapper->processor->processBlock(buffer, midi);
+        return;
+    }
+
+    int numSamples = buffer.getNumSamples();
+    int step = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get()) ? 1 : rampSubBlockSamples;
+    wrapper->rampMidiOut.clear();
+    int pos = 0;
+    while (pos < numSamples) {
+        int length = juce::jmin(step, numSamples - pos);
+        {
+            // Values are set under the lock, so a value set directly in the meantime,
+            // which cancels the ramp first, is never overwritten by a stale ramp value
+            const juce::SpinLock::ScopedLockType sl(wrapper->rampLock);
+            if (wrapper->ramps.empty()) {
+                length = numSamples - pos;
+            } else {
+                for (auto& ramp : wrapper->ramps) {
+                    ramp.elapsedSamples = juce::jmin(ramp.elapsedSamples + length, ramp.totalSamples);
+                    float t = (float)ramp.elapsedSamples / (float)ramp.totalSamples;
+                    setWrapperParameter(wrapper, ramp.index, ramp.elapsedSamples >= ramp.totalSamples
+                        ? ramp.target : ramp.start + (ramp.target - ramp.start) * t);
+                }
+                wrapper->ramps.erase(std::remove_if(wrapper->ramps.begin(), wrapper->ramps.end(),
+                    [](const ParameterRamp& r) { return r.elapsedSamples >= r.totalSamples; }), wrapper->ramps.end());
+            }
+        }
+
+        juce::AudioBuffer<float> block(buffer.getArrayOfWritePointers(), buffer.getNumChannels(), pos, length);
+        wrapper->rampMidiIn.clear();
+        wrapper->rampMidiIn.addEvents(midi, pos, length, -pos);
+        wrapper->processor->processBlock(block, wrapper->rampMidiIn);
+        wrapper->rampMidiOut.addEvents(wrapper->rampMidiIn, 0, -1, pos);
+        pos += length;
+    }
+    midi.swapWith(wrapper->rampMidiOut);
+}
+
 
 // --- Gain ---
 class GainProcessor : public BaseInternalProcessor {
@@ -116,6 +441,12 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 1; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.0f, std::numeric_limits<float>::infinity(), false, "x", true };
+        }
+        return {};
+    }
 
     juce::dsp::Gain<float> gain;
 };
@@ -220,6 +551,12 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 3; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.0f, 2.0f, false, "s" };
+        }
+        return {};
+    }
 
     juce::dsp::DelayLine<float, juce::dsp::DelayLineInterpolationTypes::Linear> delayLine;
     double sampleRate = 44100.0;
@@ -262,6 +599,12 @@ public:
     }
     float getParam(int index) override { return drive; }
     int getNumParams() override { return 1; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 1.0f, 50.0f, true, "x" };
+        }
+        return {};
+    }
 
     float drive = 0.5f; // 0-1
     juce::dsp::Gain<float> inputGain, outputGain;
@@ -290,6 +633,12 @@ public:
     }
     float getParam(int index) override { return threshold; }
     int getNumParams() override { return 1; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.1f, 1.0f };
+        }
+        return {};
+    }
 
     float threshold = 1.0f; // 1.0 = no clipping (if signal normalized), 0.1 = heavy
 };
@@ -334,6 +683,14 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 5; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.1f, 5.0f, false, "Hz" };
+            case 2: return { 1.0f, 30.0f, false, "ms" };
+            case 3: return { -0.9f, 0.9f };
+        }
+        return {};
+    }
     
     float rate = 0.2f, depth = 0.5f, delay = 0.2f, feedback = 0.5f, mix = 0.5f;
     juce::dsp::Chorus<float> chorus;
@@ -379,31 +736,65 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 5; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.1f, 10.0f, false, "Hz" };
+            case 2: return { 100.0f, 5000.0f, true, "Hz" };
+            case 3: return { -0.9f, 0.9f };
+        }
+        return {};
+    }
     
     float rate = 0.1f, depth = 0.5f, freq = 0.5f, feedback = 0.5f, mix = 0.5f;
     juce::dsp::Phaser<float> phaser;
 };
 
 // --- Compressor ---
+// Peak envelope with attack/release ballistics, as juce::dsp::Compressor, and a gain
+// computer with an optional soft knee: over the Knee width (in dB) centred on the
+// threshold the ratio fades in quadratically. A knee of 0 is a hard knee.
 class CompressorProcessor : public BaseInternalProcessor {
 public:
     CompressorProcessor() : BaseInternalProcessor("Compressor") {}
     
     void prepare(const juce::dsp::ProcessSpec& spec) override {
-        compressor.prepare(spec);
+        envelope.prepare(spec);
+        envelope.setLevelCalculationType(juce::dsp::BallisticsFilterLevelCalculationType::peak);
         update();
     }
+
+    void reset() override { envelope.reset(); }
     
     void update() {
-        compressor.setThreshold(mapRange(threshold, -60.0f, 0.0f));
-        compressor.setRatio(mapRange(ratio, 1.0f, 20.0f));
-        compressor.setAttack(mapRange(attack, 1.0f, 200.0f));
-        compressor.setRelease(mapRange(release, 20.0f, 500.0f));
+        envelope.setAttackTime(mapRange(attack, 1.0f, 200.0f));
+        envelope.setReleaseTime(mapRange(release, 20.0f, 500.0f));
+    }
+
+    // Gain reduction in dB (<= 0) for an envelope level in dB
+    static float gainComputer(float levelDb, float thresholdDb, float ratioValue, float kneeDb) {
+        float over = levelDb - thresholdDb;
+        if (2.0f * over < -kneeDb) return 0.0f;
+        if (2.0f * std::abs(over) <= kneeDb) {
+            float x = over + kneeDb / 2.0f;
+            return (1.0f / ratioValue - 1.0f) * x * x / (2.0f * kneeDb);
+        }
+        return (1.0f / ratioValue - 1.0f) * over;
     }
 
     void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
-        juce::dsp::AudioBlock<float> block(buffer);
-        compressor.process(juce::dsp::ProcessContextReplacing<float>(block));
+        float thresholdDb = mapRange(threshold, -60.0f, 0.0f);
+        float ratioValue = mapRange(ratio, 1.0f, 20.0f);
+        float kneeDb = mapRange(knee, 0.0f, 12.0f);
+
+        int numChannels = juce::jmin(buffer.getNumChannels(), (int)getTotalNumOutputChannels());
+        for (int ch = 0; ch < numChannels; ++ch) {
+            auto* data = buffer.getWritePointer(ch);
+            for (int i = 0; i < buffer.getNumSamples(); ++i) {
+                float env = envelope.processSample(ch, data[i]);
+                float levelDb = juce::Decibels::gainToDecibels(env, -200.0f);
+                data[i] *= juce::Decibels::decibelsToGain(gainComputer(levelDb, thresholdDb, ratioValue, kneeDb));
+            }
+        }
     }
 
     void setParam(int index, float value) override {
@@ -411,6 +802,7 @@ public:
         else if (index == 1) ratio = value;
         else if (index == 2) attack = value;
         else if (index == 3) release = value;
+        else if (index == 4) knee = value;
         update();
     }
     float getParam(int index) override {
@@ -418,12 +810,24 @@ public:
         if (index == 1) return ratio;
         if (index == 2) return attack;
         if (index == 3) return release;
+        if (index == 4) return knee;
         return 0.0f;
     }
-    int getNumParams() override { return 4; }
+    int getNumParams() override { return 5; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { -60.0f, 0.0f, false, "dB" };
+            case 1: return { 1.0f, 20.0f, false, ":1" };
+            case 2: return { 1.0f, 200.0f, false, "ms" };
+            case 3: return { 20.0f, 500.0f, false, "ms" };
+            case 4: return { 0.0f, 12.0f, false, "dB" };
+        }
+        return {};
+    }
     
     float threshold = 0.8f, ratio = 0.2f, attack = 0.1f, release = 0.2f;
-    juce::dsp::Compressor<float> compressor;
+    float knee = 0.0f; // 0-1 mapped to 0-12 dB, 0 = hard knee
+    juce::dsp::BallisticsFilter<float> envelope;
 };
 
 // --- Limiter ---
@@ -457,11 +861,120 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 2; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { -20.0f, 0.0f, false, "dB" };
+            case 1: return { 10.0f, 500.0f, false, "ms" };
+        }
+        return {};
+    }
     
     float threshold = 1.0f, release = 0.2f;
     juce::dsp::Limiter<float> limiter;
 };
 
+// --- Noise Gate ---
+// Attenuates the signal by 1/Ratio while its RMS level, measured over a 10 ms window
+// across all channels, stays below the threshold. The gate opens over Attack, stays open
+// for Hold once the level drops, then closes over Release, all with linear gain ramps.
+class NoiseGateProcessor : public BaseInternalProcessor {
+public:
+    NoiseGateProcessor() : BaseInternalProcessor("NoiseGate") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        window.assign((size_t)juce::jmax(1, (int)std::round(sampleRate * 0.01)), 0.0);
+        reset();
+    }
+
+    void reset() override {
+        std::fill(window.begin(), window.end(), 0.0);
+        windowPos = 0;
+        windowFilled = 0;
+        windowSum = 0.0;
+        holdRemaining = 0;
+        gain = 1.0f;
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        if (window.empty()) return;
+
+        double thresholdPower = std::pow(10.0, mapRange(threshold, -80.0f, 0.0f) / 10.0);
+        float closedGain = 1.0f - ratio; // 1/Ratio, with Ratio = 1/(1 - ratio)
+        float samplesPerMs = (float)sampleRate * 0.001f;
+        float attackStep = (1.0f - closedGain) / juce::jmax(1.0f, mapRange(attack, 0.1f, 50.0f) * samplesPerMs);
+        float releaseStep = (1.0f - closedGain) / juce::jmax(1.0f, mapRange(release, 5.0f, 1000.0f) * samplesPerMs);
+        int holdSamples = (int)std::round(mapRange(hold, 0.0f, 500.0f) * samplesPerMs);
+        int windowSize = (int)window.size();
+
+        int numChannels = buffer.getNumChannels();
+        for (int i = 0; i < buffer.getNumSamples(); ++i) {
+            double power = 0.0;
+            for (int ch = 0; ch < numChannels; ++ch) {
+                double x = buffer.getSample(ch, i);
+                power += x * x;
+            }
+            power /= juce::jmax(1, numChannels);
+            windowSum += power - window[(size_t)windowPos];
+            window[(size_t)windowPos] = power;
+            if (++windowPos == windowSize) {
+                windowPos = 0;
+                // Resynchronize the running sum so rounding errors don't build up
+                windowSum = std::accumulate(window.begin(), window.end(), 0.0);
+            }
+            windowFilled = juce::jmin(windowFilled + 1, windowSize);
+
+            // The gate cannot close before a full window has been measured
+            bool open = windowFilled < windowSize || windowSum / windowSize >= thresholdPower;
+            if (open) holdRemaining = holdSamples;
+            else if (holdRemaining > 0) { --holdRemaining; open = true; }
+
+            gain = open ? juce::jmin(1.0f, gain + attackStep) : juce::jmax(closedGain, gain - releaseStep);
+            if (gain != 1.0f) {
+                for (int ch = 0; ch < numChannels; ++ch) buffer.getWritePointer(ch)[i] *= gain;
+            }
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) threshold = value;
+        else if (index == 1) attack = value;
+        else if (index == 2) hold = value;
+        else if (index == 3) release = value;
+        else if (index == 4) ratio = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return threshold;
+        if (index == 1) return attack;
+        if (index == 2) return hold;
+        if (index == 3) return release;
+        if (index == 4) return ratio;
+        return 0.0f;
+    }
+    int getNumParams() override { return 5; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { -80.0f, 0.0f, false, "dB" };
+            case 1: return { 0.1f, 50.0f, false, "ms" };
+            case 2: return { 0.0f, 500.0f, false, "ms" };
+            case 3: return { 5.0f, 1000.0f, false, "ms" };
+        }
+        return {};
+    }
+
+    double sampleRate = 44100.0;
+    float threshold = 0.5f; // 0-1 mapped to -80 to 0 dBFS
+    float attack = 0.1f;    // 0-1 mapped to 0.1-50 ms
+    float hold = 0.1f;      // 0-1 mapped to 0-500 ms
+    float release = 0.1f;   // 0-1 mapped to 5-1000 ms
+    float ratio = 1.0f;     // 0-1 mapped to 1:1 to infinity (closed gain 1 - ratio)
+    std::vector<double> window; // Mean square of each sample in the detector window
+    int windowPos = 0, windowFilled = 0;
+    double windowSum = 0.0;
+    int holdRemaining = 0;
+    float gain = 1.0f;
+};
+
 // --- Filters (IIR) ---
 enum FilterType { LowPass, HighPass };
 class FilterProcessor : public BaseInternalProcessor {
@@ -500,6 +1013,13 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 2; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 20.0f, 20000.0f, true, "Hz" };
+            case 1: return { 0.1f, 10.0f };
+        }
+        return {};
+    }
     
     FilterType type;
     double sampleRate = 44100.0;
@@ -543,6 +1063,13 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 3; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 20.0f, 20000.0f, true, "Hz" };
+            case 2: return { 1.0f, 5.0f, false, "x" };
+        }
+        return {};
+    }
     
     float cutoff = 0.5f, resonance = 0.0f, drive = 0.0f;
     juce::dsp::LadderFilter<float> ladder;
@@ -588,110 +1115,2176 @@ public:
         return 0.0f;
     }
     int getNumParams() override { return 2; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 32.0f, 2.0f, false, "bits" };
+            case 1: return { 1.0f, 50.0f, false, "x" };
+        }
+        return {};
+    }
 
     float bitDepth = 0.0f; // 0 (32bit) -> 1 (2bit)
     float downsample = 0.0f; // 0 (1x) -> 1 (50x)
 };
 
+// --- Parametric EQ ---
+// Five bands: low shelf, three peaks, high shelf. Parameters are (band * 3) + {0=freq, 1=gain, 2=Q}.
+class ParametricEQProcessor : public BaseInternalProcessor {
+public:
+    static constexpr int kNumBands = 5;
 
-// --- Factory ---
-
-PedalboardProcessor pedalboard_create_internal_processor(const char* name) {
-    juce::String processorName(name);
-    
-    std::unique_ptr<BaseInternalProcessor> proc;
+    ParametricEQProcessor() : BaseInternalProcessor("ParametricEQ") {
+        const float defaultFreqs[kNumBands] = { 100.0f, 250.0f, 1000.0f, 4000.0f, 8000.0f };
+        for (int b = 0; b < kNumBands; ++b) {
+            // Inverse of mapRangeLog(x, 20, 20000)
+            bands[b].freq = std::log(defaultFreqs[b] / 20.0f) / std::log(1000.0f);
+        }
+    }
 
-    if (processorName == "Gain") proc = std::make_unique<GainProcessor>();
-    else if (processorName == "Reverb") proc = std::make_unique<ReverbProcessor>();
-    else if (processorName == "Chorus") proc = std::make_unique<ChorusProcessor>();
-    else if (processorName == "Distortion") proc = std::make_unique<DistortionProcessor>();
-    else if (processorName == "Clipping") proc = std::make_unique<ClippingProcessor>();
-    else if (processorName == "Phaser") proc = std::make_unique<PhaserProcessor>();
-    else if (processorName == "Compressor") proc = std::make_unique<CompressorProcessor>();
-    else if (processorName == "Limiter") proc = std::make_unique<LimiterProcessor>();
-    else if (processorName == "Delay") proc = std::make_unique<DelayProcessor>();
-    else if (processorName == "LowPass") proc = std::make_unique<FilterProcessor>(LowPass);
-    else if (processorName == "HighPass") proc = std::make_unique<FilterProcessor>(HighPass);
-    else if (processorName == "LadderFilter") proc = std::make_unique<LadderProcessor>();
-    else if (processorName == "Bitcrush") proc = std::make_unique<BitcrushProcessor>();
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        for (auto& band : bands) band.filter.prepare(spec);
+        for (int b = 0; b < kNumBands; ++b) update(b);
+    }
 
-    if (proc) {
-        auto wrapper = new ProcessorWrapper();
-        wrapper->processor = std::move(proc);
-        return static_cast<PedalboardProcessor>(wrapper);
+    void reset() override {
+        for (auto& band : bands) band.filter.reset();
     }
-    
-    return nullptr;
-}
 
-// ... Rest of the file (LoadPlugin, AudioIO, Stream) ...
+    void update(int b) {
+        auto& band = bands[b];
+        float freqHz = juce::jmin(mapRangeLog(band.freq, 20.0f, 20000.0f), (float)sampleRate * 0.49f);
+        float gainDb = mapRange(band.gain, -15.0f, 15.0f);
+        float qVal = mapRangeLog(band.q, 0.1f, 10.0f);
+        float gainFactor = juce::Decibels::decibelsToGain(gainDb);
+        band.active = gainDb != 0.0f;
 
-PedalboardProcessor pedalboard_load_plugin(const char* path) {
-    pedalboard_init();
-    juce::File file(path);
-    juce::OwnedArray<juce::PluginDescription> descriptions;
-    
-    for (int i = 0; i < g_internal->pluginFormatManager.getNumFormats(); ++i) {
-        auto* format = g_internal->pluginFormatManager.getFormat(i);
-        format->findAllTypesForFile(descriptions, path);
-        if (descriptions.size() > 0) break;
+        if (b == 0)
+            *band.filter.state = *juce::dsp::IIR::Coefficients<float>::makeLowShelf(sampleRate, freqHz, qVal, gainFactor);
+        else if (b == kNumBands - 1)
+            *band.filter.state = *juce::dsp::IIR::Coefficients<float>::makeHighShelf(sampleRate, freqHz, qVal, gainFactor);
+        else
+            *band.filter.state = *juce::dsp::IIR::Coefficients<float>::makePeakFilter(sampleRate, freqHz, qVal, gainFactor);
     }
-    
-    if (descriptions.size() == 0) return nullptr;
-    
-    juce::String error;
-    auto plugin = g_internal->pluginFormatManager.createPluginInstance(*descriptions[0], 44100.0, 512, error);
-    if (plugin == nullptr) return nullptr;
-    
-    auto wrapper = new ProcessorWrapper();
-    wrapper->processor = std::move(plugin);
-    return static_cast<PedalboardProcessor>(wrapper);
-}
 
-PedalboardAudioBuffer* pedalboard_load_audio_file(const char* path) {
-    pedalboard_init();
-    juce::File file(path);
-    std::unique_ptr<juce::AudioFormatReader> reader(g_internal->formatManager.createReaderFor(file));
-    if (reader == nullptr) return nullptr;
-    
-    auto* result = new PedalboardAudioBuffer();
-    result->num_channels = (int)reader->numChannels;
-    result->num_samples = (int)reader->lengthInSamples;
-    result->sample_rate = reader->sampleRate;
-    
-    result->data = (float**)malloc(sizeof(float*) * result->num_channels);
-    for (int i = 0; i < result->num_channels; ++i) {
-        result->data[i] = (float*)malloc(sizeof(float) * result->num_samples);
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        juce::dsp::AudioBlock<float> block(buffer);
+        for (auto& band : bands) {
+            // Bands at 0 dB are skipped so a flat EQ is bit-transparent
+            if (band.active) band.filter.process(juce::dsp::ProcessContextReplacing<float>(block));
+        }
     }
-    
-    juce::AudioBuffer<float> tempBuffer(result->data, result->num_channels, result->num_samples);
-    reader->read(&tempBuffer, 0, result->num_samples, 0, true, true);
-    return result;
-}
 
-void pedalboard_save_audio_file(const char* path, PedalboardAudioBuffer* buffer) {
-    if (buffer == nullptr) return;
-    pedalboard_init();
-    juce::File file(path);
-    if (file.existsAsFile()) file.deleteFile();
-    
-    auto* format = g_internal->formatManager.findFormatForFileExtension(file.getFileExtension());
-    if (format == nullptr) format = g_internal->formatManager.getDefaultFormat();
-    if (format == nullptr) return;
-    
-    auto* stream = new juce::FileOutputStream(file);
-    std::unique_ptr<juce::AudioFormatWriter> writer(format->createWriterFor(stream, 
-                                                                         buffer->sample_rate, 
-                                                                         (unsigned int)buffer->num_channels, 
-                                                                         16, 
-                                                                         {}, 
-                                                                         0));
-    
-    if (writer != nullptr) {
-        juce::AudioBuffer<float> tempBuffer(buffer->data, buffer->num_channels, buffer->num_samples);
-        writer->writeFromAudioSampleBuffer(tempBuffer, 0, buffer->num_samples);
+    void setParam(int index, float value) override {
+        if (index < 0 || index >= kNumBands * 3) return;
+        auto& band = bands[index / 3];
+        if (index % 3 == 0) band.freq = value;
+        else if (index % 3 == 1) band.gain = value;
+        else band.q = value;
+        update(index / 3);
+    }
+    float getParam(int index) override {
+        if (index < 0 || index >= kNumBands * 3) return 0.0f;
+        auto& band = bands[index / 3];
+        if (index % 3 == 0) return band.freq;
+        if (index % 3 == 1) return band.gain;
+        return band.q;
+    }
+    int getNumParams() override { return kNumBands * 3; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index % 3) {
+            case 0: return { 20.0f, 20000.0f, true, "Hz" };
+            case 1: return { -15.0f, 15.0f, false, "dB" };
+            default: return { 0.1f, 10.0f, true };
+        }
     }
-}
+
+    struct Band {
+        float freq = 0.5f;  // 0-1 mapped to 20-20000 Hz (log)
+        float gain = 0.5f;  // 0-1 mapped to -15 to +15 dB
+        float q = 0.5f;     // 0-1 mapped to 0.1-10 (log)
+        bool active = false;
+        juce::dsp::ProcessorDuplicator<juce::dsp::IIR::Filter<float>, juce::dsp::IIR::Coefficients<float>> filter;
+    };
+
+    double sampleRate = 44100.0;
+    Band bands[kNumBands];
+};
+
+// --- Tremolo ---
+class TremoloProcessor : public BaseInternalProcessor {
+public:
+    TremoloProcessor() : BaseInternalProcessor("Tremolo") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        reset();
+    }
+
+    void reset() override { phase = 0.0; }
+
+    // LFO in the range 0-1 for the selected shape
+    float lfo(double p) const {
+        int waveform = (int)std::round(shape * 2.0f); // 0=sine, 1=triangle, 2=square
+        if (waveform == 0) return 0.5f + 0.5f * (float)std::sin(juce::MathConstants<double>::twoPi * p);
+        if (waveform == 1) return (float)(p < 0.5 ? 2.0 * p : 2.0 - 2.0 * p);
+        return p < 0.5 ? 1.0f : 0.0f;
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        double increment = mapRangeLog(rate, 0.1f, 20.0f) / sampleRate;
+        double startPhase = phase;
+        for (int ch = 0; ch < buffer.getNumChannels(); ++ch) {
+            auto* data = buffer.getWritePointer(ch);
+            double p = startPhase;
+            for (int i = 0; i < buffer.getNumSamples(); ++i) {
+                data[i] *= 1.0f - depth * lfo(p);
+                p += increment;
+                if (p >= 1.0) p -= 1.0;
+            }
+            phase = p;
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) rate = value;
+        else if (index == 1) depth = value;
+        else if (index == 2) shape = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return rate;
+        if (index == 1) return depth;
+        if (index == 2) return shape;
+        return 0.0f;
+    }
+    int getNumParams() override { return 3; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.1f, 20.0f, true, "Hz" };
+        }
+        return {};
+    }
+    juce::StringArray getParamChoices(int index) override {
+        if (index == 2) return { "Sine", "Triangle", "Square" };
+        return {};
+    }
+
+    double sampleRate = 44100.0;
+    double phase = 0.0;
+    float rate = 0.5f;  // 0-1 mapped to 0.1-20 Hz (log)
+    float depth = 0.5f;
+    float shape = 0.0f; // 0=sine, 0.5=triangle, 1=square
+};
+
+// --- Flanger ---
+class FlangerProcessor : public BaseInternalProcessor {
+public:
+    FlangerProcessor() : BaseInternalProcessor("Flanger") {
+        delayLine.setMaximumDelayInSamples(4096); // > 14 ms at 192 kHz
+    }
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        delayLine.prepare(spec);
+        lastOutput.assign(spec.numChannels, 0.0f);
+        phase = 0.0;
+    }
+
+    void reset() override {
+        delayLine.reset();
+        std::fill(lastOutput.begin(), lastOutput.end(), 0.0f);
+        phase = 0.0;
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        int numChannels = juce::jmin(buffer.getNumChannels(), (int)lastOutput.size());
+        double increment = mapRangeLog(rate, 0.05f, 5.0f) / sampleRate;
+        float baseMs = mapRange(baseDelay, 0.5f, 7.0f);
+        float depthMs = mapRange(depth, 0.5f, 7.0f);
+        float fb = mapRange(feedback, 0.0f, 0.99f);
+
+        for (int i = 0; i < buffer.getNumSamples(); ++i) {
+            float lfo = 0.5f + 0.5f * (float)std::sin(juce::MathConstants<double>::twoPi * phase);
+            float delaySamples = (baseMs + depthMs * lfo) * 0.001f * (float)sampleRate;
+            for (int ch = 0; ch < numChannels; ++ch) {
+                auto* data = buffer.getWritePointer(ch);
+                float input = data[i];
+                delayLine.pushSample(ch, input + lastOutput[(size_t)ch] * fb);
+                float wet = delayLine.popSample(ch, delaySamples);
+                lastOutput[(size_t)ch] = wet;
+                data[i] = input * (1.0f - mix) + wet * mix;
+            }
+            phase += increment;
+            if (phase >= 1.0) phase -= 1.0;
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) rate = value;
+        else if (index == 1) depth = value;
+        else if (index == 2) feedback = value;
+        else if (index == 3) baseDelay = value;
+        else if (index == 4) mix = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return rate;
+        if (index == 1) return depth;
+        if (index == 2) return feedback;
+        if (index == 3) return baseDelay;
+        if (index == 4) return mix;
+        return 0.0f;
+    }
+    int getNumParams() override { return 5; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.05f, 5.0f, true, "Hz" };
+            case 1: return { 0.5f, 7.0f, false, "ms" };
+            case 2: return { 0.0f, 0.99f };
+            case 3: return { 0.5f, 7.0f, false, "ms" };
+        }
+        return {};
+    }
+
+    juce::dsp::DelayLine<float, juce::dsp::DelayLineInterpolationTypes::Linear> delayLine;
+    std::vector<float> lastOutput;
+    double sampleRate = 44100.0;
+    double phase = 0.0;
+
+    float rate = 0.3f;      // 0-1 mapped to 0.05-5 Hz (log)
+    float depth = 0.3f;     // 0-1 mapped to 0.5-7 ms sweep
+    float feedback = 0.5f;  // 0-1 mapped to 0-0.99
+    float baseDelay = 0.2f; // 0-1 mapped to 0.5-7 ms
+    float mix = 0.5f;
+};
+
+// --- Vibrato ---
+// A 100% wet modulated delay. The sweep amplitude is chosen so the peak pitch
+// deviation equals the Depth setting in cents.
+class VibratoProcessor : public BaseInternalProcessor {
+public:
+    VibratoProcessor() : BaseInternalProcessor("Vibrato") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        // Largest sweep occurs at the slowest rate and deepest setting
+        double maxSweep = sweepSeconds(0.1, 100.0) * sampleRate;
+        delayLine.setMaximumDelayInSamples((int)std::ceil(2.0 * maxSweep) + 16);
+        delayLine.prepare(spec);
+        phase = 0.0;
+    }
+
+    void reset() override {
+        delayLine.reset();
+        phase = 0.0;
+    }
+
+    // Delay sweep amplitude (seconds) giving a peak deviation of cents at rateHz
+    static double sweepSeconds(double rateHz, double cents) {
+        return (std::pow(2.0, cents / 1200.0) - 1.0) / (juce::MathConstants<double>::twoPi * rateHz);
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        double rateHz = mapRangeLog(rate, 0.1f, 10.0f);
+        double sweep = sweepSeconds(rateHz, mapRange(depth, 0.0f, 100.0f)) * sampleRate;
+        double centre = sweep + 2.0;
+        double increment = rateHz / sampleRate;
+        int numChannels = juce::jmin(buffer.getNumChannels(), (int)getTotalNumOutputChannels());
+
+        for (int i = 0; i < buffer.getNumSamples(); ++i) {
+            float delaySamples = (float)(centre + sweep * std::sin(juce::MathConstants<double>::twoPi * phase));
+            for (int ch = 0; ch < numChannels; ++ch) {
+                auto* data = buffer.getWritePointer(ch);
+                delayLine.pushSample(ch, data[i]);
+                data[i] = delayLine.popSample(ch, delaySamples);
+            }
+            phase += increment;
+            if (phase >= 1.0) phase -= 1.0;
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) rate = value;
+        else if (index == 1) depth = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return rate;
+        if (index == 1) return depth;
+        return 0.0f;
+    }
+    int getNumParams() override { return 2; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.1f, 10.0f, true, "Hz" };
+            case 1: return { 0.0f, 100.0f, false, "cents" };
+        }
+        return {};
+    }
+
+    juce::dsp::DelayLine<float, juce::dsp::DelayLineInterpolationTypes::Lagrange3rd> delayLine;
+    double sampleRate = 44100.0;
+    double phase = 0.0;
+    float rate = 0.5f;  // 0-1 mapped to 0.1-10 Hz (log)
+    float depth = 0.2f; // 0-1 mapped to 0-100 cents
+};
+
+// --- Freeze ---
+// STFT spectral freeze. The held magnitude spectrum follows the live spectrum with a
+// smoothing set by FreezeAmount (1 = fully frozen); trigger(true) captures a snapshot
+// and holds it until trigger(false). The output blends live and frozen spectra; at
+// FreezeAmount 0, while not triggered, the input passes through untouched.
+class FreezeProcessor : public BaseInternalProcessor {
+public:
+    static constexpr int kFftOrder = 11;
+    static constexpr int kFftSize = 1 << kFftOrder;
+    static constexpr int kHopSize = kFftSize / 4;
+
+    struct ChannelState {
+        std::vector<float> input = std::vector<float>((size_t)kFftSize, 0.0f);
+        std::vector<float> output = std::vector<float>((size_t)kFftSize, 0.0f);
+        std::vector<float> held = std::vector<float>((size_t)kFftSize / 2 + 1, 0.0f);
+        std::vector<float> phase = std::vector<float>((size_t)kFftSize / 2 + 1, 0.0f);
+        int pos = 0;
+        int hopCounter = 0;
+        bool captureNext = false;
+    };
+
+    FreezeProcessor() : BaseInternalProcessor("Freeze"), fft(kFftOrder), window((size_t)kFftSize) {
+        // Periodic Hann so overlapping frames sum to a constant
+        for (int i = 0; i < kFftSize; ++i)
+            window[(size_t)i] = 0.5f - 0.5f * std::cos(juce::MathConstants<float>::twoPi * (float)i / (float)kFftSize);
+    }
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        channels.assign(spec.numChannels, ChannelState());
+        frame.assign((size_t)kFftSize * 2, 0.0f);
+    }
+
+    void reset() override {
+        for (auto& state : channels) state = ChannelState();
+    }
+
+    bool trigger(bool enable) override {
+        if (enable) captureNext.store(true);
+        frozen.store(enable);
+        return true;
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        bool capture = captureNext.exchange(false);
+        if (capture) for (auto& state : channels) state.captureNext = true;
+        // Frames still run so the held spectrum follows the input for a later freeze
+        bool passThrough = !capture && !frozen.load() && freezeAmount == 0.0f;
+
+        int numChannels = juce::jmin(buffer.getNumChannels(), (int)channels.size());
+        for (int ch = 0; ch < numChannels; ++ch) {
+            auto& state = channels[(size_t)ch];
+            auto* data = buffer.getWritePointer(ch);
+            for (int i = 0; i < buffer.getNumSamples(); ++i) {
+                state.input[(size_t)state.pos] = data[i];
+                if (!passThrough) data[i] = state.output[(size_t)state.pos];
+                state.output[(size_t)state.pos] = 0.0f;
+                state.pos = (state.pos + 1) % kFftSize;
+                if (++state.hopCounter == kHopSize) {
+                    state.hopCounter = 0;
+                    processFrame(state);
+                }
+            }
+        }
+    }
+
+    void processFrame(ChannelState& state) {
+        // Oldest sample is at pos since the ring was just written
+        for (int i = 0; i < kFftSize; ++i) {
+            frame[(size_t)i] = state.input[(size_t)((state.pos + i) % kFftSize)] * window[(size_t)i];
+        }
+        std::fill(frame.begin() + kFftSize, frame.end(), 0.0f);
+        fft.performRealOnlyForwardTransform(frame.data());
+
+        float amount = frozen.load() ? 1.0f : freezeAmount;
+        auto* bins = reinterpret_cast<std::complex<float>*>(frame.data());
+        for (int k = 0; k <= kFftSize / 2; ++k) {
+            float mag = std::abs(bins[k]);
+            if (state.captureNext) state.held[(size_t)k] = mag;
+            else state.held[(size_t)k] = amount * state.held[(size_t)k] + (1.0f - amount) * mag;
+
+            state.phase[(size_t)k] = std::fmod(state.phase[(size_t)k]
+                + juce::MathConstants<float>::twoPi * (float)k * (float)kHopSize / (float)kFftSize,
+                juce::MathConstants<float>::twoPi);
+            auto frozenBin = std::polar(state.held[(size_t)k], state.phase[(size_t)k]);
+            bins[k] = (1.0f - blend) * bins[k] + blend * frozenBin;
+            if (k > 0 && k < kFftSize / 2) bins[kFftSize - k] = std::conj(bins[k]);
+        }
+        state.captureNext = false;
+
+        fft.performRealOnlyInverseTransform(frame.data());
+
+        // Hann analysis + synthesis at 75% overlap sums to 1.5
+        const float scale = 1.0f / 1.5f;
+        for (int i = 0; i < kFftSize; ++i) {
+            state.output[(size_t)((state.pos + i) % kFftSize)] += frame[(size_t)i] * window[(size_t)i] * scale;
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) freezeAmount = value;
+        else if (index == 1) blend = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return freezeAmount;
+        if (index == 1) return blend;
+        return 0.0f;
+    }
+    int getNumParams() override { return 2; }
+
+    juce::dsp::FFT fft;
+    std::vector<float> window;
+    std::vector<float> frame;
+    std::vector<ChannelState> channels;
+    std::atomic<bool> frozen { false };
+    std::atomic<bool> captureNext { false };
+
+    float freezeAmount = 0.0f; // 0 = live, 1 = fully frozen
+    float blend = 0.5f;        // 0 = live only, 1 = frozen only
+};
+
+// --- Pitch Shifter ---
+// Phase-vocoder pitch shift (bin remapping with phase accumulation), duration unchanged.
+// Quality picks the FFT size and overlap: fast = 1024 x4, normal = 2048 x4, high = 4096 x8.
+// While shifting, output is delayed by the FFT size, which is reported as its latency.
+class PitchShifterProcessor : public BaseInternalProcessor {
+public:
+    struct ChannelState {
+        std::vector<float> input;
+        std::vector<float> output;
+        std::vector<float> lastPhase;
+        std::vector<float> sumPhase;
+        int pos = 0;
+        int hopCounter = 0;
+    };
+
+    PitchShifterProcessor() : BaseInternalProcessor("PitchShifter") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        numChannels = (int)spec.numChannels;
+        configure(qualityTier());
+    }
+
+    void reset() override {
+        configure(tier);
+    }
+
+    int qualityTier() const {
+        if (quality < 1.0f / 3.0f) return 0;
+        if (quality < 2.0f / 3.0f) return 1;
+        return 2;
+    }
+
+    void configure(int newTier) {
+        tier = newTier;
+        int order = tier == 0 ? 10 : (tier == 1 ? 11 : 12);
+        fftSize = 1 << order;
+        overlap = tier == 2 ? 8 : 4;
+        hopSize = fftSize / overlap;
+        fft = std::make_unique<juce::dsp::FFT>(order);
+
+        // Periodic Hann so overlapping frames sum to a constant
+        window.resize((size_t)fftSize);
+        for (int i = 0; i < fftSize; ++i)
+            window[(size_t)i] = 0.5f - 0.5f * std::cos(juce::MathConstants<float>::twoPi * (float)i / (float)fftSize);
+
+        size_t numBins = (size_t)fftSize / 2 + 1;
+        frame.assign((size_t)fftSize * 2, 0.0f);
+        anaMag.assign(numBins, 0.0f);
+        anaFreq.assign(numBins, 0.0f);
+        synMag.assign(numBins, 0.0f);
+        synFreq.assign(numBins, 0.0f);
+
+        channels.assign((size_t)numChannels, ChannelState());
+        for (auto& state : channels) {
+            state.input.assign((size_t)fftSize, 0.0f);
+            state.output.assign((size_t)fftSize, 0.0f);
+            state.lastPhase.assign(numBins, 0.0f);
+            state.sumPhase.assign(numBins, 0.0f);
+        }
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        // At 0 semitones the input passes through untouched
+        float shift = mapRange(semitones, -24.0f, 24.0f);
+        if (std::abs(shift) < 1.0e-3f) return;
+
+        if (qualityTier() != tier) configure(qualityTier());
+        ratio = std::pow(2.0f, shift / 12.0f);
+
+        int n = juce::jmin(buffer.getNumChannels(), (int)channels.size());
+        for (int ch = 0; ch < n; ++ch) {
+            auto& state = channels[(size_t)ch];
+            auto* data = buffer.getWritePointer(ch);
+            for (int i = 0; i < buffer.getNumSamples(); ++i) {
+                state.input[(size_t)state.pos] = data[i];
+                data[i] = state.output[(size_t)state.pos];
+                state.output[(size_t)state.pos] = 0.0f;
+                state.pos = (state.pos + 1) % fftSize;
+                if (++state.hopCounter == hopSize) {
+                    state.hopCounter = 0;
+                    processFrame(state);
+                }
+            }
+        }
+    }
+
+    void processFrame(ChannelState& state) {
+        const float twoPi = juce::MathConstants<float>::twoPi;
+        const float expected = twoPi * (float)hopSize / (float)fftSize;
+        const int numBins = fftSize / 2 + 1;
+
+        for (int i = 0; i < fftSize; ++i) {
+            frame[(size_t)i] = state.input[(size_t)((state.pos + i) % fftSize)] * window[(size_t)i];
+        }
+        std::fill(frame.begin() + fftSize, frame.end(), 0.0f);
+        fft->performRealOnlyForwardTransform(frame.data());
+        auto* bins = reinterpret_cast<std::complex<float>*>(frame.data());
+
+        // Analysis: true frequency of each bin (in bins) from the phase advance
+        for (int k = 0; k < numBins; ++k) {
+            float phase = std::arg(bins[k]);
+            float delta = phase - state.lastPhase[(size_t)k] - (float)k * expected;
+            state.lastPhase[(size_t)k] = phase;
+            delta -= twoPi * std::round(delta / twoPi);
+            anaMag[(size_t)k] = std::abs(bins[k]);
+            anaFreq[(size_t)k] = (float)k + delta * (float)overlap / twoPi;
+        }
+
+        // Move each bin to its shifted position
+        std::fill(synMag.begin(), synMag.end(), 0.0f);
+        std::fill(synFreq.begin(), synFreq.end(), 0.0f);
+        for (int k = 0; k < numBins; ++k) {
+            int target = (int)std::round((float)k * ratio);
+            if (target >= numBins) break;
+            synMag[(size_t)target] += anaMag[(size_t)k];
+            synFreq[(size_t)target] = anaFreq[(size_t)k] * ratio;
+        }
+
+        // Synthesis: accumulate phase at the shifted frequencies
+        for (int k = 0; k < numBins; ++k) {
+            float advance = (synFreq[(size_t)k] - (float)k) * twoPi / (float)overlap + (float)k * expected;
+            state.sumPhase[(size_t)k] = std::fmod(state.sumPhase[(size_t)k] + advance, twoPi);
+            bins[k] = std::polar(synMag[(size_t)k], state.sumPhase[(size_t)k]);
+            if (k > 0 && k < fftSize / 2) bins[fftSize - k] = std::conj(bins[k]);
+        }
+
+        fft->performRealOnlyInverseTransform(frame.data());
+
+        // Squared periodic Hann summed over the overlapping frames is 3/8 of the overlap
+        const float scale = 1.0f / (0.375f * (float)overlap);
+        for (int i = 0; i < fftSize; ++i) {
+            state.output[(size_t)((state.pos + i) % fftSize)] += frame[(size_t)i] * window[(size_t)i] * scale;
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) semitones = value;
+        else if (index == 1) quality = value;
+        // The FFT size the next block runs at, matching configure()
+        bool shifting = std::abs(mapRange(semitones, -24.0f, 24.0f)) >= 1.0e-3f;
+        setLatencySamples(shifting ? 1 << (10 + qualityTier()) : 0);
+    }
+    float getParam(int index) override {
+        if (index == 0) return semitones;
+        if (index == 1) return quality;
+        return 0.0f;
+    }
+    int getNumParams() override { return 2; }
+    int getMaxLatencySamples() override { return 1 << 12; } // The FFT size of the high tier
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { -24.0f, 24.0f, false, "semitones" };
+        }
+        return {};
+    }
+    juce::StringArray getParamChoices(int index) override {
+        if (index == 1) return { "Fast", "Normal", "High" };
+        return {};
+    }
+
+    std::unique_ptr<juce::dsp::FFT> fft;
+    std::vector<float> window, frame, anaMag, anaFreq, synMag, synFreq;
+    std::vector<ChannelState> channels;
+    int numChannels = 2;
+    int tier = 1;
+    int fftSize = 2048;
+    int overlap = 4;
+    int hopSize = 512;
+    float ratio = 1.0f;
+
+    float semitones = 0.5f; // 0-1 mapped to -24..+24 semitones
+    float quality = 0.5f;   // 0 = fast, 0.5 = normal, 1 = high
+};
+
+// --- Ring Modulator ---
+// Multiplies the input by a sine carrier, producing sum and difference frequencies.
+class RingModulatorProcessor : public BaseInternalProcessor {
+public:
+    RingModulatorProcessor() : BaseInternalProcessor("RingModulator") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        reset();
+    }
+
+    void reset() override { phase = 0.0; }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        double increment = mapRangeLog(carrier, 20.0f, 5000.0f) / sampleRate;
+        double startPhase = phase;
+        for (int ch = 0; ch < buffer.getNumChannels(); ++ch) {
+            auto* data = buffer.getWritePointer(ch);
+            double p = startPhase;
+            for (int i = 0; i < buffer.getNumSamples(); ++i) {
+                float modulated = data[i] * (float)std::sin(juce::MathConstants<double>::twoPi * p);
+                data[i] = (1.0f - mix) * data[i] + mix * modulated;
+                p += increment;
+                if (p >= 1.0) p -= 1.0;
+            }
+            phase = p;
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) carrier = value;
+        else if (index == 1) mix = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return carrier;
+        if (index == 1) return mix;
+        return 0.0f;
+    }
+    int getNumParams() override { return 2; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 20.0f, 5000.0f, true, "Hz" };
+        }
+        return {};
+    }
+
+    double sampleRate = 44100.0;
+    double phase = 0.0;
+    float carrier = 0.5f; // 0-1 mapped to 20-5000 Hz (log)
+    float mix = 1.0f;
+};
+
+// --- Tape Saturation ---
+// Biased tanh saturation followed by a gentle first-order roll-off set by tape speed.
+// Bias shifts the operating point: 0 gives odd harmonics only, higher values add even
+// harmonics. Drive 0 bypasses the tape entirely.
+class TapeSaturationProcessor : public BaseInternalProcessor {
+public:
+    TapeSaturationProcessor() : BaseInternalProcessor("TapeSaturation") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        rolloff.prepare(spec);
+        dcBlocker.prepare(spec);
+        *dcBlocker.state = *juce::dsp::IIR::Coefficients<float>::makeFirstOrderHighPass(sampleRate, 10.0f);
+        update();
+    }
+
+    void reset() override {
+        rolloff.reset();
+        dcBlocker.reset();
+    }
+
+    // Roll-off frequency for 7.5, 15 and 30 IPS
+    float rolloffHz() const {
+        int ips = (int)std::round(speed * 2.0f);
+        float freq = ips == 0 ? 9000.0f : (ips == 1 ? 15000.0f : 20000.0f);
+        return juce::jmin(freq, (float)sampleRate * 0.45f);
+    }
+
+    void update() {
+        *rolloff.state = *juce::dsp::IIR::Coefficients<float>::makeFirstOrderLowPass(sampleRate, rolloffHz());
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        if (drive <= 0.0f) return;
+
+        float k = mapRange(drive, 1.0f, 10.0f);
+        float offset = mapRange(bias, 0.0f, 0.3f);
+        float rest = std::tanh(k * offset);
+        float makeup = 1.0f / std::sqrt(k);
+        for (int ch = 0; ch < buffer.getNumChannels(); ++ch) {
+            auto* data = buffer.getWritePointer(ch);
+            for (int i = 0; i < buffer.getNumSamples(); ++i)
+                data[i] = (std::tanh(k * (data[i] + offset)) - rest) * makeup;
+        }
+
+        // Asymmetric clipping leaves a DC offset behind
+        juce::dsp::AudioBlock<float> block(buffer);
+        dcBlocker.process(juce::dsp::ProcessContextReplacing<float>(block));
+        rolloff.process(juce::dsp::ProcessContextReplacing<float>(block));
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) drive = value;
+        else if (index == 1) bias = value;
+        else if (index == 2) { speed = value; update(); }
+    }
+    float getParam(int index) override {
+        if (index == 0) return drive;
+        if (index == 1) return bias;
+        if (index == 2) return speed;
+        return 0.0f;
+    }
+    int getNumParams() override { return 3; }
+    juce::StringArray getParamChoices(int index) override {
+        if (index == 2) return { "7.5 IPS", "15 IPS", "30 IPS" };
+        return {};
+    }
+
+    double sampleRate = 44100.0;
+    float drive = 0.5f; // 0-1 mapped to 1-10x into the tanh curve
+    float bias = 0.2f;  // 0 = odd harmonics only, 1 = strongest even harmonics
+    float speed = 0.5f; // 0 = 7.5 IPS, 0.5 = 15 IPS, 1 = 30 IPS
+    using IIRFilter = juce::dsp::ProcessorDuplicator<juce::dsp::IIR::Filter<float>, juce::dsp::IIR::Coefficients<float>>;
+    IIRFilter rolloff, dcBlocker;
+};
+
+// --- Stereo Widener ---
+// Scales the side signal of a stereo pair so that side energy is multiplied by Width:
+// 0 folds to mono, 1 leaves the signal untouched, 2 doubles the side energy.
+// Buffers without exactly two channels pass through.
+class StereoWidenerProcessor : public BaseInternalProcessor {
+public:
+    StereoWidenerProcessor() : BaseInternalProcessor("StereoWidener") {}
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        float w = mapRange(width, 0.0f, 2.0f);
+        if (buffer.getNumChannels() != 2 || w == 1.0f) return;
+
+        float sideGain = std::sqrt(w);
+        auto* left = buffer.getWritePointer(0);
+        auto* right = buffer.getWritePointer(1);
+        for (int i = 0; i < buffer.getNumSamples(); ++i) {
+            float mid = 0.5f * (left[i] + right[i]);
+            float side = 0.5f * (left[i] - right[i]) * sideGain;
+            left[i] = mid + side;
+            right[i] = mid - side;
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) width = value;
+    }
+    float getParam(int index) override { return index == 0 ? width : 0.0f; }
+    int getNumParams() override { return 1; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.0f, 2.0f };
+        }
+        return {};
+    }
+
+    float width = 0.5f; // 0-1 mapped to 0-2, 0.5 = original
+};
+
+// --- Mid/Side ---
+// Converts a stereo pair between left/right and mid/side: M = (L+R)/2, S = (L-R)/2 and
+// back with L = M+S, R = M-S. Buffers without exactly two channels pass through.
+class MidSideProcessor : public BaseInternalProcessor {
+public:
+    MidSideProcessor(bool encode)
+        : BaseInternalProcessor(encode ? "MidSideEncoder" : "MidSideDecoder"), encoder(encode) {}
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        if (buffer.getNumChannels() != 2) return;
+        auto* a = buffer.getWritePointer(0);
+        auto* b = buffer.getWritePointer(1);
+        float scale = encoder ? 0.5f : 1.0f;
+        for (int i = 0; i < buffer.getNumSamples(); ++i) {
+            float sum = (a[i] + b[i]) * scale;
+            float diff = (a[i] - b[i]) * scale;
+            a[i] = sum;
+            b[i] = diff;
+        }
+    }
+
+    void setParam(int, float) override {}
+    float getParam(int) override { return 0.0f; }
+    int getNumParams() override { return 0; }
+
+    bool encoder;
+};
+
+// --- Panner ---
+// Sin/cos panning normalized to unity at centre, so centre is transparent and the side
+// panned towards keeps unity gain (no +3 dB boost) while the other side follows
+// sqrt(2) * cos down to silence. A mono source reaches it as two identical channels
+// (an AudioStream copies a mono input to every output); a single-channel buffer has
+// nowhere to pan to and passes through.
+class PannerProcessor : public BaseInternalProcessor {
+public:
+    PannerProcessor() : BaseInternalProcessor("Panner") {}
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        if (buffer.getNumChannels() != 2) return;
+        // 0 = hard left, pi/4 = centre, pi/2 = hard right
+        float angle = pan * juce::MathConstants<float>::halfPi;
+        float leftGain = juce::jmin(1.0f, juce::MathConstants<float>::sqrt2 * std::cos(angle));
+        float rightGain = juce::jmin(1.0f, juce::MathConstants<float>::sqrt2 * std::sin(angle));
+        buffer.applyGain(0, 0, buffer.getNumSamples(), juce::jmax(0.0f, leftGain));
+        buffer.applyGain(1, 0, buffer.getNumSamples(), juce::jmax(0.0f, rightGain));
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) pan = value;
+    }
+    float getParam(int index) override { return index == 0 ? pan : 0.0f; }
+    int getNumParams() override { return 1; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { -1.0f, 1.0f };
+        }
+        return {};
+    }
+
+    float pan = 0.5f; // 0-1 mapped to -1 (hard left) to +1 (hard right)
+};
+
+// --- Auto Gain Control ---
+// Measures a short-term (100 ms) RMS level across all channels and steers a linked gain
+// towards the target level. Attack sets how fast gain falls when the signal gets louder,
+// Release how fast it rises when the signal gets quieter. Gain is limited to +/-40 dB and
+// held while the input is below -70 dBFS so silence is not boosted into noise.
+class AutoGainControlProcessor : public BaseInternalProcessor {
+public:
+    AutoGainControlProcessor() : BaseInternalProcessor("AutoGainControl") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        reset();
+    }
+
+    void reset() override {
+        meanSquare = 0.0;
+        gainDb = 0.0;
+    }
+
+    double coefficient(double ms) const {
+        return std::exp(-1.0 / (ms * 0.001 * sampleRate));
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        double targetDb = mapRange(targetLevel, -40.0f, 0.0f);
+        double rmsCoeff = coefficient(100.0);
+        double attackCoeff = coefficient(mapRange(attack, 1.0f, 500.0f));
+        double releaseCoeff = coefficient(mapRange(release, 10.0f, 2000.0f));
+        int numChannels = buffer.getNumChannels();
+
+        for (int i = 0; i < buffer.getNumSamples(); ++i) {
+            double peakSquare = 0.0;
+            for (int ch = 0; ch < numChannels; ++ch) {
+                double x = buffer.getSample(ch, i);
+                peakSquare = std::max(peakSquare, x * x);
+            }
+            meanSquare = rmsCoeff * meanSquare + (1.0 - rmsCoeff) * peakSquare;
+
+            double levelDb = 10.0 * std::log10(meanSquare + 1.0e-12);
+            if (levelDb > -70.0) {
+                double desired = juce::jlimit(-40.0, 40.0, targetDb - levelDb);
+                double coeff = desired < gainDb ? attackCoeff : releaseCoeff;
+                gainDb = desired + coeff * (gainDb - desired);
+            }
+
+            float gain = juce::Decibels::decibelsToGain((float)gainDb);
+            for (int ch = 0; ch < numChannels; ++ch)
+                buffer.setSample(ch, i, buffer.getSample(ch, i) * gain);
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) targetLevel = value;
+        else if (index == 1) attack = value;
+        else if (index == 2) release = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return targetLevel;
+        if (index == 1) return attack;
+        if (index == 2) return release;
+        return 0.0f;
+    }
+    int getNumParams() override { return 3; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { -40.0f, 0.0f, false, "dB" };
+            case 1: return { 1.0f, 500.0f, false, "ms" };
+            case 2: return { 10.0f, 2000.0f, false, "ms" };
+        }
+        return {};
+    }
+
+    double sampleRate = 44100.0;
+    double meanSquare = 0.0;
+    double gainDb = 0.0;
+    float targetLevel = 0.55f; // 0-1 mapped to -40..0 dBFS RMS (0.55 = -18 dBFS)
+    float attack = 0.02f;      // 0-1 mapped to 1-500 ms
+    float release = 0.1f;      // 0-1 mapped to 10-2000 ms
+};
+
+// --- Multiband Compressor ---
+// Four bands split at 150 Hz, 800 Hz and 5 kHz. Each split takes a 4th-order
+// Linkwitz-Riley low-pass of what remains and passes the difference on to the next
+// band, so the bands always sum back to the input. Every band has its own compressor
+// and makeup gain; parameter band*5 + offset addresses threshold (0), ratio (1),
+// attack (2), release (3) and makeup (4).
+class MultibandCompressorProcessor : public BaseInternalProcessor {
+public:
+    static constexpr int kNumBands = 4;
+    static constexpr int kParamsPerBand = 5;
+
+    MultibandCompressorProcessor() : BaseInternalProcessor("MultibandCompressor") {
+        const float crossovers[kNumBands - 1] = { 150.0f, 800.0f, 5000.0f };
+        for (int i = 0; i < kNumBands - 1; ++i) {
+            splits[i].setType(juce::dsp::LinkwitzRileyFilterType::lowpass);
+            splits[i].setCutoffFrequency(crossovers[i]);
+        }
+    }
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        for (auto& split : splits) split.prepare(spec);
+        for (auto& band : bands) {
+            band.compressor.prepare(spec);
+            band.buffer.setSize((int)spec.numChannels, (int)spec.maximumBlockSize);
+        }
+        remainder.setSize((int)spec.numChannels, (int)spec.maximumBlockSize);
+        for (int i = 0; i < kNumBands; ++i) update(i);
+    }
+
+    void reset() override {
+        for (auto& split : splits) split.reset();
+        for (auto& band : bands) band.compressor.reset();
+    }
+
+    void update(int index) {
+        auto& band = bands[index];
+        band.compressor.setThreshold(mapRange(band.threshold, -60.0f, 0.0f));
+        band.compressor.setRatio(mapRange(band.ratio, 1.0f, 20.0f));
+        band.compressor.setAttack(mapRange(band.attack, 1.0f, 200.0f));
+        band.compressor.setRelease(mapRange(band.release, 20.0f, 500.0f));
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        int numChannels = buffer.getNumChannels();
+        int numSamples = buffer.getNumSamples();
+        remainder.setSize(numChannels, numSamples, false, false, true);
+        for (auto& band : bands) band.buffer.setSize(numChannels, numSamples, false, false, true);
+
+        remainder.makeCopyOf(buffer, true);
+        for (int i = 0; i < kNumBands; ++i) {
+            auto& band = bands[i].buffer;
+            for (int ch = 0; ch < numChannels; ++ch) band.copyFrom(ch, 0, remainder, ch, 0, numSamples);
+            if (i == kNumBands - 1) break;
+
+            juce::dsp::AudioBlock<float> block(band);
+            splits[i].process(juce::dsp::ProcessContextReplacing<float>(block));
+            for (int ch = 0; ch < numChannels; ++ch) remainder.addFrom(ch, 0, band, ch, 0, numSamples, -1.0f);
+        }
+
+        buffer.clear();
+        for (auto& band : bands) {
+            juce::dsp::AudioBlock<float> block(band.buffer);
+            band.compressor.process(juce::dsp::ProcessContextReplacing<float>(block));
+            float makeup = juce::Decibels::decibelsToGain(mapRange(band.makeup, 0.0f, 24.0f));
+            for (int ch = 0; ch < numChannels; ++ch) buffer.addFrom(ch, 0, band.buffer, ch, 0, numSamples, makeup);
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index < 0 || index >= getNumParams()) return;
+        auto& band = bands[index / kParamsPerBand];
+        switch (index % kParamsPerBand) {
+            case 0: band.threshold = value; break;
+            case 1: band.ratio = value; break;
+            case 2: band.attack = value; break;
+            case 3: band.release = value; break;
+            case 4: band.makeup = value; break;
+        }
+        update(index / kParamsPerBand);
+    }
+    float getParam(int index) override {
+        if (index < 0 || index >= getNumParams()) return 0.0f;
+        auto& band = bands[index / kParamsPerBand];
+        switch (index % kParamsPerBand) {
+            case 0: return band.threshold;
+            case 1: return band.ratio;
+            case 2: return band.attack;
+            case 3: return band.release;
+            default: return band.makeup;
+        }
+    }
+    int getNumParams() override { return kNumBands * kParamsPerBand; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index % kParamsPerBand) {
+            case 0: return { -60.0f, 0.0f, false, "dB" };
+            case 1: return { 1.0f, 20.0f, false, ":1" };
+            case 2: return { 1.0f, 200.0f, false, "ms" };
+            case 3: return { 20.0f, 500.0f, false, "ms" };
+            default: return { 0.0f, 24.0f, false, "dB" };
+        }
+    }
+
+    struct Band {
+        juce::dsp::Compressor<float> compressor;
+        juce::AudioBuffer<float> buffer;
+        float threshold = 0.8f; // 0-1 mapped to -60..0 dB
+        float ratio = 0.1f;     // 0-1 mapped to 1-20
+        float attack = 0.1f;    // 0-1 mapped to 1-200 ms
+        float release = 0.2f;   // 0-1 mapped to 20-500 ms
+        float makeup = 0.0f;    // 0-1 mapped to 0-24 dB
+    };
+    Band bands[kNumBands];
+    juce::dsp::LinkwitzRileyFilter<float> splits[kNumBands - 1];
+    juce::AudioBuffer<float> remainder;
+};
+
+// --- Transient Shaper ---
+// Level-independent attack/sustain control. Attack compares envelope followers with a
+// fast (1 ms) and slow (30 ms) attack, which differ only at onsets; Sustain compares
+// followers with a fast (20 ms) and slow (300 ms) release, which differ only while the
+// sound decays. Each difference in dB, scaled by its parameter, is applied as a gain
+// (limited to +/-24 dB) linked across channels.
+class TransientShaperProcessor : public BaseInternalProcessor {
+public:
+    TransientShaperProcessor() : BaseInternalProcessor("TransientShaper") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        reset();
+    }
+
+    void reset() override {
+        fastAttack = slowAttack = fastRelease = slowRelease = 0.0;
+    }
+
+    double coefficient(double ms) const {
+        return std::exp(-1.0 / (ms * 0.001 * sampleRate));
+    }
+
+    static void follow(double& env, double x, double attackCoeff, double releaseCoeff) {
+        double coeff = x > env ? attackCoeff : releaseCoeff;
+        env = coeff * env + (1.0 - coeff) * x;
+    }
+
+    static double toDb(double x) { return 20.0 * std::log10(x + 1.0e-9); }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        double attackAmount = mapRange(attack, -1.0f, 1.0f);
+        double sustainAmount = mapRange(sustain, -1.0f, 1.0f);
+        if (attackAmount == 0.0 && sustainAmount == 0.0) return;
+
+        double att1 = coefficient(1.0), att30 = coefficient(30.0);
+        double rel20 = coefficient(20.0), rel300 = coefficient(300.0);
+        int numChannels = buffer.getNumChannels();
+
+        for (int i = 0; i < buffer.getNumSamples(); ++i) {
+            double x = 0.0;
+            for (int ch = 0; ch < numChannels; ++ch) x = std::max(x, (double)std::abs(buffer.getSample(ch, i)));
+
+            follow(fastAttack, x, att1, rel20);
+            follow(slowAttack, x, att30, rel20);
+            follow(fastRelease, x, att1, rel20);
+            follow(slowRelease, x, att1, rel300);
+
+            double gainDb = attackAmount * (toDb(fastAttack) - toDb(slowAttack))
+                          + sustainAmount * (toDb(slowRelease) - toDb(fastRelease));
+            float gain = juce::Decibels::decibelsToGain((float)juce::jlimit(-24.0, 24.0, gainDb));
+            for (int ch = 0; ch < numChannels; ++ch)
+                buffer.setSample(ch, i, buffer.getSample(ch, i) * gain);
+        }
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) attack = value;
+        else if (index == 1) sustain = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return attack;
+        if (index == 1) return sustain;
+        return 0.0f;
+    }
+    int getNumParams() override { return 2; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { -1.0f, 1.0f };
+            case 1: return { -1.0f, 1.0f };
+        }
+        return {};
+    }
+
+    double sampleRate = 44100.0;
+    double fastAttack = 0.0, slowAttack = 0.0, fastRelease = 0.0, slowRelease = 0.0;
+    float attack = 0.5f;  // 0-1 mapped to -1 (soften) to +1 (punch)
+    float sustain = 0.5f; // 0-1 mapped to -1 (shorter) to +1 (longer)
+};
+
+// --- Convolution Reverb ---
+// Zero-latency uniformly partitioned convolution (overlap-save). The impulse response is
+// cut into kBlockSize partitions whose spectra are precomputed. The partly filled current
+// input block is transformed on every call and multiplied by the first partition, so
+// output is available immediately; the contribution of earlier blocks through the later
+// partitions is summed once per completed block. Output channel n uses IR channel
+// n % numIrChannels, and the IR is resampled in prepare() if its rate differs.
+class ConvolutionReverbProcessor : public BaseInternalProcessor {
+public:
+    static constexpr int kBlockOrder = 9;
+    static constexpr int kBlockSize = 1 << kBlockOrder;
+    static constexpr int kFftSize = kBlockSize * 2;
+
+    using Spectrum = std::vector<std::complex<float>>;
+
+    struct ChannelState {
+        std::vector<float> input = std::vector<float>((size_t)kFftSize, 0.0f); // previous block + current block
+        std::vector<Spectrum> history; // spectra of completed blocks, newest at historyPos
+        Spectrum accumulated = Spectrum((size_t)kFftSize); // earlier blocks' contribution to the current one
+        int historyPos = 0;
+        int pos = 0; // samples written to the current block
+    };
+
+    ConvolutionReverbProcessor(juce::AudioBuffer<float> ir, double irRate)
+        : BaseInternalProcessor("ConvolutionReverb"), impulse(std::move(ir)), impulseRate(irRate), fft(kBlockOrder + 1) {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        auto ir = impulseAt(spec.sampleRate);
+        int numPartitions = juce::jmax(1, (ir.getNumSamples() + kBlockSize - 1) / kBlockSize);
+
+        frame.assign((size_t)kFftSize * 2, 0.0f);
+        partitions.assign((size_t)ir.getNumChannels(), std::vector<Spectrum>((size_t)numPartitions));
+        for (int ch = 0; ch < ir.getNumChannels(); ++ch) {
+            for (int p = 0; p < numPartitions; ++p) {
+                std::fill(frame.begin(), frame.end(), 0.0f);
+                int count = juce::jmin(kBlockSize, ir.getNumSamples() - p * kBlockSize);
+                if (count > 0) std::copy_n(ir.getReadPointer(ch, p * kBlockSize), count, frame.begin());
+                fft.performRealOnlyForwardTransform(frame.data());
+                auto* bins = reinterpret_cast<std::complex<float>*>(frame.data());
+                partitions[(size_t)ch][(size_t)p].assign(bins, bins + kFftSize);
+            }
+        }
+
+        ChannelState initial;
+        initial.history.assign((size_t)numPartitions, Spectrum((size_t)kFftSize));
+        channels.assign(spec.numChannels, initial);
+    }
+
+    void reset() override {
+        for (auto& state : channels) {
+            std::fill(state.input.begin(), state.input.end(), 0.0f);
+            for (auto& spectrum : state.history) std::fill(spectrum.begin(), spectrum.end(), std::complex<float>());
+            std::fill(state.accumulated.begin(), state.accumulated.end(), std::complex<float>());
+            state.historyPos = 0;
+            state.pos = 0;
+        }
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        if (partitions.empty()) return;
+        int numChannels = juce::jmin(buffer.getNumChannels(), (int)channels.size());
+        for (int ch = 0; ch < numChannels; ++ch) {
+            auto& state = channels[(size_t)ch];
+            const auto& ir = partitions[(size_t)ch % partitions.size()];
+            auto* data = buffer.getWritePointer(ch);
+
+            for (int done = 0; done < buffer.getNumSamples();) {
+                int count = juce::jmin(buffer.getNumSamples() - done, kBlockSize - state.pos);
+                std::copy_n(data + done, count, state.input.begin() + kBlockSize + state.pos);
+
+                // Spectrum of the previous and (partial) current block
+                std::fill(frame.begin(), frame.end(), 0.0f);
+                std::copy(state.input.begin(), state.input.end(), frame.begin());
+                fft.performRealOnlyForwardTransform(frame.data());
+                auto* bins = reinterpret_cast<std::complex<float>*>(frame.data());
+                bool blockComplete = state.pos + count == kBlockSize;
+                if (blockComplete) {
+                    state.historyPos = (state.historyPos + 1) % (int)state.history.size();
+                    std::copy(bins, bins + kFftSize, state.history[(size_t)state.historyPos].begin());
+                }
+
+                for (int k = 0; k < kFftSize; ++k) bins[k] = bins[k] * ir[0][(size_t)k] + state.accumulated[(size_t)k];
+                fft.performRealOnlyInverseTransform(frame.data());
+
+                // The second half of an overlap-save frame holds the valid output
+                for (int i = 0; i < count; ++i) {
+                    float wet = frame[(size_t)(kBlockSize + state.pos + i)];
+                    data[done + i] = (1.0f - mix) * data[done + i] + mix * wet;
+                }
+                state.pos += count;
+                done += count;
+
+                if (blockComplete) {
+                    std::copy(state.input.begin() + kBlockSize, state.input.end(), state.input.begin());
+                    state.pos = 0;
+                    accumulate(state, ir);
+                }
+            }
+        }
+    }
+
+    // Sums the completed blocks' spectra through partitions 1..n-1 for the next block.
+    void accumulate(ChannelState& state, const std::vector<Spectrum>& ir) {
+        std::fill(state.accumulated.begin(), state.accumulated.end(), std::complex<float>());
+        int numPartitions = (int)ir.size();
+        for (int p = 1; p < numPartitions; ++p) {
+            int index = ((state.historyPos - (p - 1)) % numPartitions + numPartitions) % numPartitions;
+            const auto& x = state.history[(size_t)index];
+            const auto& h = ir[(size_t)p];
+            for (int k = 0; k < kFftSize; ++k) state.accumulated[(size_t)k] += x[(size_t)k] * h[(size_t)k];
+        }
+    }
+
+    // Returns the impulse response at sampleRate, resampled with a Hann-windowed sinc if
+    // needed. The result is scaled by the rate ratio so the reverb's gain is unchanged.
+    juce::AudioBuffer<float> impulseAt(double sampleRate) const {
+        if (impulseRate <= 0.0 || sampleRate <= 0.0 || impulseRate == sampleRate) return impulse;
+
+        const int halfTaps = 16;
+        double ratio = impulseRate / sampleRate; // input samples per output sample
+        double cutoff = juce::jmin(1.0, 1.0 / ratio);
+        int inLength = impulse.getNumSamples();
+        int outLength = juce::jmax(1, (int)std::ceil(inLength / ratio));
+        juce::AudioBuffer<float> out(impulse.getNumChannels(), outLength);
+
+        for (int ch = 0; ch < impulse.getNumChannels(); ++ch) {
+            const float* in = impulse.getReadPointer(ch);
+            float* dest = out.getWritePointer(ch);
+            for (int i = 0; i < outLength; ++i) {
+                double centre = i * ratio;
+                int first = (int)std::floor(centre - halfTaps / cutoff) + 1;
+                int last = (int)std::floor(centre + halfTaps / cutoff);
+                double sum = 0.0;
+                for (int j = juce::jmax(0, first); j <= juce::jmin(inLength - 1, last); ++j) {
+                    double x = (j - centre) * cutoff;
+                    double sinc = x == 0.0 ? 1.0 : std::sin(juce::MathConstants<double>::pi * x) / (juce::MathConstants<double>::pi * x);
+                    double hann = 0.5 + 0.5 * std::cos(juce::MathConstants<double>::pi * x / halfTaps);
+                    sum += in[j] * cutoff * sinc * hann;
+                }
+                dest[i] = (float)(sum * ratio);
+            }
+        }
+        return out;
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) mix = value;
+    }
+    float getParam(int index) override {
+        return index == 0 ? mix : 0.0f;
+    }
+    int getNumParams() override { return 1; }
+
+    // The impulse response is saved with its rate, channel after channel, as base64 floats
+    void saveExtraState(juce::XmlElement& xml) override {
+        auto* element = xml.createNewChildElement("Impulse");
+        element->setAttribute("rate", impulseRate);
+        element->setAttribute("channels", impulse.getNumChannels());
+        element->setAttribute("samples", impulse.getNumSamples());
+        juce::MemoryBlock data;
+        for (int ch = 0; ch < impulse.getNumChannels(); ++ch) {
+            data.append(impulse.getReadPointer(ch), sizeof(float) * (size_t)impulse.getNumSamples());
+        }
+        element->addTextElement(data.toBase64Encoding());
+    }
+
+    bool loadExtraState(const juce::XmlElement& xml) override {
+        auto* element = xml.getChildByName("Impulse");
+        if (element == nullptr) return false;
+        int numChannels = element->getIntAttribute("channels");
+        int numSamples = element->getIntAttribute("samples");
+        double rate = element->getDoubleAttribute("rate");
+        juce::MemoryBlock data;
+        if (numChannels <= 0 || numSamples <= 0 || !(rate > 0.0) || !data.fromBase64Encoding(element->getAllSubText())
+            || data.getSize() != sizeof(float) * (size_t)numChannels * (size_t)numSamples) return false;
+
+        juce::AudioBuffer<float> ir(numChannels, numSamples);
+        for (int ch = 0; ch < numChannels; ++ch) {
+            std::memcpy(ir.getWritePointer(ch), static_cast<const float*>(data.getData()) + (size_t)ch * (size_t)numSamples,
+                        sizeof(float) * (size_t)numSamples);
+        }
+        impulse = std::move(ir);
+        impulseRate = rate;
+        if (getSampleRate() > 0.0) prepareToPlay(getSampleRate(), getBlockSize());
+        return true;
+    }
+
+    juce::AudioBuffer<float> impulse;
+    double impulseRate;
+    juce::dsp::FFT fft;
+    std::vector<float> frame;
+    std::vector<std::vector<Spectrum>> partitions; // [IR channel][partition]
+    std::vector<ChannelState> channels;
+
+    float mix = 1.0f; // 0 = dry, 1 = wet
+};
+
+// --- DC Filter ---
+// First-order high-pass at 5 Hz that removes DC offset while leaving audible
+// frequencies untouched. It has no parameters.
+class DCFilterProcessor : public BaseInternalProcessor {
+public:
+    DCFilterProcessor() : BaseInternalProcessor("DCFilter") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        filter.prepare(spec);
+        *filter.state = *juce::dsp::IIR::Coefficients<float>::makeFirstOrderHighPass(spec.sampleRate, 5.0f);
+        filter.reset();
+    }
+
+    void reset() override { filter.reset(); }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        juce::dsp::AudioBlock<float> block(buffer);
+        filter.process(juce::dsp::ProcessContextReplacing<float>(block));
+    }
+
+    void setParam(int, float) override {}
+    float getParam(int) override { return 0.0f; }
+    int getNumParams() override { return 0; }
+
+    juce::dsp::ProcessorDuplicator<juce::dsp::IIR::Filter<float>, juce::dsp::IIR::Coefficients<float>> filter;
+};
+
+// --- Time Stretch ---
+// Phase-vocoder time stretch: analysis frames are read from the input every
+// kHopSize * speed samples and resynthesised every kHopSize samples with their phases
+// advanced at each bin's measured frequency, so duration changes while pitch does not.
+// Each block is queued in full before output is synthesised, so a single call sees the
+// whole input. Output is delayed by kLatency / speed samples (1536 at 1x: the frame size
+// minus one hop), which is reported as the processor's latency. When slowing down the
+// input queue grows until it holds kQueueHeadroom samples more than a block, and input
+// beyond that is dropped; when speeding up the output runs ahead of the input and is
+// silent until more arrives. At 1x the input passes through untouched and any queued
+// audio is dropped. Buffers are sized in prepare, so processing does not allocate.
+class TimeStretchProcessor : public BaseInternalProcessor {
+public:
+    static constexpr int kFftOrder = 11;
+    static constexpr int kFftSize = 1 << kFftOrder;
+    static constexpr int kHopSize = kFftSize / 4;
+    static constexpr int kLatency = kFftSize - kHopSize;
+    static constexpr int kQueueHeadroom = kFftSize * 8;
+
+    struct ChannelState {
+        std::vector<float> queue; // input not yet analysed, the first queued samples are valid
+        int queued = 0;
+        std::vector<float> accum = std::vector<float>((size_t)kFftSize, 0.0f); // overlap-add of synthesised frames
+        std::vector<float> ready; // finished output samples, the first numReady are valid
+        int numReady = 0;
+        std::vector<float> lastPhase = std::vector<float>((size_t)kFftSize / 2 + 1, 0.0f);
+        std::vector<float> sumPhase = std::vector<float>((size_t)kFftSize / 2 + 1, 0.0f);
+        double analysisPos = 0.0; // start of the next analysis frame in queue
+        int lastStart = 0;
+        bool firstFrame = true;
+    };
+
+    TimeStretchProcessor() : BaseInternalProcessor("TimeStretch"), fft(kFftOrder), window((size_t)kFftSize) {
+        // Periodic Hann so overlapping frames sum to a constant
+        for (int i = 0; i < kFftSize; ++i)
+            window[(size_t)i] = 0.5f - 0.5f * std::cos(juce::MathConstants<float>::twoPi * (float)i / (float)kFftSize);
+    }
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        channels.assign(spec.numChannels, ChannelState());
+        for (auto& state : channels) {
+            state.queue.resize((size_t)spec.maximumBlockSize + kQueueHeadroom);
+            // A frame is only synthesised while less than a block is ready
+            state.ready.resize((size_t)spec.maximumBlockSize + kHopSize);
+        }
+        frame.assign((size_t)kFftSize * 2, 0.0f);
+        reset();
+    }
+
+    void reset() override {
+        for (auto& state : channels) {
+            // Start with a frame's worth of silence minus one hop, so the first frame
+            // ends one hop into the input
+            std::fill(state.queue.begin(), state.queue.end(), 0.0f);
+            state.queued = kLatency;
+            std::fill(state.accum.begin(), state.accum.end(), 0.0f);
+            state.numReady = 0;
+            std::fill(state.lastPhase.begin(), state.lastPhase.end(), 0.0f);
+            std::fill(state.sumPhase.begin(), state.sumPhase.end(), 0.0f);
+            state.analysisPos = 0.0;
+            state.lastStart = 0;
+            state.firstFrame = true;
+        }
+    }
+
+    double speed() const { return mapRangeLog(ratio, 0.5f, 2.0f); }
+    static bool isUnity(double speed) { return std::abs(speed - 1.0) < 1.0e-3; }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        double currentSpeed = speed();
+        if (isUnity(currentSpeed)) {
+            if (active) reset();
+            active = false;
+            return;
+        }
+        active = true;
+
+        int numSamples = buffer.getNumSamples();
+        int numChannels = juce::jmin(buffer.getNumChannels(), (int)channels.size());
+        for (int ch = 0; ch < numChannels; ++ch) {
+            auto& state = channels[(size_t)ch];
+            auto* data = buffer.getWritePointer(ch);
+            int capacity = (int)state.queue.size();
+
+            // Drop analysed input once enough has built up to make the copy worthwhile,
+            // or when the block would not fit otherwise
+            int consumed = juce::jmin((int)state.analysisPos, state.lastStart);
+            if (consumed > 0 && (consumed >= kFftSize * 4 || state.queued + numSamples > capacity)) {
+                std::copy(state.queue.begin() + consumed, state.queue.begin() + state.queued, state.queue.begin());
+                state.queued -= consumed;
+                state.analysisPos -= consumed;
+                state.lastStart -= consumed;
+            }
+
+            // Input the queue has no room for is dropped
+            int count = juce::jmin(numSamples, capacity - state.queued);
+            std::copy_n(data, count, state.queue.begin() + state.queued);
+            state.queued += count;
+
+            while (state.numReady < numSamples && (int)state.analysisPos + kFftSize <= state.queued) {
+                processFrame(state);
+                state.analysisPos += kHopSize * currentSpeed;
+            }
+
+            count = juce::jmin(numSamples, state.numReady);
+            std::copy_n(state.ready.begin(), count, data);
+            std::fill(data + count, data + numSamples, 0.0f);
+            std::copy(state.ready.begin() + count, state.ready.begin() + state.numReady, state.ready.begin());
+            state.numReady -= count;
+        }
+    }
+
+    void processFrame(ChannelState& state) {
+        const float twoPi = juce::MathConstants<float>::twoPi;
+        const int numBins = kFftSize / 2 + 1;
+        int start = (int)state.analysisPos;
+        int analysisHop = start - state.lastStart;
+
+        for (int i = 0; i < kFftSize; ++i) frame[(size_t)i] = state.queue[(size_t)(start + i)] * window[(size_t)i];
+        std::fill(frame.begin() + kFftSize, frame.end(), 0.0f);
+        fft.performRealOnlyForwardTransform(frame.data());
+        auto* bins = reinterpret_cast<std::complex<float>*>(frame.data());
+
+        for (int k = 0; k < numBins; ++k) {
+            float phase = std::arg(bins[k]);
+            if (state.firstFrame || analysisHop <= 0) {
+                state.sumPhase[(size_t)k] = phase;
+            } else {
+                // Measured frequency (radians per sample) from the phase advance over the analysis hop
+                float binFreq = twoPi * (float)k / (float)kFftSize;
+                float delta = phase - state.lastPhase[(size_t)k] - binFreq * (float)analysisHop;
+                delta -= twoPi * std::round(delta / twoPi);
+                float freq = binFreq + delta / (float)analysisHop;
+                state.sumPhase[(size_t)k] = std::fmod(state.sumPhase[(size_t)k] + freq * (float)kHopSize, twoPi);
+            }
+            state.lastPhase[(size_t)k] = phase;
+            bins[k] = std::polar(std::abs(bins[k]), state.sumPhase[(size_t)k]);
+            if (k > 0 && k < kFftSize / 2) bins[kFftSize - k] = std::conj(bins[k]);
+        }
+        state.firstFrame = false;
+        state.lastStart = start;
+
+        fft.performRealOnlyInverseTransform(frame.data());
+
+        // Squared periodic Hann at 4x overlap sums to 1.5
+        const float scale = 1.0f / 1.5f;
+        for (int i = 0; i < kFftSize; ++i) state.accum[(size_t)i] += frame[(size_t)i] * window[(size_t)i] * scale;
+
+        // The first hop is complete: no later frame overlaps it
+        std::copy_n(state.accum.begin(), kHopSize, state.ready.begin() + state.numReady);
+        state.numReady += kHopSize;
+        std::copy(state.accum.begin() + kHopSize, state.accum.end(), state.accum.begin());
+        std::fill(state.accum.end() - kHopSize, state.accum.end(), 0.0f);
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) ratio = value;
+        double currentSpeed = speed();
+        setLatencySamples(isUnity(currentSpeed) ? 0 : (int)std::round(kLatency / currentSpeed));
+    }
+    float getParam(int index) override { return index == 0 ? ratio : 0.0f; }
+    int getNumParams() override { return 1; }
+    int getMaxLatencySamples() override { return kLatency * 2; } // At half speed
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.5f, 2.0f, true, "x" };
+        }
+        return {};
+    }
+
+    juce::dsp::FFT fft;
+    std::vector<float> window, frame;
+    std::vector<ChannelState> channels;
+    bool active = false;
+
+    float ratio = 0.5f; // 0-1 mapped to 0.5x-2x speed (log), 0.5 = 1x
+};
+
+// --- Waveform Follower ---
+// Peak envelope follower that passes audio through unchanged. The envelope of each
+// channel (up to kMaxChannels) rises at the attack rate and falls at the release rate,
+// and is stored after every block so it can be read from another thread while a
+// stream runs.
+class WaveformFollowerProcessor : public BaseInternalProcessor {
+public:
+    static constexpr int kMaxChannels = 16;
+
+    WaveformFollowerProcessor() : BaseInternalProcessor("WaveformFollower") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        sampleRate = spec.sampleRate;
+        reset();
+    }
+
+    void reset() override {
+        for (int ch = 0; ch < kMaxChannels; ++ch) {
+            envelope[ch] = 0.0f;
+            levels[ch].store(0.0f);
+        }
+    }
+
+    float coefficient(float ms) const {
+        return (float)std::exp(-1.0 / (ms * 0.001 * sampleRate));
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        float attackCoeff = coefficient(mapRangeLog(attack, 0.1f, 100.0f));
+        float releaseCoeff = coefficient(mapRangeLog(release, 1.0f, 1000.0f));
+        int numChannels = std::min(buffer.getNumChannels(), kMaxChannels);
+
+        for (int ch = 0; ch < numChannels; ++ch) {
+            const float* data = buffer.getReadPointer(ch);
+            float env = envelope[ch];
+            for (int i = 0; i < buffer.getNumSamples(); ++i) {
+                float x = std::abs(data[i]);
+                float coeff = x > env ? attackCoeff : releaseCoeff;
+                env = x + coeff * (env - x);
+            }
+            envelope[ch] = env;
+            levels[ch].store(env);
+        }
+    }
+
+    bool getEnvelopeLevel(int channel, float& level) override {
+        if (channel < 0 || channel >= kMaxChannels) return false;
+        level = levels[channel].load();
+        return true;
+    }
+
+    void setParam(int index, float value) override {
+        if (index == 0) attack = value;
+        else if (index == 1) release = value;
+    }
+    float getParam(int index) override {
+        if (index == 0) return attack;
+        if (index == 1) return release;
+        return 0.0f;
+    }
+    int getNumParams() override { return 2; }
+    ParamMapping getParamMapping(int index) override {
+        switch (index) {
+            case 0: return { 0.1f, 100.0f, true, "ms" };
+            case 1: return { 1.0f, 1000.0f, true, "ms" };
+        }
+        return {};
+    }
+
+    double sampleRate = 44100.0;
+    std::array<float, kMaxChannels> envelope {};
+    std::array<std::atomic<float>, kMaxChannels> levels {};
+    float attack = 0.33f;  // 0-1 mapped log to 0.1-100 ms (0.33 = ~1 ms)
+    float release = 0.67f; // 0-1 mapped log to 1-1000 ms (0.67 = ~100 ms)
+};
+
+// --- MIDI Thru ---
+// Passes audio through untouched and forwards incoming MIDI to its output.
+class MIDIThruProcessor : public BaseInternalProcessor {
+public:
+    MIDIThruProcessor() : BaseInternalProcessor("MIDIThru") {}
+
+    bool acceptsMidi() const override { return true; }
+    bool producesMidi() const override { return true; }
+
+    void processBlock(juce::AudioBuffer<float>&, juce::MidiBuffer&) override {
+        // MIDI events are left in the buffer so they reach the output unchanged
+    }
+
+    void setParam(int, float) override {}
+    float getParam(int) override { return 0.0f; }
+    int getNumParams() override { return 0; }
+};
+
+// --- External ---
+// Hands each block to Go, which runs it elsewhere (e.g., in a sandbox host process).
+// Lets such processors be chain stages; parameters stay on the Go side.
+class ExternalProcessor : public BaseInternalProcessor {
+public:
+    explicit ExternalProcessor(uintptr_t ctx) : BaseInternalProcessor("External"), context(ctx) {}
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer&) override {
+        goPedalboardProcessExternal(context, buffer.getArrayOfWritePointers(), buffer.getNumChannels(),
+                                    buffer.getNumSamples(), getSampleRate());
+    }
+
+    void setParam(int, float) override {}
+    float getParam(int) override { return 0.0f; }
+    int getNumParams() override { return 0; }
+
+private:
+    uintptr_t context;
+};
+
+// --- Processor Chain ---
+// Runs a list of processors in series. Stages are not owned by the chain; the Go
+// side keeps them alive. The stage list is guarded by a spin lock so stages can be
+// swapped while an audio stream is running.
+class ChainProcessor : public BaseInternalProcessor {
+public:
+    ChainProcessor() : BaseInternalProcessor("Chain") {}
+
+    void prepare(const juce::dsp::ProcessSpec& spec) override {
+        setRateAndBufferSizeDetails(spec.sampleRate, (int)spec.maximumBlockSize);
+        const juce::SpinLock::ScopedLockType sl(lock);
+        for (auto* stage : stages) prepareStage(stage);
+    }
+
+    void reset() override {
+        const juce::SpinLock::ScopedLockType sl(lock);
+        for (auto* stage : stages) stage->processor->reset();
+    }
+
+    void releaseResources() override {
+        const juce::SpinLock::ScopedLockType sl(lock);
+        for (auto* stage : stages) stage->processor->releaseResources();
+    }
+
+    void prepareStage(ProcessorWrapper* stage) {
+        if (stage == nullptr || getSampleRate() <= 0.0) return;
+        stage->processor->setRateAndBufferSizeDetails(getSampleRate(), getBlockSize());
+        prepareWrapper(stage, getSampleRate(), getBlockSize(), numChannels);
+    }
+
+    void processBlock(juce::AudioBuffer<float>& buffer, juce::MidiBuffer& midi) override {
+        const juce::SpinLock::ScopedLockType sl(lock);
+        for (size_t i = 0; i < stages.size(); ++i) {
+            if (!enabled[i]) continue;
+            auto* stage = stages[i];
+            if (stage->processor->getSampleRate() != getSampleRate()) prepareStage(stage);
+            processWrapper(stage, buffer, midi);
+        }
+    }
+
+    void insert(int index, ProcessorWrapper* stage) {
+        prepareStage(stage);
+        const juce::SpinLock::ScopedLockType sl(lock);
+        index = juce::jlimit(0, (int)stages.size(), index);
+        stages.insert(stages.begin() + index, stage);
+        enabled.insert(enabled.begin() + index, true);
+    }
+
+    bool remove(int index) {
+        const juce::SpinLock::ScopedLockType sl(lock);
+        if (index < 0 || index >= (int)stages.size()) return false;
+        stages.erase(stages.begin() + index);
+        enabled.erase(enabled.begin() + index);
+        return true;
+    }
+
+    bool replace(int index, ProcessorWrapper* stage) {
+        // Prepare outside the lock so the audio thread is only blocked for the swap
+        prepareStage(stage);
+        const juce::SpinLock::ScopedLockType sl(lock);
+        if (index < 0 || index >= (int)stages.size()) return false;
+        stages[(size_t)index] = stage;
+        return true;
+    }
+
+    // A disabled stage stays in the chain but is skipped, as if bypassed.
+    bool setEnabled(int index, bool enable) {
+        const juce::SpinLock::ScopedLockType sl(lock);
+        if (index < 0 || index >= (int)stages.size()) return false;
+        enabled[(size_t)index] = enable;
+        return true;
+    }
+
+    // The latency of the enabled stages added up, which is what the chain delays audio by.
+    int latencySamples() {
+        const juce::SpinLock::ScopedLockType sl(lock);
+        int total = 0;
+        for (size_t i = 0; i < stages.size(); ++i) {
+            if (enabled[i]) total += wrapperLatency(stages[i]);
+        }
+        return total;
+    }
+
+    // The most latency the current stages can add up to, with every stage enabled.
+    int getMaxLatencySamples() override {
+        const juce::SpinLock::ScopedLockType sl(lock);
+        int total = 0;
+        for (auto* stage : stages) {
+            auto* internal = dynamic_cast<BaseInternalProcessor*>(stage->processor.get());
+            total += internal ? internal->getMaxLatencySamples() : stage->processor->getLatencySamples();
+        }
+        return total;
+    }
+
+    void setParam(int, float) override {}
+    float getParam(int) override { return 0.0f; }
+    int getNumParams() override { return 0; }
+
+    juce::SpinLock lock;
+    std::vector<ProcessorWrapper*> stages;
+    std::vector<bool> enabled; // Parallel to stages
+    int numChannels = 2;       // Channels the stages are prepared for, set by prepareWrapper
+};
+
+// Returns the latency a wrapped processor reports or, for a chain, that of its stages.
+static int wrapperLatency(ProcessorWrapper* wrapper) {
+    if (auto* chain = dynamic_cast<ChainProcessor*>(wrapper->processor.get())) return chain->latencySamples();
+    return wrapper->processor->getLatencySamples();
+}
+
+// Prepares the wrapped processor for numChannels of audio and sizes its bypass delay for
+// the most latency it can report, so bypassing does not allocate while processing.
+static void prepareWrapper(ProcessorWrapper* wrapper, double sampleRate, int blockSize, int numChannels) {
+    auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get());
+    if (auto* chain = dynamic_cast<ChainProcessor*>(internal)) chain->numChannels = numChannels;
+    wrapper->processor->prepareToPlay(sampleRate, blockSize);
+
+    int maxLatency = internal ? internal->getMaxLatencySamples() : wrapper->processor->getLatencySamples();
+    wrapper->bypassDelay.setSize(numChannels, maxLatency);
+    wrapper->bypassDelay.clear();
+    wrapper->bypassDelayLength = 0;
+    wrapper->bypassDelayPos = 0;
+}
+
+static ChainProcessor* asChain(PedalboardProcessor chain) {
+    if (!chain) return nullptr;
+    return dynamic_cast<ChainProcessor*>(static_cast<ProcessorWrapper*>(chain)->processor.get());
+}
+
+PedalboardProcessor pedalboard_create_convolution_reverb(float** impulse_response, int num_channels, int num_samples, double sample_rate) {
+    if (impulse_response == nullptr || num_channels <= 0 || num_samples <= 0) return nullptr;
+
+    // Copy the IR, since the caller's memory is released after this call
+    juce::AudioBuffer<float> ir(num_channels, num_samples);
+    for (int ch = 0; ch < num_channels; ++ch) ir.copyFrom(ch, 0, impulse_response[ch], num_samples);
+
+    auto wrapper = new ProcessorWrapper();
+    wrapper->processor = std::make_unique<ConvolutionReverbProcessor>(std::move(ir), sample_rate);
+    return static_cast<PedalboardProcessor>(wrapper);
+}
+
+PedalboardProcessor pedalboard_create_external_processor(uintptr_t context) {
+    auto wrapper = new ProcessorWrapper();
+    wrapper->processor = std::make_unique<ExternalProcessor>(context);
+    return static_cast<PedalboardProcessor>(wrapper);
+}
+
+PedalboardProcessor pedalboard_create_chain() {
+    auto wrapper = new ProcessorWrapper();
+    wrapper->processor = std::make_unique<ChainProcessor>();
+    return static_cast<PedalboardProcessor>(wrapper);
+}
+
+int pedalboard_chain_insert(PedalboardProcessor chain, int index, PedalboardProcessor processor) {
+    auto* c = asChain(chain);
+    if (c == nullptr || !processor) return 0;
+    c->insert(index, static_cast<ProcessorWrapper*>(processor));
+    return 1;
+}
+
+int pedalboard_chain_set_stage_enabled(PedalboardProcessor chain, int index, int enabled) {
+    auto* c = asChain(chain);
+    return (c != nullptr && c->setEnabled(index, enabled != 0)) ? 1 : 0;
+}
+
+int pedalboard_chain_remove(PedalboardProcessor chain, int index) {
+    auto* c = asChain(chain);
+    return (c != nullptr && c->remove(index)) ? 1 : 0;
+}
+
+int pedalboard_chain_replace(PedalboardProcessor chain, int index, PedalboardProcessor processor) {
+    auto* c = asChain(chain);
+    if (c == nullptr || !processor) return 0;
+    return c->replace(index, static_cast<ProcessorWrapper*>(processor)) ? 1 : 0;
+}
+
+// --- Factory ---
+
+PedalboardProcessor pedalboard_create_internal_processor(const char* name) {
+    juce::String processorName(name);
+    
+    std::unique_ptr<BaseInternalProcessor> proc;
+
+    if (processorName == "Gain") proc = std::make_unique<GainProcessor>();
+    else if (processorName == "Reverb") proc = std::make_unique<ReverbProcessor>();
+    else if (processorName == "Chorus") proc = std::make_unique<ChorusProcessor>();
+    else if (processorName == "Distortion") proc = std::make_unique<DistortionProcessor>();
+    else if (processorName == "Clipping") proc = std::make_unique<ClippingProcessor>();
+    else if (processorName == "Phaser") proc = std::make_unique<PhaserProcessor>();
+    else if (processorName == "Compressor") proc = std::make_unique<CompressorProcessor>();
+    else if (processorName == "Limiter") proc = std::make_unique<LimiterProcessor>();
+    else if (processorName == "NoiseGate") proc = std::make_unique<NoiseGateProcessor>();
+    else if (processorName == "Delay") proc = std::make_unique<DelayProcessor>();
+    else if (processorName == "LowPass") proc = std::make_unique<FilterProcessor>(LowPass);
+    else if (processorName == "HighPass") proc = std::make_unique<FilterProcessor>(HighPass);
+    else if (processorName == "LadderFilter") proc = std::make_unique<LadderProcessor>();
+    else if (processorName == "Bitcrush") proc = std::make_unique<BitcrushProcessor>();
+    else if (processorName == "MIDIThru") proc = std::make_unique<MIDIThruProcessor>();
+    else if (processorName == "ParametricEQ") proc = std::make_unique<ParametricEQProcessor>();
+    else if (processorName == "Tremolo") proc = std::make_unique<TremoloProcessor>();
+    else if (processorName == "Flanger") proc = std::make_unique<FlangerProcessor>();
+    else if (processorName == "Vibrato") proc = std::make_unique<VibratoProcessor>();
+    else if (processorName == "Freeze") proc = std::make_unique<FreezeProcessor>();
+    else if (processorName == "PitchShifter") proc = std::make_unique<PitchShifterProcessor>();
+    else if (processorName == "RingModulator") proc = std::make_unique<RingModulatorProcessor>();
+    else if (processorName == "TapeSaturation") proc = std::make_unique<TapeSaturationProcessor>();
+    else if (processorName == "StereoWidener") proc = std::make_unique<StereoWidenerProcessor>();
+    else if (processorName == "MidSideEncoder") proc = std::make_unique<MidSideProcessor>(true);
+    else if (processorName == "MidSideDecoder") proc = std::make_unique<MidSideProcessor>(false);
+    else if (processorName == "Panner") proc = std::make_unique<PannerProcessor>();
+    else if (processorName == "AutoGainControl") proc = std::make_unique<AutoGainControlProcessor>();
+    else if (processorName == "MultibandCompressor") proc = std::make_unique<MultibandCompressorProcessor>();
+    else if (processorName == "TransientShaper") proc = std::make_unique<TransientShaperProcessor>();
+    else if (processorName == "DCFilter") proc = std::make_unique<DCFilterProcessor>();
+    else if (processorName == "TimeStretch") proc = std::make_unique<TimeStretchProcessor>();
+    else if (processorName == "WaveformFollower") proc = std::make_unique<WaveformFollowerProcessor>();
+
+    if (proc) {
+        proc->captureParamDefaults();
+        auto wrapper = new ProcessorWrapper();
+        wrapper->processor = std::move(proc);
+        return static_cast<PedalboardProcessor>(wrapper);
+    }
+    
+    return nullptr;
+}
+
+// ... Rest of the file (LoadPlugin, AudioIO, Stream) ...
+
+// Instantiates the first plugin found at path, or returns nullptr.
+static std::unique_ptr<juce::AudioPluginInstance> createPlugin(const char* path) {
+    pedalboard_init();
+    juce::OwnedArray<juce::PluginDescription> descriptions;
+    
+    for (int i = 0; i < g_internal->pluginFormatManager.getNumFormats(); ++i) {
+        auto* format = g_internal->pluginFormatManager.getFormat(i);
+        format->findAllTypesForFile(descriptions, path);
+        if (descriptions.size() > 0) break;
+    }
+    
+    if (descriptions.size() == 0) return nullptr;
+    
+    juce::String error;
+    return g_internal->pluginFormatManager.createPluginInstance(*descriptions[0], 44100.0, 512, error);
+}
+
+PedalboardProcessor pedalboard_load_plugin(const char* path) {
+    auto plugin = createPlugin(path);
+    if (plugin == nullptr) return nullptr;
+    
+    auto wrapper = new ProcessorWrapper();
+    wrapper->processor = std::move(plugin);
+    return static_cast<PedalboardProcessor>(wrapper);
+}
+
+// Returns the channel set of num_channels for a main bus: the usual layout for that
+// count (mono, stereo, 5.1...) or discrete channels, and disabled for 0.
+static juce::AudioChannelSet mainBusChannelSet(int numChannels) {
+    if (numChannels <= 0) return juce::AudioChannelSet::disabled();
+    return juce::AudioChannelSet::canonicalChannelSet(numChannels);
+}
+
+PedalboardProcessor pedalboard_load_plugin_with_layout(const char* path, int num_inputs, int num_outputs, int* layout_supported) {
+    *layout_supported = 1;
+    auto plugin = createPlugin(path);
+    if (plugin == nullptr) return nullptr;
+
+    // The layout must be set before the plugin is prepared, which happens on first use
+    auto layout = plugin->getBusesLayout();
+    if ((layout.inputBuses.isEmpty() && num_inputs > 0) || layout.outputBuses.isEmpty()) {
+        *layout_supported = 0;
+        return nullptr;
+    }
+    if (!layout.inputBuses.isEmpty()) layout.inputBuses.getReference(0) = mainBusChannelSet(num_inputs);
+    layout.outputBuses.getReference(0) = mainBusChannelSet(num_outputs);
+    if (!plugin->setBusesLayout(layout)) {
+        *layout_supported = 0;
+        return nullptr;
+    }
+
+    auto wrapper = new ProcessorWrapper();
+    wrapper->processor = std::move(plugin);
+    return static_cast<PedalboardProcessor>(wrapper);
+}
+
+PedalboardStringList* pedalboard_lv2_search_path(const char* dir) {
+#if JUCE_PLUGINHOST_LV2
+    pedalboard_init();
+    for (int i = 0; i < g_internal->pluginFormatManager.getNumFormats(); ++i) {
+        auto* format = g_internal->pluginFormatManager.getFormat(i);
+        if (format->getName() != "LV2") continue;
+        // Loads the bundles in dir into the LV2 world and lists every plugin it knows
+        return newStringList(format->searchPathsForPlugins(juce::FileSearchPath(juce::String::fromUTF8(dir)), false, false));
+    }
+#else
+    juce::ignoreUnused(dir);
+#endif
+    return nullptr;
+}
+
+PedalboardPluginDescription* pedalboard_describe_plugin(const char* path) {
+    pedalboard_init();
+    juce::OwnedArray<juce::PluginDescription> descriptions;
+    for (int i = 0; i < g_internal->pluginFormatManager.getNumFormats(); ++i) {
+        g_internal->pluginFormatManager.getFormat(i)->findAllTypesForFile(descriptions, path);
+        if (descriptions.size() > 0) break;
+    }
+    if (descriptions.size() == 0) return nullptr;
+
+    // Only describe plugins that can actually be instantiated
+    const auto& desc = *descriptions[0];
+    juce::String error;
+    if (g_internal->pluginFormatManager.createPluginInstance(desc, 44100.0, 512, error) == nullptr) return nullptr;
+
+    auto* result = new PedalboardPluginDescription();
+    result->name = copyString(desc.name);
+    result->vendor = copyString(desc.manufacturerName);
+    result->category = copyString(desc.category);
+    result->unique_id = copyString(juce::String::toHexString(desc.uniqueId));
+    result->version = copyString(desc.version);
+    return result;
+}
+
+void pedalboard_plugin_description_free(PedalboardPluginDescription* description) {
+    if (description == nullptr) return;
+    free(description->name);
+    free(description->vendor);
+    free(description->category);
+    free(description->unique_id);
+    free(description->version);
+    delete description;
+}
+
+#if JUCE_MAC
+// Converts a four-character code such as "aufx" to its OSType.
+static OSType fourCharCode(const juce::String& code) {
+    if (code.length() != 4) return 0;
+    OSType value = 0;
+    for (int i = 0; i < 4; ++i) value = (value << 8) | (OSType)(code[i] & 0xff);
+    return value;
+}
+
+// Returns the string value of key in dict, or an empty string.
+static juce::String dictionaryString(CFDictionaryRef dict, CFStringRef key) {
+    auto value = (CFStringRef)CFDictionaryGetValue(dict, key);
+    if (value == nullptr || CFGetTypeID(value) != CFStringGetTypeID()) return {};
+    return juce::String::fromCFString(value);
+}
+#endif
+
+PedalboardPluginDescription* pedalboard_describe_audio_unit_bundle(const char* path, int* supported) {
+    *supported = 1;
+#if JUCE_MAC
+    auto url = CFURLCreateFromFileSystemRepresentation(nullptr, (const UInt8*)path, (CFIndex)strlen(path), true);
+    if (url == nullptr) return nullptr;
+    auto bundle = CFBundleCreate(nullptr, url);
+    CFRelease(url);
+    if (bundle == nullptr) return nullptr;
+
+    PedalboardPluginDescription* result = nullptr;
+    auto components = (CFArrayRef)CFBundleGetValueForInfoDictionaryKey(bundle, CFSTR("AudioComponents"));
+    if (components != nullptr && CFGetTypeID(components) == CFArrayGetTypeID() && CFArrayGetCount(components) > 0) {
+        auto entry = (CFDictionaryRef)CFArrayGetValueAtIndex(components, 0);
+        if (CFGetTypeID(entry) == CFDictionaryGetTypeID()) {
+            auto type = dictionaryString(entry, CFSTR("type"));
+            AudioComponentDescription desc = {};
+            desc.componentType = fourCharCode(type);
+            desc.componentSubType = fourCharCode(dictionaryString(entry, CFSTR("subtype")));
+            desc.componentManufacturer = fourCharCode(dictionaryString(entry, CFSTR("manufacturer")));
+
+            auto name = dictionaryString(entry, CFSTR("name"));
+            UInt32 version = 0;
+            auto number = (CFNumberRef)CFDictionaryGetValue(entry, CFSTR("version"));
+            if (number != nullptr && CFGetTypeID(number) == CFNumberGetTypeID()) CFNumberGetValue(number, kCFNumberSInt32Type, &version);
+
+            // A registered component reports what hosts display
+            if (auto component = AudioComponentFindNext(nullptr, &desc)) {
+                CFStringRef registeredName = nullptr;
+                if (AudioComponentCopyName(component, &registeredName) == noErr && registeredName != nullptr) {
+                    name = juce::String::fromCFString(registeredName);
+                    CFRelease(registeredName);
+                }
+                AudioComponentGetVersion(component, &version);
+            }
+
+            // The version is packed as 0xMMMMmmbb
+            juce::String versionString;
+            if (version != 0) {
+                versionString << (int)(version >> 16) << "." << (int)((version >> 8) & 0xff) << "." << (int)(version & 0xff);
+            } else if (auto shortVersion = (CFStringRef)CFBundleGetValueForInfoDictionaryKey(bundle, CFSTR("CFBundleShortVersionString"));
+                       shortVersion != nullptr && CFGetTypeID(shortVersion) == CFStringGetTypeID()) {
+                versionString = juce::String::fromCFString(shortVersion);
+            }
+
+            result = new PedalboardPluginDescription();
+            result->name = copyString(name);
+            result->vendor = copyString({});
+            result->category = copyString(type);
+            // As AudioUnitPluginFormat computes PluginDescription::uniqueId
+            result->unique_id = copyString(juce::String::toHexString((int)(desc.componentType ^ desc.componentSubType ^ desc.componentManufacturer)));
+            result->version = copyString(versionString);
+        }
+    }
+    CFRelease(bundle);
+    return result;
+#else
+    juce::ignoreUnused(path);
+    *supported = 0;
+    return nullptr;
+#endif
+}
+
+// --- Markers ---
+// Markers are stored as cue points with labels (WAV cue/adtl chunks, AIFF MARK chunk).
+// The marker type and colour are kept in a cue note as "type=<n>;color=<c>", which only WAV supports.
+
+static void writeMarkers(const PedalboardAudioBuffer* buffer, juce::StringPairArray& values) {
+    if (buffer->num_markers <= 0) return;
+    values.set("NumCuePoints", juce::String(buffer->num_markers));
+    values.set("NumCueLabels", juce::String(buffer->num_markers));
+    values.set("NumCueNotes", juce::String(buffer->num_markers));
+    for (int i = 0; i < buffer->num_markers; ++i) {
+        const auto& marker = buffer->markers[i];
+        juce::String id(i + 1);
+        juce::String index(i);
+        values.set("Cue" + index + "Identifier", id);
+        values.set("Cue" + index + "Offset", juce::String(marker.position));
+        values.set("CueLabel" + index + "Identifier", id);
+        values.set("CueLabel" + index + "Text", juce::String::fromUTF8(marker.name ? marker.name : ""));
+        values.set("CueNote" + index + "Identifier", id);
+        values.set("CueNote" + index + "Text", "type=" + juce::String(marker.type) + ";color="
+                   + juce::String::fromUTF8(marker.color ? marker.color : ""));
+    }
+}
+
+static void readMarkers(const juce::StringPairArray& values, PedalboardAudioBuffer* result) {
+    int numCues = values.getValue("NumCuePoints", "0").getIntValue();
+    int numLabels = values.getValue("NumCueLabels", "0").getIntValue();
+    int numNotes = values.getValue("NumCueNotes", "0").getIntValue();
+    result->num_markers = 0;
+    result->markers = nullptr;
+    if (numCues <= 0) return;
+
+    result->markers = (PedalboardMarker*)calloc((size_t)numCues, sizeof(PedalboardMarker));
+    result->num_markers = numCues;
+    for (int i = 0; i < numCues; ++i) {
+        auto& marker = result->markers[i];
+        juce::String index(i);
+        auto id = values.getValue("Cue" + index + "Identifier", "");
+        marker.position = values.getValue("Cue" + index + "Offset", "0").getLargeIntValue();
+
+        juce::String name, note;
+        for (int l = 0; l < numLabels; ++l) {
+            if (values.getValue("CueLabel" + juce::String(l) + "Identifier", "") == id) {
+                name = values.getValue("CueLabel" + juce::String(l) + "Text", "");
+                break;
+            }
+        }
+        for (int n = 0; n < numNotes; ++n) {
+            if (values.getValue("CueNote" + juce::String(n) + "Identifier", "") == id) {
+                note = values.getValue("CueNote" + juce::String(n) + "Text", "");
+                break;
+            }
+        }
+
+        juce::String color;
+        marker.type = 0;
+        for (auto& field : juce::StringArray::fromTokens(note, ";", "")) {
+            if (field.startsWith("type=")) marker.type = field.fromFirstOccurrenceOf("=", false, false).getIntValue();
+            else if (field.startsWith("color=")) color = field.fromFirstOccurrenceOf("=", false, false);
+        }
+        marker.name = copyString(name);
+        marker.color = copyString(color);
+    }
+}
+
+// Reads all of reader into a newly allocated buffer, or returns nullptr if reader is null.
+static PedalboardAudioBuffer* readAudioBuffer(std::unique_ptr<juce::AudioFormatReader> reader) {
+    if (reader == nullptr) return nullptr;
+    
+    auto* result = new PedalboardAudioBuffer();
+    result->num_channels = (int)reader->numChannels;
+    result->num_samples = (int)reader->lengthInSamples;
+    result->sample_rate = reader->sampleRate;
+    result->bits_per_sample = (int)reader->bitsPerSample;
+    
+    result->data = (float**)malloc(sizeof(float*) * result->num_channels);
+    for (int i = 0; i < result->num_channels; ++i) {
+        result->data[i] = (float*)malloc(sizeof(float) * result->num_samples);
+    }
+    
+    juce::AudioBuffer<float> tempBuffer(result->data, result->num_channels, result->num_samples);
+    reader->read(&tempBuffer, 0, result->num_samples, 0, true, true);
+
+    readMarkers(reader->metadataValues, result);
+    return result;
+}
+
+PedalboardAudioBuffer* pedalboard_load_audio_file(const char* path) {
+    pedalboard_init();
+    juce::File file(path);
+    return readAudioBuffer(std::unique_ptr<juce::AudioFormatReader>(g_internal->formatManager.createReaderFor(file)));
+}
+
+PedalboardAudioBuffer* pedalboard_load_audio_data(const void* data, size_t size) {
+    if (data == nullptr || size == 0) return nullptr;
+    pedalboard_init();
+    auto stream = std::make_unique<juce::MemoryInputStream>(data, size, false);
+    return readAudioBuffer(std::unique_ptr<juce::AudioFormatReader>(g_internal->formatManager.createReaderFor(std::move(stream))));
+}
+
+int pedalboard_save_audio_file(const char* path, PedalboardAudioBuffer* buffer) {
+    return pedalboard_save_audio_file_with_depth(path, buffer, 16);
+}
+
+int pedalboard_save_audio_file_with_depth(const char* path, PedalboardAudioBuffer* buffer, int bits_per_sample) {
+    if (buffer == nullptr) return 0;
+    pedalboard_init();
+    juce::File file(path);
+    if (file.existsAsFile()) file.deleteFile();
+    
+    auto* format = g_internal->formatManager.findFormatForFileExtension(file.getFileExtension());
+    if (format == nullptr) format = g_internal->formatManager.getDefaultFormat();
+    if (format == nullptr) return 0;
+    
+    juce::StringPairArray metadata;
+    writeMarkers(buffer, metadata);
+
+    auto stream = std::make_unique<juce::FileOutputStream>(file);
+    if (stream->failedToOpen()) return 0;
+    std::unique_ptr<juce::AudioFormatWriter> writer(format->createWriterFor(stream.get(), 
+                                                                         buffer->sample_rate, 
+                                                                         (unsigned int)buffer->num_channels, 
+                                                                         bits_per_sample, 
+                                                                         metadata, 
+                                                                         0));
+    if (writer == nullptr) return 0;
+    // The writer owns the stream once created
+    stream.release();
+
+    juce::AudioBuffer<float> tempBuffer(buffer->data, buffer->num_channels, buffer->num_samples);
+    return writer->writeFromAudioSampleBuffer(tempBuffer, 0, buffer->num_samples) ? 1 : 0;
+}
 
 void pedalboard_audio_buffer_free(PedalboardAudioBuffer* buffer) {
     if (buffer == nullptr) return;
@@ -699,6 +3292,11 @@ void pedalboard_audio_buffer_free(PedalboardAudioBuffer* buffer) {
         free(buffer->data[i]);
     }
     free(buffer->data);
+    for (int i = 0; i < buffer->num_markers; ++i) {
+        free(buffer->markers[i].name);
+        free(buffer->markers[i].color);
+    }
+    free(buffer->markers);
     delete buffer;
 }
 
@@ -706,36 +3304,107 @@ void pedalboard_processor_free(PedalboardProcessor processor) {
     if (processor) delete static_cast<ProcessorWrapper*>(processor);
 }
 
+// Drops any pending ramp on a parameter, so a value set directly is not overwritten.
+static void cancelRamp(ProcessorWrapper* wrapper, int index) {
+    const juce::SpinLock::ScopedLockType sl(wrapper->rampLock);
+    wrapper->ramps.erase(std::remove_if(wrapper->ramps.begin(), wrapper->ramps.end(),
+        [index](const ParameterRamp& r) { return r.index == index; }), wrapper->ramps.end());
+}
+
 void pedalboard_processor_set_parameter(PedalboardProcessor processor, int index, float value) {
     if (!processor) return;
     auto* wrapper = static_cast<ProcessorWrapper*>(processor);
-    
-    // Check if it's our internal base class
-    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
-        internal->setParam(index, value);
+    cancelRamp(wrapper, index);
+    setWrapperParameter(wrapper, index, value);
+}
+
+float pedalboard_processor_get_parameter(PedalboardProcessor processor, int index) {
+    if (!processor) return 0.0f;
+    return getWrapperParameter(static_cast<ProcessorWrapper*>(processor), index);
+}
+
+void pedalboard_processor_ramp_parameter(PedalboardProcessor processor, int index, float target, int duration_samples) {
+    if (!processor) return;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+
+    // A new ramp replaces any pending ramp on the same parameter
+    cancelRamp(wrapper, index);
+    const juce::SpinLock::ScopedLockType sl(wrapper->rampLock);
+    if (duration_samples <= 0) {
+        setWrapperParameter(wrapper, index, target);
         return;
     }
+    wrapper->ramps.push_back({ index, getWrapperParameter(wrapper, index), target, duration_samples });
+}
 
-    // External plugin
-    auto& params = wrapper->processor->getParameters();
-    if (index >= 0 && index < params.size()) {
-        params[index]->setValueNotifyingHost(value);
-    }
+int pedalboard_processor_set_parameter_text(PedalboardProcessor processor, int index, const char* text) {
+    if (!processor || text == nullptr) return 0;
+    if (index < 0 || index >= pedalboard_processor_get_num_parameters(processor)) return -1;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    float value = 0.0f;
+    if (!getWrapperParameterValueForText(wrapper, index, juce::String::fromUTF8(text), value)) return 0;
+    cancelRamp(wrapper, index);
+    setWrapperParameter(wrapper, index, value);
+    return 1;
 }
 
-float pedalboard_processor_get_parameter(PedalboardProcessor processor, int index) {
-    if (!processor) return 0.0f;
+char* pedalboard_processor_get_parameter_name(PedalboardProcessor processor, int index) {
+    if (!processor || index < 0 || index >= pedalboard_processor_get_num_parameters(processor)) return nullptr;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    // Internal processors don't name their parameters
+    if (dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) return copyString({});
+    return copyString(wrapper->processor->getParameters()[index]->getName(256));
+}
+
+char* pedalboard_processor_get_parameter_text(PedalboardProcessor processor, int index) {
+    if (!processor || index < 0 || index >= pedalboard_processor_get_num_parameters(processor)) return nullptr;
+    return copyString(getWrapperParameterText(static_cast<ProcessorWrapper*>(processor), index));
+}
+
+void pedalboard_processor_reset(PedalboardProcessor processor) {
+    if (!processor) return;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    const std::lock_guard<std::mutex> guard(wrapper->processLock);
+    wrapper->processor->reset();
+}
+
+int pedalboard_processor_trigger(PedalboardProcessor processor, int enable) {
+    if (!processor) return 0;
     auto* wrapper = static_cast<ProcessorWrapper*>(processor);
-    
     if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
-        return internal->getParam(index);
+        return internal->trigger(enable != 0) ? 1 : 0;
     }
+    return 0;
+}
 
-    auto& params = wrapper->processor->getParameters();
-    if (index >= 0 && index < params.size()) {
-        return params[index]->getValue();
+void pedalboard_processor_set_bypassed(PedalboardProcessor processor, int bypassed) {
+    if (!processor) return;
+    static_cast<ProcessorWrapper*>(processor)->bypassed.store(bypassed != 0);
+}
+
+int pedalboard_processor_is_bypassed(PedalboardProcessor processor) {
+    if (!processor) return 0;
+    return static_cast<ProcessorWrapper*>(processor)->bypassed.load() ? 1 : 0;
+}
+
+int64_t pedalboard_processor_get_processed_samples(PedalboardProcessor processor) {
+    if (!processor) return 0;
+    return static_cast<ProcessorWrapper*>(processor)->processedSamples.load();
+}
+
+int pedalboard_processor_get_latency_samples(PedalboardProcessor processor) {
+    if (!processor) return 0;
+    return wrapperLatency(static_cast<ProcessorWrapper*>(processor));
+}
+
+float pedalboard_processor_get_envelope_level(PedalboardProcessor processor, int channel) {
+    if (!processor) return 0.0f;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    float level = 0.0f;
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        internal->getEnvelopeLevel(channel, level);
     }
-    return 0.0f;
+    return level;
 }
 
 int pedalboard_processor_get_num_parameters(PedalboardProcessor processor) {
@@ -749,26 +3418,171 @@ int pedalboard_processor_get_num_parameters(PedalboardProcessor processor) {
     return wrapper->processor->getParameters().size();
 }
 
-void pedalboard_processor_process(PedalboardProcessor processor, float** samples, int num_channels, int num_samples, double sample_rate) {
-    if (!processor) return;
+void* pedalboard_processor_get_state(PedalboardProcessor processor, size_t* size) {
+    if (!processor) return nullptr;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    juce::MemoryBlock state;
+    {
+        const std::lock_guard<std::mutex> guard(wrapper->processLock);
+        wrapper->processor->getStateInformation(state);
+    }
+    if (state.getSize() == 0) return nullptr;
+
+    void* data = std::malloc(state.getSize());
+    if (!data) return nullptr;
+    std::memcpy(data, state.getData(), state.getSize());
+    *size = state.getSize();
+    return data;
+}
+
+int pedalboard_processor_set_state(PedalboardProcessor processor, const void* data, size_t size) {
+    if (!processor || !data || size == 0 || size > (size_t)std::numeric_limits<int>::max()) return 0;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    const std::lock_guard<std::mutex> guard(wrapper->processLock);
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        return internal->loadState(data, (int)size) ? 1 : 0;
+    }
+    // Plugins don't report whether they accepted their state. One that kept its previous
+    // state, although data differs from it, is taken to have rejected data.
+    juce::MemoryBlock before, after;
+    wrapper->processor->getStateInformation(before);
+    wrapper->processor->setStateInformation(data, (int)size);
+    wrapper->processor->getStateInformation(after);
+    return (after != before || before.matches(data, size)) ? 1 : 0;
+}
+
+int pedalboard_processor_get_parameter_range(PedalboardProcessor processor, int index, float* min, float* max, float* default_value) {
+    if (!processor || index < 0 || index >= pedalboard_processor_get_num_parameters(processor)) return 0;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        auto mapping = internal->getParamMapping(index);
+        *min = juce::jmin(mapping.at0, mapping.at1);
+        *max = juce::jmax(mapping.at0, mapping.at1);
+        *default_value = mapping.toValue(internal->getParamDefault(index));
+        return 1;
+    }
+
+    auto* param = wrapper->processor->getParameters()[index];
+    if (auto* ranged = dynamic_cast<juce::RangedAudioParameter*>(param)) {
+        const auto& range = ranged->getNormalisableRange();
+        *min = range.start;
+        *max = range.end;
+        *default_value = range.convertFrom0to1(ranged->getDefaultValue());
+    } else {
+        // Hosted plugin parameters only expose normalized values
+        *min = 0.0f;
+        *max = 1.0f;
+        *default_value = param->getDefaultValue();
+    }
+    return 1;
+}
+
+char* pedalboard_processor_get_parameter_unit(PedalboardProcessor processor, int index) {
+    if (!processor || index < 0 || index >= pedalboard_processor_get_num_parameters(processor)) return nullptr;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        return copyString(internal->getParamMapping(index).unit);
+    }
+    return copyString(wrapper->processor->getParameters()[index]->getLabel());
+}
+
+PedalboardStringList* pedalboard_processor_get_parameter_choices(PedalboardProcessor processor, int index) {
+    if (!processor || index < 0 || index >= pedalboard_processor_get_num_parameters(processor)) return nullptr;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+
+    juce::StringArray choices;
+    if (auto* internal = dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get())) {
+        choices = internal->getParamChoices(index);
+    } else {
+        auto* param = wrapper->processor->getParameters()[index];
+        if (param->isDiscrete()) choices = param->getAllValueStrings();
+    }
+
+    return newStringList(choices);
+}
+
+void pedalboard_string_list_free(PedalboardStringList* list) {
+    if (list == nullptr) return;
+    for (int i = 0; i < list->count; ++i) free(list->items[i]);
+    free(list->items);
+    delete list;
+}
+
+int pedalboard_processor_process(PedalboardProcessor processor, float** samples, int num_channels, int num_samples, double sample_rate) {
+    if (!processor || samples == nullptr || num_channels <= 0 || num_samples < 0) return 0;
     auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    const std::lock_guard<std::mutex> guard(wrapper->processLock);
+
+    // Plugins read and write every bus channel; internal processors handle any count
+    if (dynamic_cast<BaseInternalProcessor*>(wrapper->processor.get()) == nullptr) {
+        int needed = juce::jmax(wrapper->processor->getTotalNumInputChannels(), wrapper->processor->getTotalNumOutputChannels());
+        if (num_channels < needed) return 0;
+    }
     juce::AudioBuffer<float> buffer(samples, num_channels, num_samples);
     
-    // Simplified prepare call if rate changes
-    if (wrapper->processor->getSampleRate() != sample_rate) {
-        wrapper->processor->prepareToPlay(sample_rate, num_samples);
+    // Prepare on the first call, when the rate changes or when a block is larger or has
+    // more channels than prepared for
+    if (wrapper->processor->getSampleRate() != sample_rate || num_samples > wrapper->processor->getBlockSize()
+        || num_channels > wrapper->bypassDelay.getNumChannels()) {
+        prepareWrapper(wrapper, sample_rate, num_samples, num_channels);
     }
     
-    wrapper->processor->processBlock(buffer, wrapper->midiBuffer);
+    processWrapper(wrapper, buffer, wrapper->midiBuffer);
+
+    // Whatever is left in the MIDI buffer is the processor's output
+    wrapper->midiOutput.swapWith(wrapper->midiBuffer);
+    wrapper->midiBuffer.clear();
+    return 1;
+}
+
+void pedalboard_processor_add_midi_event(PedalboardProcessor processor, const unsigned char* data, int size, int sample_offset) {
+    if (!processor || data == nullptr || size <= 0) return;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    wrapper->midiBuffer.addEvent(data, size, sample_offset);
+}
+
+PedalboardMidiEventList* pedalboard_processor_get_midi_output(PedalboardProcessor processor) {
+    if (!processor) return nullptr;
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+
+    auto* list = new PedalboardMidiEventList();
+    list->num_events = wrapper->midiOutput.getNumEvents();
+    list->events = nullptr;
+    if (list->num_events > 0) {
+        list->events = (PedalboardMidiEvent*)malloc(sizeof(PedalboardMidiEvent) * list->num_events);
+        int i = 0;
+        for (const auto metadata : wrapper->midiOutput) {
+            auto& ev = list->events[i++];
+            ev.size = metadata.numBytes;
+            ev.sample_offset = metadata.samplePosition;
+            ev.data = (unsigned char*)malloc(metadata.numBytes);
+            memcpy(ev.data, metadata.data, metadata.numBytes);
+        }
+    }
+    return list;
+}
+
+void pedalboard_midi_event_list_free(PedalboardMidiEventList* list) {
+    if (list == nullptr) return;
+    for (int i = 0; i < list->num_events; ++i) {
+        free(list->events[i].data);
+    }
+    free(list->events);
+    delete list;
 }
 
 // --- Audio Stream ---
-class AudioStreamInternal : public juce::AudioIODeviceCallback {
+class AudioStreamInternal : public juce::AudioIODeviceCallback, public juce::MidiInputCallback {
 public:
-    AudioStreamInternal(ProcessorWrapper* proc) : processorWrapper(proc) {
-        juce::String error = deviceManager.initialiseWithDefaultDevices(2, 2);
+    AudioStreamInternal(ProcessorWrapper* proc, bool openDefaultDevices = true) : processorWrapper(proc) {
+        if (openDefaultDevices) {
+            juce::String error = deviceManager.initialiseWithDefaultDevices(2, 2);
+        }
     }
     ~AudioStreamInternal() {
+        if (midiInput) midiInput->stop();
+        endCapture();
         stop();
         deviceManager.closeAudioDevice();
     }
@@ -779,22 +3593,187 @@ public:
                                           int numOutputChannels,
                                           int numSamples,
                                           const juce::AudioIODeviceCallbackContext& context) override {
+        auto callbackStart = juce::Time::getHighResolutionTicks();
+        checkDeviceXruns();
+
         juce::AudioBuffer<float> buffer(outputChannelData, numOutputChannels, numSamples);
+        captureInput(inputChannelData, numInputChannels, numSamples);
+
+        for (int i = 0; i < numInputChannels && i < kMaxMeterChannels; ++i) {
+            if (inputChannelData[i] != nullptr) {
+                auto maxValue = juce::FloatVectorOperations::findMaximum(inputChannelData[i], numSamples);
+                auto minValue = juce::FloatVectorOperations::findMinimum(inputChannelData[i], numSamples);
+                holdPeak(inputPeaks[i], maxValue, minValue);
+                holdPeak(meterInputPeaks[i], maxValue, minValue);
+            }
+        }
+        numMeteredInputs.store(std::min(numInputChannels, kMaxMeterChannels));
+
+        // A mono input feeds every output so stereo processors (e.g. Panner) hear it on both sides
         for (int i = 0; i < numOutputChannels; ++i) {
             if (i < numInputChannels && inputChannelData[i] != nullptr) {
                 buffer.copyFrom(i, 0, inputChannelData[i], numSamples);
+            } else if (numInputChannels == 1 && inputChannelData[0] != nullptr) {
+                buffer.copyFrom(i, 0, inputChannelData[0], numSamples);
             } else {
                 buffer.clear(i, 0, numSamples);
             }
         }
         if (processorWrapper && processorWrapper->processor) {
-             processorWrapper->processor->processBlock(buffer, processorWrapper->midiBuffer);
+             processWrapper(processorWrapper, buffer, processorWrapper->midiBuffer);
+        }
+
+        for (int i = 0; i < numOutputChannels && i < kMaxMeterChannels; ++i) {
+            auto range = buffer.findMinMax(i, 0, numSamples);
+            holdPeak(outputPeaks[i], range.getEnd(), range.getStart());
+            holdPeak(meterOutputPeaks[i], range.getEnd(), range.getStart());
+        }
+        numMeteredOutputs.store(std::min(numOutputChannels, kMaxMeterChannels));
+
+        // Processing slower than real time means input arrives faster than we consume it
+        double elapsed = juce::Time::highResolutionTicksToSeconds(juce::Time::getHighResolutionTicks() - callbackStart);
+        if (currentSampleRate > 0.0 && elapsed > numSamples / currentSampleRate) {
+            reportXrun(PEDALBOARD_XRUN_OVERRUN);
+        }
+    }
+
+    // Reports an underrun when the driver's dropout counter increases.
+    void checkDeviceXruns() {
+        auto* device = deviceManager.getCurrentAudioDevice();
+        if (device == nullptr) return;
+        int count = device->getXRunCount();
+        if (count < 0) return; // not supported by this driver
+        if (count > lastXrunCount) {
+            if (lastXrunCount >= 0) reportXrun(PEDALBOARD_XRUN_UNDERRUN);
+        }
+        lastXrunCount = count;
+    }
+
+    void reportXrun(int type) {
+        uintptr_t context = xrunContext.load();
+        if (context == 0) return;
+        auto now = std::chrono::system_clock::now().time_since_epoch();
+        goPedalboardXrun(context, type, (long long)std::chrono::duration_cast<std::chrono::nanoseconds>(now).count());
+    }
+
+    void setXrunContext(uintptr_t context) {
+        xrunContext.store(context);
+        // Wait for any in-flight callback to finish with the previous context
+        const juce::ScopedLock sl(deviceManager.getAudioCallbackLock());
+    }
+
+    // Raises the held peak to the absolute peak of the block without locking.
+    static void holdPeak(std::atomic<float>& held, float maxValue, float minValue) {
+        float peak = std::max(std::abs(maxValue), std::abs(minValue));
+        float current = held.load();
+        while (peak > current && !held.compare_exchange_weak(current, peak)) {}
+    }
+
+    int getPeakLevels(float* in, int maxIn, float* out, int maxOut, int* numIn, int* numOut) {
+        *numIn = std::min(numMeteredInputs.load(), maxIn);
+        *numOut = std::min(numMeteredOutputs.load(), maxOut);
+        for (int i = 0; i < *numIn; ++i) in[i] = inputPeaks[i].load();
+        for (int i = 0; i < *numOut; ++i) out[i] = outputPeaks[i].load();
+        return PEDALBOARD_OK;
+    }
+
+    // Like getPeakLevels, for the peaks since the previous call, which are reset.
+    int takeMeterLevels(float* in, int maxIn, float* out, int maxOut, int* numIn, int* numOut) {
+        *numIn = std::min(numMeteredInputs.load(), maxIn);
+        *numOut = std::min(numMeteredOutputs.load(), maxOut);
+        for (int i = 0; i < *numIn; ++i) in[i] = meterInputPeaks[i].exchange(0.0f);
+        for (int i = 0; i < *numOut; ++i) out[i] = meterOutputPeaks[i].exchange(0.0f);
+        return PEDALBOARD_OK;
+    }
+
+    // Copies input samples into the capture ring, if one is installed.
+    // Runs on the audio thread; the ring is allocated by the caller beforehand.
+    void captureInput(const float* const* inputChannelData, int numInputChannels, int numSamples) {
+        auto* ring = captureRing.load();
+        if (ring == nullptr) return;
+        long long start = ring->written.load();
+        for (int ch = 0; ch < ring->numChannels; ++ch) {
+            const float* src = (ch < numInputChannels) ? inputChannelData[ch] : nullptr;
+            for (int i = 0; i < numSamples; ++i) {
+                ring->channels[ch][(start + i) % ring->capacity] = src != nullptr ? src[i] : 0.0f;
+            }
+        }
+        ring->written.store(start + numSamples);
+    }
+
+    void beginCapture(float** channels, int numChannels, int capacity) {
+        endCapture();
+        auto ring = std::make_unique<CaptureRing>();
+        ring->channels = channels;
+        ring->numChannels = numChannels;
+        ring->capacity = capacity;
+        ownedRing = std::move(ring);
+        captureRing.store(ownedRing.get());
+    }
+
+    void endCapture() {
+        captureRing.store(nullptr);
+        // Wait for any in-flight callback to finish with the ring
+        const juce::ScopedLock sl(deviceManager.getAudioCallbackLock());
+        ownedRing.reset();
+    }
+
+    long long getCapturedSamples() {
+        auto* ring = captureRing.load();
+        return ring != nullptr ? ring->written.load() : 0;
+    }
+
+    int openMidiInput(const char* identifier) {
+        if (midiInput) return PEDALBOARD_OK;
+        juce::String id = identifier != nullptr ? juce::String::fromUTF8(identifier)
+                                                : juce::MidiInput::getDefaultDevice().identifier;
+        if (id.isEmpty()) return PEDALBOARD_ERR_DEVICE_NOT_FOUND;
+
+        midiOpenTime = juce::Time::getMillisecondCounterHiRes() * 0.001;
+        midiInput = juce::MidiInput::openDevice(id, this);
+        if (!midiInput) return PEDALBOARD_ERR_DEVICE_NOT_FOUND;
+        midiInput->start();
+        return PEDALBOARD_OK;
+    }
+
+    // Called on the MIDI thread; queues short messages without blocking.
+    void handleIncomingMidiMessage(juce::MidiInput*, const juce::MidiMessage& message) override {
+        int size = message.getRawDataSize();
+        if (message.isSysEx() || size > 3) return;
+
+        int start1, size1, start2, size2;
+        midiFifo.prepareToWrite(1, start1, size1, start2, size2);
+        if (size1 + size2 == 0) {
+            droppedMidiEvents.fetch_add(1);
+            return;
         }
+        auto& ev = midiEvents[(size_t)(size1 > 0 ? start1 : start2)];
+        memcpy(ev.data, message.getRawData(), (size_t)size);
+        ev.size = size;
+        ev.timestamp = message.getTimeStamp() - midiOpenTime;
+        midiFifo.finishedWrite(1);
+    }
+
+    int readMidiInput(PedalboardMidiInputEvent* events, int maxEvents) {
+        int start1, size1, start2, size2;
+        midiFifo.prepareToRead(maxEvents, start1, size1, start2, size2);
+        for (int i = 0; i < size1; ++i) events[i] = midiEvents[(size_t)(start1 + i)];
+        for (int i = 0; i < size2; ++i) events[size1 + i] = midiEvents[(size_t)(start2 + i)];
+        midiFifo.finishedRead(size1 + size2);
+        return size1 + size2;
+    }
+
+    void resetPeakHold() {
+        for (auto& p : inputPeaks) p.store(0.0f);
+        for (auto& p : outputPeaks) p.store(0.0f);
     }
 
     void audioDeviceAboutToStart(juce::AudioIODevice* device) override {
+        currentSampleRate = device->getCurrentSampleRate();
+        lastXrunCount = device->getXRunCount();
         if (processorWrapper && processorWrapper->processor) {
-            processorWrapper->processor->prepareToPlay(device->getCurrentSampleRate(), device->getCurrentBufferSizeSamples());
+            prepareWrapper(processorWrapper, device->getCurrentSampleRate(), device->getCurrentBufferSizeSamples(),
+                           device->getActiveOutputChannels().countNumberOfSetBits());
         }
     }
 
@@ -804,10 +3783,77 @@ public:
         }
     }
     
-    void start() { deviceManager.addAudioCallback(this); }
-    void stop() { deviceManager.removeAudioCallback(this); }
+    int start() {
+        if (deviceManager.getCurrentAudioDevice() == nullptr) return PEDALBOARD_ERR_DEVICE_OPEN;
+        deviceManager.addAudioCallback(this);
+        running = true;
+        return PEDALBOARD_OK;
+    }
+    void stop() { deviceManager.removeAudioCallback(this); running = false; }
+
+    int setBufferSize(int samples) {
+        auto* device = deviceManager.getCurrentAudioDevice();
+        if (device == nullptr) return PEDALBOARD_ERR_DEVICE_OPEN;
+        if (!device->getAvailableBufferSizes().contains(samples)) return PEDALBOARD_ERR_UNSUPPORTED;
+
+        bool wasRunning = running;
+        if (wasRunning) stop();
+
+        auto setup = deviceManager.getAudioDeviceSetup();
+        setup.bufferSize = samples;
+        juce::String error = deviceManager.setAudioDeviceSetup(setup, true);
+
+        if (wasRunning && start() != PEDALBOARD_OK) return PEDALBOARD_ERR_DEVICE_OPEN;
+        return error.isEmpty() ? PEDALBOARD_OK : PEDALBOARD_ERR_DEVICE_OPEN;
+    }
+
+    double getLatencySeconds() {
+        auto* device = deviceManager.getCurrentAudioDevice();
+        if (device == nullptr) return 0.0;
+        double sampleRate = device->getCurrentSampleRate();
+        if (sampleRate <= 0.0) return 0.0;
+
+        // Device latencies already include the driver's buffering for each direction
+        int samples = device->getInputLatencyInSamples() + device->getOutputLatencyInSamples();
+        if (processorWrapper && processorWrapper->processor) {
+            samples += processorWrapper->processor->getLatencySamples();
+        }
+        return samples / sampleRate;
+    }
+
+    struct CaptureRing {
+        float** channels = nullptr;
+        int numChannels = 0;
+        int capacity = 0;
+        std::atomic<long long> written { 0 };
+    };
+
     juce::AudioDeviceManager deviceManager;
     ProcessorWrapper* processorWrapper;
+    bool running = false;
+
+    std::unique_ptr<CaptureRing> ownedRing;
+    std::atomic<CaptureRing*> captureRing { nullptr };
+
+    static constexpr int kMidiQueueSize = 4096;
+    std::unique_ptr<juce::MidiInput> midiInput;
+    double midiOpenTime = 0.0;
+    juce::AbstractFifo midiFifo { kMidiQueueSize };
+    std::array<PedalboardMidiInputEvent, kMidiQueueSize> midiEvents {};
+    std::atomic<long long> droppedMidiEvents { 0 };
+
+    std::atomic<uintptr_t> xrunContext { 0 };
+    double currentSampleRate = 0.0;
+    int lastXrunCount = -1;
+
+    static constexpr int kMaxMeterChannels = PEDALBOARD_MAX_METER_CHANNELS;
+    std::array<std::atomic<float>, kMaxMeterChannels> inputPeaks {};
+    std::array<std::atomic<float>, kMaxMeterChannels> outputPeaks {};
+    // Peaks since the last takeMeterLevels call, kept apart from the held peaks
+    std::array<std::atomic<float>, kMaxMeterChannels> meterInputPeaks {};
+    std::array<std::atomic<float>, kMaxMeterChannels> meterOutputPeaks {};
+    std::atomic<int> numMeteredInputs { 0 };
+    std::atomic<int> numMeteredOutputs { 0 };
 };
 
 PedalboardAudioStream pedalboard_create_audio_stream(PedalboardProcessor processor) {
@@ -817,16 +3863,239 @@ PedalboardAudioStream pedalboard_create_audio_stream(PedalboardProcessor process
     return new AudioStreamInternal(wrapper);
 }
 
-void pedalboard_audio_stream_start(PedalboardAudioStream stream) {
-    if (stream) static_cast<AudioStreamInternal*>(stream)->start();
+int pedalboard_audio_stream_start(PedalboardAudioStream stream) {
+    if (!stream) return PEDALBOARD_ERR_DEVICE_OPEN;
+    return static_cast<AudioStreamInternal*>(stream)->start();
 }
 
 void pedalboard_audio_stream_stop(PedalboardAudioStream stream) {
     if (stream) static_cast<AudioStreamInternal*>(stream)->stop();
 }
 
+int pedalboard_audio_stream_set_buffer_size(PedalboardAudioStream stream, int samples) {
+    if (!stream) return PEDALBOARD_ERR_DEVICE_OPEN;
+    return static_cast<AudioStreamInternal*>(stream)->setBufferSize(samples);
+}
+
+double pedalboard_audio_stream_get_latency(PedalboardAudioStream stream) {
+    if (!stream) return 0.0;
+    return static_cast<AudioStreamInternal*>(stream)->getLatencySeconds();
+}
+
+void pedalboard_audio_stream_get_peak_levels(PedalboardAudioStream stream, float* input_peaks, float* output_peaks,
+                                             int* num_inputs, int* num_outputs) {
+    *num_inputs = 0;
+    *num_outputs = 0;
+    if (!stream) return;
+    static_cast<AudioStreamInternal*>(stream)->getPeakLevels(input_peaks, PEDALBOARD_MAX_METER_CHANNELS,
+                                                            output_peaks, PEDALBOARD_MAX_METER_CHANNELS,
+                                                            num_inputs, num_outputs);
+}
+
+void pedalboard_audio_stream_take_meter_levels(PedalboardAudioStream stream, float* input_peaks, float* output_peaks,
+                                               int* num_inputs, int* num_outputs) {
+    *num_inputs = 0;
+    *num_outputs = 0;
+    if (!stream) return;
+    static_cast<AudioStreamInternal*>(stream)->takeMeterLevels(input_peaks, PEDALBOARD_MAX_METER_CHANNELS,
+                                                              output_peaks, PEDALBOARD_MAX_METER_CHANNELS,
+                                                              num_inputs, num_outputs);
+}
+
+void pedalboard_audio_stream_reset_peak_hold(PedalboardAudioStream stream) {
+    if (stream) static_cast<AudioStreamInternal*>(stream)->resetPeakHold();
+}
+
+int pedalboard_audio_stream_is_running(PedalboardAudioStream stream) {
+    if (!stream) return 0;
+    return static_cast<AudioStreamInternal*>(stream)->running ? 1 : 0;
+}
+
+int pedalboard_audio_stream_get_num_input_channels(PedalboardAudioStream stream) {
+    if (!stream) return 0;
+    auto* device = static_cast<AudioStreamInternal*>(stream)->deviceManager.getCurrentAudioDevice();
+    return device != nullptr ? device->getActiveInputChannels().countNumberOfSetBits() : 0;
+}
+
+double pedalboard_audio_stream_get_sample_rate(PedalboardAudioStream stream) {
+    if (!stream) return 0.0;
+    auto* device = static_cast<AudioStreamInternal*>(stream)->deviceManager.getCurrentAudioDevice();
+    return device != nullptr ? device->getCurrentSampleRate() : 0.0;
+}
+
+void pedalboard_audio_stream_begin_capture(PedalboardAudioStream stream, float** channels, int num_channels, int capacity) {
+    if (!stream || channels == nullptr || num_channels <= 0 || capacity <= 0) return;
+    static_cast<AudioStreamInternal*>(stream)->beginCapture(channels, num_channels, capacity);
+}
+
+long long pedalboard_audio_stream_get_captured_samples(PedalboardAudioStream stream) {
+    if (!stream) return 0;
+    return static_cast<AudioStreamInternal*>(stream)->getCapturedSamples();
+}
+
+void pedalboard_audio_stream_end_capture(PedalboardAudioStream stream) {
+    if (stream) static_cast<AudioStreamInternal*>(stream)->endCapture();
+}
+
+int pedalboard_audio_stream_open_midi_input(PedalboardAudioStream stream, const char* identifier) {
+    if (!stream) return PEDALBOARD_ERR_DEVICE_OPEN;
+    return static_cast<AudioStreamInternal*>(stream)->openMidiInput(identifier);
+}
+
+int pedalboard_audio_stream_read_midi_input(PedalboardAudioStream stream, PedalboardMidiInputEvent* events, int max_events) {
+    if (!stream || events == nullptr || max_events <= 0) return 0;
+    return static_cast<AudioStreamInternal*>(stream)->readMidiInput(events, max_events);
+}
+
+long long pedalboard_audio_stream_get_dropped_midi_events(PedalboardAudioStream stream) {
+    if (!stream) return 0;
+    return static_cast<AudioStreamInternal*>(stream)->droppedMidiEvents.load();
+}
+
+void pedalboard_audio_stream_set_xrun_callback(PedalboardAudioStream stream, uintptr_t context) {
+    if (stream) static_cast<AudioStreamInternal*>(stream)->setXrunContext(context);
+}
+
 void pedalboard_audio_stream_free(PedalboardAudioStream stream) {
     if (stream) delete static_cast<AudioStreamInternal*>(stream);
 }
 
+// --- Audio Devices ---
+PedalboardAudioDeviceList* pedalboard_enumerate_audio_devices(int is_input) {
+    pedalboard_init();
+    const bool wantInput = is_input != 0;
+
+    juce::AudioDeviceManager deviceManager;
+    std::vector<PedalboardAudioDevice> found;
+
+    for (auto* type : deviceManager.getAvailableDeviceTypes()) {
+        type->scanForDevices();
+        auto names = type->getDeviceNames(wantInput);
+        int defaultIndex = type->getDefaultDeviceIndex(wantInput);
+
+        for (int i = 0; i < names.size(); ++i) {
+            PedalboardAudioDevice dev = {};
+            dev.id = copyString(type->getTypeName() + ":" + names[i]);
+            dev.name = copyString(names[i]);
+            dev.is_default = (i == defaultIndex) ? 1 : 0;
+
+            std::unique_ptr<juce::AudioIODevice> device(
+                type->createDevice(wantInput ? juce::String() : names[i],
+                                   wantInput ? names[i] : juce::String()));
+            if (device != nullptr) {
+                auto channels = wantInput ? device->getInputChannelNames() : device->getOutputChannelNames();
+                dev.max_channels = channels.size();
+
+                auto rates = device->getAvailableSampleRates();
+                dev.num_sample_rates = rates.size();
+                if (rates.size() > 0) {
+                    dev.sample_rates = (double*)malloc(sizeof(double) * rates.size());
+                    for (int r = 0; r < rates.size(); ++r) {
+                        dev.sample_rates[r] = rates[r];
+                    }
+                }
+            }
+            found.push_back(dev);
+        }
+    }
+
+    auto* list = new PedalboardAudioDeviceList();
+    list->num_devices = (int)found.size();
+    list->devices = nullptr;
+    if (!found.empty()) {
+        list->devices = (PedalboardAudioDevice*)malloc(sizeof(PedalboardAudioDevice) * found.size());
+        std::copy(found.begin(), found.end(), list->devices);
+    }
+    return list;
+}
+
+void pedalboard_audio_device_list_free(PedalboardAudioDeviceList* list) {
+    if (list == nullptr) return;
+    for (int i = 0; i < list->num_devices; ++i) {
+        free(list->devices[i].id);
+        free(list->devices[i].name);
+        free(list->devices[i].sample_rates);
+    }
+    free(list->devices);
+    delete list;
+}
+
+// Splits a "Type:Name" device ID and checks that the device exists.
+static bool resolveDeviceId(juce::AudioDeviceManager& deviceManager, const juce::String& id, bool isInput,
+                            juce::String& typeName, juce::String& deviceName) {
+    typeName = id.upToFirstOccurrenceOf(":", false, false);
+    deviceName = id.fromFirstOccurrenceOf(":", false, false);
+    for (auto* type : deviceManager.getAvailableDeviceTypes()) {
+        if (type->getTypeName() != typeName) continue;
+        type->scanForDevices();
+        return type->getDeviceNames(isInput).contains(deviceName);
+    }
+    return false;
+}
+
+PedalboardAudioStream pedalboard_create_audio_stream_with_config(PedalboardProcessor processor, const PedalboardAudioStreamConfig* config, int* error) {
+    pedalboard_init();
+    auto setError = [error](int code) { if (error) *error = code; };
+    setError(PEDALBOARD_OK);
+    if (!processor || !config) {
+        setError(PEDALBOARD_ERR_DEVICE_OPEN);
+        return nullptr;
+    }
+
+    auto* wrapper = static_cast<ProcessorWrapper*>(processor);
+    auto stream = std::make_unique<AudioStreamInternal>(wrapper, false);
+    auto& deviceManager = stream->deviceManager;
+
+    int numInputs = config->num_input_channels > 0 ? config->num_input_channels : 2;
+    int numOutputs = config->num_output_channels > 0 ? config->num_output_channels : 2;
+
+    juce::String inputType, inputName, outputType, outputName;
+    bool hasInput = config->input_device_id != nullptr && config->input_device_id[0] != '\0';
+    bool hasOutput = config->output_device_id != nullptr && config->output_device_id[0] != '\0';
+    if (hasInput && !resolveDeviceId(deviceManager, config->input_device_id, true, inputType, inputName)) {
+        setError(PEDALBOARD_ERR_DEVICE_NOT_FOUND);
+        return nullptr;
+    }
+    if (hasOutput && !resolveDeviceId(deviceManager, config->output_device_id, false, outputType, outputName)) {
+        setError(PEDALBOARD_ERR_DEVICE_NOT_FOUND);
+        return nullptr;
+    }
+    if (hasInput && hasOutput && inputType != outputType) {
+        // JUCE can only open devices of a single driver type at once
+        setError(PEDALBOARD_ERR_DEVICE_OPEN);
+        return nullptr;
+    }
+
+    juce::String result = deviceManager.initialise(numInputs, numOutputs, nullptr, true);
+    if (result.isNotEmpty()) {
+        setError(PEDALBOARD_ERR_DEVICE_OPEN);
+        return nullptr;
+    }
+
+    juce::String typeName = hasInput ? inputType : outputType;
+    if (typeName.isNotEmpty() && typeName != deviceManager.getCurrentAudioDeviceType()) {
+        deviceManager.setCurrentAudioDeviceType(typeName, true);
+    }
+
+    auto setup = deviceManager.getAudioDeviceSetup();
+    if (hasInput) setup.inputDeviceName = inputName;
+    if (hasOutput) setup.outputDeviceName = outputName;
+    if (config->sample_rate > 0) setup.sampleRate = config->sample_rate;
+    if (config->buffer_size > 0) setup.bufferSize = config->buffer_size;
+    setup.useDefaultInputChannels = false;
+    setup.inputChannels.clear();
+    setup.inputChannels.setRange(0, numInputs, true);
+    setup.useDefaultOutputChannels = false;
+    setup.outputChannels.clear();
+    setup.outputChannels.setRange(0, numOutputs, true);
+
+    result = deviceManager.setAudioDeviceSetup(setup, true);
+    if (result.isNotEmpty()) {
+        setError(PEDALBOARD_ERR_DEVICE_OPEN);
+        return nullptr;
+    }
+
+    return stream.release();
+}
+
 } // extern "C"
@@ -0,0 +1,74 @@
+package pedalboard
+
+import (
+	"math"
+	"testing"
+)
+
+func TestABComparison(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.5)
+	chain, err := NewProcessorChain(gain)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	ab := NewABComparison(chain)
+	if err := ab.ApplyA(); err == nil {
+		t.Error("Expected error applying A before it was captured")
+	}
+	if err := ab.SnapshotA(); err != nil {
+		t.Fatalf("SnapshotA failed: %v", err)
+	}
+	gain.SetParameter(0, 0.25)
+	if err := ab.SnapshotB(); err != nil {
+		t.Fatalf("SnapshotB failed: %v", err)
+	}
+
+	if err := ab.ApplyA(); err != nil {
+		t.Fatalf("ApplyA failed: %v", err)
+	}
+	if gain.GetParameter(0) != 0.5 {
+		t.Errorf("Expected A's gain, got %f", gain.GetParameter(0))
+	}
+
+	result := ab.Compare()
+	if len(result.Differences) != 1 || result.Differences[0].Stage != 0 || result.Differences[0].ValueB != 0.25 {
+		t.Errorf("Expected the gain to differ, got %+v", result.Differences)
+	}
+	if result.HasPerceptualDifference {
+		t.Error("Expected no perceptual difference without test audio")
+	}
+
+	// Halving the gain lowers every band by 6 dB
+	sine := &AudioBuffer{Data: [][]float32{make([]float32, 44100)}, SampleRate: 44100}
+	for i := range sine.Data[0] {
+		sine.Data[0][i] = float32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/44100))
+	}
+	ab.SetTestAudio(sine)
+	chain.Process([][]float32{make([]float32, 512)}, 44100)
+	processed := processedSamples(chain.Processor())
+	result = ab.Compare()
+	if !result.HasPerceptualDifference || math.Abs(result.PerceptualDifferenceDB-6.02) > 0.5 {
+		t.Errorf("Expected a perceptual difference of about 6 dB, got %+v", result)
+	}
+	if gain.GetParameter(0) != 0.5 {
+		t.Error("Expected Compare to leave the current state")
+	}
+	if n := processedSamples(chain.Processor()); n != processed {
+		t.Errorf("Expected Compare not to process through the chain, processed %d samples", n-processed)
+	}
+
+	other, _ := NewInternalProcessor("Gain")
+	chain.Add(other)
+	if err := ab.ApplyB(); err == nil {
+		t.Error("Expected error applying B after the chain changed")
+	}
+
+	// Snapshots stay comparable after stages are removed from the chain
+	chain.Remove(1)
+	chain.Remove(0)
+	if result := ab.Compare(); !result.HasPerceptualDifference {
+		t.Error("Expected a perceptual difference after the chain's stages were removed")
+	}
+}
@@ -0,0 +1,558 @@
+package pedalboard
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// minAnalysisSamples is the shortest buffer accepted by the spectral measurements.
+const minAnalysisSamples = 1024
+
+// blackmanHarris returns a 4-term Blackman-Harris window of length n.
+func blackmanHarris(n int) []float64 {
+	w := make([]float64, n)
+	if n == 1 {
+		w[0] = 1
+		return w
+	}
+	for i := range w {
+		x := 2 * math.Pi * float64(i) / float64(n-1)
+		w[i] = 0.35875 - 0.48829*math.Cos(x) + 0.14128*math.Cos(2*x) - 0.01168*math.Cos(3*x)
+	}
+	return w
+}
+
+// powerSpectrum returns the power of bins 0..fftSize/2 of the windowed samples.
+// fftSize is the largest power of two not exceeding len(samples).
+func powerSpectrum(samples []float32, window []float64) []float64 {
+	fftSize := len(window)
+	spec := make([]complex128, fftSize)
+	for i := 0; i < fftSize; i++ {
+		spec[i] = complex(float64(samples[i])*window[i], 0)
+	}
+	fft(spec)
+
+	power := make([]float64, fftSize/2+1)
+	for k := range power {
+		re, im := real(spec[k]), imag(spec[k])
+		power[k] = re*re + im*im
+	}
+	return power
+}
+
+// largestPowerOfTwo returns the largest power of two less than or equal to n.
+func largestPowerOfTwo(n int) int {
+	p := 1
+	for p*2 <= n {
+		p *= 2
+	}
+	return p
+}
+
+// bandPower sums the power of the bins within halfWidth of the strongest bin near centre.
+func bandPower(power []float64, centre float64, halfWidth int) float64 {
+	c := int(math.Round(centre))
+	peak := c
+	for k := c - halfWidth; k <= c+halfWidth; k++ {
+		if k >= 0 && k < len(power) && power[k] > power[peak] {
+			peak = k
+		}
+	}
+	var sum float64
+	for k := peak - halfWidth; k <= peak+halfWidth; k++ {
+		if k >= 0 && k < len(power) {
+			sum += power[k]
+		}
+	}
+	return sum
+}
+
+// MeasureHarmonicDistortion measures the total harmonic distortion of a sine wave
+// in the first channel of the buffer.
+// fundamentalHz: The frequency of the test tone. Must not exceed Nyquist/10 so that
+// harmonics 2 to 10 fall below Nyquist.
+// Returns the power of harmonics 2-10 relative to the fundamental as a percentage,
+// or an error if the buffer is too short or the frequency is out of range.
+func (b *AudioBuffer) MeasureHarmonicDistortion(fundamentalHz float64) (THD float64, err error) {
+	if len(b.Data) == 0 || len(b.Data[0]) < minAnalysisSamples {
+		return 0, fmt.Errorf("%w: need at least %d samples for analysis", ErrTooShort, minAnalysisSamples)
+	}
+	if b.SampleRate <= 0 {
+		return 0, fmt.Errorf("%w: sample rate %f", ErrInvalidArgument, b.SampleRate)
+	}
+	nyquist := b.SampleRate / 2
+	if fundamentalHz <= 0 || fundamentalHz > nyquist/10 {
+		return 0, fmt.Errorf("%w: fundamental %.1f Hz (0-%.1f Hz)", ErrOutOfRange, fundamentalHz, nyquist/10)
+	}
+
+	fftSize := largestPowerOfTwo(len(b.Data[0]))
+	power := powerSpectrum(b.Data[0], blackmanHarris(fftSize))
+	binHz := b.SampleRate / float64(fftSize)
+
+	// The Blackman-Harris main lobe spans +/-4 bins
+	const halfWidth = 4
+	fundamental := bandPower(power, fundamentalHz/binHz, halfWidth)
+	if fundamental == 0 {
+		return 0, fmt.Errorf("%w: no signal at %.1f Hz", ErrSilentBuffer, fundamentalHz)
+	}
+
+	var harmonics float64
+	for h := 2; h <= 10; h++ {
+		harmonics += bandPower(power, float64(h)*fundamentalHz/binHz, halfWidth)
+	}
+
+	return 100 * math.Sqrt(harmonics/fundamental), nil
+}
+
+// hannWindow returns a periodic Hann window of length n.
+func hannWindow(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return w
+}
+
+// monoMix returns the average of all channels.
+func (b *AudioBuffer) monoMix() []float32 {
+	if len(b.Data) == 1 {
+		return b.Data[0]
+	}
+	n := len(b.Data[0])
+	mix := make([]float32, n)
+	scale := 1 / float32(len(b.Data))
+	for _, channel := range b.Data {
+		for i := 0; i < n && i < len(channel); i++ {
+			mix[i] += channel[i] * scale
+		}
+	}
+	return mix
+}
+
+// FFT computes the magnitude spectrum of one channel from its first fftSize samples
+// (zero-padded if the channel is shorter) with a Hann window.
+// channel: The channel to analyse.
+// fftSize: The FFT length. Must be a power of two.
+// Returns fftSize/2+1 magnitudes in dB from DC to Nyquist, scaled so that a full-scale
+// sine centred on a bin reads 0 dB. Silent bins read -200 dB. Returns ErrOutOfRange for
+// an invalid channel and ErrInvalidArgument if fftSize is not a power of two.
+func (b *AudioBuffer) FFT(channel, fftSize int) (magnitudeDB []float32, err error) {
+	if channel < 0 || channel >= len(b.Data) {
+		return nil, fmt.Errorf("%w: channel %d (0-%d)", ErrOutOfRange, channel, len(b.Data)-1)
+	}
+	if fftSize < 2 || fftSize&(fftSize-1) != 0 {
+		return nil, fmt.Errorf("%w: FFT size %d is not a power of two", ErrInvalidArgument, fftSize)
+	}
+
+	window := hannWindow(fftSize)
+	var windowSum float64
+	x := make([]complex128, fftSize)
+	for i, w := range window {
+		windowSum += w
+		if i < len(b.Data[channel]) {
+			x[i] = complex(float64(b.Data[channel][i])*w, 0)
+		}
+	}
+	fft(x)
+
+	magnitudeDB = make([]float32, fftSize/2+1)
+	for k := range magnitudeDB {
+		// Single-sided amplitude: double every bin except DC and Nyquist
+		scale := 2 / windowSum
+		if k == 0 || k == fftSize/2 {
+			scale = 1 / windowSum
+		}
+		magnitudeDB[k] = float32(20 * math.Log10(math.Max(cmplx.Abs(x[k])*scale, 1e-10)))
+	}
+	return magnitudeDB, nil
+}
+
+// GenerateSpectrogramImage computes a spectrogram of the buffer suitable for rendering
+// as an image. Channels are mixed to mono and analysed with a Hann window and a hop of
+// fftSize/4.
+// fftSize: The FFT length. Must be a power of two of at least 16.
+// dynamicRange: The range in dB below the loudest bin that is kept.
+// Returns the spectrogram as [frame][bin] with fftSize/2+1 bins per frame. Each value is
+// a magnitude in dB, scaled as by FFT so that a full-scale sine centred on a bin reads
+// 0 dB, and clipped to no less than dynamicRange dB below the loudest bin.
+func (b *AudioBuffer) GenerateSpectrogramImage(fftSize int, dynamicRange float64) ([][]float64, error) {
+	if fftSize < 16 || fftSize&(fftSize-1) != 0 {
+		return nil, fmt.Errorf("%w: fft size must be a power of two >= 16, got %d", ErrInvalidArgument, fftSize)
+	}
+	if dynamicRange <= 0 {
+		return nil, fmt.Errorf("%w: dynamic range must be positive, got %f", ErrInvalidArgument, dynamicRange)
+	}
+	if len(b.Data) == 0 || len(b.Data[0]) < fftSize {
+		return nil, fmt.Errorf("%w: need at least %d samples for fft size %d", ErrTooShort, fftSize, fftSize)
+	}
+
+	samples := b.monoMix()
+	window := hannWindow(fftSize)
+	var windowSum float64
+	for _, w := range window {
+		windowSum += w
+	}
+	hop := fftSize / 4
+	numFrames := (len(samples)-fftSize)/hop + 1
+
+	image := make([][]float64, numFrames)
+	maxDB := math.Inf(-1)
+	for f := range image {
+		power := powerSpectrum(samples[f*hop:], window)
+		for k, p := range power {
+			// Single-sided amplitude, as in FFT
+			scale := 2 / windowSum
+			if k == 0 || k == fftSize/2 {
+				scale = 1 / windowSum
+			}
+			db := 10 * math.Log10(p*scale*scale+1e-20)
+			power[k] = db
+			if db > maxDB {
+				maxDB = db
+			}
+		}
+		image[f] = power
+	}
+
+	floor := maxDB - dynamicRange
+	for _, frame := range image {
+		for k, db := range frame {
+			frame[k] = math.Max(db, floor)
+		}
+	}
+	return image, nil
+}
+
+// MeasureStereoWidth returns how wide a stereo recording is, from 0 (mono) to 1
+// (fully decorrelated channels), based on the RMS of the mid (L+R)/2 and side (L-R)/2
+// signals. The normalised difference (S-M)/(S+M) runs from -1 for mono to 0 for
+// decorrelated channels; it is shifted by one and clamped so out-of-phase material
+// (where side exceeds mid) also reports 1. Silence reports 0.
+// Returns an error if the buffer does not have exactly two channels.
+func (b *AudioBuffer) MeasureStereoWidth() (float64, error) {
+	if len(b.Data) != 2 {
+		return 0, fmt.Errorf("%w: stereo width requires 2 channels, got %d", ErrNotStereo, len(b.Data))
+	}
+	left, right := b.Data[0], b.Data[1]
+	n := len(left)
+	if len(right) < n {
+		n = len(right)
+	}
+	if n == 0 {
+		return 0, ErrEmptyBuffer
+	}
+
+	var midSum, sideSum float64
+	for i := 0; i < n; i++ {
+		mid := (float64(left[i]) + float64(right[i])) / 2
+		side := (float64(left[i]) - float64(right[i])) / 2
+		midSum += mid * mid
+		sideSum += side * side
+	}
+	midRMS := math.Sqrt(midSum / float64(n))
+	sideRMS := math.Sqrt(sideSum / float64(n))
+	if midRMS+sideRMS == 0 {
+		return 0, nil
+	}
+
+	width := 1 + (sideRMS-midRMS)/(sideRMS+midRMS)
+	return math.Min(width, 1), nil
+}
+
+// PhaseCorrelation returns the normalised zero-lag correlation between channels 0 and 1,
+// as shown by a correlation meter: +1 when the channels are identical (fully mono
+// compatible), 0 when they are unrelated and -1 when one is the inverse of the other.
+// Negative values mean the channels partly or fully cancel when summed to mono.
+// Silence reports +1.
+// Returns ErrNotStereo if the buffer does not have exactly two channels.
+func (b *AudioBuffer) PhaseCorrelation() (float32, error) {
+	if len(b.Data) != 2 {
+		return 0, fmt.Errorf("%w: %d channels", ErrNotStereo, len(b.Data))
+	}
+	left, right := b.Data[0], b.Data[1]
+	n := min(len(left), len(right))
+	if n == 0 {
+		return 0, ErrEmptyBuffer
+	}
+	return float32(channelCorrelation(left[:n], right[:n])), nil
+}
+
+// octaveBand is a frequency band covering FFT bins lo..hi inclusive.
+type octaveBand struct {
+	centre float64
+	lo, hi int
+}
+
+// thirdOctaveBands returns the standard 1/3-octave bands (centred on 1 kHz) from 20 Hz
+// up to the Nyquist frequency for an FFT of fftSize. Bands narrower than one bin are skipped.
+func thirdOctaveBands(sampleRate float64, fftSize int) []octaveBand {
+	var bands []octaveBand
+	binHz := sampleRate / float64(fftSize)
+	edge := math.Pow(2, 1.0/6)
+	for band := -17; ; band++ { // band -17 is centred on 19.7 Hz
+		centre := 1000 * math.Pow(2, float64(band)/3)
+		if centre*edge > sampleRate/2 {
+			break
+		}
+		lo := int(math.Ceil(centre / edge / binHz))
+		hi := int(math.Floor(centre * edge / binHz))
+		if hi >= lo && lo >= 1 {
+			bands = append(bands, octaveBand{centre: centre, lo: lo, hi: hi})
+		}
+	}
+	return bands
+}
+
+// SpectrumDiffReport describes how the spectrum of a processed buffer differs from a
+// reference, in 1/3-octave bands.
+type SpectrumDiffReport struct {
+	// FrequencyBins holds the centre frequency of each band in Hz.
+	FrequencyBins []float64
+	// GainDiffDB holds the reference level minus the processed level for each band, in dB.
+	// Positive values mean the processed audio was cut, negative values that it was boosted.
+	GainDiffDB []float64
+	// MaxDiffdB is the entry of GainDiffDB with the largest magnitude.
+	MaxDiffdB float64
+}
+
+// SpectrumCompare compares the spectrum of the buffer (the processed audio) with reference.
+// Channels are mixed to mono and both signals are truncated to the largest power of two
+// of samples the shorter one holds, so neither is zero-padded, and Hann windowed. Band
+// powers are summed over standard 1/3-octave bands (centred on 1 kHz) from 20 Hz up to
+// the Nyquist frequency; bands narrower than one FFT bin are skipped.
+// Returns an error if either buffer is too short or the sample rates differ.
+func (b *AudioBuffer) SpectrumCompare(reference *AudioBuffer) (SpectrumDiffReport, error) {
+	if reference == nil || len(reference.Data) == 0 || len(b.Data) == 0 {
+		return SpectrumDiffReport{}, ErrEmptyBuffer
+	}
+	if b.SampleRate <= 0 || b.SampleRate != reference.SampleRate {
+		return SpectrumDiffReport{}, fmt.Errorf("%w: sample rates differ: %v and %v", ErrInvalidArgument, b.SampleRate, reference.SampleRate)
+	}
+	n := len(b.Data[0])
+	if len(reference.Data[0]) < n {
+		n = len(reference.Data[0])
+	}
+	if n < minAnalysisSamples {
+		return SpectrumDiffReport{}, fmt.Errorf("%w: need at least %d samples for analysis", ErrTooShort, minAnalysisSamples)
+	}
+
+	fftSize := largestPowerOfTwo(n)
+	window := hannWindow(fftSize)
+	processed := powerSpectrum(b.monoMix(), window)
+	ref := powerSpectrum(reference.monoMix(), window)
+
+	var report SpectrumDiffReport
+	for _, band := range thirdOctaveBands(b.SampleRate, fftSize) {
+		var processedPower, refPower float64
+		for k := band.lo; k <= band.hi; k++ {
+			processedPower += processed[k]
+			refPower += ref[k]
+		}
+		diff := 10*math.Log10(refPower+1e-20) - 10*math.Log10(processedPower+1e-20)
+		report.FrequencyBins = append(report.FrequencyBins, band.centre)
+		report.GainDiffDB = append(report.GainDiffDB, diff)
+		if math.Abs(diff) > math.Abs(report.MaxDiffdB) {
+			report.MaxDiffdB = diff
+		}
+	}
+	return report, nil
+}
+
+// monoCompatibilityFFTSize is the largest frame used by MonoCompatibilityTest.
+const monoCompatibilityFFTSize = 8192
+
+// MonoCompatibilityTest folds a stereo buffer to mono ((L+R)/2) and compares the mono
+// spectrum with the stereo spectrum ((|L|²+|R|²)/2) in 1/3-octave bands, averaged over
+// Hann-windowed frames. Identical channels keep all their energy and uncorrelated
+// channels lose 3 dB; both count as fully compatible. A band whose mono level is more
+// than 6 dB below its stereo level, because of phase cancellation, is a problem band.
+// Bands more than 60 dB below the loudest band are ignored. Silence scores 1.
+// Returns compatibilityScore from 0 (severe mono issues) to 1 (fully mono compatible),
+// weighted by band energy, and the centre frequencies in Hz of the problem bands.
+// Returns an error if the buffer does not have exactly two channels or is too short.
+func (b *AudioBuffer) MonoCompatibilityTest() (compatibilityScore float64, problemFrequencies []float64, err error) {
+	if len(b.Data) != 2 {
+		return 0, nil, fmt.Errorf("%w: mono compatibility requires 2 channels, got %d", ErrNotStereo, len(b.Data))
+	}
+	if b.SampleRate <= 0 {
+		return 0, nil, fmt.Errorf("%w: sample rate %f", ErrInvalidArgument, b.SampleRate)
+	}
+	left, right := b.Data[0], b.Data[1]
+	n := len(left)
+	if len(right) < n {
+		n = len(right)
+	}
+	if n < minAnalysisSamples {
+		return 0, nil, fmt.Errorf("%w: need at least %d samples for analysis", ErrTooShort, minAnalysisSamples)
+	}
+
+	fftSize := largestPowerOfTwo(n)
+	if fftSize > monoCompatibilityFFTSize {
+		fftSize = monoCompatibilityFFTSize
+	}
+	window := hannWindow(fftSize)
+	hop := fftSize / 2
+	stereo := make([]float64, fftSize/2+1)
+	mono := make([]float64, fftSize/2+1)
+	mix := make([]float32, fftSize)
+	for start := 0; start+fftSize <= n; start += hop {
+		l := powerSpectrum(left[start:], window)
+		r := powerSpectrum(right[start:], window)
+		for i := range mix {
+			mix[i] = (left[start+i] + right[start+i]) / 2
+		}
+		m := powerSpectrum(mix, window)
+		for k := range stereo {
+			stereo[k] += (l[k] + r[k]) / 2
+			mono[k] += m[k]
+		}
+	}
+
+	bands := thirdOctaveBands(b.SampleRate, fftSize)
+	stereoPower := make([]float64, len(bands))
+	monoPower := make([]float64, len(bands))
+	var loudest float64
+	for i, band := range bands {
+		for k := band.lo; k <= band.hi; k++ {
+			stereoPower[i] += stereo[k]
+			monoPower[i] += mono[k]
+		}
+		loudest = math.Max(loudest, stereoPower[i])
+	}
+	if loudest == 0 {
+		return 1, nil, nil
+	}
+
+	var weighted, total float64
+	for i, band := range bands {
+		if stereoPower[i] < loudest*1e-6 {
+			continue
+		}
+		// Uncorrelated channels keep half their energy (-3 dB), which scores 1
+		score := math.Min(1, 2*monoPower[i]/stereoPower[i])
+		if score < 0.5 {
+			problemFrequencies = append(problemFrequencies, band.centre)
+		}
+		weighted += score * stereoPower[i]
+		total += stereoPower[i]
+	}
+	return weighted / total, problemFrequencies, nil
+}
+
+// MeasureGroupDelay treats the first channel of the buffer as an impulse response and
+// returns its group delay, the negative derivative of the phase spectrum. The delay is
+// computed as Re(FFT(n·h) / FFT(h)), which avoids unwrapping the phase.
+// binCount: The number of frequencies to report, evenly spaced from 0 Hz up to (but not
+// including) the Nyquist frequency.
+// Returns the frequencies in Hz and the group delay at each in milliseconds. Where the
+// magnitude response is more than 120 dB below its peak the delay is undefined and
+// reported as NaN. Returns an error if the buffer is empty or binCount is not positive.
+func (b *AudioBuffer) MeasureGroupDelay(binCount int) (frequencies, groupDelayMs []float64, err error) {
+	if len(b.Data) == 0 || len(b.Data[0]) == 0 {
+		return nil, nil, ErrEmptyBuffer
+	}
+	if b.SampleRate <= 0 {
+		return nil, nil, fmt.Errorf("%w: sample rate %f", ErrInvalidArgument, b.SampleRate)
+	}
+	if binCount <= 0 {
+		return nil, nil, fmt.Errorf("%w: bin count must be positive, got %d", ErrInvalidArgument, binCount)
+	}
+
+	impulse := b.Data[0]
+	fftSize := nextPowerOfTwo(len(impulse))
+	if fftSize < 2*binCount {
+		fftSize = nextPowerOfTwo(2 * binCount)
+	}
+	h := make([]complex128, fftSize)
+	nh := make([]complex128, fftSize)
+	for n, s := range impulse {
+		h[n] = complex(float64(s), 0)
+		nh[n] = complex(float64(n)*float64(s), 0)
+	}
+	fft(h)
+	fft(nh)
+
+	var peak float64
+	for k := 0; k <= fftSize/2; k++ {
+		re, im := real(h[k]), imag(h[k])
+		peak = math.Max(peak, re*re+im*im)
+	}
+
+	frequencies = make([]float64, binCount)
+	groupDelayMs = make([]float64, binCount)
+	for i := range frequencies {
+		k := i * (fftSize / 2) / binCount
+		frequencies[i] = float64(k) * b.SampleRate / float64(fftSize)
+		re, im := real(h[k]), imag(h[k])
+		power := re*re + im*im
+		if power == 0 || power < peak*1e-12 {
+			groupDelayMs[i] = math.NaN()
+			continue
+		}
+		delaySamples := real(nh[k] / h[k])
+		groupDelayMs[i] = delaySamples / b.SampleRate * 1000
+	}
+	return frequencies, groupDelayMs, nil
+}
+
+// MeasureLatency finds the delay between reference (for example, the input to a chain)
+// and b (the processed output) by cross-correlating their mono mixes. The correlation
+// peak is found by magnitude, so a polarity-inverting process is measured too.
+// reference: The unprocessed signal, at the same sample rate as b.
+// Returns the latency in samples (negative if b leads reference) and a confidence from
+// 0 to 1: the correlation peak normalised by the signals' energies, near 1 when b is a
+// delayed, scaled copy of reference and near 0 when they are unrelated.
+// Returns ErrSilentBuffer if either buffer is silent.
+func (b *AudioBuffer) MeasureLatency(reference *AudioBuffer) (samples int, confidence float64, err error) {
+	if reference == nil || len(b.Data) == 0 || len(b.Data[0]) == 0 || len(reference.Data) == 0 || len(reference.Data[0]) == 0 {
+		return 0, 0, ErrEmptyBuffer
+	}
+	if b.SampleRate != reference.SampleRate {
+		return 0, 0, fmt.Errorf("%w: sample rates differ: %v and %v", ErrInvalidArgument, b.SampleRate, reference.SampleRate)
+	}
+
+	processed := b.monoMix()
+	original := reference.monoMix()
+	var processedEnergy, originalEnergy float64
+	for _, s := range processed {
+		processedEnergy += float64(s) * float64(s)
+	}
+	for _, s := range original {
+		originalEnergy += float64(s) * float64(s)
+	}
+	if processedEnergy == 0 || originalEnergy == 0 {
+		return 0, 0, ErrSilentBuffer
+	}
+
+	// Zero padding to the full length keeps the circular correlation linear
+	fftSize := nextPowerOfTwo(len(processed) + len(original))
+	x := make([]complex128, fftSize)
+	y := make([]complex128, fftSize)
+	for i, s := range processed {
+		x[i] = complex(float64(s), 0)
+	}
+	for i, s := range original {
+		y[i] = complex(float64(s), 0)
+	}
+	fft(x)
+	fft(y)
+	for k := range x {
+		x[k] *= cmplx.Conj(y[k])
+	}
+	ifft(x)
+
+	// Lag k sits at index k for k >= 0 and at fftSize+k for k < 0
+	peak := 0.0
+	for lag := -(len(original) - 1); lag < len(processed); lag++ {
+		index := lag
+		if index < 0 {
+			index += fftSize
+		}
+		if v := math.Abs(real(x[index])); v > peak {
+			peak = v
+			samples = lag
+		}
+	}
+	return samples, peak / math.Sqrt(processedEnergy*originalEnergy), nil
+}
@@ -0,0 +1,377 @@
+package pedalboard
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMeasureHarmonicDistortion(t *testing.T) {
+	const sampleRate = 48000.0
+	const numSamples = 16384
+
+	clean := sineBuffer(1000, 0.5, sampleRate, numSamples)
+	thd, err := clean.MeasureHarmonicDistortion(1000)
+	if err != nil {
+		t.Fatalf("MeasureHarmonicDistortion failed: %v", err)
+	}
+	if thd > 0.01 {
+		t.Errorf("Expected near-zero THD for a pure sine, got %f%%", thd)
+	}
+
+	// Add a second harmonic at 10% of the fundamental amplitude
+	distorted := sineBuffer(1000, 0.5, sampleRate, numSamples)
+	for i := range distorted.Data[0] {
+		distorted.Data[0][i] += float32(0.05 * math.Sin(2*math.Pi*2000*float64(i)/sampleRate))
+	}
+	thd, err = distorted.MeasureHarmonicDistortion(1000)
+	if err != nil {
+		t.Fatalf("MeasureHarmonicDistortion failed: %v", err)
+	}
+	if math.Abs(thd-10) > 0.1 {
+		t.Errorf("Expected THD of 10%%, got %f%%", thd)
+	}
+
+	if _, err := clean.MeasureHarmonicDistortion(5000); err == nil {
+		t.Error("Expected error for fundamental above Nyquist/10")
+	}
+	if _, err := sineBuffer(1000, 0.5, sampleRate, 100).MeasureHarmonicDistortion(1000); err == nil {
+		t.Error("Expected error for short buffer")
+	}
+}
+
+func TestGenerateSpectrogramImage(t *testing.T) {
+	const sampleRate = 8000.0
+	const fftSize = 256
+	buffer := sineBuffer(1000, 0.5, sampleRate, 4096)
+
+	image, err := buffer.GenerateSpectrogramImage(fftSize, 80)
+	if err != nil {
+		t.Fatalf("GenerateSpectrogramImage failed: %v", err)
+	}
+	expectedFrames := (4096-fftSize)/(fftSize/4) + 1
+	if len(image) != expectedFrames {
+		t.Fatalf("Expected %d frames, got %d", expectedFrames, len(image))
+	}
+	if len(image[0]) != fftSize/2+1 {
+		t.Fatalf("Expected %d bins, got %d", fftSize/2+1, len(image[0]))
+	}
+
+	// The 1 kHz tone falls exactly on bin 32 and reads -6 dB at half scale
+	for f, frame := range image {
+		peak := 0
+		for k, v := range frame {
+			if v > frame[peak] {
+				peak = k
+			}
+		}
+		if peak != 32 {
+			t.Errorf("Frame %d: expected peak at bin 32, got %d", f, peak)
+		}
+		if math.Abs(frame[peak]-20*math.Log10(0.5)) > 0.1 {
+			t.Errorf("Frame %d: expected the peak at -6.02 dB, got %f", f, frame[peak])
+		}
+		for k, v := range frame {
+			if v < frame[peak]-80-1e-9 {
+				t.Fatalf("Frame %d bin %d below the dynamic range: %f", f, k, v)
+			}
+		}
+	}
+
+	if _, err := buffer.GenerateSpectrogramImage(100, 80); err == nil {
+		t.Error("Expected error for non power of two fft size")
+	}
+	if _, err := buffer.GenerateSpectrogramImage(fftSize, 0); err == nil {
+		t.Error("Expected error for zero dynamic range")
+	}
+}
+
+func TestMeasureStereoWidth(t *testing.T) {
+	mono := sineBuffer(440, 0.5, 44100, 44100).Data[0]
+	other := sineBuffer(1234, 0.5, 44100, 44100).Data[0]
+
+	cases := []struct {
+		name     string
+		left     []float32
+		right    []float32
+		expected float64
+	}{
+		{"mono", mono, mono, 0},
+		{"decorrelated", mono, other, 1},
+		{"silence", make([]float32, 100), make([]float32, 100), 0},
+	}
+	for _, c := range cases {
+		buffer := &AudioBuffer{Data: [][]float32{c.left, c.right}, SampleRate: 44100}
+		width, err := buffer.MeasureStereoWidth()
+		if err != nil {
+			t.Fatalf("%s: MeasureStereoWidth failed: %v", c.name, err)
+		}
+		if math.Abs(width-c.expected) > 0.01 {
+			t.Errorf("%s: expected width %f, got %f", c.name, c.expected, width)
+		}
+	}
+
+	if _, err := sineBuffer(440, 0.5, 44100, 100).MeasureStereoWidth(); err == nil {
+		t.Error("Expected error for mono buffer")
+	}
+}
+
+func TestPhaseCorrelation(t *testing.T) {
+	mono := sineBuffer(440, 0.5, 44100, 44100).Data[0]
+	inverted := make([]float32, len(mono))
+	for i, s := range mono {
+		inverted[i] = -s
+	}
+	other := sineBuffer(1234, 0.5, 44100, 44100).Data[0]
+
+	cases := []struct {
+		name        string
+		left, right []float32
+		expected    float64
+	}{
+		{"mono", mono, mono, 1},
+		{"inverted", mono, inverted, -1},
+		{"unrelated", mono, other, 0},
+	}
+	for _, c := range cases {
+		buffer := &AudioBuffer{Data: [][]float32{c.left, c.right}, SampleRate: 44100}
+		corr, err := buffer.PhaseCorrelation()
+		if err != nil {
+			t.Fatalf("%s: PhaseCorrelation failed: %v", c.name, err)
+		}
+		if math.Abs(float64(corr)-c.expected) > 0.01 {
+			t.Errorf("%s: expected correlation %f, got %f", c.name, c.expected, corr)
+		}
+	}
+
+	if _, err := sineBuffer(440, 0.5, 44100, 100).PhaseCorrelation(); !errors.Is(err, ErrNotStereo) {
+		t.Errorf("Expected ErrNotStereo for mono buffer, got %v", err)
+	}
+}
+
+func TestSpectrumCompare(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]float32, 48000)
+	quieter := make([]float32, len(noise))
+	for i := range noise {
+		noise[i] = float32(rng.Float64()*2 - 1)
+		quieter[i] = noise[i] * 0.5
+	}
+	reference := &AudioBuffer{Data: [][]float32{noise}, SampleRate: 48000}
+
+	same, err := reference.SpectrumCompare(reference)
+	if err != nil {
+		t.Fatalf("SpectrumCompare failed: %v", err)
+	}
+	if len(same.FrequencyBins) == 0 || len(same.FrequencyBins) != len(same.GainDiffDB) {
+		t.Fatalf("Expected matching bands, got %d frequencies and %d diffs", len(same.FrequencyBins), len(same.GainDiffDB))
+	}
+	if math.Abs(same.MaxDiffdB) > 1e-9 {
+		t.Errorf("Expected no difference against itself, got %f dB", same.MaxDiffdB)
+	}
+
+	// Halving the level is a 6 dB cut in every band
+	processed := &AudioBuffer{Data: [][]float32{quieter}, SampleRate: 48000}
+	report, err := processed.SpectrumCompare(reference)
+	if err != nil {
+		t.Fatalf("SpectrumCompare failed: %v", err)
+	}
+	for i, diff := range report.GainDiffDB {
+		if math.Abs(diff-6.02) > 0.01 {
+			t.Errorf("Band %.0f Hz: expected 6.02 dB, got %f", report.FrequencyBins[i], diff)
+		}
+	}
+	if last := report.FrequencyBins[len(report.FrequencyBins)-1]; last*math.Pow(2, 1.0/6) > 24000 {
+		t.Errorf("Band %.0f Hz extends past Nyquist", last)
+	}
+
+	// A longer reference is truncated to the processed length rather than compared with
+	// zeros past its end
+	longer := &AudioBuffer{Data: [][]float32{append(append([]float32(nil), noise...), noise...)}, SampleRate: 48000}
+	report, err = processed.SpectrumCompare(longer)
+	if err != nil {
+		t.Fatalf("SpectrumCompare failed: %v", err)
+	}
+	for i, diff := range report.GainDiffDB {
+		if math.Abs(diff-6.02) > 0.01 {
+			t.Errorf("Band %.0f Hz: expected 6.02 dB against a longer reference, got %f", report.FrequencyBins[i], diff)
+		}
+	}
+
+	other := &AudioBuffer{Data: [][]float32{quieter}, SampleRate: 44100}
+	if _, err := processed.SpectrumCompare(other); err == nil {
+		t.Error("Expected error for mismatched sample rates")
+	}
+}
+
+func TestMonoCompatibilityTest(t *testing.T) {
+	tone := sineBuffer(1000, 0.5, 44100, 44100).Data[0]
+	inverted := make([]float32, len(tone))
+	for i, s := range tone {
+		inverted[i] = -s
+	}
+	other := sineBuffer(5000, 0.5, 44100, 44100).Data[0]
+
+	mono := &AudioBuffer{Data: [][]float32{tone, tone}, SampleRate: 44100}
+	score, problems, err := mono.MonoCompatibilityTest()
+	if err != nil {
+		t.Fatalf("MonoCompatibilityTest failed: %v", err)
+	}
+	if math.Abs(score-1) > 1e-6 || len(problems) != 0 {
+		t.Errorf("Identical channels: expected score 1 and no problems, got %f %v", score, problems)
+	}
+
+	decorrelated := &AudioBuffer{Data: [][]float32{tone, other}, SampleRate: 44100}
+	if score, problems, _ := decorrelated.MonoCompatibilityTest(); score < 0.99 || len(problems) != 0 {
+		t.Errorf("Unrelated channels: expected score 1 and no problems, got %f %v", score, problems)
+	}
+
+	// Polarity-inverted channels cancel completely around 1 kHz
+	outOfPhase := &AudioBuffer{Data: [][]float32{tone, inverted}, SampleRate: 44100}
+	score, problems, err = outOfPhase.MonoCompatibilityTest()
+	if err != nil {
+		t.Fatalf("MonoCompatibilityTest failed: %v", err)
+	}
+	if score > 0.01 {
+		t.Errorf("Out-of-phase channels: expected score near 0, got %f", score)
+	}
+	found := false
+	for _, f := range problems {
+		if math.Abs(f-1000) < 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected 1 kHz in problem frequencies, got %v", problems)
+	}
+
+	if _, _, err := sineBuffer(1000, 0.5, 44100, 44100).MonoCompatibilityTest(); err == nil {
+		t.Error("Expected error for mono buffer")
+	}
+}
+
+func TestMeasureGroupDelay(t *testing.T) {
+	const sampleRate = 48000.0
+
+	// A symmetric (linear-phase) FIR delays every frequency by half its length
+	taps := make([]float32, 21)
+	for i := range taps {
+		taps[i] = float32(0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/20))
+	}
+	buffer := &AudioBuffer{Data: [][]float32{taps}, SampleRate: sampleRate}
+	frequencies, delays, err := buffer.MeasureGroupDelay(64)
+	if err != nil {
+		t.Fatalf("MeasureGroupDelay failed: %v", err)
+	}
+	if len(frequencies) != 64 || len(delays) != 64 {
+		t.Fatalf("Expected 64 bins, got %d frequencies and %d delays", len(frequencies), len(delays))
+	}
+	expected := 10 / sampleRate * 1000
+	for i, d := range delays {
+		if math.IsNaN(d) {
+			continue
+		}
+		if math.Abs(d-expected) > 1e-6 {
+			t.Errorf("%.0f Hz: expected %f ms, got %f ms", frequencies[i], expected, d)
+		}
+	}
+	if frequencies[0] != 0 || frequencies[63] >= sampleRate/2 {
+		t.Errorf("Unexpected frequency range %f-%f Hz", frequencies[0], frequencies[63])
+	}
+
+	if _, _, err := buffer.MeasureGroupDelay(0); err == nil {
+		t.Error("Expected error for zero bin count")
+	}
+}
+
+func TestFFT(t *testing.T) {
+	const sampleRate = 44100.0
+	const fftSize = 4096
+	// Centre the tone on bin 100
+	freq := 100 * sampleRate / fftSize
+	buffer := sineBuffer(freq, 0.5, sampleRate, fftSize)
+
+	spectrum, err := buffer.FFT(0, fftSize)
+	if err != nil {
+		t.Fatalf("FFT failed: %v", err)
+	}
+	if len(spectrum) != fftSize/2+1 {
+		t.Fatalf("Expected %d bins, got %d", fftSize/2+1, len(spectrum))
+	}
+	peak := 0
+	for k, db := range spectrum {
+		if db > spectrum[peak] {
+			peak = k
+		}
+	}
+	if peak != 100 {
+		t.Errorf("Expected peak at bin 100, got %d", peak)
+	}
+	if want := 20 * math.Log10(0.5); math.Abs(float64(spectrum[100])-want) > 0.1 {
+		t.Errorf("Expected peak level %.2f dB, got %.2f dB", want, spectrum[100])
+	}
+	if spectrum[400] > -100 {
+		t.Errorf("Expected far bins to be near silent, got %.2f dB", spectrum[400])
+	}
+
+	if _, err := buffer.FFT(1, fftSize); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange, got %v", err)
+	}
+	if _, err := buffer.FFT(0, 1000); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestMeasureLatency(t *testing.T) {
+	const delay = 123
+	rng := rand.New(rand.NewSource(1))
+	input := make([]float32, 8192)
+	for i := range input {
+		input[i] = float32(rng.Float64()*2 - 1)
+	}
+	delayed := make([]float32, len(input)+delay)
+	inverted := make([]float32, len(delayed))
+	for i, s := range input {
+		delayed[i+delay] = 0.5 * s
+		inverted[i+delay] = -s
+	}
+	reference := &AudioBuffer{Data: [][]float32{input}, SampleRate: 48000}
+
+	for name, output := range map[string][]float32{"delayed": delayed, "inverted": inverted} {
+		processed := &AudioBuffer{Data: [][]float32{output, output}, SampleRate: 48000}
+		samples, confidence, err := processed.MeasureLatency(reference)
+		if err != nil {
+			t.Fatalf("%s: MeasureLatency failed: %v", name, err)
+		}
+		if samples != delay {
+			t.Errorf("%s: expected latency of %d samples, got %d", name, delay, samples)
+		}
+		if confidence < 0.99 {
+			t.Errorf("%s: expected confidence near 1, got %f", name, confidence)
+		}
+	}
+
+	// The processed signal leads the reference
+	early := &AudioBuffer{Data: [][]float32{input[50:]}, SampleRate: 48000}
+	if samples, _, _ := early.MeasureLatency(reference); samples != -50 {
+		t.Errorf("Expected latency of -50 samples, got %d", samples)
+	}
+
+	other := make([]float32, len(input))
+	for i := range other {
+		other[i] = float32(rng.Float64()*2 - 1)
+	}
+	unrelated := &AudioBuffer{Data: [][]float32{other}, SampleRate: 48000}
+	if _, confidence, _ := unrelated.MeasureLatency(reference); confidence > 0.1 {
+		t.Errorf("Expected low confidence for unrelated signals, got %f", confidence)
+	}
+
+	silent := &AudioBuffer{Data: [][]float32{make([]float32, 100)}, SampleRate: 48000}
+	if _, _, err := silent.MeasureLatency(reference); !errors.Is(err, ErrSilentBuffer) {
+		t.Errorf("Expected ErrSilentBuffer, got %v", err)
+	}
+	if _, _, err := unrelated.MeasureLatency(&AudioBuffer{Data: [][]float32{input}, SampleRate: 44100}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for mismatched sample rates, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ParameterEvent is a parameter change captured by a ParameterRecorder.
+type ParameterEvent struct {
+	// Offset is the number of samples the processor had processed since recording
+	// started when the change was made.
+	Offset int64
+	// Index is the parameter index.
+	Index int
+	// Value is the normalized value that was set.
+	Value float32
+}
+
+// ParameterRecorder captures the SetParameter and SetParameterFromText calls made on a
+// processor, e.g. knob movements during a live performance, for replay with a
+// ParameterReplayer. Text changes are recorded as the normalized value they set.
+// Events are timed by the samples the processor has processed, so it should be running
+// (typically in an AudioStream) while recording; a change lands at the next block, and
+// its offset is that of the block boundary.
+type ParameterRecorder struct {
+	mu     sync.Mutex
+	p      *Processor
+	start  int64
+	events []ParameterEvent
+}
+
+// Start begins recording the parameter changes of p, discarding any previous recording.
+// A processor can only be recorded by one recorder at a time; starting another replaces it.
+func (r *ParameterRecorder) Start(p *Processor) {
+	r.Stop()
+	r.mu.Lock()
+	r.p = p
+	r.start = processedSamples(p)
+	r.events = nil
+	r.mu.Unlock()
+	p.recorder.Store(r)
+}
+
+// Stop ends recording and returns the recorded events in the order they were made.
+// Returns nil if nothing was recorded.
+func (r *ParameterRecorder) Stop() []ParameterEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.p != nil {
+		r.p.recorder.CompareAndSwap(r, nil)
+		r.p = nil
+	}
+	return r.events
+}
+
+// record appends a change made while recording.
+func (r *ParameterRecorder) record(index int, value float32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.p == nil {
+		return
+	}
+	r.events = append(r.events, ParameterEvent{
+		Offset: processedSamples(r.p) - r.start,
+		Index:  index,
+		Value:  value,
+	})
+}
+
+// processedSamples returns the number of samples p has processed since it was created.
+func processedSamples(p *Processor) int64 {
+	if p.handle == nil {
+		return 0
+	}
+	return int64(C.pedalboard_processor_get_processed_samples(p.handle))
+}
+
+// ParameterReplayer plays back events recorded by a ParameterRecorder. The zero value
+// is ready to use.
+type ParameterReplayer struct {
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// Play applies events to p with their original timing. Like recording, playback is
+// timed by the samples p processes, so p should be running (typically in an
+// AudioStream); an event is applied at the first block boundary at or after its offset,
+// counted from when Play is called, and playback pauses while p is not processing.
+// sampleRate, which should be the rate p is running at, only sets how long Play sleeps
+// between checks. Play blocks until the last event is applied or Stop is called.
+// Events are applied in order of offset; events with the same offset keep their order.
+// Returns an error if sampleRate is not positive or an event's parameter index is out
+// of range for p; no events are applied in that case.
+func (r *ParameterReplayer) Play(p *Processor, events []ParameterEvent, sampleRate float64) error {
+	if sampleRate <= 0 {
+		return fmt.Errorf("%w: sample rate %v", ErrInvalidArgument, sampleRate)
+	}
+	numParams := p.NumParameters()
+	for _, e := range events {
+		if e.Index < 0 || e.Index >= numParams {
+			return fmt.Errorf("%w: index %d (0-%d)", ErrParameterOutOfRange, e.Index, numParams-1)
+		}
+	}
+
+	events = append([]ParameterEvent(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Offset < events[j].Offset })
+
+	stop := make(chan struct{})
+	r.mu.Lock()
+	if r.stop != nil {
+		close(r.stop)
+	}
+	r.stop = stop
+	r.mu.Unlock()
+
+	start := processedSamples(p)
+	for _, e := range events {
+		for {
+			remaining := e.Offset - (processedSamples(p) - start)
+			if remaining <= 0 {
+				break
+			}
+			wait := max(time.Duration(float64(remaining)/sampleRate*float64(time.Second)), minReplayWait)
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return nil
+			}
+		}
+		p.SetParameter(e.Index, e.Value)
+	}
+
+	r.mu.Lock()
+	if r.stop == stop {
+		r.stop = nil
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// minReplayWait bounds how often Play checks the processed samples while waiting.
+const minReplayWait = time.Millisecond
+
+// Stop ends a running Play call. Events not yet applied are skipped.
+func (r *ParameterReplayer) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
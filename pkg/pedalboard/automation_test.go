@@ -0,0 +1,89 @@
+package pedalboard
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParameterRecorder(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	block := func(n int) [][]float32 { return [][]float32{make([]float32, n)} }
+	gain.Process(block(100), 44100.0)
+
+	var recorder ParameterRecorder
+	recorder.Start(gain)
+	gain.SetParameter(0, 0.1)
+	gain.Process(block(512), 44100.0)
+	gain.SetParameter(0, 0.2)
+	gain.Process(block(256), 44100.0)
+	gain.SetParameter(0, 0.3)
+	gain.Process(block(64), 44100.0)
+	if err := gain.SetParameterFromText(0, "0.5"); err != nil {
+		t.Fatalf("SetParameterFromText failed: %v", err)
+	}
+	events := recorder.Stop()
+	gain.SetParameter(0, 0.4)
+
+	want := []ParameterEvent{{0, 0, 0.1}, {512, 0, 0.2}, {768, 0, 0.3}, {832, 0, 0.5}}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("Event %d: expected %v, got %v", i, want[i], events[i])
+		}
+	}
+}
+
+func TestParameterReplayer(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	events := []ParameterEvent{{Offset: 441, Index: 0, Value: 0.75}, {Offset: 0, Index: 0, Value: 0.25}}
+
+	var replayer ParameterReplayer
+	done := make(chan error)
+	go func() { done <- replayer.Play(gain, events, 44100.0) }()
+
+	// Playback follows the samples processed, not the wall clock
+	time.Sleep(50 * time.Millisecond)
+	if v := gain.GetParameter(0); v != 0.25 {
+		t.Errorf("Expected only the event at offset 0 before processing, got %f", v)
+	}
+	gain.Process([][]float32{make([]float32, 400)}, 44100.0)
+	time.Sleep(20 * time.Millisecond)
+	if v := gain.GetParameter(0); v != 0.25 {
+		t.Errorf("Expected the event at offset 441 to wait for 441 samples, got %f", v)
+	}
+	gain.Process([][]float32{make([]float32, 100)}, 44100.0)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Play failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected playback to finish once 441 samples were processed")
+	}
+	if v := gain.GetParameter(0); v != 0.75 {
+		t.Errorf("Expected the last event to be applied last, got %f", v)
+	}
+
+	if err := replayer.Play(gain, []ParameterEvent{{Index: 5}}, 44100.0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter, got %v", err)
+	}
+	if err := replayer.Play(gain, events, 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for a zero sample rate, got %v", err)
+	}
+
+	// Stop skips the events not yet applied
+	go func() { done <- replayer.Play(gain, []ParameterEvent{{Offset: 44100, Index: 0, Value: 0.5}}, 44100.0) }()
+	time.Sleep(10 * time.Millisecond)
+	replayer.Stop()
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected Stop to end playback")
+	}
+	if v := gain.GetParameter(0); v != 0.75 {
+		t.Errorf("Expected the stopped event to be skipped, got %f", v)
+	}
+}
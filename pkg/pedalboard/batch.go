@@ -0,0 +1,135 @@
+package pedalboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BatchOptions configures BatchProcess.
+type BatchOptions struct {
+	// NumWorkers is the number of files processed at once; 0 uses one per CPU.
+	NumWorkers int
+	// OutputFormat is the output file extension, such as "wav" or "flac". Empty keeps
+	// each input's extension.
+	OutputFormat string
+	// OnProgress, if set, is called after each file (successful or not) with the number
+	// of files finished so far and the total.
+	OnProgress func(done, total int)
+	// OnError, if set, is called for each file that fails.
+	OnError func(path string, err error)
+}
+
+// BatchProcess loads each input file, processes it through chain and saves the result
+// to outputDir under the same file name (with the extension changed if
+// opts.OutputFormat is set). Files are processed in parallel, each through a fresh copy
+// of chain built with the same processors, parameter values and disabled stages, so
+// workers don't share effect state and tails don't carry over between files.
+// Callbacks are never called concurrently.
+// A failing file is reported to opts.OnError and does not stop the batch.
+// Returns an error if the batch could not start, or the joined errors of all failed files.
+func BatchProcess(inputPaths []string, outputDir string, chain *ProcessorChain, opts BatchOptions) error {
+	if chain == nil {
+		return fmt.Errorf("%w: chain is nil", ErrInvalidArgument)
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// Fail early if the chain cannot be copied for the workers
+	if _, err := chain.clone(); err != nil {
+		return err
+	}
+
+	workers := opts.NumWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		mu   sync.Mutex
+		done int
+		errs []error
+		wg   sync.WaitGroup
+	)
+	paths := make(chan string)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				err := batchProcessFile(path, outputDir, chain, opts.OutputFormat)
+
+				mu.Lock()
+				done++
+				if err != nil {
+					err = fmt.Errorf("%s: %w", path, err)
+					errs = append(errs, err)
+					if opts.OnError != nil {
+						opts.OnError(path, err)
+					}
+				}
+				if opts.OnProgress != nil {
+					opts.OnProgress(done, len(inputPaths))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range inputPaths {
+		paths <- path
+	}
+	close(paths)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// batchProcessFile processes one file of a batch through a fresh copy of chain.
+func batchProcessFile(path, outputDir string, chain *ProcessorChain, format string) error {
+	buffer, err := LoadAudioFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := chain.clone()
+	if err != nil {
+		return err
+	}
+	result, err := c.ProcessToNewBuffer(buffer)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(path)
+	if format != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + "." + strings.TrimPrefix(format, ".")
+	}
+	return SaveAudioFile(filepath.Join(outputDir, name), result)
+}
+
+// clone builds a new chain with fresh copies of the stages, carrying their state as
+// snapshotProcessor does, and the same disabled stages.
+func (c *ProcessorChain) clone() (*ProcessorChain, error) {
+	copied, err := NewProcessorChain()
+	if err != nil {
+		return nil, err
+	}
+	for i, stage := range c.stages {
+		p, err := snapshotProcessor(stage)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %w", i, err)
+		}
+		if err := copied.Add(p); err != nil {
+			return nil, err
+		}
+		if c.disabled[i] {
+			if err := copied.DisableStage(i); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return copied, nil
+}
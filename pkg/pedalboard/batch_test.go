@@ -0,0 +1,70 @@
+package pedalboard
+
+import (
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestBatchProcess(t *testing.T) {
+	inDir, outDir := t.TempDir(), t.TempDir()
+	var inputs []string
+	for _, name := range []string{"a.wav", "b.wav"} {
+		path := filepath.Join(inDir, name)
+		if err := SaveAudioFile(path, sineBuffer(440, 0.5, 44100, 4410)); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		inputs = append(inputs, path)
+	}
+	missing := filepath.Join(inDir, "missing.wav")
+	inputs = append(inputs, missing)
+
+	half, _ := NewInternalProcessor("Gain")
+	half.SetParameter(0, 0.5)
+	// Worker copies keep the bypass flag
+	bypassed, _ := NewInternalProcessor("Gain")
+	bypassed.SetParameter(0, 0.1)
+	bypassed.SetBypassed(true)
+	chain, err := NewProcessorChain(half, bypassed)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	var mu sync.Mutex
+	var failed []string
+	lastDone := 0
+	err = BatchProcess(inputs, outDir, chain, BatchOptions{
+		NumWorkers: 2,
+		OnProgress: func(done, total int) {
+			lastDone = done
+			if total != len(inputs) {
+				t.Errorf("Expected total %d, got %d", len(inputs), total)
+			}
+		},
+		OnError: func(path string, err error) {
+			mu.Lock()
+			failed = append(failed, path)
+			mu.Unlock()
+		},
+	})
+	if err == nil {
+		t.Error("Expected an error reporting the missing file")
+	}
+	if len(failed) != 1 || failed[0] != missing {
+		t.Errorf("Expected only %s to fail, got %v", missing, failed)
+	}
+	if lastDone != len(inputs) {
+		t.Errorf("Expected progress to reach %d, got %d", len(inputs), lastDone)
+	}
+
+	for _, name := range []string{"a.wav", "b.wav"} {
+		out, err := LoadAudioFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatalf("Expected output %s: %v", name, err)
+		}
+		if level := rms(out.Data[0]); math.Abs(level-0.25/math.Sqrt2) > 0.01 {
+			t.Errorf("%s: expected the gain to be applied, RMS %f", name, level)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package pedalboard
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// beatFrameSize and beatHopSize set the onset analysis resolution.
+	beatFrameSize = 1024
+	beatHopSize   = 512
+	// beatMinBPM and beatMaxBPM bound the tempos tracked by the comb filter bank.
+	beatMinBPM = 60.0
+	beatMaxBPM = 180.0
+	// beatCombFeedback is the comb filter feedback; higher values need more beats to lock.
+	beatCombFeedback = 0.8
+	// beatEnergyDecay is the per-frame decay of each comb's output energy.
+	beatEnergyDecay = 0.995
+)
+
+// BeatEvent is a beat found by a BeatDetector.
+type BeatEvent struct {
+	// SampleOffset is the position of the beat within the buffer passed to Process.
+	SampleOffset int
+	// Confidence is how clearly the winning tempo stands out from the others, from 0 to 1.
+	Confidence float64
+}
+
+// BeatDetector tracks beats in a stream of audio blocks. An onset strength (spectral
+// flux) is computed every 512 samples and fed to a bank of comb filters, one per beat
+// period between 60 and 180 BPM. The comb with the most energy, weighted towards the
+// expected tempo, gives the tempo, and the phase where its output peaks gives the beat.
+// A BeatDetector is not safe for concurrent use.
+type BeatDetector struct {
+	sampleRate float64
+	bpm        float64
+	onBeat     func(BeatEvent)
+
+	window   []float64
+	pending  []float32 // samples not yet analysed
+	prevMag  []float64
+	combs    []beatComb
+	frame    int // index of the next onset frame
+	lastBeat int // frame of the last reported beat
+}
+
+// beatComb is a feedback comb filter with a delay of period onset frames.
+type beatComb struct {
+	period int
+	weight float64
+	output []float64 // last period outputs, indexed by frame % period
+	energy float64
+}
+
+// NewBeatDetector creates a beat detector.
+// bpm: The expected tempo, used to choose between related tempos such as 60 and 120 BPM.
+// Values outside 60-180 are clamped; 0 assumes 120 BPM.
+// sampleRate: The sample rate of the audio passed to Process.
+// Returns an error wrapping ErrInvalidArgument if sampleRate is not positive.
+func NewBeatDetector(bpm float64, sampleRate float64) (*BeatDetector, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("%w: sample rate %f", ErrInvalidArgument, sampleRate)
+	}
+	if bpm <= 0 {
+		bpm = 120
+	}
+	bpm = math.Max(beatMinBPM, math.Min(beatMaxBPM, bpm))
+
+	d := &BeatDetector{
+		sampleRate: sampleRate,
+		bpm:        bpm,
+		window:     hannWindow(beatFrameSize),
+		lastBeat:   -1 << 30,
+	}
+	framesPerMinute := 60 * sampleRate / beatHopSize
+	minPeriod := int(math.Ceil(framesPerMinute / beatMaxBPM))
+	maxPeriod := int(math.Floor(framesPerMinute / beatMinBPM))
+	for period := minPeriod; period <= maxPeriod; period++ {
+		// Log-Gaussian preference around the expected tempo
+		octaves := math.Log2(framesPerMinute / float64(period) / bpm)
+		d.combs = append(d.combs, beatComb{
+			period: period,
+			weight: math.Exp(-0.5 * octaves * octaves / (0.7 * 0.7)),
+			output: make([]float64, period),
+		})
+	}
+	return d, nil
+}
+
+// OnBeat registers a function called from Process for every beat found, in order.
+// Passing nil removes the callback.
+func (d *BeatDetector) OnBeat(fn func(event BeatEvent)) {
+	d.onBeat = fn
+}
+
+// BPM returns the current tempo estimate, or the expected tempo before any audio
+// has been processed.
+func (d *BeatDetector) BPM() float64 {
+	if best := d.bestComb(); best != nil && best.energy > 0 {
+		return 60 * d.sampleRate / beatHopSize / float64(best.period)
+	}
+	return d.bpm
+}
+
+// Process analyses the next block of audio. Channels are mixed to mono and blocks may
+// be any length; analysis frames carry over between calls.
+// Returns the beats found in this block.
+func (d *BeatDetector) Process(buffer *AudioBuffer) []BeatEvent {
+	if buffer == nil || len(buffer.Data) == 0 || len(d.combs) == 0 {
+		return nil
+	}
+
+	// pending holds beatFrameSize-beatHopSize samples of overlap from earlier blocks
+	carried := len(d.pending)
+	d.pending = append(d.pending, buffer.monoMix()...)
+
+	var events []BeatEvent
+	start := 0
+	for start+beatFrameSize <= len(d.pending) {
+		if d.analyseFrame(d.pending[start : start+beatFrameSize]) {
+			event := BeatEvent{
+				// The beat is reported at the end of the frame that revealed it
+				SampleOffset: start + beatFrameSize - carried,
+				Confidence:   d.confidence(),
+			}
+			if event.SampleOffset < 0 {
+				event.SampleOffset = 0
+			}
+			events = append(events, event)
+			if d.onBeat != nil {
+				d.onBeat(event)
+			}
+		}
+		start += beatHopSize
+	}
+	d.pending = append(d.pending[:0], d.pending[start:]...)
+	return events
+}
+
+// analyseFrame updates the onset function and comb filters with one frame and
+// reports whether the frame is a beat.
+func (d *BeatDetector) analyseFrame(samples []float32) bool {
+	power := powerSpectrum(samples, d.window)
+	var flux float64
+	for k, p := range power {
+		mag := math.Log1p(math.Sqrt(p))
+		if d.prevMag != nil && mag > d.prevMag[k] {
+			flux += mag - d.prevMag[k]
+		}
+		power[k] = mag
+	}
+	d.prevMag = power
+
+	for i := range d.combs {
+		c := &d.combs[i]
+		slot := d.frame % c.period
+		out := beatCombFeedback*c.output[slot] + (1-beatCombFeedback)*flux
+		c.output[slot] = out
+		c.energy = beatEnergyDecay*c.energy + (1-beatEnergyDecay)*out*out
+	}
+
+	n := d.frame
+	d.frame++
+	best := d.bestComb()
+	if best.energy == 0 || n < 2*best.period || n-d.lastBeat < best.period/2 {
+		return false
+	}
+
+	// The current frame is on the beat if it is the strongest phase of the winning comb
+	current := best.output[n%best.period]
+	for _, v := range best.output {
+		if v > current {
+			return false
+		}
+	}
+	d.lastBeat = n
+	return true
+}
+
+// bestComb returns the comb with the most weighted energy.
+func (d *BeatDetector) bestComb() *beatComb {
+	var best *beatComb
+	for i := range d.combs {
+		c := &d.combs[i]
+		if best == nil || c.energy*c.weight > best.energy*best.weight {
+			best = c
+		}
+	}
+	return best
+}
+
+// confidence compares the winning comb's weighted energy with the average.
+func (d *BeatDetector) confidence() float64 {
+	best := d.bestComb()
+	var sum float64
+	for _, c := range d.combs {
+		sum += c.energy * c.weight
+	}
+	top := best.energy * best.weight
+	if top == 0 {
+		return 0
+	}
+	mean := sum / float64(len(d.combs))
+	return (top - mean) / top
+}
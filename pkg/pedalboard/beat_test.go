@@ -0,0 +1,79 @@
+package pedalboard
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// clickTrack returns numSamples of short noise bursts every 60/bpm seconds.
+func clickTrack(bpm, sampleRate float64, numSamples int) []float32 {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]float32, numSamples)
+	interval := 60 / bpm * sampleRate
+	for beat := 0.0; int(beat) < numSamples; beat += interval {
+		for i := 0; i < 400 && int(beat)+i < numSamples; i++ {
+			decay := math.Exp(-float64(i) / 80)
+			data[int(beat)+i] = float32((rng.Float64()*2 - 1) * decay)
+		}
+	}
+	return data
+}
+
+func TestBeatDetector(t *testing.T) {
+	const sampleRate = 44100.0
+	const blockSize = 4096
+	track := clickTrack(120, sampleRate, 12*44100)
+
+	if _, err := NewBeatDetector(120, 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for a zero sample rate, got %v", err)
+	}
+	detector, err := NewBeatDetector(100, sampleRate)
+	if err != nil {
+		t.Fatalf("Failed to create BeatDetector: %v", err)
+	}
+	var callbacks int
+	detector.OnBeat(func(BeatEvent) { callbacks++ })
+
+	// Feed the track in blocks and record absolute beat positions
+	var beats []int
+	var events int
+	for start := 0; start < len(track); start += blockSize {
+		end := start + blockSize
+		if end > len(track) {
+			end = len(track)
+		}
+		block := &AudioBuffer{Data: [][]float32{track[start:end]}, SampleRate: sampleRate}
+		for _, event := range detector.Process(block) {
+			beats = append(beats, start+event.SampleOffset)
+			events++
+			if event.Confidence < 0 || event.Confidence > 1 {
+				t.Errorf("Confidence %f out of range", event.Confidence)
+			}
+		}
+	}
+
+	if callbacks != events {
+		t.Errorf("Expected %d callbacks, got %d", events, callbacks)
+	}
+	if bpm := detector.BPM(); math.Abs(bpm-120) > 3 {
+		t.Errorf("Expected tempo near 120 BPM, got %.1f", bpm)
+	}
+
+	// Once locked, beats should be half a second apart
+	var locked []int
+	for _, b := range beats {
+		if b > 6*44100 {
+			locked = append(locked, b)
+		}
+	}
+	if len(locked) < 10 {
+		t.Fatalf("Expected at least 10 beats in the last 6 seconds, got %d", len(locked))
+	}
+	for i := 1; i < len(locked); i++ {
+		if interval := locked[i] - locked[i-1]; math.Abs(float64(interval)-22050) > 2*beatHopSize {
+			t.Errorf("Beat %d: expected an interval near 22050 samples, got %d", i, interval)
+		}
+	}
+}
@@ -0,0 +1,799 @@
+package pedalboard
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// int16Scale is the full-scale value used for 16-bit PCM conversion.
+const int16Scale = 32767.0
+
+// CrossChannelGain adds a scaled copy of one channel into another.
+// For every sample i it computes Data[toCh][i] += gain * Data[fromCh][i].
+// A gain of -1.0 subtracts the source channel, which is useful for
+// mid/side processing and de-correlation.
+// Returns an error if either channel index is out of range.
+func (b *AudioBuffer) CrossChannelGain(fromCh, toCh int, gain float32) error {
+	if fromCh < 0 || fromCh >= len(b.Data) {
+		return fmt.Errorf("%w: source channel %d (0-%d)", ErrOutOfRange, fromCh, len(b.Data)-1)
+	}
+	if toCh < 0 || toCh >= len(b.Data) {
+		return fmt.Errorf("%w: destination channel %d (0-%d)", ErrOutOfRange, toCh, len(b.Data)-1)
+	}
+
+	src := b.Data[fromCh]
+	dst := b.Data[toCh]
+	n := len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] += gain * src[i]
+	}
+	return nil
+}
+
+// FromInt16 creates an AudioBuffer from signed 16-bit PCM samples laid out as
+// [channel][sample]. Samples are scaled to float32 by dividing by 32767.
+func FromInt16(data [][]int16, sampleRate float64) *AudioBuffer {
+	out := make([][]float32, len(data))
+	for ch := range data {
+		out[ch] = make([]float32, len(data[ch]))
+		for i, s := range data[ch] {
+			out[ch][i] = float32(s) / int16Scale
+		}
+	}
+	return &AudioBuffer{
+		Data:       out,
+		SampleRate: sampleRate,
+	}
+}
+
+// ToInt16 converts the buffer to signed 16-bit PCM laid out as [channel][sample].
+// Samples are scaled by 32767 and clamped to the int16 range.
+// dither: If true, TPDF (triangular) dither of +/-1 LSB is added before rounding.
+// Returns an error if the buffer is empty.
+func (b *AudioBuffer) ToInt16(dither bool) ([][]int16, error) {
+	if len(b.Data) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+
+	out := make([][]int16, len(b.Data))
+	for ch := range b.Data {
+		out[ch] = make([]int16, len(b.Data[ch]))
+		for i, s := range b.Data[ch] {
+			v := float64(s) * int16Scale
+			if dither {
+				v += rand.Float64() - rand.Float64()
+			}
+			v = math.Round(v)
+			if v > math.MaxInt16 {
+				v = math.MaxInt16
+			} else if v < math.MinInt16 {
+				v = math.MinInt16
+			}
+			out[ch][i] = int16(v)
+		}
+	}
+	return out, nil
+}
+
+// duplicateChannelCorrelation is the correlation above which two channels are considered identical.
+const duplicateChannelCorrelation = 0.999
+
+// Autochannel detects channels that duplicate an earlier channel (correlation above 0.999)
+// and removes them. A stereo file holding the same mono signal on both channels becomes mono.
+// Returns a new AudioBuffer without the duplicates, or the original buffer unchanged if
+// no duplicate channels were found. Returns an error if the buffer is empty.
+func (b *AudioBuffer) Autochannel() (*AudioBuffer, error) {
+	if len(b.Data) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+
+	var kept []int
+	for ch := range b.Data {
+		duplicate := false
+		for _, k := range kept {
+			if channelCorrelation(b.Data[k], b.Data[ch]) > duplicateChannelCorrelation {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, ch)
+		}
+	}
+
+	if len(kept) == len(b.Data) {
+		return b, nil
+	}
+
+	data := make([][]float32, len(kept))
+	for i, ch := range kept {
+		data[i] = make([]float32, len(b.Data[ch]))
+		copy(data[i], b.Data[ch])
+	}
+	return &AudioBuffer{
+		Data:       data,
+		SampleRate: b.SampleRate,
+		Markers:    append([]Marker(nil), b.Markers...),
+	}, nil
+}
+
+// MixDown mixes all channels into a mono buffer using a gain per channel, e.g. to
+// apply a custom surround downmix matrix row. Each output sample is the sum over
+// channels of gains[ch] * Data[ch][i]. Markers are kept.
+// gains: One gain per channel.
+// Returns the mono AudioBuffer or an error if len(gains) does not match the channel count.
+func (b *AudioBuffer) MixDown(gains []float32) (*AudioBuffer, error) {
+	if len(b.Data) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+	if len(gains) != len(b.Data) {
+		return nil, fmt.Errorf("%w: got %d gains for %d channels", ErrInvalidArgument, len(gains), len(b.Data))
+	}
+
+	mix := make([]float32, len(b.Data[0]))
+	for ch, channel := range b.Data {
+		for i := 0; i < len(mix) && i < len(channel); i++ {
+			mix[i] += gains[ch] * channel[i]
+		}
+	}
+
+	return &AudioBuffer{
+		Data:       [][]float32{mix},
+		SampleRate: b.SampleRate,
+		Markers:    append([]Marker(nil), b.Markers...),
+	}, nil
+}
+
+// SplitAt splits the buffer at a sample boundary into [0, sampleIndex) and
+// [sampleIndex, end). Both halves are independent copies with the same sample rate;
+// markers go to the half containing them, with positions in the second half made
+// relative to its start. The original buffer is unmodified.
+// sampleIndex: The first sample of the second half, from 0 to the buffer length.
+// Returns the two halves or an error if sampleIndex is out of range.
+func (b *AudioBuffer) SplitAt(sampleIndex int) (*AudioBuffer, *AudioBuffer, error) {
+	if len(b.Data) == 0 {
+		return nil, nil, ErrEmptyBuffer
+	}
+	numSamples := len(b.Data[0])
+	if sampleIndex < 0 || sampleIndex > numSamples {
+		return nil, nil, fmt.Errorf("%w: sample index %d (0-%d)", ErrOutOfRange, sampleIndex, numSamples)
+	}
+
+	first := &AudioBuffer{Data: make([][]float32, len(b.Data)), SampleRate: b.SampleRate}
+	second := &AudioBuffer{Data: make([][]float32, len(b.Data)), SampleRate: b.SampleRate}
+	for ch, channel := range b.Data {
+		split := min(sampleIndex, len(channel))
+		first.Data[ch] = append([]float32(nil), channel[:split]...)
+		second.Data[ch] = append([]float32(nil), channel[split:]...)
+	}
+
+	if b.SampleRate > 0 {
+		splitTime := time.Duration(float64(sampleIndex) / b.SampleRate * float64(time.Second))
+		for _, m := range b.Markers {
+			if m.Position < splitTime {
+				first.Markers = append(first.Markers, m)
+			} else {
+				m.Position -= splitTime
+				second.Markers = append(second.Markers, m)
+			}
+		}
+	}
+	return first, second, nil
+}
+
+// WrapAround returns a copy of the buffer with its samples circularly shifted, as
+// used to rotate FFT frames. A positive offset shifts right, so the last offset
+// samples become the start; a negative offset shifts left. Offsets wrap modulo the
+// buffer length. Markers move with their samples. The original buffer is unmodified.
+func (b *AudioBuffer) WrapAround(offset int) *AudioBuffer {
+	out := &AudioBuffer{Data: make([][]float32, len(b.Data)), SampleRate: b.SampleRate}
+	for ch, channel := range b.Data {
+		n := len(channel)
+		out.Data[ch] = make([]float32, n)
+		if n == 0 {
+			continue
+		}
+		shift := ((offset % n) + n) % n
+		copy(out.Data[ch], channel[n-shift:])
+		copy(out.Data[ch][shift:], channel[:n-shift])
+	}
+
+	if len(b.Data) > 0 && len(b.Data[0]) > 0 && b.SampleRate > 0 {
+		n := len(b.Data[0])
+		shift := ((offset % n) + n) % n
+		for _, m := range b.Markers {
+			pos := ((int(math.Round(m.Position.Seconds()*b.SampleRate))+shift)%n + n) % n
+			m.Position = time.Duration(float64(pos) / b.SampleRate * float64(time.Second))
+			out.Markers = append(out.Markers, m)
+		}
+	} else {
+		out.Markers = append(out.Markers, b.Markers...)
+	}
+	return out
+}
+
+// TrimLeadingSilence returns a copy of the buffer without the silence at its start,
+// searching forward from the first sample only. Markers in the removed part are
+// dropped; the others are made relative to the new start. The original buffer is
+// unmodified.
+// threshold: The absolute sample value, on any channel, above which audio is not silence.
+// padMs: How much of the silence to keep before the first sound, in milliseconds, so
+// transient attacks are not cut.
+// Returns the trimmed copy, ErrSilentBuffer if no sample exceeds threshold, or an error
+// if the buffer is empty or threshold or padMs is negative.
+func (b *AudioBuffer) TrimLeadingSilence(threshold float32, padMs float64) (*AudioBuffer, error) {
+	pad, err := b.checkTrimSilence(threshold, padMs)
+	if err != nil {
+		return nil, err
+	}
+	numSamples := len(b.Data[0])
+	for i := 0; i < numSamples; i++ {
+		if b.exceedsAt(i, threshold) {
+			_, trimmed, err := b.SplitAt(max(i-pad, 0))
+			return trimmed, err
+		}
+	}
+	return nil, ErrSilentBuffer
+}
+
+// TrimTrailingSilence returns a copy of the buffer without the silence at its end,
+// searching backward from the last sample only. Markers in the removed part are
+// dropped. The original buffer is unmodified.
+// threshold: The absolute sample value, on any channel, above which audio is not silence.
+// padMs: How much of the silence to keep after the last sound, in milliseconds, so
+// decays are not cut.
+// Returns the trimmed copy, ErrSilentBuffer if no sample exceeds threshold, or an error
+// if the buffer is empty or threshold or padMs is negative.
+func (b *AudioBuffer) TrimTrailingSilence(threshold float32, padMs float64) (*AudioBuffer, error) {
+	pad, err := b.checkTrimSilence(threshold, padMs)
+	if err != nil {
+		return nil, err
+	}
+	numSamples := len(b.Data[0])
+	for i := numSamples - 1; i >= 0; i-- {
+		if b.exceedsAt(i, threshold) {
+			trimmed, _, err := b.SplitAt(min(i+1+pad, numSamples))
+			return trimmed, err
+		}
+	}
+	return nil, ErrSilentBuffer
+}
+
+// checkTrimSilence validates the arguments of the silence trimming methods and returns
+// padMs in samples.
+func (b *AudioBuffer) checkTrimSilence(threshold float32, padMs float64) (int, error) {
+	if len(b.Data) == 0 || len(b.Data[0]) == 0 {
+		return 0, ErrEmptyBuffer
+	}
+	if threshold < 0 || padMs < 0 {
+		return 0, fmt.Errorf("%w: threshold %g and padding %g ms must not be negative", ErrInvalidArgument, threshold, padMs)
+	}
+	if padMs > 0 && b.SampleRate <= 0 {
+		return 0, fmt.Errorf("%w: padding needs a sample rate, got %g", ErrInvalidArgument, b.SampleRate)
+	}
+	return int(math.Round(padMs / 1000 * b.SampleRate)), nil
+}
+
+// exceedsAt reports whether the sample at index i exceeds threshold on any channel.
+func (b *AudioBuffer) exceedsAt(i int, threshold float32) bool {
+	for _, channel := range b.Data {
+		if i < len(channel) && (channel[i] > threshold || channel[i] < -threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// channelCorrelation returns the normalised zero-lag correlation of two channels.
+// Two silent channels are treated as perfectly correlated.
+func channelCorrelation(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var ab, aa, bb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		ab += x * y
+		aa += x * x
+		bb += y * y
+	}
+	if aa == 0 && bb == 0 {
+		return 1
+	}
+	if aa == 0 || bb == 0 {
+		return 0
+	}
+	return ab / math.Sqrt(aa*bb)
+}
+
+// BreakIntoFrames splits the buffer into overlapping frames for frame-based processing
+// such as STFT. Frame i starts at sample i*hopSize; trailing samples that do not fill a
+// whole frame are dropped. Each frame is an independent copy of the audio.
+// frameSize: The number of samples per frame.
+// hopSize: The number of samples between the starts of consecutive frames.
+// Returns the frames or an error if frameSize exceeds the buffer length or hopSize <= 0.
+func (b *AudioBuffer) BreakIntoFrames(frameSize, hopSize int) ([]*AudioBuffer, error) {
+	if len(b.Data) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+	if hopSize <= 0 {
+		return nil, fmt.Errorf("%w: hop size %d", ErrInvalidArgument, hopSize)
+	}
+	numSamples := len(b.Data[0])
+	if frameSize <= 0 || frameSize > numSamples {
+		return nil, fmt.Errorf("%w: frame size %d (1-%d)", ErrOutOfRange, frameSize, numSamples)
+	}
+
+	numFrames := (numSamples-frameSize)/hopSize + 1
+	frames := make([]*AudioBuffer, numFrames)
+	for f := range frames {
+		start := f * hopSize
+		data := make([][]float32, len(b.Data))
+		for ch := range b.Data {
+			data[ch] = make([]float32, frameSize)
+			copy(data[ch], b.Data[ch][start:start+frameSize])
+		}
+		frames[f] = &AudioBuffer{
+			Data:       data,
+			SampleRate: b.SampleRate,
+		}
+	}
+	return frames, nil
+}
+
+// FromFrames reassembles frames into a single buffer using overlap-add, the inverse
+// of BreakIntoFrames. Frame i is added at sample i*hopSize and overlapping samples
+// are summed. The output length is (len(frames)-1)*hopSize + frameSize.
+// frames: The frames to join. All frames must have the same channel count and length.
+// hopSize: The number of samples between the starts of consecutive frames.
+// Returns the joined AudioBuffer or an error if the frames are inconsistent.
+func FromFrames(frames []*AudioBuffer, hopSize int) (*AudioBuffer, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%w: no frames", ErrEmptyBuffer)
+	}
+	if hopSize <= 0 {
+		return nil, fmt.Errorf("%w: hop size %d", ErrInvalidArgument, hopSize)
+	}
+
+	numChannels := len(frames[0].Data)
+	if numChannels == 0 {
+		return nil, fmt.Errorf("%w: frame 0", ErrEmptyBuffer)
+	}
+	frameSize := len(frames[0].Data[0])
+	for f, frame := range frames {
+		if len(frame.Data) != numChannels {
+			return nil, fmt.Errorf("%w: frame %d has %d channels, expected %d", ErrInvalidArgument, f, len(frame.Data), numChannels)
+		}
+		for ch := range frame.Data {
+			if len(frame.Data[ch]) != frameSize {
+				return nil, fmt.Errorf("%w: frame %d channel %d has %d samples, expected %d", ErrInvalidArgument, f, ch, len(frame.Data[ch]), frameSize)
+			}
+		}
+	}
+
+	numSamples := (len(frames)-1)*hopSize + frameSize
+	data := make([][]float32, numChannels)
+	for ch := range data {
+		data[ch] = make([]float32, numSamples)
+	}
+	for f, frame := range frames {
+		start := f * hopSize
+		for ch := range frame.Data {
+			dst := data[ch][start : start+frameSize]
+			for i, s := range frame.Data[ch] {
+				dst[i] += s
+			}
+		}
+	}
+
+	return &AudioBuffer{
+		Data:       data,
+		SampleRate: frames[0].SampleRate,
+	}, nil
+}
+
+// AverageBuffers returns the per-sample mean of buffers. Averaging repeated takes of
+// the same signal (e.g. several measurement sweeps) keeps the coherent signal while
+// uncorrelated noise drops by sqrt(len(buffers)).
+// buffers: The buffers to average. All must have the same channel count, length and
+// sample rate.
+// Returns a new AudioBuffer or an error if the buffers are empty or inconsistent.
+func AverageBuffers(buffers []*AudioBuffer) (*AudioBuffer, error) {
+	if len(buffers) == 0 {
+		return nil, fmt.Errorf("%w: no buffers", ErrEmptyBuffer)
+	}
+	first := buffers[0]
+	if first == nil || len(first.Data) == 0 {
+		return nil, fmt.Errorf("%w: buffer 0", ErrEmptyBuffer)
+	}
+	numSamples := len(first.Data[0])
+	for i, buffer := range buffers {
+		if buffer == nil || len(buffer.Data) != len(first.Data) {
+			return nil, fmt.Errorf("%w: buffer %d has a different channel count", ErrInvalidArgument, i)
+		}
+		if buffer.SampleRate != first.SampleRate {
+			return nil, fmt.Errorf("%w: buffer %d has sample rate %f, expected %f", ErrInvalidArgument, i, buffer.SampleRate, first.SampleRate)
+		}
+		for ch := range buffer.Data {
+			if len(buffer.Data[ch]) != numSamples {
+				return nil, fmt.Errorf("%w: buffer %d channel %d has %d samples, expected %d", ErrInvalidArgument, i, ch, len(buffer.Data[ch]), numSamples)
+			}
+		}
+	}
+
+	sum := make([]float64, numSamples)
+	data := make([][]float32, len(first.Data))
+	for ch := range data {
+		for i := range sum {
+			sum[i] = 0
+		}
+		for _, buffer := range buffers {
+			for i, s := range buffer.Data[ch] {
+				sum[i] += float64(s)
+			}
+		}
+		data[ch] = make([]float32, numSamples)
+		for i, s := range sum {
+			data[ch][i] = float32(s / float64(len(buffers)))
+		}
+	}
+
+	return &AudioBuffer{
+		Data:       data,
+		SampleRate: first.SampleRate,
+	}, nil
+}
+
+// resampleHalfTaps is the number of sinc zero crossings on each side of the
+// interpolation kernel used by Resample.
+const resampleHalfTaps = 16
+
+// Resample converts the buffer to a new sample rate using windowed-sinc
+// interpolation. When downsampling, the kernel is widened so content above the
+// new Nyquist frequency is filtered out. Markers keep their time positions.
+// sampleRate: The target sample rate in Hz.
+// Returns a new AudioBuffer or an error if the buffer is empty or a rate is invalid.
+func (b *AudioBuffer) Resample(sampleRate float64) (*AudioBuffer, error) {
+	if len(b.Data) == 0 || len(b.Data[0]) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+	if sampleRate <= 0 || b.SampleRate <= 0 {
+		return nil, fmt.Errorf("%w: sample rate %v to %v", ErrInvalidArgument, b.SampleRate, sampleRate)
+	}
+
+	ratio := sampleRate / b.SampleRate
+	cutoff := math.Min(1.0, ratio)
+	radius := resampleHalfTaps / cutoff
+	numIn := len(b.Data[0])
+	numOut := int(math.Round(float64(numIn) * ratio))
+
+	data := make([][]float32, len(b.Data))
+	for ch := range data {
+		data[ch] = make([]float32, numOut)
+	}
+	for i := 0; i < numOut; i++ {
+		pos := float64(i) / ratio
+		first := int(math.Max(0, math.Ceil(pos-radius)))
+		last := int(math.Min(float64(numIn-1), math.Floor(pos+radius)))
+		for k := first; k <= last; k++ {
+			x := pos - float64(k)
+			// Hann-windowed sinc, scaled so the passband gain stays at 1
+			w := cutoff * (0.5 + 0.5*math.Cos(math.Pi*x/radius))
+			if arg := math.Pi * cutoff * x; arg != 0 {
+				w *= math.Sin(arg) / arg
+			}
+			for ch := range data {
+				data[ch][i] += float32(w * float64(b.Data[ch][k]))
+			}
+		}
+	}
+
+	return &AudioBuffer{
+		Data:       data,
+		SampleRate: sampleRate,
+		Markers:    append([]Marker(nil), b.Markers...),
+	}, nil
+}
+
+// upsampleTapsPerFactor sets the anti-imaging filter length used by Upsample.
+const upsampleTapsPerFactor = 64
+
+// Upsample raises the sample rate by an integer factor. It inserts factor-1 zeros
+// between samples and removes the resulting images with a linear-phase windowed-sinc
+// low-pass filter at the original Nyquist frequency. The filter delay is compensated,
+// so the output is aligned with the input and has exactly factor times as many samples.
+// factor: The upsampling factor: 2, 4 or 8.
+// Returns a new AudioBuffer at SampleRate*factor or an error if the buffer is empty or
+// the factor is unsupported.
+func (b *AudioBuffer) Upsample(factor int) (*AudioBuffer, error) {
+	switch factor {
+	case 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("%w: upsampling factor %d (must be 2, 4 or 8)", ErrInvalidArgument, factor)
+	}
+	if len(b.Data) == 0 || len(b.Data[0]) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+
+	// Blackman-windowed sinc; the gain of factor makes up for the inserted zeros
+	numTaps := upsampleTapsPerFactor*factor + 1
+	delay := numTaps / 2
+	kernel := make([]float64, numTaps)
+	for n := range kernel {
+		x := float64(n-delay) / float64(factor)
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(n)/float64(numTaps-1)) + 0.08*math.Cos(4*math.Pi*float64(n)/float64(numTaps-1))
+		kernel[n] = sinc * w
+	}
+
+	data := make([][]float32, len(b.Data))
+	for ch, samples := range b.Data {
+		stuffed := make([]float32, len(samples)*factor)
+		for i, s := range samples {
+			stuffed[i*factor] = s
+		}
+		filtered := fftConvolve(stuffed, kernel)
+		data[ch] = make([]float32, len(stuffed))
+		for i := range data[ch] {
+			data[ch][i] = float32(filtered[i+delay])
+		}
+	}
+
+	return &AudioBuffer{
+		Data:       data,
+		SampleRate: b.SampleRate * float64(factor),
+		Markers:    append([]Marker(nil), b.Markers...),
+	}, nil
+}
+
+// Envelope generates a linear ADSR envelope at the buffer's sample rate, for shaping
+// test signals by multiplying it into Data. The level rises from 0 to 1 over attackMs,
+// falls to sustainLevel over decayMs, holds, and falls to 0 over the last releaseMs of
+// the envelope, reaching 0 on the final sample. If the envelope is too short for every
+// stage, release starts early from whatever level has been reached.
+// numSamples: The length of the envelope in samples.
+// Returns nil if numSamples or the buffer's sample rate is not positive.
+func (b *AudioBuffer) Envelope(attackMs, decayMs, sustainLevel, releaseMs float64, numSamples int) []float32 {
+	if numSamples <= 0 || b.SampleRate <= 0 {
+		return nil
+	}
+	toSamples := func(ms float64) float64 {
+		return math.Max(0, ms) * b.SampleRate / 1000
+	}
+	attack, decay, release := toSamples(attackMs), toSamples(decayMs), toSamples(releaseMs)
+
+	// Level of the attack/decay/sustain stages at sample i
+	held := func(i float64) float64 {
+		switch {
+		case i < attack:
+			return i / attack
+		case i < attack+decay:
+			return 1 - (1-sustainLevel)*(i-attack)/decay
+		default:
+			return sustainLevel
+		}
+	}
+
+	env := make([]float32, numSamples)
+	releaseStart := math.Max(0, float64(numSamples)-release)
+	releaseLevel := held(releaseStart)
+	for i := range env {
+		x := float64(i)
+		if x < releaseStart {
+			env[i] = float32(held(x))
+		} else {
+			env[i] = float32(releaseLevel * (1 - (x-releaseStart+1)/(float64(numSamples)-releaseStart)))
+		}
+	}
+	return env
+}
+
+// InvertPhase flips the polarity of every channel in place.
+func (b *AudioBuffer) InvertPhase() {
+	for ch := range b.Data {
+		b.InvertPhaseChannel(ch)
+	}
+}
+
+// InvertPhaseChannel flips the polarity of channel ch in place.
+// Returns an error if the channel index is out of range.
+func (b *AudioBuffer) InvertPhaseChannel(ch int) error {
+	if ch < 0 || ch >= len(b.Data) {
+		return fmt.Errorf("%w: channel %d (0-%d)", ErrOutOfRange, ch, len(b.Data)-1)
+	}
+	for i := range b.Data[ch] {
+		b.Data[ch][i] = -b.Data[ch][i]
+	}
+	return nil
+}
+
+// SampleAt returns sample sampleIndex of channel ch.
+// Returns an error wrapping ErrOutOfRange if either index is out of range.
+func (b *AudioBuffer) SampleAt(ch, sampleIndex int) (float32, error) {
+	if err := b.checkSampleIndex(ch, sampleIndex); err != nil {
+		return 0, err
+	}
+	return b.Data[ch][sampleIndex], nil
+}
+
+// SetSampleAt sets sample sampleIndex of channel ch to value.
+// Returns an error wrapping ErrOutOfRange if either index is out of range.
+func (b *AudioBuffer) SetSampleAt(ch, sampleIndex int, value float32) error {
+	if err := b.checkSampleIndex(ch, sampleIndex); err != nil {
+		return err
+	}
+	b.Data[ch][sampleIndex] = value
+	return nil
+}
+
+// InterpolatedSampleAt returns the value of channel ch at a fractional sample position,
+// using 4-point cubic Hermite (Catmull-Rom) interpolation between the neighbouring
+// samples. Integer positions return the sample itself; at the ends of the buffer the
+// first and last samples are repeated to fill the missing neighbours.
+// position: From 0 to the index of the last sample.
+// Returns an error wrapping ErrOutOfRange if ch or position is out of range.
+func (b *AudioBuffer) InterpolatedSampleAt(ch int, position float64) (float32, error) {
+	if ch < 0 || ch >= len(b.Data) {
+		return 0, fmt.Errorf("%w: channel %d (0-%d)", ErrOutOfRange, ch, len(b.Data)-1)
+	}
+	samples := b.Data[ch]
+	last := len(samples) - 1
+	if !(position >= 0 && position <= float64(last)) {
+		return 0, fmt.Errorf("%w: position %v (0-%d)", ErrOutOfRange, position, last)
+	}
+
+	i := int(position)
+	t := position - float64(i)
+	at := func(j int) float64 { return float64(samples[max(0, min(j, last))]) }
+	y0, y1, y2, y3 := at(i-1), at(i), at(i+1), at(i+2)
+
+	c1 := 0.5 * (y2 - y0)
+	c2 := y0 - 2.5*y1 + 2*y2 - 0.5*y3
+	c3 := 0.5*(y3-y0) + 1.5*(y1-y2)
+	return float32(((c3*t+c2)*t+c1)*t + y1), nil
+}
+
+// checkSampleIndex verifies that channel ch and sample sampleIndex exist.
+func (b *AudioBuffer) checkSampleIndex(ch, sampleIndex int) error {
+	if ch < 0 || ch >= len(b.Data) {
+		return fmt.Errorf("%w: channel %d (0-%d)", ErrOutOfRange, ch, len(b.Data)-1)
+	}
+	if sampleIndex < 0 || sampleIndex >= len(b.Data[ch]) {
+		return fmt.Errorf("%w: sample index %d (0-%d)", ErrOutOfRange, sampleIndex, len(b.Data[ch])-1)
+	}
+	return nil
+}
+
+// ContainsNaNOrInf reports whether any sample in any channel is NaN or infinite.
+func (b *AudioBuffer) ContainsNaNOrInf() bool {
+	for _, channel := range b.Data {
+		for _, s := range channel {
+			if v := float64(s); math.IsNaN(v) || math.IsInf(v, 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsFinite reports whether every sample is a finite number.
+func (b *AudioBuffer) IsFinite() bool {
+	return !b.ContainsNaNOrInf()
+}
+
+// ReplaceNaN replaces every NaN sample with replacement, e.g. to recover from a
+// plugin that produced invalid output.
+// Returns the number of samples replaced.
+func (b *AudioBuffer) ReplaceNaN(replacement float32) int {
+	return b.replaceWhere(replacement, math.IsNaN)
+}
+
+// ReplaceInf replaces every positive or negative infinite sample with replacement.
+// Returns the number of samples replaced.
+func (b *AudioBuffer) ReplaceInf(replacement float32) int {
+	return b.replaceWhere(replacement, func(v float64) bool { return math.IsInf(v, 0) })
+}
+
+// replaceWhere sets every sample matching match to replacement and returns the count.
+func (b *AudioBuffer) replaceWhere(replacement float32, match func(float64) bool) int {
+	count := 0
+	for _, channel := range b.Data {
+		for i, s := range channel {
+			if match(float64(s)) {
+				channel[i] = replacement
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// AddDCOffset adds offset to every sample in every channel, e.g. to build test
+// signals for DC filtering.
+func (b *AudioBuffer) AddDCOffset(offset float32) {
+	for ch := range b.Data {
+		b.ChannelDCOffset(ch, offset)
+	}
+}
+
+// ChannelDCOffset adds offset to every sample of channel ch.
+// Returns an error if the channel index is out of range.
+func (b *AudioBuffer) ChannelDCOffset(ch int, offset float32) error {
+	if ch < 0 || ch >= len(b.Data) {
+		return fmt.Errorf("%w: channel %d (0-%d)", ErrOutOfRange, ch, len(b.Data)-1)
+	}
+	for i := range b.Data[ch] {
+		b.Data[ch][i] += offset
+	}
+	return nil
+}
+
+// RemoveDCOffset subtracts each channel's mean from its samples, so every channel
+// averages to zero. Unlike the DCFilter processor it works on the whole buffer at once
+// and leaves low frequencies untouched.
+func (b *AudioBuffer) RemoveDCOffset() {
+	for _, channel := range b.Data {
+		if len(channel) == 0 {
+			continue
+		}
+		var sum float64
+		for _, s := range channel {
+			sum += float64(s)
+		}
+		mean := float32(sum / float64(len(channel)))
+		for i := range channel {
+			channel[i] -= mean
+		}
+	}
+}
+
+// WaveformPeak summarises one display column of a waveform.
+type WaveformPeak struct {
+	Min, Max, RMS float32
+}
+
+// Thumbnail returns the data needed to draw a waveform of one channel: the channel is
+// split into width equal windows and the minimum, maximum and RMS of each are computed.
+// When there are fewer samples than columns, columns repeat the nearest sample.
+// width: The number of display columns.
+// channel: The channel to summarise.
+// Returns ErrOutOfRange if channel is invalid or width is not positive.
+func (b *AudioBuffer) Thumbnail(width int, channel int) ([]WaveformPeak, error) {
+	if channel < 0 || channel >= len(b.Data) {
+		return nil, fmt.Errorf("%w: channel %d (0-%d)", ErrOutOfRange, channel, len(b.Data)-1)
+	}
+	if width <= 0 {
+		return nil, fmt.Errorf("%w: width %d", ErrOutOfRange, width)
+	}
+
+	samples := b.Data[channel]
+	n := len(samples)
+	peaks := make([]WaveformPeak, width)
+	if n == 0 {
+		return peaks, nil
+	}
+	for col := range peaks {
+		start := col * n / width
+		end := (col + 1) * n / width
+		if end <= start {
+			end = start + 1
+		}
+		lo, hi := samples[start], samples[start]
+		var sum float64
+		for _, s := range samples[start:end] {
+			lo = min(lo, s)
+			hi = max(hi, s)
+			sum += float64(s) * float64(s)
+		}
+		peaks[col] = WaveformPeak{Min: lo, Max: hi, RMS: float32(math.Sqrt(sum / float64(end-start)))}
+	}
+	return peaks, nil
+}
@@ -0,0 +1,702 @@
+package pedalboard
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCrossChannelGain(t *testing.T) {
+	buffer := &AudioBuffer{
+		Data: [][]float32{
+			{1.0, 0.5, -0.5},
+			{0.25, 0.25, 0.25},
+		},
+		SampleRate: 44100.0,
+	}
+
+	if err := buffer.CrossChannelGain(0, 1, -1.0); err != nil {
+		t.Fatalf("CrossChannelGain failed: %v", err)
+	}
+
+	expected := []float32{-0.75, -0.25, 0.75}
+	for i, sample := range buffer.Data[1] {
+		if sample != expected[i] {
+			t.Errorf("Sample %d: expected %f, got %f", i, expected[i], sample)
+		}
+	}
+
+	// Source channel must be untouched
+	if buffer.Data[0][0] != 1.0 {
+		t.Errorf("Source channel was modified: %f", buffer.Data[0][0])
+	}
+
+	if err := buffer.CrossChannelGain(2, 0, 1.0); err == nil {
+		t.Error("Expected error for out-of-range source channel")
+	}
+	if err := buffer.CrossChannelGain(0, -1, 1.0); err == nil {
+		t.Error("Expected error for out-of-range destination channel")
+	}
+}
+
+func TestInt16RoundTrip(t *testing.T) {
+	const sampleRate = 44100.0
+	const numSamples = 4410
+	amplitude := float32(math.Pow(10, -6.0/20.0)) // -6 dBFS
+
+	original := &AudioBuffer{
+		Data:       [][]float32{make([]float32, numSamples)},
+		SampleRate: sampleRate,
+	}
+	for i := range original.Data[0] {
+		original.Data[0][i] = amplitude * float32(math.Sin(2*math.Pi*1000*float64(i)/sampleRate))
+	}
+
+	const lsb = 1.0 / 32767.0
+	for _, dither := range []bool{false, true} {
+		pcm, err := original.ToInt16(dither)
+		if err != nil {
+			t.Fatalf("ToInt16 failed: %v", err)
+		}
+
+		restored := FromInt16(pcm, sampleRate)
+		if restored.SampleRate != sampleRate {
+			t.Errorf("Expected sample rate %f, got %f", sampleRate, restored.SampleRate)
+		}
+
+		// Without dither rounding is within half an LSB; TPDF dither adds up to one more LSB.
+		limit := 0.5 * lsb
+		if dither {
+			limit = 1.5 * lsb
+		}
+		for i := range original.Data[0] {
+			diff := math.Abs(float64(original.Data[0][i] - restored.Data[0][i]))
+			if diff > limit+1e-7 {
+				t.Errorf("dither=%v sample %d: error %g exceeds %g", dither, i, diff, limit)
+				break
+			}
+		}
+	}
+}
+
+func TestToInt16Clamp(t *testing.T) {
+	buffer := &AudioBuffer{Data: [][]float32{{2.0, -2.0, 0.0}}, SampleRate: 44100.0}
+	pcm, err := buffer.ToInt16(false)
+	if err != nil {
+		t.Fatalf("ToInt16 failed: %v", err)
+	}
+	if pcm[0][0] != math.MaxInt16 || pcm[0][1] != math.MinInt16 || pcm[0][2] != 0 {
+		t.Errorf("Unexpected clamped values: %v", pcm[0])
+	}
+
+	empty := &AudioBuffer{}
+	if _, err := empty.ToInt16(false); err == nil {
+		t.Error("Expected error for empty buffer")
+	}
+}
+
+func TestAutochannel(t *testing.T) {
+	mono := []float32{0.1, -0.2, 0.3, -0.4}
+	dup := &AudioBuffer{
+		Data:       [][]float32{mono, append([]float32(nil), mono...)},
+		SampleRate: 44100.0,
+		Markers:    []Marker{{Name: "start"}},
+	}
+	result, err := dup.Autochannel()
+	if err != nil {
+		t.Fatalf("Autochannel failed: %v", err)
+	}
+	if len(result.Data) != 1 {
+		t.Fatalf("Expected 1 channel, got %d", len(result.Data))
+	}
+	for i := range mono {
+		if result.Data[0][i] != mono[i] {
+			t.Errorf("Sample %d: expected %f, got %f", i, mono[i], result.Data[0][i])
+		}
+	}
+	result.Markers[0].Name = "renamed"
+	if dup.Markers[0].Name != "start" {
+		t.Error("Expected Autochannel to copy the markers")
+	}
+
+	stereo := &AudioBuffer{
+		Data:       [][]float32{mono, {0.4, 0.3, -0.2, 0.1}},
+		SampleRate: 44100.0,
+	}
+	result, err = stereo.Autochannel()
+	if err != nil {
+		t.Fatalf("Autochannel failed: %v", err)
+	}
+	if result != stereo {
+		t.Error("Expected original buffer to be returned for distinct channels")
+	}
+
+	if _, err := (&AudioBuffer{}).Autochannel(); err == nil {
+		t.Error("Expected error for empty buffer")
+	}
+}
+
+func TestMixDown(t *testing.T) {
+	buffer := &AudioBuffer{
+		Data: [][]float32{
+			{1, 0, 0.5},
+			{0, 1, 0.5},
+			{1, 1, 1},
+		},
+		SampleRate: 48000.0,
+		Markers:    []Marker{{Name: "start"}},
+	}
+
+	mono, err := buffer.MixDown([]float32{0.5, 0.25, -1})
+	if err != nil {
+		t.Fatalf("MixDown failed: %v", err)
+	}
+	if len(mono.Data) != 1 {
+		t.Fatalf("Expected a mono buffer, got %d channels", len(mono.Data))
+	}
+	expected := []float32{-0.5, -0.75, -0.625}
+	for i, want := range expected {
+		if mono.Data[0][i] != want {
+			t.Errorf("Sample %d: expected %f, got %f", i, want, mono.Data[0][i])
+		}
+	}
+	if mono.SampleRate != 48000.0 {
+		t.Errorf("Expected sample rate 48000, got %f", mono.SampleRate)
+	}
+	mono.Markers[0].Name = "renamed"
+	if buffer.Markers[0].Name != "start" {
+		t.Error("Expected the markers to be copied")
+	}
+
+	if _, err := buffer.MixDown([]float32{1, 1}); err == nil {
+		t.Error("Expected error for mismatched gain count")
+	}
+}
+
+func TestSplitAt(t *testing.T) {
+	buffer := &AudioBuffer{
+		Data:       [][]float32{{1, 2, 3, 4}, {5, 6, 7, 8}},
+		SampleRate: 4.0,
+		Markers: []Marker{
+			{Name: "early", Position: 250 * time.Millisecond},
+			{Name: "late", Position: 750 * time.Millisecond},
+		},
+	}
+
+	first, second, err := buffer.SplitAt(1)
+	if err != nil {
+		t.Fatalf("SplitAt failed: %v", err)
+	}
+	if len(first.Data[0]) != 1 || first.Data[1][0] != 5 {
+		t.Errorf("Unexpected first half: %v", first.Data)
+	}
+	if len(second.Data[0]) != 3 || second.Data[0][0] != 2 || second.Data[1][2] != 8 {
+		t.Errorf("Unexpected second half: %v", second.Data)
+	}
+	if first.SampleRate != 4.0 || second.SampleRate != 4.0 {
+		t.Error("Expected both halves to keep the sample rate")
+	}
+	if len(first.Markers) != 0 || len(second.Markers) != 2 || second.Markers[1].Position != 500*time.Millisecond {
+		t.Errorf("Unexpected markers: %v / %v", first.Markers, second.Markers)
+	}
+
+	// The halves are copies
+	first.Data[0][0] = 100
+	if buffer.Data[0][0] != 1 {
+		t.Error("Original buffer was modified")
+	}
+
+	if _, second, err := buffer.SplitAt(4); err != nil || len(second.Data[0]) != 0 {
+		t.Errorf("Expected an empty second half when splitting at the end: %v", err)
+	}
+	if _, _, err := buffer.SplitAt(5); err == nil {
+		t.Error("Expected error for out-of-range index")
+	}
+	if _, _, err := buffer.SplitAt(-1); err == nil {
+		t.Error("Expected error for negative index")
+	}
+}
+
+func TestWrapAround(t *testing.T) {
+	buffer := &AudioBuffer{
+		Data:       [][]float32{{1, 2, 3, 4}, {5, 6, 7, 8}},
+		SampleRate: 4.0,
+		Markers:    []Marker{{Name: "last", Position: 750 * time.Millisecond}},
+	}
+
+	for _, c := range []struct {
+		offset int
+		want   []float32
+	}{
+		{1, []float32{4, 1, 2, 3}},
+		{-1, []float32{2, 3, 4, 1}},
+		{0, []float32{1, 2, 3, 4}},
+		{6, []float32{3, 4, 1, 2}},
+		{-9, []float32{2, 3, 4, 1}},
+	} {
+		out := buffer.WrapAround(c.offset)
+		for i, v := range c.want {
+			if out.Data[0][i] != v {
+				t.Errorf("offset %d: expected %v, got %v", c.offset, c.want, out.Data[0])
+				break
+			}
+		}
+	}
+
+	out := buffer.WrapAround(1)
+	if out.Data[1][0] != 8 || out.SampleRate != 4.0 {
+		t.Errorf("Unexpected second channel or sample rate: %v, %f", out.Data[1], out.SampleRate)
+	}
+	if len(out.Markers) != 1 || out.Markers[0].Position != 0 {
+		t.Errorf("Expected the marker to wrap to the start, got %v", out.Markers)
+	}
+	out.Data[0][0] = 100
+	if buffer.Data[0][3] != 4 {
+		t.Error("Original buffer was modified")
+	}
+}
+
+func TestTrimSilence(t *testing.T) {
+	// 1 kHz, so 1 ms is one sample
+	buffer := &AudioBuffer{
+		Data:       [][]float32{{0, 0, 0.01, 0, 0.5, 0.2, 0, 0, 0}, {0, 0, 0, -0.6, 0, 0, 0.02, 0, 0}},
+		SampleRate: 1000,
+		Markers:    []Marker{{Name: "early", Position: time.Millisecond}, {Name: "hit", Position: 4 * time.Millisecond}},
+	}
+
+	leading, err := buffer.TrimLeadingSilence(0.1, 0)
+	if err != nil {
+		t.Fatalf("TrimLeadingSilence failed: %v", err)
+	}
+	if len(leading.Data[0]) != 6 || leading.Data[1][0] != -0.6 {
+		t.Errorf("Expected audio to start at the first loud sample on any channel, got %v", leading.Data)
+	}
+	if len(leading.Markers) != 1 || leading.Markers[0].Position != time.Millisecond {
+		t.Errorf("Expected only the hit marker, moved to 1ms, got %v", leading.Markers)
+	}
+	if padded, _ := buffer.TrimLeadingSilence(0.1, 2); len(padded.Data[0]) != 8 {
+		t.Errorf("Expected 2ms of padding to keep 2 more samples, got %d", len(padded.Data[0]))
+	}
+	if padded, _ := buffer.TrimLeadingSilence(0.1, 50); len(padded.Data[0]) != 9 {
+		t.Errorf("Expected padding to stop at the start, got %d samples", len(padded.Data[0]))
+	}
+
+	trailing, err := buffer.TrimTrailingSilence(0.1, 0)
+	if err != nil {
+		t.Fatalf("TrimTrailingSilence failed: %v", err)
+	}
+	if len(trailing.Data[0]) != 6 || trailing.Data[0][5] != 0.2 {
+		t.Errorf("Expected audio to end at the last loud sample, got %v", trailing.Data)
+	}
+	if len(trailing.Markers) != 2 {
+		t.Errorf("Expected both markers to be kept, got %v", trailing.Markers)
+	}
+	if padded, _ := buffer.TrimTrailingSilence(0.01, 1); len(padded.Data[0]) != 8 {
+		t.Errorf("Expected a lower threshold and 1ms of padding to keep 8 samples, got %d", len(padded.Data[0]))
+	}
+	if buffer.Data[0][0] != 0 || len(buffer.Data[0]) != 9 {
+		t.Error("Original buffer was modified")
+	}
+
+	if _, err := buffer.TrimLeadingSilence(1, 0); !errors.Is(err, ErrSilentBuffer) {
+		t.Errorf("Expected ErrSilentBuffer, got %v", err)
+	}
+	if _, err := buffer.TrimTrailingSilence(-0.1, 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for a negative threshold, got %v", err)
+	}
+	if _, err := (&AudioBuffer{}).TrimTrailingSilence(0.1, 0); !errors.Is(err, ErrEmptyBuffer) {
+		t.Errorf("Expected ErrEmptyBuffer, got %v", err)
+	}
+}
+
+func TestBreakIntoFrames(t *testing.T) {
+	buffer := &AudioBuffer{
+		Data: [][]float32{
+			{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+			{0, -1, -2, -3, -4, -5, -6, -7, -8, -9},
+		},
+		SampleRate: 44100.0,
+	}
+
+	frames, err := buffer.BreakIntoFrames(4, 2)
+	if err != nil {
+		t.Fatalf("BreakIntoFrames failed: %v", err)
+	}
+	if len(frames) != 4 {
+		t.Fatalf("Expected 4 frames, got %d", len(frames))
+	}
+	for f, frame := range frames {
+		if len(frame.Data) != 2 || len(frame.Data[0]) != 4 {
+			t.Fatalf("Frame %d has wrong shape", f)
+		}
+		if frame.Data[0][0] != float32(f*2) || frame.Data[1][3] != -float32(f*2+3) {
+			t.Errorf("Frame %d has wrong content: %v", f, frame.Data)
+		}
+	}
+
+	// Frames must be copies
+	frames[0].Data[0][0] = 100
+	if buffer.Data[0][0] != 0 {
+		t.Error("Modifying a frame changed the original buffer")
+	}
+
+	if _, err := buffer.BreakIntoFrames(11, 2); err == nil {
+		t.Error("Expected error for frame size larger than buffer")
+	}
+	if _, err := buffer.BreakIntoFrames(4, 0); err == nil {
+		t.Error("Expected error for zero hop size")
+	}
+}
+
+func TestFromFrames(t *testing.T) {
+	buffer := &AudioBuffer{
+		Data:       [][]float32{{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		SampleRate: 44100.0,
+	}
+	frames, err := buffer.BreakIntoFrames(4, 2)
+	if err != nil {
+		t.Fatalf("BreakIntoFrames failed: %v", err)
+	}
+
+	joined, err := FromFrames(frames, 2)
+	if err != nil {
+		t.Fatalf("FromFrames failed: %v", err)
+	}
+	if len(joined.Data[0]) != (len(frames)-1)*2+4 {
+		t.Fatalf("Expected %d samples, got %d", (len(frames)-1)*2+4, len(joined.Data[0]))
+	}
+
+	// Interior samples are covered by two frames, the edges by one
+	expected := []float32{1, 1, 2, 2, 2, 2, 2, 2, 1, 1}
+	for i, s := range joined.Data[0] {
+		if s != expected[i] {
+			t.Errorf("Sample %d: expected %f, got %f", i, expected[i], s)
+		}
+	}
+
+	stereo := &AudioBuffer{Data: [][]float32{{1, 1, 1, 1}, {1, 1, 1, 1}}}
+	if _, err := FromFrames([]*AudioBuffer{frames[0], stereo}, 2); err == nil {
+		t.Error("Expected error for mismatched channel counts")
+	}
+	if _, err := FromFrames(nil, 2); err == nil {
+		t.Error("Expected error for no frames")
+	}
+}
+
+func TestAverageBuffers(t *testing.T) {
+	a := &AudioBuffer{Data: [][]float32{{1, 2, 3}, {0, 0, 1}}, SampleRate: 44100.0}
+	b := &AudioBuffer{Data: [][]float32{{3, 2, 1}, {1, 0, -1}}, SampleRate: 44100.0}
+
+	avg, err := AverageBuffers([]*AudioBuffer{a, b})
+	if err != nil {
+		t.Fatalf("AverageBuffers failed: %v", err)
+	}
+	expected := [][]float32{{2, 2, 2}, {0.5, 0, 0}}
+	for ch := range expected {
+		for i, want := range expected[ch] {
+			if avg.Data[ch][i] != want {
+				t.Errorf("Channel %d sample %d: expected %f, got %f", ch, i, want, avg.Data[ch][i])
+			}
+		}
+	}
+	if avg.SampleRate != 44100.0 {
+		t.Errorf("Expected sample rate 44100, got %f", avg.SampleRate)
+	}
+
+	if _, err := AverageBuffers(nil); err == nil {
+		t.Error("Expected error for no buffers")
+	}
+	short := &AudioBuffer{Data: [][]float32{{1, 2}, {0, 0}}, SampleRate: 44100.0}
+	if _, err := AverageBuffers([]*AudioBuffer{a, short}); err == nil {
+		t.Error("Expected error for mismatched lengths")
+	}
+	mono := &AudioBuffer{Data: [][]float32{{1, 2, 3}}, SampleRate: 44100.0}
+	if _, err := AverageBuffers([]*AudioBuffer{a, mono}); err == nil {
+		t.Error("Expected error for mismatched channel counts")
+	}
+}
+
+func TestResample(t *testing.T) {
+	buffer := sineBuffer(1000, 0.5, 44100, 44100)
+	resampled, err := buffer.Resample(48000)
+	if err != nil {
+		t.Fatalf("Resample failed: %v", err)
+	}
+	if resampled.SampleRate != 48000 {
+		t.Errorf("Expected sample rate 48000, got %v", resampled.SampleRate)
+	}
+	if len(resampled.Data[0]) != 48000 {
+		t.Fatalf("Expected 48000 samples, got %d", len(resampled.Data[0]))
+	}
+
+	// Away from the edges the output should match a sine generated at the new rate
+	expected := sineBuffer(1000, 0.5, 48000, 48000)
+	for i := 1000; i < 47000; i++ {
+		if diff := math.Abs(float64(resampled.Data[0][i] - expected.Data[0][i])); diff > 1e-3 {
+			t.Fatalf("Sample %d: expected %f, got %f", i, expected.Data[0][i], resampled.Data[0][i])
+		}
+	}
+
+	// Downsampling removes content above the new Nyquist frequency
+	high := sineBuffer(15000, 0.5, 44100, 44100)
+	down, err := high.Resample(22050)
+	if err != nil {
+		t.Fatalf("Resample failed: %v", err)
+	}
+	if level := rms(down.Data[0][1000 : len(down.Data[0])-1000]); level > 0.01 {
+		t.Errorf("Expected 15 kHz to be filtered when downsampling to 22.05 kHz, RMS %f", level)
+	}
+
+	if _, err := buffer.Resample(0); err == nil {
+		t.Error("Expected error for zero sample rate")
+	}
+}
+
+func TestUpsample(t *testing.T) {
+	buffer := sineBuffer(1000, 0.5, 44100, 8192)
+	for _, factor := range []int{2, 4, 8} {
+		up, err := buffer.Upsample(factor)
+		if err != nil {
+			t.Fatalf("Upsample(%d) failed: %v", factor, err)
+		}
+		rate := 44100.0 * float64(factor)
+		if up.SampleRate != rate {
+			t.Errorf("Factor %d: expected sample rate %v, got %v", factor, rate, up.SampleRate)
+		}
+		if len(up.Data[0]) != 8192*factor {
+			t.Fatalf("Factor %d: expected %d samples, got %d", factor, 8192*factor, len(up.Data[0]))
+		}
+
+		// Images would show up as a deviation from the ideal sine at the new rate
+		expected := sineBuffer(1000, 0.5, rate, len(up.Data[0]))
+		for i := 100 * factor; i < len(up.Data[0])-100*factor; i++ {
+			if diff := math.Abs(float64(up.Data[0][i] - expected.Data[0][i])); diff > 1e-3 {
+				t.Fatalf("Factor %d sample %d: expected %f, got %f", factor, i, expected.Data[0][i], up.Data[0][i])
+			}
+		}
+	}
+
+	if _, err := buffer.Upsample(3); err == nil {
+		t.Error("Expected error for factor 3")
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	buffer := &AudioBuffer{SampleRate: 1000} // 1 sample per ms
+	env := buffer.Envelope(10, 10, 0.5, 20, 100)
+	if len(env) != 100 {
+		t.Fatalf("Expected 100 samples, got %d", len(env))
+	}
+
+	checks := map[int]float32{
+		0:  0,    // start of attack
+		5:  0.5,  // halfway up
+		10: 1,    // peak
+		15: 0.75, // halfway through decay
+		20: 0.5,  // sustain
+		79: 0.5,  // last sustain sample
+		89: 0.25, // halfway through release
+		99: 0,    // end
+	}
+	for i, expected := range checks {
+		if math.Abs(float64(env[i]-expected)) > 1e-6 {
+			t.Errorf("Sample %d: expected %f, got %f", i, expected, env[i])
+		}
+	}
+
+	// Release starts early, from the attack level, when the envelope is short
+	short := buffer.Envelope(100, 0, 1, 10, 20)
+	if math.Abs(float64(short[9]-0.09)) > 1e-6 || short[19] != 0 {
+		t.Errorf("Unexpected short envelope: %v", short)
+	}
+
+	if buffer.Envelope(10, 10, 0.5, 10, 0) != nil {
+		t.Error("Expected nil for zero length")
+	}
+}
+
+func TestInvertPhase(t *testing.T) {
+	buffer := sineBuffer(440, 0.5, 44100.0, 1000)
+	buffer.Data = append(buffer.Data, sineBuffer(1000, 0.25, 44100.0, 1000).Data[0])
+	inverted := &AudioBuffer{Data: [][]float32{
+		append([]float32(nil), buffer.Data[0]...),
+		append([]float32(nil), buffer.Data[1]...),
+	}, SampleRate: buffer.SampleRate}
+
+	inverted.InvertPhase()
+	for ch := range buffer.Data {
+		for i := range buffer.Data[ch] {
+			if sum := buffer.Data[ch][i] + inverted.Data[ch][i]; sum != 0 {
+				t.Fatalf("Channel %d sample %d: expected silence, got %f", ch, i, sum)
+			}
+		}
+	}
+
+	if err := inverted.InvertPhaseChannel(1); err != nil {
+		t.Fatalf("InvertPhaseChannel failed: %v", err)
+	}
+	if inverted.Data[1][10] != buffer.Data[1][10] || inverted.Data[0][10] != -buffer.Data[0][10] {
+		t.Error("Expected only channel 1 to flip back")
+	}
+	if err := inverted.InvertPhaseChannel(2); err == nil {
+		t.Error("Expected error for out-of-range channel")
+	}
+}
+
+func TestSampleAt(t *testing.T) {
+	buffer := &AudioBuffer{Data: [][]float32{{1, 2, 3}, {4, 5, 6}}}
+
+	if v, err := buffer.SampleAt(1, 2); err != nil || v != 6 {
+		t.Errorf("Expected 6, got %f (%v)", v, err)
+	}
+	if err := buffer.SetSampleAt(0, 1, 0.5); err != nil {
+		t.Fatalf("SetSampleAt failed: %v", err)
+	}
+	if buffer.Data[0][1] != 0.5 {
+		t.Errorf("Expected 0.5, got %f", buffer.Data[0][1])
+	}
+
+	for _, c := range [][2]int{{2, 0}, {-1, 0}, {0, 3}, {0, -1}} {
+		if _, err := buffer.SampleAt(c[0], c[1]); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("SampleAt(%d, %d): expected ErrOutOfRange, got %v", c[0], c[1], err)
+		}
+		if err := buffer.SetSampleAt(c[0], c[1], 1); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("SetSampleAt(%d, %d): expected ErrOutOfRange, got %v", c[0], c[1], err)
+		}
+	}
+}
+
+func TestInterpolatedSampleAt(t *testing.T) {
+	// Cubic interpolation reproduces a quadratic exactly away from the edges
+	ramp := make([]float32, 8)
+	for i := range ramp {
+		ramp[i] = float32(i * i)
+	}
+	buffer := &AudioBuffer{Data: [][]float32{ramp}}
+
+	for _, c := range []struct{ position, want float64 }{{3, 9}, {3.5, 12.25}, {2.25, 5.0625}, {0, 0}, {7, 49}} {
+		v, err := buffer.InterpolatedSampleAt(0, c.position)
+		if err != nil {
+			t.Fatalf("InterpolatedSampleAt(%v) failed: %v", c.position, err)
+		}
+		if math.Abs(float64(v)-c.want) > 1e-4 {
+			t.Errorf("InterpolatedSampleAt(%v): expected %v, got %v", c.position, c.want, v)
+		}
+	}
+
+	for _, position := range []float64{-0.1, 7.01, math.NaN()} {
+		if _, err := buffer.InterpolatedSampleAt(0, position); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("position %v: expected ErrOutOfRange, got %v", position, err)
+		}
+	}
+	if _, err := buffer.InterpolatedSampleAt(1, 0); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange for a missing channel, got %v", err)
+	}
+}
+
+func TestReplaceNaNAndInf(t *testing.T) {
+	nan := float32(math.NaN())
+	inf := float32(math.Inf(1))
+	buffer := &AudioBuffer{
+		Data: [][]float32{
+			{0.5, nan, inf, -inf},
+			{nan, 0.25, 0, nan},
+		},
+		SampleRate: 44100.0,
+	}
+	if buffer.IsFinite() || !buffer.ContainsNaNOrInf() {
+		t.Fatal("Expected buffer with NaN and Inf to be reported as non-finite")
+	}
+
+	if n := buffer.ReplaceNaN(0.1); n != 3 {
+		t.Errorf("Expected 3 NaN samples replaced, got %d", n)
+	}
+	if buffer.Data[0][1] != 0.1 || buffer.Data[1][0] != 0.1 || buffer.Data[1][3] != 0.1 {
+		t.Errorf("NaN samples not replaced: %v", buffer.Data)
+	}
+	if buffer.IsFinite() {
+		t.Error("Expected Inf samples to remain")
+	}
+
+	if n := buffer.ReplaceInf(-1); n != 2 {
+		t.Errorf("Expected 2 Inf samples replaced, got %d", n)
+	}
+	if buffer.Data[0][2] != -1 || buffer.Data[0][3] != -1 {
+		t.Errorf("Inf samples not replaced: %v", buffer.Data[0])
+	}
+	if buffer.Data[0][0] != 0.5 || buffer.Data[1][1] != 0.25 {
+		t.Errorf("Finite samples were modified: %v", buffer.Data)
+	}
+	if !buffer.IsFinite() {
+		t.Error("Expected buffer to be finite after replacement")
+	}
+	if n := buffer.ReplaceNaN(0); n != 0 {
+		t.Errorf("Expected no replacements on a finite buffer, got %d", n)
+	}
+}
+
+func TestDCOffset(t *testing.T) {
+	buffer := sineBuffer(1000, 0.5, 44100.0, 4410)
+	buffer.Data = append(buffer.Data, append([]float32(nil), buffer.Data[0]...))
+	original := [][]float32{append([]float32(nil), buffer.Data[0]...), append([]float32(nil), buffer.Data[1]...)}
+
+	buffer.AddDCOffset(0.25)
+	if buffer.Data[0][0] != original[0][0]+0.25 || buffer.Data[1][100] != original[1][100]+0.25 {
+		t.Errorf("AddDCOffset did not offset every channel")
+	}
+	if err := buffer.ChannelDCOffset(1, -0.5); err != nil {
+		t.Fatalf("ChannelDCOffset failed: %v", err)
+	}
+	if math.Abs(float64(buffer.Data[1][100]-(original[1][100]-0.25))) > 1e-6 || buffer.Data[0][100] != original[0][100]+0.25 {
+		t.Errorf("ChannelDCOffset should only change channel 1")
+	}
+	if err := buffer.ChannelDCOffset(2, 0.1); err == nil {
+		t.Error("Expected error for out-of-range channel")
+	}
+
+	// The sine spans whole cycles, so removing the mean restores it
+	buffer.RemoveDCOffset()
+	for ch := range original {
+		for i, want := range original[ch] {
+			if math.Abs(float64(buffer.Data[ch][i]-want)) > 1e-5 {
+				t.Fatalf("Channel %d sample %d: expected %f, got %f", ch, i, want, buffer.Data[ch][i])
+			}
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	buffer := &AudioBuffer{
+		Data:       [][]float32{{1, -1, 0.5, 0.5, 0, -0.25}},
+		SampleRate: 44100.0,
+	}
+
+	peaks, err := buffer.Thumbnail(3, 0)
+	if err != nil {
+		t.Fatalf("Thumbnail failed: %v", err)
+	}
+	expected := []WaveformPeak{
+		{Min: -1, Max: 1, RMS: 1},
+		{Min: 0.5, Max: 0.5, RMS: 0.5},
+		{Min: -0.25, Max: 0, RMS: float32(math.Sqrt(0.03125))},
+	}
+	for i, want := range expected {
+		got := peaks[i]
+		if got.Min != want.Min || got.Max != want.Max || math.Abs(float64(got.RMS-want.RMS)) > 1e-6 {
+			t.Errorf("Column %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// More columns than samples
+	if peaks, err := buffer.Thumbnail(12, 0); err != nil || len(peaks) != 12 || peaks[11].Max != -0.25 {
+		t.Errorf("Unexpected wide thumbnail: %v (%v)", peaks, err)
+	}
+
+	if _, err := buffer.Thumbnail(3, 1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange for invalid channel, got %v", err)
+	}
+	if _, err := buffer.Thumbnail(0, 0); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange for zero width, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"fmt"
+	"sync"
+)
+
+// ProcessorChain runs a series of processors one after another.
+// A chain can be processed offline with Process, or run live by passing
+// Processor() to NewAudioStream. Stages can be changed while a stream is running.
+type ProcessorChain struct {
+	proc     *Processor
+	stages   []*Processor // Keep references to prevent GC
+	disabled []bool       // Parallel to stages
+	frozen   []*Processor // Snapshots running in place of stages while frozen, else nil
+
+	restartLock sync.Mutex
+	restart     *autoRestart // nil unless EnableAutoRestart is active
+}
+
+// NewProcessorChain creates a chain running the given processors in order.
+// Returns the chain or an error if creation failed.
+func NewProcessorChain(processors ...*Processor) (*ProcessorChain, error) {
+	handle := C.pedalboard_create_chain()
+	if handle == nil {
+		return nil, fmt.Errorf("%w: failed to create processor chain", ErrOutOfMemory)
+	}
+	c := &ProcessorChain{proc: wrapProcessor(handle)}
+	for _, p := range processors {
+		if err := c.Add(p); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Processor returns the chain as a single Processor, e.g. for use with NewAudioStream.
+func (c *ProcessorChain) Processor() *Processor {
+	return c.proc
+}
+
+// GetStage returns the processor at index. It is the live stage, not a copy: changing
+// its parameters changes what the chain plays (once thawed, if the chain is frozen).
+// Returns an error if index is out of range.
+func (c *ProcessorChain) GetStage(index int) (*Processor, error) {
+	if index < 0 || index >= len(c.stages) {
+		return nil, fmt.Errorf("%w: stage index %d (0-%d)", ErrOutOfRange, index, len(c.stages)-1)
+	}
+	return c.stages[index], nil
+}
+
+// Len returns the number of stages in the chain, including disabled ones.
+func (c *ProcessorChain) Len() int {
+	return len(c.stages)
+}
+
+// Stages returns the processors of the chain in order, as for GetStage. The slice is a
+// copy; changing it does not change the chain.
+func (c *ProcessorChain) Stages() []*Processor {
+	return append([]*Processor(nil), c.stages...)
+}
+
+// Add appends a processor to the end of the chain.
+func (c *ProcessorChain) Add(p *Processor) error {
+	if p == nil {
+		return fmt.Errorf("%w: cannot add nil processor to chain", ErrInvalidArgument)
+	}
+	if c.frozen != nil {
+		return fmt.Errorf("%w: cannot add a stage", ErrChainFrozen)
+	}
+	if C.pedalboard_chain_insert(c.proc.handle, C.int(len(c.stages)), p.handle) == 0 {
+		return fmt.Errorf("%w: processor cannot be added to chain", ErrInvalidArgument)
+	}
+	c.stages = append(c.stages, p)
+	c.disabled = append(c.disabled, false)
+	return nil
+}
+
+// Remove removes the processor at index from the chain.
+// Returns an error if index is out of range.
+func (c *ProcessorChain) Remove(index int) error {
+	if index < 0 || index >= len(c.stages) {
+		return fmt.Errorf("%w: stage index %d (0-%d)", ErrOutOfRange, index, len(c.stages)-1)
+	}
+	if c.frozen != nil {
+		return fmt.Errorf("%w: cannot remove a stage", ErrChainFrozen)
+	}
+	if C.pedalboard_chain_remove(c.proc.handle, C.int(index)) == 0 {
+		return fmt.Errorf("%w: stage index %d", ErrOutOfRange, index)
+	}
+	c.stages = append(c.stages[:index], c.stages[index+1:]...)
+	c.disabled = append(c.disabled[:index], c.disabled[index+1:]...)
+	return nil
+}
+
+// DisableStage bypasses the processor at index without removing it, keeping its
+// position and settings. Replacing a disabled stage keeps it disabled.
+// Returns an error if index is out of range.
+func (c *ProcessorChain) DisableStage(index int) error {
+	return c.setStageEnabled(index, false)
+}
+
+// EnableStage re-enables a stage disabled with DisableStage.
+// Returns an error if index is out of range.
+func (c *ProcessorChain) EnableStage(index int) error {
+	return c.setStageEnabled(index, true)
+}
+
+// IsStageEnabled reports whether the stage at index is processed.
+// Returns false if index is out of range.
+func (c *ProcessorChain) IsStageEnabled(index int) bool {
+	return index >= 0 && index < len(c.stages) && !c.disabled[index]
+}
+
+func (c *ProcessorChain) setStageEnabled(index int, enabled bool) error {
+	if index < 0 || index >= len(c.stages) {
+		return fmt.Errorf("%w: stage index %d (0-%d)", ErrOutOfRange, index, len(c.stages)-1)
+	}
+	flag := 0
+	if enabled {
+		flag = 1
+	}
+	if C.pedalboard_chain_set_stage_enabled(c.proc.handle, C.int(index), C.int(flag)) == 0 {
+		return fmt.Errorf("%w: stage index %d", ErrOutOfRange, index)
+	}
+	c.disabled[index] = !enabled
+	return nil
+}
+
+// SetBypassed bypasses the whole chain or runs it again, for comparing the processed
+// signal with the dry one. A bypassed chain passes audio through unmodified, delayed by
+// the total latency of its enabled stages so the timing matches the processed signal.
+// Bypass applies to Process and to any stream running the chain; the bypass state of
+// each stage is kept.
+func (c *ProcessorChain) SetBypassed(bypassed bool) {
+	c.proc.SetBypassed(bypassed)
+}
+
+// Bypassed reports whether the whole chain is bypassed; see SetBypassed.
+func (c *ProcessorChain) Bypassed() bool {
+	return c.proc.Bypassed()
+}
+
+// ToggleBypassed switches the chain between bypassed and processing, and returns
+// whether it is now bypassed.
+func (c *ProcessorChain) ToggleBypassed() bool {
+	bypassed := !c.Bypassed()
+	c.SetBypassed(bypassed)
+	return bypassed
+}
+
+// Process processes a block of audio data through every stage of the chain in order.
+// buffer: The audio data to process (modified in-place).
+// sampleRate: The sample rate of the audio data.
+func (c *ProcessorChain) Process(buffer [][]float32, sampleRate float64) {
+	if r := c.activeRestart(); r != nil && !c.Bypassed() {
+		c.processWithRestart(r, buffer, sampleRate)
+		return
+	}
+	c.proc.Process(buffer, sampleRate)
+}
+
+// ProcessToNewBuffer processes a copy of in through the chain and returns the result.
+// The input buffer is left unmodified; markers are carried over to the result.
+// Returns an error if in is nil or empty.
+func (c *ProcessorChain) ProcessToNewBuffer(in *AudioBuffer) (*AudioBuffer, error) {
+	if in == nil || len(in.Data) == 0 || len(in.Data[0]) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+	out := &AudioBuffer{
+		Data:       make([][]float32, len(in.Data)),
+		SampleRate: in.SampleRate,
+		Markers:    append([]Marker(nil), in.Markers...),
+	}
+	for ch, samples := range in.Data {
+		out.Data[ch] = append([]float32(nil), samples...)
+	}
+	c.Process(out.Data, out.SampleRate)
+	return out, nil
+}
+
+// Reset clears the internal state of every stage; see Processor.Reset.
+// It is safe to call while a stream is running the chain.
+func (c *ProcessorChain) Reset() {
+	c.proc.Reset()
+}
+
+// ProcessBufferList processes each buffer through the chain, resetting the chain before
+// each one so every buffer starts from the same state and no tail carries over from
+// the previous one. The inputs are left unmodified.
+// Returns the processed copies in order, or an error if any buffer is nil or empty.
+func (c *ProcessorChain) ProcessBufferList(buffers []*AudioBuffer) ([]*AudioBuffer, error) {
+	results := make([]*AudioBuffer, len(buffers))
+	for i, buffer := range buffers {
+		if buffer == nil || len(buffer.Data) == 0 || len(buffer.Data[0]) == 0 {
+			return nil, fmt.Errorf("buffer %d: %w", i, ErrEmptyBuffer)
+		}
+	}
+	for i, buffer := range buffers {
+		c.Reset()
+		result, err := c.ProcessToNewBuffer(buffer)
+		if err != nil {
+			return nil, fmt.Errorf("buffer %d: %w", i, err)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// ReplaceProcessor atomically swaps the processor at index in the chain for replacement.
+// It is safe to call while a stream is running the chain: the replacement is prepared
+// first, and the audio thread only waits for the pointer swap.
+// Returns an error if index is out of range or replacement is nil.
+func ReplaceProcessor(chain *ProcessorChain, index int, replacement *Processor) error {
+	if replacement == nil {
+		return fmt.Errorf("%w: replacement processor is nil", ErrInvalidArgument)
+	}
+	if index < 0 || index >= len(chain.stages) {
+		return fmt.Errorf("%w: stage index %d (0-%d)", ErrOutOfRange, index, len(chain.stages)-1)
+	}
+	if chain.frozen != nil {
+		return fmt.Errorf("%w: cannot replace a stage", ErrChainFrozen)
+	}
+	if C.pedalboard_chain_replace(chain.proc.handle, C.int(index), replacement.handle) == 0 {
+		return fmt.Errorf("%w: processor cannot replace stage %d", ErrInvalidArgument, index)
+	}
+	chain.stages[index] = replacement
+	return nil
+}
+
+// SyncedChain is a ProcessorChain that is safe for concurrent use from Go.
+//
+// Locking model: Process takes a read lock, so any number of goroutines may call it at
+// once (the calls themselves are serialized inside the processor). SetParameter, Add
+// and Remove take the write lock and wait for running Process calls to finish, so a
+// change never lands in the middle of a block. While auto-restart is enabled (see
+// ProcessorChain.EnableAutoRestart, which must be called before wrapping the chain),
+// Process takes the write lock too, because restarting a stage replaces it.
+//
+// Process is the only method suitable for an audio or real-time goroutine: it blocks
+// only while a change is being applied, and changes are short. All methods acquire the
+// lock themselves, so callers must not hold any lock of their own that another method
+// call is waiting on, and must not call SyncedChain methods from inside a callback
+// running under Process.
+type SyncedChain struct {
+	mu    sync.RWMutex
+	chain *ProcessorChain
+}
+
+// NewSyncedChain wraps chain. The chain must not be used directly afterwards.
+func NewSyncedChain(chain *ProcessorChain) *SyncedChain {
+	return &SyncedChain{chain: chain}
+}
+
+// Process processes a block of audio data through the chain; see ProcessorChain.Process.
+// Safe to call from multiple goroutines, including an audio goroutine.
+func (s *SyncedChain) Process(buffer [][]float32, sampleRate float64) {
+	if s.chain.activeRestart() != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	} else {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+	s.chain.Process(buffer, sampleRate)
+}
+
+// SetParameter sets parameter index of the processor at stage.
+// Returns an error if stage is out of range.
+func (s *SyncedChain) SetParameter(stage, index int, value float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stage < 0 || stage >= len(s.chain.stages) {
+		return fmt.Errorf("%w: stage index %d (0-%d)", ErrOutOfRange, stage, len(s.chain.stages)-1)
+	}
+	s.chain.stages[stage].SetParameter(index, value)
+	return nil
+}
+
+// Add appends a processor to the end of the chain; see ProcessorChain.Add.
+func (s *SyncedChain) Add(p *Processor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chain.Add(p)
+}
+
+// Remove removes the processor at index from the chain; see ProcessorChain.Remove.
+func (s *SyncedChain) Remove(index int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chain.Remove(index)
+}
+
+// Len returns the number of stages in the chain.
+func (s *SyncedChain) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.chain.stages)
+}
@@ -0,0 +1,354 @@
+package pedalboard
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestProcessorChain(t *testing.T) {
+	gainA, _ := NewInternalProcessor("Gain")
+	gainA.SetParameter(0, 0.5)
+	gainB, _ := NewInternalProcessor("Gain")
+	gainB.SetParameter(0, 0.5)
+
+	chain, err := NewProcessorChain(gainA, gainB)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	buffer := [][]float32{{1, 1, 1, 1}, {1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	for c := range buffer {
+		for i, s := range buffer[c] {
+			if s != 0.25 {
+				t.Errorf("Channel %d sample %d: expected 0.25, got %f", c, i, s)
+			}
+		}
+	}
+
+	if err := chain.Remove(5); err == nil {
+		t.Error("Expected error removing out-of-range stage")
+	}
+}
+
+func TestGetStage(t *testing.T) {
+	gainA, _ := NewInternalProcessor("Gain")
+	gainB, _ := NewInternalProcessor("Gain")
+	chain, err := NewProcessorChain(gainA, gainB)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	if chain.Len() != 2 {
+		t.Errorf("Expected 2 stages, got %d", chain.Len())
+	}
+	stage, err := chain.GetStage(1)
+	if err != nil || stage != gainB {
+		t.Fatalf("Expected the second processor, got %p (%v)", stage, err)
+	}
+	// The stage is live
+	stage.SetParameter(0, 0.5)
+	gainA.SetParameter(0, 0.5)
+	buffer := [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 0.25 {
+		t.Errorf("Expected the parameter change to reach the chain, got %f", buffer[0][3])
+	}
+
+	stages := chain.Stages()
+	if len(stages) != 2 || stages[0] != gainA || stages[1] != gainB {
+		t.Errorf("Expected the stages in order, got %v", stages)
+	}
+	stages[0] = nil
+	if first, _ := chain.GetStage(0); first != gainA {
+		t.Error("Expected changing the returned slice to leave the chain alone")
+	}
+
+	for _, index := range []int{-1, 2} {
+		if _, err := chain.GetStage(index); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Expected ErrOutOfRange for index %d, got %v", index, err)
+		}
+	}
+}
+
+func TestReplaceProcessor(t *testing.T) {
+	half, _ := NewInternalProcessor("Gain")
+	half.SetParameter(0, 0.5)
+	chain, err := NewProcessorChain(half)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	unity, _ := NewInternalProcessor("Gain")
+	unity.SetParameter(0, 1.0)
+	if err := ReplaceProcessor(chain, 0, unity); err != nil {
+		t.Fatalf("ReplaceProcessor failed: %v", err)
+	}
+
+	buffer := [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	for i, s := range buffer[0] {
+		if s != 1.0 {
+			t.Errorf("Sample %d: expected 1.0 after replacement, got %f", i, s)
+		}
+	}
+
+	if err := ReplaceProcessor(chain, 1, unity); err == nil {
+		t.Error("Expected error for out-of-range index")
+	}
+	if err := ReplaceProcessor(chain, 0, nil); err == nil {
+		t.Error("Expected error for nil replacement")
+	}
+}
+
+func TestProcessToNewBuffer(t *testing.T) {
+	half, _ := NewInternalProcessor("Gain")
+	half.SetParameter(0, 0.5)
+	chain, err := NewProcessorChain(half)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	in := &AudioBuffer{Data: [][]float32{{1, 1, 1, 1}}, SampleRate: 48000}
+	out, err := chain.ProcessToNewBuffer(in)
+	if err != nil {
+		t.Fatalf("ProcessToNewBuffer failed: %v", err)
+	}
+	if out.SampleRate != in.SampleRate {
+		t.Errorf("Expected sample rate %v, got %v", in.SampleRate, out.SampleRate)
+	}
+	for i := range in.Data[0] {
+		if in.Data[0][i] != 1.0 {
+			t.Errorf("Input sample %d was modified: %f", i, in.Data[0][i])
+		}
+		if out.Data[0][i] != 0.5 {
+			t.Errorf("Output sample %d: expected 0.5, got %f", i, out.Data[0][i])
+		}
+	}
+
+	if _, err := chain.ProcessToNewBuffer(&AudioBuffer{}); err == nil {
+		t.Error("Expected error for empty buffer")
+	}
+}
+
+func TestProcessBufferList(t *testing.T) {
+	// A 0.5 s echo: the impulse in the first buffer would echo into the second
+	delay, _ := NewInternalProcessor("Delay")
+	chain, err := NewProcessorChain(delay)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	impulse := &AudioBuffer{Data: [][]float32{make([]float32, 1000)}, SampleRate: 44100}
+	impulse.Data[0][0] = 1
+	silence := &AudioBuffer{Data: [][]float32{make([]float32, 44100)}, SampleRate: 44100}
+
+	results, err := chain.ProcessBufferList([]*AudioBuffer{impulse, silence})
+	if err != nil {
+		t.Fatalf("ProcessBufferList failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0] == impulse || impulse.Data[0][0] != 1 {
+		t.Error("Expected the input buffer to be left unmodified")
+	}
+	for i, s := range results[1].Data[0] {
+		if s != 0 {
+			t.Fatalf("Expected no tail from the previous buffer, got %f at sample %d", s, i)
+		}
+	}
+
+	if _, err := chain.ProcessBufferList([]*AudioBuffer{impulse, nil}); !errors.Is(err, ErrEmptyBuffer) {
+		t.Errorf("Expected ErrEmptyBuffer for nil buffer, got %v", err)
+	}
+}
+
+func TestChainFreeze(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.5)
+	chain, err := NewProcessorChain(gain)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	if err := chain.Freeze(); err != nil {
+		t.Fatalf("Freeze failed: %v", err)
+	}
+	if !chain.FrozenState() {
+		t.Error("Expected the chain to be frozen")
+	}
+	gain.SetParameter(0, 0.25)
+	buffer := [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 0.5 {
+		t.Errorf("Expected the frozen gain, got %f", buffer[0][3])
+	}
+	if gain.GetParameter(0) != 0.25 {
+		t.Error("Expected the live stage to keep its change while frozen")
+	}
+	if err := chain.Add(gain); !errors.Is(err, ErrChainFrozen) {
+		t.Errorf("Expected ErrChainFrozen adding a stage to a frozen chain, got %v", err)
+	}
+
+	if err := chain.Thaw(); err != nil {
+		t.Fatalf("Thaw failed: %v", err)
+	}
+	if chain.FrozenState() {
+		t.Error("Expected the chain to be live after Thaw")
+	}
+	buffer = [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 0.25 {
+		t.Errorf("Expected the live gain after Thaw, got %f", buffer[0][3])
+	}
+
+	// A bypassed stage stays bypassed in the frozen snapshot
+	bypassed, _ := NewInternalProcessor("Gain")
+	bypassed.SetParameter(0, 0.5)
+	bypassed.SetBypassed(true)
+	chain, err = NewProcessorChain(bypassed)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	if err := chain.Freeze(); err != nil {
+		t.Fatalf("Freeze failed: %v", err)
+	}
+	buffer = [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 1 {
+		t.Errorf("Expected the bypassed stage to pass audio through while frozen, got %f", buffer[0][3])
+	}
+}
+
+func TestDisableStage(t *testing.T) {
+	half, _ := NewInternalProcessor("Gain")
+	half.SetParameter(0, 0.5)
+	quarter, _ := NewInternalProcessor("Gain")
+	quarter.SetParameter(0, 0.25)
+	chain, err := NewProcessorChain(half, quarter)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	if err := chain.DisableStage(1); err != nil {
+		t.Fatalf("DisableStage failed: %v", err)
+	}
+	if chain.IsStageEnabled(1) || !chain.IsStageEnabled(0) {
+		t.Error("Expected only stage 1 to be disabled")
+	}
+	buffer := [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 0.5 {
+		t.Errorf("Expected the disabled stage to be skipped, got %f", buffer[0][3])
+	}
+
+	// Removing a stage shifts the enabled states with it
+	if err := chain.Remove(0); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if chain.IsStageEnabled(0) {
+		t.Error("Expected the disabled stage to stay disabled after moving to index 0")
+	}
+	if err := chain.EnableStage(0); err != nil {
+		t.Fatalf("EnableStage failed: %v", err)
+	}
+	buffer = [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 0.25 {
+		t.Errorf("Expected the re-enabled stage to process, got %f", buffer[0][3])
+	}
+
+	if err := chain.DisableStage(3); err == nil {
+		t.Error("Expected error for out-of-range stage")
+	}
+	if chain.IsStageEnabled(-1) {
+		t.Error("Expected out-of-range stage to report disabled")
+	}
+}
+
+func TestChainBypass(t *testing.T) {
+	half, _ := NewInternalProcessor("Gain")
+	half.SetParameter(0, 0.5)
+	chain, err := NewProcessorChain(half)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	if !chain.ToggleBypassed() || !chain.Bypassed() {
+		t.Fatal("Expected ToggleBypassed to bypass the chain")
+	}
+	buffer := [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 1 {
+		t.Errorf("Expected a bypassed chain to pass audio through, got %f", buffer[0][3])
+	}
+	if half.Bypassed() {
+		t.Error("Expected bypassing the chain to leave its stages alone")
+	}
+
+	if chain.ToggleBypassed() || chain.Bypassed() {
+		t.Fatal("Expected ToggleBypassed to run the chain again")
+	}
+	buffer = [][]float32{{1, 1, 1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][3] != 0.5 {
+		t.Errorf("Expected the chain to process again, got %f", buffer[0][3])
+	}
+}
+
+func TestSyncedChain(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	chain, err := NewProcessorChain(gain)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	synced := NewSyncedChain(chain)
+
+	// Run with -race: processing, parameter changes and stage edits overlap
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buffer := [][]float32{make([]float32, 256), make([]float32, 256)}
+			for i := 0; i < 200; i++ {
+				synced.Process(buffer, 44100)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if err := synced.SetParameter(0, 0, float32(i%10)/10); err != nil {
+				t.Errorf("SetParameter failed: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			extra, _ := NewInternalProcessor("Gain")
+			if err := synced.Add(extra); err != nil {
+				t.Errorf("Add failed: %v", err)
+				return
+			}
+			if err := synced.Remove(synced.Len() - 1); err != nil {
+				t.Errorf("Remove failed: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	if synced.Len() != 1 {
+		t.Errorf("Expected 1 stage, got %d", synced.Len())
+	}
+	if err := synced.SetParameter(1, 0, 0.5); err == nil {
+		t.Error("Expected error for out of range stage")
+	}
+}
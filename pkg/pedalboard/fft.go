@@ -0,0 +1,107 @@
+package pedalboard
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// nextPowerOfTwo returns the smallest power of two greater than or equal to n.
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft computes the discrete Fourier transform of x in place.
+// len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	// Iterative radix-2 butterflies
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even := x[start+k]
+				odd := w * x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// ifft computes the inverse discrete Fourier transform of x in place.
+// len(x) must be a power of two.
+func ifft(x []complex128) {
+	for i := range x {
+		x[i] = cmplx.Conj(x[i])
+	}
+	fft(x)
+	scale := 1.0 / float64(len(x))
+	for i := range x {
+		x[i] = cmplx.Conj(x[i]) * complex(scale, 0)
+	}
+}
+
+// fftConvolve returns the full linear convolution of signal and kernel
+// (length len(signal)+len(kernel)-1) using FFT overlap-add.
+func fftConvolve(signal []float32, kernel []float64) []float64 {
+	if len(signal) == 0 || len(kernel) == 0 {
+		return nil
+	}
+	out := make([]float64, len(signal)+len(kernel)-1)
+
+	fftSize := nextPowerOfTwo(2 * len(kernel))
+	blockSize := fftSize - len(kernel) + 1
+
+	kernelSpec := make([]complex128, fftSize)
+	for i, k := range kernel {
+		kernelSpec[i] = complex(k, 0)
+	}
+	fft(kernelSpec)
+
+	block := make([]complex128, fftSize)
+	for start := 0; start < len(signal); start += blockSize {
+		end := start + blockSize
+		if end > len(signal) {
+			end = len(signal)
+		}
+		for i := range block {
+			block[i] = 0
+		}
+		for i := start; i < end; i++ {
+			block[i-start] = complex(float64(signal[i]), 0)
+		}
+
+		fft(block)
+		for i := range block {
+			block[i] *= kernelSpec[i]
+		}
+		ifft(block)
+
+		for i := 0; i < fftSize && start+i < len(out); i++ {
+			out[start+i] += real(block[i])
+		}
+	}
+	return out
+}
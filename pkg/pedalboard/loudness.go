@@ -0,0 +1,310 @@
+package pedalboard
+
+import (
+	"fmt"
+	"math"
+)
+
+// ISO 226:2003 equal-loudness contour parameters.
+var (
+	iso226Freqs = []float64{
+		20, 25, 31.5, 40, 50, 63, 80, 100, 125, 160, 200, 250, 315, 400, 500,
+		630, 800, 1000, 1250, 1600, 2000, 2500, 3150, 4000, 5000, 6300, 8000, 10000, 12500,
+	}
+	iso226Af = []float64{
+		0.532, 0.506, 0.480, 0.455, 0.432, 0.409, 0.387, 0.367, 0.349, 0.330, 0.315, 0.301, 0.288, 0.276, 0.267,
+		0.259, 0.253, 0.250, 0.246, 0.244, 0.243, 0.243, 0.243, 0.242, 0.242, 0.245, 0.254, 0.271, 0.301,
+	}
+	iso226Lu = []float64{
+		-31.6, -27.2, -23.0, -19.1, -15.9, -13.0, -10.3, -8.1, -6.2, -4.5, -3.1, -2.0, -1.1, -0.4, 0.0,
+		0.3, 0.5, 0.0, -2.7, -4.1, -1.0, 1.7, 2.5, 1.2, -2.1, -7.1, -11.2, -10.7, -3.1,
+	}
+	iso226Tf = []float64{
+		78.5, 68.7, 59.5, 51.1, 44.0, 37.5, 31.5, 26.5, 22.1, 17.9, 14.4, 11.4, 8.6, 6.2, 4.4,
+		3.0, 2.2, 2.4, 3.5, 1.7, -1.3, -4.2, -6.0, -5.4, -1.5, 6.0, 12.6, 13.9, 12.3,
+	}
+)
+
+const (
+	// minListenLevelPhons and maxListenLevelPhons bound the range over which ISO 226 is defined.
+	minListenLevelPhons = 20.0
+	maxListenLevelPhons = 90.0
+	// equalLoudnessTaps is the length of the equal-loudness FIR filter (odd, for an integer delay).
+	equalLoudnessTaps = 4095
+)
+
+// iso226SPL returns the sound pressure level in dB for each ISO 226 frequency
+// that is perceived as equally loud as a 1 kHz tone at the given phon level.
+func iso226SPL(phons float64) []float64 {
+	spl := make([]float64, len(iso226Freqs))
+	for i := range iso226Freqs {
+		af := iso226Af[i]
+		a := 4.47e-3*(math.Pow(10, 0.025*phons)-1.15) +
+			math.Pow(0.4*math.Pow(10, (iso226Tf[i]+iso226Lu[i])/10-9), af)
+		spl[i] = (10/af)*math.Log10(a) - iso226Lu[i] + 94
+	}
+	return spl
+}
+
+// equalLoudnessGainDB returns the weighting gain in dB at freq, interpolating
+// the contour in log-frequency and holding the end values outside 20 Hz - 12.5 kHz.
+func equalLoudnessGainDB(spl []float64, ref, freq float64) float64 {
+	last := len(iso226Freqs) - 1
+	if freq <= iso226Freqs[0] {
+		return ref - spl[0]
+	}
+	if freq >= iso226Freqs[last] {
+		return ref - spl[last]
+	}
+	for i := 0; i < last; i++ {
+		if freq <= iso226Freqs[i+1] {
+			t := (math.Log(freq) - math.Log(iso226Freqs[i])) /
+				(math.Log(iso226Freqs[i+1]) - math.Log(iso226Freqs[i]))
+			return ref - (spl[i] + t*(spl[i+1]-spl[i]))
+		}
+	}
+	return ref - spl[last]
+}
+
+// equalLoudnessKernel designs a linear-phase FIR filter whose magnitude response
+// follows the inverted ISO 226 contour, normalised to 0 dB at 1 kHz.
+func equalLoudnessKernel(phons, sampleRate float64) []float64 {
+	spl := iso226SPL(phons)
+	ref := spl[17] // 1 kHz
+
+	fftSize := nextPowerOfTwo(equalLoudnessTaps + 1)
+	spectrum := make([]complex128, fftSize)
+	for k := 0; k <= fftSize/2; k++ {
+		freq := float64(k) * sampleRate / float64(fftSize)
+		gain := math.Pow(10, equalLoudnessGainDB(spl, ref, freq)/20)
+		spectrum[k] = complex(gain, 0)
+		if k > 0 && k < fftSize/2 {
+			spectrum[fftSize-k] = complex(gain, 0)
+		}
+	}
+	ifft(spectrum)
+
+	// Centre the zero-phase response and apply a Hann window.
+	kernel := make([]float64, equalLoudnessTaps)
+	centre := (equalLoudnessTaps - 1) / 2
+	for n := range kernel {
+		idx := (n - centre + fftSize) % fftSize
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(n)/float64(equalLoudnessTaps-1))
+		kernel[n] = real(spectrum[idx]) * window
+	}
+	return kernel
+}
+
+// EqualLoudness returns a copy of the buffer weighted by the ISO 226 equal-loudness
+// contour, so that frequency-domain analysis reflects perceived loudness.
+// The filter is linear-phase and its delay is compensated, so the output is
+// time-aligned with the input and has the same length.
+// listenLevelPhons: The listening level in phons, typically 60 to 80. Must be within 20 to 90.
+// Returns the weighted AudioBuffer or an error if the level is out of range.
+func (b *AudioBuffer) EqualLoudness(listenLevelPhons float64) (*AudioBuffer, error) {
+	if listenLevelPhons < minListenLevelPhons || listenLevelPhons > maxListenLevelPhons {
+		return nil, fmt.Errorf("%w: listen level %.1f phons (%.0f-%.0f)", ErrOutOfRange,
+			listenLevelPhons, minListenLevelPhons, maxListenLevelPhons)
+	}
+	if len(b.Data) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+	if b.SampleRate <= 0 {
+		return nil, fmt.Errorf("%w: sample rate %f", ErrInvalidArgument, b.SampleRate)
+	}
+
+	kernel := equalLoudnessKernel(listenLevelPhons, b.SampleRate)
+	delay := (len(kernel) - 1) / 2
+
+	out := make([][]float32, len(b.Data))
+	for ch, samples := range b.Data {
+		out[ch] = make([]float32, len(samples))
+		if len(samples) == 0 {
+			continue
+		}
+		filtered := fftConvolve(samples, kernel)
+		for i := range out[ch] {
+			out[ch][i] = float32(filtered[i+delay])
+		}
+	}
+
+	return &AudioBuffer{
+		Data:       out,
+		SampleRate: b.SampleRate,
+	}, nil
+}
+
+// BS.1770-4 loudness measurement constants.
+const (
+	// lufsBlockSeconds and lufsStepSeconds set the 400 ms gating blocks and their 75% overlap.
+	lufsBlockSeconds = 0.4
+	lufsStepSeconds  = 0.1
+	// lufsShortTermSeconds is the short-term loudness window.
+	lufsShortTermSeconds = 3.0
+	// lufsAbsoluteGate and lufsRelativeGate are the integration gates in LUFS and LU.
+	lufsAbsoluteGate = -70.0
+	lufsRelativeGate = -10.0
+)
+
+// biquad is a second-order IIR filter in direct form I, normalized so a0 = 1.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeightingFilters returns the two BS.1770 K-weighting stages (a high-shelf
+// modelling the head, then a high-pass) for the given sample rate. The analog
+// prototypes are bilinear-transformed, giving the standard coefficients at 48 kHz.
+func kWeightingFilters(sampleRate float64) (shelf, highPass biquad) {
+	const shelfGainDB, shelfFreq, shelfQ = 3.999843853973347, 1681.974450955533, 0.7071752369554196
+	k := math.Tan(math.Pi * shelfFreq / sampleRate)
+	vh := math.Pow(10, shelfGainDB/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf = biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	const highPassFreq, highPassQ = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * highPassFreq / sampleRate)
+	a0 = 1 + k/highPassQ + k*k
+	highPass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/highPassQ + k*k) / a0,
+	}
+	return shelf, highPass
+}
+
+// lufsChannelWeights returns the BS.1770 weight of each channel. Six channels are
+// taken as 5.1 (L, R, C, LFE, Ls, Rs): the LFE is excluded and surrounds get +1.5 dB.
+func lufsChannelWeights(numChannels int) []float64 {
+	weights := make([]float64, numChannels)
+	for i := range weights {
+		weights[i] = 1
+	}
+	if numChannels == 6 {
+		weights[3] = 0
+		weights[4], weights[5] = 1.41, 1.41
+	}
+	return weights
+}
+
+// LUFS measures loudness per ITU-R BS.1770-4. Each channel is K-weighted and the
+// weighted mean squares are summed over 400 ms blocks overlapping by 75%.
+// Returns the integrated loudness (blocks gated at -70 LUFS and then 10 LU below the
+// ungated level, averaged) and the maximum short-term loudness (3 s windows, or the
+// whole buffer if shorter). A buffer that is silent after gating measures -Inf.
+// Returns ErrTooShort if the buffer is shorter than one 400 ms block.
+func (b *AudioBuffer) LUFS() (integratedLUFS, shortTermLUFS float64, err error) {
+	if len(b.Data) == 0 || len(b.Data[0]) == 0 {
+		return 0, 0, ErrEmptyBuffer
+	}
+	if b.SampleRate <= 0 {
+		return 0, 0, fmt.Errorf("%w: sample rate %f", ErrInvalidArgument, b.SampleRate)
+	}
+	numSamples := len(b.Data[0])
+	blockSize := int(math.Round(lufsBlockSeconds * b.SampleRate))
+	if numSamples < blockSize {
+		return 0, 0, fmt.Errorf("%w: %d samples, need at least %d (400 ms)", ErrTooShort, numSamples, blockSize)
+	}
+
+	// energy[i] is the weighted sum over channels of squared K-weighted samples before i
+	energy := make([]float64, numSamples+1)
+	for ch, weight := range lufsChannelWeights(len(b.Data)) {
+		if weight == 0 {
+			continue
+		}
+		shelf, highPass := kWeightingFilters(b.SampleRate)
+		var sum float64
+		for i := 0; i < numSamples && i < len(b.Data[ch]); i++ {
+			y := highPass.process(shelf.process(float64(b.Data[ch][i])))
+			sum += weight * y * y
+			energy[i+1] += sum
+		}
+	}
+	meanSquare := func(start, length int) float64 {
+		return (energy[start+length] - energy[start]) / float64(length)
+	}
+	loudness := func(z float64) float64 {
+		return -0.691 + 10*math.Log10(z)
+	}
+
+	step := int(math.Round(lufsStepSeconds * b.SampleRate))
+	var blocks []float64
+	for start := 0; start+blockSize <= numSamples; start += step {
+		blocks = append(blocks, meanSquare(start, blockSize))
+	}
+
+	integratedLUFS = math.Inf(-1)
+	gate := func(threshold float64) (float64, int) {
+		var sum float64
+		var count int
+		for _, z := range blocks {
+			if loudness(z) > threshold {
+				sum += z
+				count++
+			}
+		}
+		return sum, count
+	}
+	if sum, count := gate(lufsAbsoluteGate); count > 0 {
+		relative := loudness(sum/float64(count)) + lufsRelativeGate
+		if sum, count := gate(math.Max(relative, lufsAbsoluteGate)); count > 0 {
+			integratedLUFS = loudness(sum / float64(count))
+		}
+	}
+
+	window := int(math.Round(lufsShortTermSeconds * b.SampleRate))
+	if window > numSamples {
+		window = numSamples
+	}
+	shortTermLUFS = math.Inf(-1)
+	for start := 0; start+window <= numSamples; start += step {
+		shortTermLUFS = math.Max(shortTermLUFS, loudness(meanSquare(start, window)))
+	}
+	return integratedLUFS, shortTermLUFS, nil
+}
+
+// LoudnessNormalize returns a copy of b scaled so its integrated loudness (see
+// AudioBuffer.LUFS) equals targetLUFS, e.g. -14 for most streaming platforms.
+// The gain is applied as is, so a large boost can push peaks above full scale; follow
+// with a limiter if needed. Markers are kept.
+// Returns ErrTooShort if the buffer is under 400 ms and ErrSilentBuffer if nothing is
+// louder than the -70 LUFS gate.
+func LoudnessNormalize(b *AudioBuffer, targetLUFS float64) (*AudioBuffer, error) {
+	integrated, _, err := b.LUFS()
+	if err != nil {
+		return nil, err
+	}
+	if math.IsInf(integrated, -1) {
+		return nil, ErrSilentBuffer
+	}
+
+	gain := float32(math.Pow(10, (targetLUFS-integrated)/20))
+	out := &AudioBuffer{
+		Data:       make([][]float32, len(b.Data)),
+		SampleRate: b.SampleRate,
+		Markers:    append([]Marker(nil), b.Markers...),
+	}
+	for ch, channel := range b.Data {
+		out.Data[ch] = make([]float32, len(channel))
+		for i, s := range channel {
+			out.Data[ch][i] = s * gain
+		}
+	}
+	return out, nil
+}
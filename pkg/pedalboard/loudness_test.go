@@ -0,0 +1,124 @@
+package pedalboard
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func sineBuffer(freq, amplitude, sampleRate float64, numSamples int) *AudioBuffer {
+	data := make([]float32, numSamples)
+	for i := range data {
+		data[i] = float32(amplitude * math.Sin(2*math.Pi*freq*float64(i)/sampleRate))
+	}
+	return &AudioBuffer{Data: [][]float32{data}, SampleRate: sampleRate}
+}
+
+func rms(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+func TestEqualLoudness(t *testing.T) {
+	const sampleRate = 44100.0
+	const numSamples = 44100
+
+	measure := func(freq float64) float64 {
+		weighted, err := sineBuffer(freq, 0.5, sampleRate, numSamples).EqualLoudness(60)
+		if err != nil {
+			t.Fatalf("EqualLoudness failed: %v", err)
+		}
+		if len(weighted.Data[0]) != numSamples {
+			t.Fatalf("Expected %d samples, got %d", numSamples, len(weighted.Data[0]))
+		}
+		// Skip the filter edges
+		return rms(weighted.Data[0][4096 : numSamples-4096])
+	}
+
+	ref := measure(1000)
+	if math.Abs(20*math.Log10(ref/(0.5/math.Sqrt2))) > 0.5 {
+		t.Errorf("Expected ~0 dB at 1 kHz, got %f dB", 20*math.Log10(ref/(0.5/math.Sqrt2)))
+	}
+
+	low := measure(50)
+	if low >= ref*0.1 {
+		t.Errorf("Expected 50 Hz to be strongly attenuated relative to 1 kHz: %f vs %f", low, ref)
+	}
+}
+
+func TestEqualLoudnessRange(t *testing.T) {
+	buffer := sineBuffer(1000, 0.5, 44100.0, 1024)
+	for _, phons := range []float64{0, 10, 100} {
+		if _, err := buffer.EqualLoudness(phons); err == nil {
+			t.Errorf("Expected error for %f phons", phons)
+		}
+	}
+}
+
+func TestLUFS(t *testing.T) {
+	const sampleRate = 48000.0
+	// BS.1770 calibration: a 1 kHz sine at -23 dBFS in both channels reads -23 LUFS
+	sine := sineBuffer(1000, math.Pow(10, -23.0/20), sampleRate, 10*48000)
+	sine.Data = append(sine.Data, append([]float32(nil), sine.Data[0]...))
+
+	integrated, shortTerm, err := sine.LUFS()
+	if err != nil {
+		t.Fatalf("LUFS failed: %v", err)
+	}
+	if math.Abs(integrated+23) > 0.1 {
+		t.Errorf("Expected -23 LUFS integrated, got %.2f", integrated)
+	}
+	if math.Abs(shortTerm+23) > 0.1 {
+		t.Errorf("Expected -23 LUFS short-term, got %.2f", shortTerm)
+	}
+
+	// Appended silence is gated out of the integrated loudness
+	sine.Data[0] = append(sine.Data[0], make([]float32, 10*48000)...)
+	sine.Data[1] = append(sine.Data[1], make([]float32, 10*48000)...)
+	if integrated, _, err := sine.LUFS(); err != nil || math.Abs(integrated+23) > 0.1 {
+		t.Errorf("Expected silence to be gated, got %.2f (%v)", integrated, err)
+	}
+
+	short := sineBuffer(1000, 0.5, sampleRate, 48000/4)
+	if _, _, err := short.LUFS(); !errors.Is(err, ErrTooShort) {
+		t.Errorf("Expected ErrTooShort for 250 ms, got %v", err)
+	}
+}
+
+func TestLoudnessNormalize(t *testing.T) {
+	const sampleRate = 48000.0
+	// Stereo 1 kHz sine at -30 dBFS per channel measures -30 LUFS
+	quiet := sineBuffer(1000, math.Pow(10, -30.0/20), sampleRate, 5*48000)
+	quiet.Data = append(quiet.Data, append([]float32(nil), quiet.Data[0]...))
+	quiet.Markers = []Marker{{Name: "start"}}
+	if before, _, err := quiet.LUFS(); err != nil || math.Abs(before+30) > 0.1 {
+		t.Fatalf("Expected -30 LUFS before normalizing, got %.2f (%v)", before, err)
+	}
+
+	loud, err := LoudnessNormalize(quiet, -14)
+	if err != nil {
+		t.Fatalf("LoudnessNormalize failed: %v", err)
+	}
+	if after, _, err := loud.LUFS(); err != nil || math.Abs(after+14) > 0.05 {
+		t.Errorf("Expected -14 LUFS after normalizing, got %.2f (%v)", after, err)
+	}
+	if quiet.Data[0][12] == loud.Data[0][12] {
+		t.Error("Expected the input buffer to be left unmodified")
+	}
+	loud.Markers[0].Name = "renamed"
+	if quiet.Markers[0].Name != "start" {
+		t.Error("Expected the markers to be copied")
+	}
+
+	silent := &AudioBuffer{Data: [][]float32{make([]float32, 48000)}, SampleRate: sampleRate}
+	if _, err := LoudnessNormalize(silent, -14); !errors.Is(err, ErrSilentBuffer) {
+		t.Errorf("Expected ErrSilentBuffer, got %v", err)
+	}
+	short := sineBuffer(1000, 0.5, sampleRate, 48000/10)
+	if _, err := LoudnessNormalize(short, -14); !errors.Is(err, ErrTooShort) {
+		t.Errorf("Expected ErrTooShort, got %v", err)
+	}
+}
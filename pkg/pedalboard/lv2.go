@@ -0,0 +1,80 @@
+package pedalboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// lv2Dirs holds the plugin URIs the host reported when each directory of LV2 bundles
+// was registered, so every directory is searched only once.
+var lv2Dirs = struct {
+	sync.Mutex
+	uris map[string][]string
+}{uris: make(map[string][]string)}
+
+// pluginIdentifier returns what the plugin host loads path by: the path itself for VST3
+// and AU plugins, and the plugin URI for an LV2 bundle, since LV2 plugins are
+// identified by URI rather than by file.
+func pluginIdentifier(path string) (string, error) {
+	if pluginFormatForPath(path) != PluginFormatLV2 {
+		return path, nil
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return lv2PluginURI(absPath)
+}
+
+// lv2PluginURI returns the URI of the first plugin listed in an LV2 bundle's
+// manifest.ttl, out of the plugins the host found in the bundle's directory.
+func lv2PluginURI(bundle string) (string, error) {
+	manifest, err := os.ReadFile(filepath.Join(bundle, "manifest.ttl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s has no manifest.ttl", ErrInvalidPluginBundle, bundle)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read LV2 manifest: %w", err)
+	}
+
+	dir := filepath.Dir(bundle)
+	lv2Dirs.Lock()
+	uris, ok := lv2Dirs.uris[dir]
+	if !ok {
+		uris, err = lv2SearchPath(dir)
+		if err == nil {
+			lv2Dirs.uris[dir] = uris
+		}
+	}
+	lv2Dirs.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	uri, ok := manifestPluginURI(string(manifest), bundle, uris)
+	if !ok {
+		return "", fmt.Errorf("%w: the LV2 host found no plugin of %s", ErrInvalidPluginBundle, bundle)
+	}
+	return uri, nil
+}
+
+// manifestPluginURI returns the URI in uris that manifest mentions first, written either
+// in full or relative to the bundle.
+func manifestPluginURI(manifest, bundle string, uris []string) (string, bool) {
+	base := "file://" + filepath.ToSlash(bundle) + "/"
+	first, pos := "", len(manifest)
+	for _, uri := range uris {
+		i := strings.Index(manifest, "<"+uri+">")
+		if i < 0 && strings.HasPrefix(uri, base) {
+			i = strings.Index(manifest, "<"+strings.TrimPrefix(uri, base)+">")
+		}
+		if i >= 0 && i < pos {
+			first, pos = uri, i
+		}
+	}
+	return first, first != ""
+}
@@ -0,0 +1,31 @@
+package pedalboard
+
+/*
+#include <stdlib.h>
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// lv2SearchPath makes the LV2 bundles in dir known to the plugin host and returns the
+// URIs of all plugins it knows.
+func lv2SearchPath(dir string) ([]string, error) {
+	cDir := C.CString(dir)
+	defer C.free(unsafe.Pointer(cDir))
+	list := C.pedalboard_lv2_search_path(cDir)
+	if list == nil {
+		return nil, fmt.Errorf("%w: this build has no LV2 host", ErrUnsupportedFormat)
+	}
+	defer C.pedalboard_string_list_free(list)
+
+	uris := make([]string, int(list.count))
+	if len(uris) > 0 {
+		for i, item := range unsafe.Slice(list.items, len(uris)) {
+			uris[i] = C.GoString(item)
+		}
+	}
+	return uris, nil
+}
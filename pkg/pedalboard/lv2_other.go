@@ -0,0 +1,10 @@
+//go:build !linux
+
+package pedalboard
+
+import "fmt"
+
+// lv2SearchPath reports that LV2 plugins are only hosted on Linux.
+func lv2SearchPath(dir string) ([]string, error) {
+	return nil, fmt.Errorf("%w: LV2 plugins are only supported on Linux", ErrUnsupportedFormat)
+}
@@ -0,0 +1,89 @@
+package pedalboard
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// egAmpManifest is the manifest.ttl of the eg-amp example plugin from the LV2 distribution.
+const egAmpManifest = `# LV2 plugins are installed in a bundle
+@prefix lv2:  <http://lv2plug.in/ns/lv2core#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+
+<http://lv2plug.in/plugins/eg-amp>
+	a lv2:Plugin ;
+	lv2:binary <amp.so>  ;
+	rdfs:seeAlso <amp.ttl> .
+`
+
+func TestManifestPluginURI(t *testing.T) {
+	uris := []string{"http://example.org/other", "http://lv2plug.in/plugins/eg-amp"}
+	if uri, ok := manifestPluginURI(egAmpManifest, "/lv2/eg-amp.lv2", uris); !ok || uri != "http://lv2plug.in/plugins/eg-amp" {
+		t.Errorf("Expected the eg-amp URI, got %q", uri)
+	}
+
+	// Relative IRIs resolve against the bundle; the first plugin listed wins
+	manifest := "<second> a lv2:Plugin .\n<http://example.org/other> a lv2:Plugin .\n"
+	uris = append(uris, "file:///lv2/x.lv2/second")
+	if uri, ok := manifestPluginURI(manifest, "/lv2/x.lv2", uris); !ok || uri != "file:///lv2/x.lv2/second" {
+		t.Errorf("Expected the relative plugin IRI, got %q", uri)
+	}
+	if _, ok := manifestPluginURI("<http://example.org/x> a lv2:Plugin .", "/lv2/x.lv2", uris); ok {
+		t.Error("Expected no URI for a plugin the host does not know")
+	}
+
+	empty := filepath.Join(t.TempDir(), "Empty.lv2")
+	os.Mkdir(empty, 0o755)
+	if err := checkPluginPath(empty); !errors.Is(err, ErrInvalidPluginBundle) {
+		t.Errorf("Expected ErrInvalidPluginBundle for a bundle without manifest.ttl, got %v", err)
+	}
+	if _, err := lv2PluginURI(empty); !errors.Is(err, ErrInvalidPluginBundle) {
+		t.Errorf("Expected ErrInvalidPluginBundle for a bundle without manifest.ttl, got %v", err)
+	}
+}
+
+func TestLoadLV2Plugin(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("LV2 plugins are only supported on Linux")
+	}
+	var bundle string
+	for _, dir := range append(filepath.SplitList(os.Getenv("LV2_PATH")), "/usr/lib/lv2", "/usr/local/lib/lv2", "/usr/lib/x86_64-linux-gnu/lv2") {
+		if _, err := os.Stat(filepath.Join(dir, "eg-amp.lv2", "manifest.ttl")); err == nil {
+			bundle = filepath.Join(dir, "eg-amp.lv2")
+			break
+		}
+	}
+	if bundle == "" {
+		t.Skip("eg-amp.lv2 is not installed")
+	}
+
+	amp, err := LoadPlugin(bundle)
+	if err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	if amp.NumParameters() < 1 {
+		t.Fatal("Expected eg-amp to have a gain parameter")
+	}
+
+	// The gain defaults to 0 dB; its minimum, -90 dB, mutes
+	buffer := [][]float32{{0.5, 0.5, 0.5, 0.5}}
+	if err := amp.ProcessContext(context.Background(), buffer, 44100.0); err != nil {
+		t.Fatalf("ProcessContext failed: %v", err)
+	}
+	for i, s := range buffer[0] {
+		if math.Abs(float64(s)-0.5) > 1e-4 {
+			t.Errorf("Sample %d: expected 0.5 at 0 dB, got %f", i, s)
+		}
+	}
+	amp.SetParameter(0, 0)
+	buffer = [][]float32{{0.5, 0.5, 0.5, 0.5}}
+	amp.Process(buffer, 44100.0)
+	if buffer[0][3] != 0 {
+		t.Errorf("Expected silence at the minimum gain, got %f", buffer[0][3])
+	}
+}
@@ -0,0 +1,172 @@
+package pedalboard
+
+/*
+#include <stdlib.h>
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// auTypeCategories names the Audio Unit component types.
+var auTypeCategories = map[string]string{
+	"aufx": "Effect",
+	"aumf": "MusicEffect",
+	"aumu": "Instrument",
+	"aumi": "MIDIProcessor",
+	"augn": "Generator",
+}
+
+// PluginMetadata reads a plugin's name, vendor, version, category and unique ID from
+// its bundle without loading the plugin, which is fast enough to catalog a plugin
+// library on startup. VST3 bundles are read from Contents/Resources/moduleinfo.json
+// (written by VST3 SDK 3.7.5 and later). Audio Unit bundles are read on macOS from the
+// AudioComponents entry of their Info.plist, with the name and version reported by
+// AudioComponentCopyName and AudioComponentGetVersion when the component is registered,
+// so unregistered bundles can be read too. UniqueID is the same as ScanPlugins reports.
+// Returns ErrPluginNotFound if path does not exist, ErrNoPluginMetadata if the bundle
+// does not carry the metadata (use ScanPlugins for such plugins), or
+// ErrUnsupportedFormat if path is not a VST3 or AU bundle, or is an AU bundle outside
+// macOS.
+func PluginMetadata(path string) (*PluginInfo, error) {
+	if err := checkPluginPath(path); err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a single-file plugin, not a bundle", ErrNoPluginMetadata, path)
+	}
+
+	switch pluginFormatForPath(path) {
+	case PluginFormatVST3:
+		return vst3Metadata(absPath)
+	case PluginFormatAU:
+		return auMetadata(absPath)
+	default:
+		return nil, fmt.Errorf("%w: %s is not a .vst3 or .component bundle", ErrUnsupportedFormat, path)
+	}
+}
+
+// vst3ModuleInfo is the part of a VST3 moduleinfo.json that PluginMetadata uses.
+type vst3ModuleInfo struct {
+	Name        string `json:"Name"`
+	Version     string `json:"Version"`
+	FactoryInfo struct {
+		Vendor string `json:"Vendor"`
+	} `json:"Factory Info"`
+	Classes []struct {
+		CID           string   `json:"CID"`
+		Category      string   `json:"Category"`
+		Name          string   `json:"Name"`
+		Vendor        string   `json:"Vendor"`
+		Version       string   `json:"Version"`
+		SubCategories []string `json:"Sub Categories"`
+	} `json:"Classes"`
+}
+
+// vst3Metadata describes the first audio processor class of a VST3 bundle.
+func vst3Metadata(path string) (*PluginInfo, error) {
+	data, err := os.ReadFile(filepath.Join(path, "Contents", "Resources", "moduleinfo.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s has no moduleinfo.json", ErrNoPluginMetadata, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin metadata: %w", err)
+	}
+	var module vst3ModuleInfo
+	if err := json.Unmarshal(data, &module); err != nil {
+		return nil, fmt.Errorf("failed to parse moduleinfo.json of %s: %w", path, err)
+	}
+
+	for _, class := range module.Classes {
+		if class.Category != "Audio Module Class" {
+			continue
+		}
+		uniqueID, err := vst3UniqueID(class.CID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrNoPluginMetadata, path, err)
+		}
+		info := &PluginInfo{
+			Path:     path,
+			Name:     class.Name,
+			Vendor:   class.Vendor,
+			Category: strings.Join(class.SubCategories, "|"),
+			UniqueID: uniqueID,
+			Version:  class.Version,
+			Format:   PluginFormatVST3,
+		}
+		if info.Vendor == "" {
+			info.Vendor = module.FactoryInfo.Vendor
+		}
+		if info.Version == "" {
+			info.Version = module.Version
+		}
+		return info, nil
+	}
+	return nil, fmt.Errorf("%w: %s has no audio processor class", ErrNoPluginMetadata, path)
+}
+
+// vst3UniqueID converts a class ID as written in moduleinfo.json (32 hex digits) to the
+// unique ID ScanPlugins reports, which the VST3 format derives by hashing the four
+// 32-bit words of the class ID.
+func vst3UniqueID(cid string) (string, error) {
+	words, err := hex.DecodeString(cid)
+	if err != nil || len(words) != 16 {
+		return "", fmt.Errorf("invalid class ID %q", cid)
+	}
+	var hash uint32
+	for i := 0; i < 16; i += 4 {
+		word := uint32(words[i])<<24 | uint32(words[i+1])<<16 | uint32(words[i+2])<<8 | uint32(words[i+3])
+		hash = hash*31 + word
+	}
+	return strconv.FormatUint(uint64(hash), 16), nil
+}
+
+// auMetadata describes the first component listed in an Audio Unit bundle.
+func auMetadata(path string) (*PluginInfo, error) {
+	if _, err := os.Stat(filepath.Join(path, "Contents", "Info.plist")); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s has no Info.plist", ErrNoPluginMetadata, path)
+	}
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var supported C.int
+	desc := C.pedalboard_describe_audio_unit_bundle(cPath, &supported)
+	if supported == 0 {
+		return nil, fmt.Errorf("%w: Audio Units are only supported on macOS", ErrUnsupportedFormat)
+	}
+	if desc == nil {
+		return nil, fmt.Errorf("%w: %s lists no AudioComponents", ErrNoPluginMetadata, path)
+	}
+	defer C.pedalboard_plugin_description_free(desc)
+
+	// Components are named "Vendor: Name"
+	info := &PluginInfo{
+		Path:     path,
+		Name:     C.GoString(desc.name),
+		UniqueID: C.GoString(desc.unique_id),
+		Version:  C.GoString(desc.version),
+		Format:   PluginFormatAU,
+	}
+	if vendor, name, ok := strings.Cut(info.Name, ": "); ok {
+		info.Vendor, info.Name = vendor, name
+	}
+	componentType := C.GoString(desc.category)
+	info.Category = auTypeCategories[componentType]
+	if info.Category == "" {
+		info.Category = componentType
+	}
+	return info, nil
+}
@@ -0,0 +1,130 @@
+package pedalboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeBundleFile writes content to name inside bundle, creating directories as needed.
+func writeBundleFile(t *testing.T, bundle, name, content string) {
+	t.Helper()
+	path := filepath.Join(bundle, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginMetadataVST3(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "Echo.vst3")
+	writeBundleFile(t, bundle, "Contents/Resources/moduleinfo.json", `{
+  "Name": "Echo",
+  "Version": "2.1.0",
+  "Factory Info": {"Vendor": "Acme Audio"},
+  "Classes": [
+    {"CID": "00000000000000000000000000000001", "Category": "Component Controller Class", "Name": "Echo Controller"},
+    {"CID": "ABCDEF0123456789ABCDEF0123456789", "Category": "Audio Module Class", "Name": "Echo", "Sub Categories": ["Fx", "Delay"]}
+  ]
+}`)
+
+	info, err := PluginMetadata(bundle)
+	if err != nil {
+		t.Fatalf("PluginMetadata failed: %v", err)
+	}
+	expected := PluginInfo{
+		Path:     bundle,
+		Name:     "Echo",
+		Vendor:   "Acme Audio",
+		Category: "Fx|Delay",
+		UniqueID: "6a772750", // As ScanPlugins derives it from the class ID
+		Version:  "2.1.0",
+		Format:   PluginFormatVST3,
+	}
+	if *info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *info)
+	}
+
+	old := filepath.Join(t.TempDir(), "Old.vst3")
+	if err := os.MkdirAll(filepath.Join(old, "Contents"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PluginMetadata(old); !errors.Is(err, ErrNoPluginMetadata) {
+		t.Errorf("Expected ErrNoPluginMetadata without moduleinfo.json, got %v", err)
+	}
+}
+
+func TestPluginMetadataAU(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "Echo.component")
+	writeBundleFile(t, bundle, "Contents/MacOS/Echo", "")
+	writeBundleFile(t, bundle, "Contents/Info.plist", `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleShortVersionString</key>
+	<string>2.1</string>
+	<key>AudioComponents</key>
+	<array>
+		<dict>
+			<key>name</key>
+			<string>Acme Audio: Echo</string>
+			<key>manufacturer</key>
+			<string>Acme</string>
+			<key>type</key>
+			<string>aufx</string>
+			<key>subtype</key>
+			<string>Echo</string>
+			<key>version</key>
+			<integer>131328</integer>
+			<key>sandboxSafe</key>
+			<true/>
+		</dict>
+	</array>
+</dict>
+</plist>`)
+
+	if _, err := PluginMetadata(filepath.Join(t.TempDir(), "Missing.component")); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("Expected ErrPluginNotFound, got %v", err)
+	}
+	if runtime.GOOS != "darwin" {
+		if _, err := PluginMetadata(bundle); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Expected ErrUnsupportedFormat outside macOS, got %v", err)
+		}
+		return
+	}
+
+	expected := PluginInfo{
+		Path:     bundle,
+		Name:     "Echo",
+		Vendor:   "Acme Audio",
+		Category: "Effect",
+		UniqueID: "65756372", // 'aufx' ^ 'Echo' ^ 'Acme', as ScanPlugins reports it
+		Version:  "2.1.0",
+		Format:   PluginFormatAU,
+	}
+	info, err := PluginMetadata(bundle)
+	if err != nil {
+		t.Fatalf("PluginMetadata failed: %v", err)
+	}
+	if *info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *info)
+	}
+
+	// Binary property lists read the same
+	plist := filepath.Join(bundle, "Contents", "Info.plist")
+	if err := exec.Command("plutil", "-convert", "binary1", plist).Run(); err != nil {
+		t.Fatalf("plutil failed: %v", err)
+	}
+	info, err = PluginMetadata(bundle)
+	if err != nil {
+		t.Fatalf("PluginMetadata failed for a binary Info.plist: %v", err)
+	}
+	if *info != expected {
+		t.Errorf("Expected %+v from a binary Info.plist, got %+v", expected, *info)
+	}
+}
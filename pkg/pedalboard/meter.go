@@ -0,0 +1,77 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"math"
+	"time"
+)
+
+// defaultVUMeterInterval is the VU meter update interval used when
+// AudioStreamConfig.VUMeterInterval is zero.
+const defaultVUMeterInterval = 50 * time.Millisecond
+
+// SetVUMeterCallback registers cb to receive the peak level of every input and output
+// channel, in dBFS, once per AudioStreamConfig.VUMeterInterval (50 ms by default).
+// Each call reports the peaks of the audio processed since the previous call, so short
+// transients are never missed; a silent channel reports -Inf. The held peaks reported by
+// PeakLevels are not affected. Passing nil removes the callback.
+//
+// Unlike the xrun callback, cb runs on a background goroutine, not the audio thread, so
+// it may block or update a UI. Calls are not made while a previous call is running,
+// and Close waits for a running call to return.
+func (s *AudioStream) SetVUMeterCallback(cb func(inputLevels, outputLevels []float32)) {
+	if cb == nil {
+		s.vuCallback.Store(nil)
+		return
+	}
+	s.vuCallback.Store(&cb)
+	s.vuOnce.Do(func() {
+		s.wg.Add(1)
+		go s.pollVUMeter()
+	})
+}
+
+func (s *AudioStream) pollVUMeter() {
+	defer s.wg.Done()
+
+	interval := s.vuInterval
+	if interval <= 0 {
+		interval = defaultVUMeterInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var cIn, cOut [C.PEDALBOARD_MAX_METER_CHANNELS]C.float
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		var numIn, numOut C.int
+		C.pedalboard_audio_stream_take_meter_levels(s.handle, &cIn[0], &cOut[0], &numIn, &numOut)
+		cb := s.vuCallback.Load()
+		if cb == nil {
+			continue
+		}
+		(*cb)(peaksToDBFS(cIn[:numIn]), peaksToDBFS(cOut[:numOut]))
+	}
+}
+
+// peaksToDBFS converts linear peak levels to dBFS.
+func peaksToDBFS(peaks []C.float) []float32 {
+	levels := make([]float32, len(peaks))
+	for i, peak := range peaks {
+		levels[i] = linearToDBFS(float32(peak))
+	}
+	return levels
+}
+
+// linearToDBFS converts a linear level to dBFS; 0 is -Inf.
+func linearToDBFS(level float32) float32 {
+	return float32(20 * math.Log10(float64(level)))
+}
@@ -0,0 +1,40 @@
+package pedalboard
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVUMeterCallback(t *testing.T) {
+	if v := linearToDBFS(1); v != 0 {
+		t.Errorf("Expected full scale to be 0 dBFS, got %v", v)
+	}
+	if v := linearToDBFS(0.5); math.Abs(float64(v)+6.0206) > 1e-3 {
+		t.Errorf("Expected half scale to be -6.02 dBFS, got %v", v)
+	}
+	if v := linearToDBFS(0); !math.IsInf(float64(v), -1) {
+		t.Errorf("Expected silence to be -Inf dBFS, got %v", v)
+	}
+
+	gain, _ := NewInternalProcessor("Gain")
+	stream, err := NewAudioStreamWithConfig(AudioStreamConfig{Processor: gain, VUMeterInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Logf("Audio stream creation failed (expected in some environments): %v", err)
+		return
+	}
+	defer stream.Close()
+
+	var calls atomic.Int64
+	stream.SetVUMeterCallback(func(inputLevels, outputLevels []float32) { calls.Add(1) })
+	if err := stream.Start(); err != nil {
+		t.Fatalf("Failed to start the stream: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	stream.Stop()
+	stream.SetVUMeterCallback(nil)
+	if calls.Load() == 0 {
+		t.Error("Expected the VU meter callback to be called")
+	}
+}
@@ -0,0 +1,96 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"time"
+	"unsafe"
+)
+
+const (
+	// midiInBufferSize is the capacity of the channel returned by AudioStream.MIDIIn.
+	midiInBufferSize = 4096
+	// midiPollInterval is how often incoming MIDI is drained from the device queue.
+	midiPollInterval = 2 * time.Millisecond
+)
+
+// MIDIIn returns a channel delivering events from the stream's MIDI input device
+// as they arrive. The device is the one set in AudioStreamConfig.MIDIInputDeviceID,
+// or the default MIDI input. The input is opened on the first call; later calls
+// return the same channel. System exclusive messages are not delivered.
+//
+// The channel is buffered with a capacity of 4096 events. If the application falls
+// behind, excess events are discarded and counted by DroppedMIDIEvents. The channel
+// is closed when the stream is closed, or immediately if no MIDI input could be opened
+// or the stream has already been closed.
+func (s *AudioStream) MIDIIn() <-chan MIDIEvent {
+	s.midiOnce.Do(func() {
+		s.midiCh = make(chan MIDIEvent, midiInBufferSize)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.closing() {
+			close(s.midiCh)
+			return
+		}
+
+		var cID *C.char
+		if s.midiInputID != "" {
+			cID = C.CString(s.midiInputID)
+			defer C.free(unsafe.Pointer(cID))
+		}
+		if C.pedalboard_audio_stream_open_midi_input(s.handle, cID) != C.PEDALBOARD_OK {
+			close(s.midiCh)
+			return
+		}
+
+		s.wg.Add(1)
+		go s.pollMIDI()
+	})
+	return s.midiCh
+}
+
+// DroppedMIDIEvents returns the number of MIDI input events discarded because
+// the application did not read them quickly enough.
+func (s *AudioStream) DroppedMIDIEvents() int64 {
+	return s.midiDropped.Load() + int64(C.pedalboard_audio_stream_get_dropped_midi_events(s.handle))
+}
+
+func (s *AudioStream) pollMIDI() {
+	defer s.wg.Done()
+	defer close(s.midiCh)
+
+	var cEvents [256]C.PedalboardMidiInputEvent
+	ticker := time.NewTicker(midiPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			n := int(C.pedalboard_audio_stream_read_midi_input(s.handle, &cEvents[0], C.int(len(cEvents))))
+			for _, cEv := range cEvents[:n] {
+				data := make([]byte, int(cEv.size))
+				for i := range data {
+					data[i] = byte(cEv.data[i])
+				}
+				ev := newMIDIEvent(data, 0, time.Duration(float64(cEv.timestamp)*float64(time.Second)))
+				select {
+				case s.midiCh <- ev:
+				default:
+					s.midiDropped.Add(1)
+				}
+			}
+			if n < len(cEvents) {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package pedalboard
+
+import (
+	"testing"
+)
+
+func TestNewMIDIEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		status  byte
+		data1   byte
+		data2   byte
+		channel int
+	}{
+		{"note on", []byte{0x90, 60, 100}, 0x90, 60, 100, 1},
+		{"note off", []byte{0x8F, 60, 0}, 0x8F, 60, 0, 16},
+		{"control change", []byte{0xB3, 7, 127}, 0xB3, 7, 127, 4},
+		{"program change", []byte{0xC0, 5}, 0xC0, 5, 0, 1},
+		{"pitch bend", []byte{0xE9, 0x00, 0x40}, 0xE9, 0x00, 0x40, 10},
+		{"timing clock", []byte{0xF8}, 0xF8, 0, 0, 0},
+		{"empty", nil, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		ev := newMIDIEvent(tt.data, 3, 0)
+		if ev.Status != tt.status || ev.Data1 != tt.data1 || ev.Data2 != tt.data2 || ev.Channel != tt.channel {
+			t.Errorf("%s: expected status %#x, data %d %d, channel %d; got status %#x, data %d %d, channel %d",
+				tt.name, tt.status, tt.data1, tt.data2, tt.channel, ev.Status, ev.Data1, ev.Data2, ev.Channel)
+		}
+		if ev.SampleOffset != 3 {
+			t.Errorf("%s: expected sample offset 3, got %d", tt.name, ev.SampleOffset)
+		}
+	}
+}
+
+func TestMIDIInAfterClose(t *testing.T) {
+	s := &AudioStream{done: make(chan struct{})}
+	close(s.done)
+	if _, ok := <-s.MIDIIn(); ok {
+		t.Error("Expected a closed channel")
+	}
+}
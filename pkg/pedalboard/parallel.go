@@ -0,0 +1,86 @@
+package pedalboard
+
+import (
+	"fmt"
+)
+
+// ParallelChain sends the same input through several chains and mixes their outputs,
+// as used for parallel compression, mid/side processing and multiband splits.
+// The outputs of chains with less latency are delayed to match the chain with the most,
+// so the mix stays in time. It is not safe for concurrent use.
+type ParallelChain struct {
+	chains  []*ProcessorChain
+	gains   []float32
+	input   [][]float32 // Reused copy of the input
+	scratch [][]float32 // Reused copy of the input for each chain
+	delays  []delayLine // Parallel to chains
+}
+
+// NewParallelChain creates a parallel chain from chains. Each chain's output is mixed
+// with a gain of 1/N, so N chains that leave the signal unchanged sum to the input.
+func NewParallelChain(chains ...*ProcessorChain) *ParallelChain {
+	p := &ParallelChain{chains: chains, gains: make([]float32, len(chains)), delays: make([]delayLine, len(chains))}
+	for i := range p.gains {
+		p.gains[i] = 1 / float32(len(chains))
+	}
+	return p
+}
+
+// SetChainGain sets the linear gain applied to the output of the chain at index before
+// mixing, replacing its default of 1/N.
+// Returns an error if index is out of range.
+func (p *ParallelChain) SetChainGain(index int, gain float32) error {
+	if index < 0 || index >= len(p.chains) {
+		return fmt.Errorf("%w: chain index %d (0-%d)", ErrOutOfRange, index, len(p.chains)-1)
+	}
+	p.gains[index] = gain
+	return nil
+}
+
+// Process runs a copy of buffer through each chain in turn and replaces buffer with the
+// sum of their outputs, each scaled by its chain gain and delayed by the difference
+// between its latency and the largest chain latency.
+// buffer: The audio data to process (modified in-place).
+// sampleRate: The sample rate of the audio data.
+// Returns an error if there are no chains or buffer is empty.
+func (p *ParallelChain) Process(buffer [][]float32, sampleRate float64) error {
+	if len(p.chains) == 0 {
+		return fmt.Errorf("%w: parallel chain has no chains", ErrInvalidArgument)
+	}
+	if len(buffer) == 0 {
+		return ErrEmptyBuffer
+	}
+
+	if len(p.input) < len(buffer) {
+		p.input = append(p.input, make([][]float32, len(buffer)-len(p.input))...)
+		p.scratch = append(p.scratch, make([][]float32, len(buffer)-len(p.scratch))...)
+	}
+	input := p.input[:len(buffer)]
+	for ch, samples := range buffer {
+		input[ch] = append(input[ch][:0], samples...)
+		clear(samples)
+	}
+
+	maxLatency := 0
+	for _, chain := range p.chains {
+		maxLatency = max(maxLatency, chain.Processor().LatencySamples())
+	}
+
+	for i, chain := range p.chains {
+		copied := p.scratch[:len(buffer)]
+		for ch, samples := range input {
+			copied[ch] = append(copied[ch][:0], samples...)
+		}
+		delay := maxLatency - chain.Processor().LatencySamples()
+		chain.Process(copied, sampleRate)
+		p.delays[i].process(copied, delay)
+		gain := p.gains[i]
+		for ch, samples := range copied {
+			mix := buffer[ch]
+			for j := 0; j < len(mix) && j < len(samples); j++ {
+				mix[j] += gain * samples[j]
+			}
+		}
+	}
+	return nil
+}
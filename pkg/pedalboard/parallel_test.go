@@ -0,0 +1,69 @@
+package pedalboard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParallelChain(t *testing.T) {
+	newGainChain := func(gain float32) *ProcessorChain {
+		p, _ := NewInternalProcessor("Gain")
+		p.SetParameter(0, gain)
+		chain, err := NewProcessorChain(p)
+		if err != nil {
+			t.Fatalf("Failed to create chain: %v", err)
+		}
+		return chain
+	}
+
+	parallel := NewParallelChain(newGainChain(1), newGainChain(0.5))
+	buffer := [][]float32{{1, 1, 1, 1}, {-0.5, -0.5, -0.5, -0.5}}
+	if err := parallel.Process(buffer, 44100.0); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	// (1 + 0.5) / 2
+	if buffer[0][3] != 0.75 || buffer[1][3] != -0.375 {
+		t.Errorf("Expected equal-gain mix, got %v", buffer)
+	}
+
+	if err := parallel.SetChainGain(1, 2); err != nil {
+		t.Fatalf("SetChainGain failed: %v", err)
+	}
+	buffer = [][]float32{{1, 1, 1, 1}}
+	parallel.Process(buffer, 44100.0)
+	// 0.5 * 1 + 2 * 0.5
+	if buffer[0][3] != 1.5 {
+		t.Errorf("Expected 1.5 after SetChainGain, got %v", buffer[0][3])
+	}
+
+	// A chain without latency is delayed to line up with one that has some
+	shifter, _ := NewInternalProcessor("PitchShifter")
+	shifter.SetParameter(0, 0.75) // +12 semitones, 2048 samples of latency
+	shifted, _ := NewProcessorChain(shifter)
+	aligned := NewParallelChain(shifted, newGainChain(1))
+	aligned.SetChainGain(0, 0)
+	aligned.SetChainGain(1, 1)
+	for block := 0; block < 2; block++ {
+		impulse := [][]float32{make([]float32, 1500)}
+		if block == 0 {
+			impulse[0][0] = 1
+		}
+		aligned.Process(impulse, 44100.0)
+		for i, s := range impulse[0] {
+			want := float32(0)
+			if block*1500+i == 2048 {
+				want = 1
+			}
+			if s != want {
+				t.Fatalf("Block %d sample %d: expected %v, got %v", block, i, want, s)
+			}
+		}
+	}
+
+	if err := parallel.SetChainGain(2, 1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange, got %v", err)
+	}
+	if err := NewParallelChain().Process(buffer, 44100.0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for no chains, got %v", err)
+	}
+}
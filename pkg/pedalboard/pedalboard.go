@@ -9,11 +9,80 @@ package pedalboard
 */
 import "C"
 import (
+	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
 	"runtime"
+	"runtime/cgo"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
+var (
+	// ErrProcessorNotFound is returned by NewInternalProcessor for an unknown processor name.
+	ErrProcessorNotFound = errors.New("processor not found")
+	// ErrPluginLoadFailed is returned when a plugin exists but could not be loaded.
+	ErrPluginLoadFailed = errors.New("failed to load plugin")
+	// ErrInvalidParameter is returned when a parameter cannot take the requested value.
+	ErrInvalidParameter = errors.New("invalid parameter")
+	// ErrParameterOutOfRange is returned when a parameter index is outside the processor's
+	// parameters. It wraps ErrInvalidParameter, so checking for ErrInvalidParameter catches
+	// both a bad index and a bad value.
+	ErrParameterOutOfRange = fmt.Errorf("%w: parameter out of range", ErrInvalidParameter)
+	// ErrParseFailure is returned when text cannot be parsed as a parameter value.
+	ErrParseFailure = errors.New("failed to parse parameter value")
+	// ErrUnsupportedFormat is returned when an audio file or sample format cannot be read or written.
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	// ErrEmptyBuffer is returned when an operation is given a buffer without samples.
+	ErrEmptyBuffer = errors.New("empty buffer")
+	// ErrDeviceNotFound is returned when a requested audio device does not exist.
+	ErrDeviceNotFound = errors.New("audio device not found")
+	// ErrAlreadyRunning is returned by AudioStream.RunContext when the stream is already running.
+	ErrAlreadyRunning = errors.New("audio stream already running")
+	// ErrOutOfRange is returned when an index, such as a channel, is outside the valid range.
+	ErrOutOfRange = errors.New("out of range")
+	// ErrInvalidArgument is returned when an argument has an unsupported value.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrTooShort is returned when a buffer is too short for the requested measurement.
+	ErrTooShort = errors.New("buffer too short")
+	// ErrNotStereo is returned when a stereo measurement is requested on a buffer without two channels.
+	ErrNotStereo = errors.New("buffer is not stereo")
+	// ErrSilentBuffer is returned when an operation needs a signal but the buffer is silent.
+	ErrSilentBuffer = errors.New("buffer is silent")
+	// ErrPluginNotFound is returned by LoadPlugin when the plugin path does not exist.
+	ErrPluginNotFound = errors.New("plugin not found")
+	// ErrInvalidPluginBundle is returned by LoadPlugin for a directory that is not a plugin bundle.
+	ErrInvalidPluginBundle = errors.New("not a valid plugin bundle")
+	// ErrSandboxFailed is returned when the host process of a sandboxed plugin crashed, timed out or could not be started.
+	ErrSandboxFailed = errors.New("sandboxed plugin failed")
+	// ErrNoPluginMetadata is returned by PluginMetadata when a plugin bundle carries no static metadata.
+	ErrNoPluginMetadata = errors.New("plugin has no static metadata")
+	// ErrInvalidPreset is returned by LoadPreset for data that is not a preset of the processor.
+	ErrInvalidPreset = errors.New("invalid preset")
+	// ErrUnsupportedLayout is returned by LoadPluginWithConfig when a plugin does not support the requested channel layout.
+	ErrUnsupportedLayout = errors.New("unsupported channel layout")
+	// ErrStreamClosed is returned by AudioStream methods called after Close.
+	ErrStreamClosed = errors.New("audio stream closed")
+	// ErrDeviceFailed is returned when an audio device cannot be opened, reconfigured or listed.
+	ErrDeviceFailed = errors.New("audio device failed")
+	// ErrNotSupported is returned when a processor does not support the requested operation.
+	ErrNotSupported = errors.New("operation not supported")
+	// ErrOutOfMemory is returned when memory for passing audio to the native layer cannot be allocated.
+	ErrOutOfMemory = errors.New("out of memory")
+	// ErrChainFrozen is returned when adding, removing or replacing a stage of a frozen ProcessorChain.
+	ErrChainFrozen = errors.New("processor chain is frozen")
+	// ErrNoState is returned by SavePreset when a processor has no state to save.
+	ErrNoState = errors.New("processor has no state")
+	// ErrSaveFailed is returned when an audio file cannot be written.
+	ErrSaveFailed = errors.New("failed to save audio file")
+)
+
 func init() {
 	C.pedalboard_init()
 }
@@ -22,36 +91,168 @@ func init() {
 // It wraps a JUCE AudioProcessor instance.
 type Processor struct {
 	handle C.PedalboardProcessor
+	// recreate builds a fresh instance of the same processor, or is nil if that is
+	// not possible (e.g. for chains).
+	recreate func() (*Processor, error)
+	// sandbox is set for a plugin hosted in another process; handle is then an
+	// external processor that forwards blocks to it.
+	sandbox *sandboxClient
+	// recorder, if set, receives every SetParameter call; see ParameterRecorder.
+	recorder atomic.Pointer[ParameterRecorder]
 }
 
+// Parameter indexes of the "Compressor" processor.
+const (
+	CompressorParamThreshold = 0 // -60 to 0 dB
+	CompressorParamRatio     = 1 // 1 to 20
+	CompressorParamAttack    = 2 // 1 to 200 ms
+	CompressorParamRelease   = 3 // 20 to 500 ms
+	CompressorParamKnee      = 4 // 0 (hard) to 12 dB
+)
+
 // NewInternalProcessor creates a new internal processor by name.
 // Supported names: "Gain", "Reverb".
-// Returns a pointer to the Processor or an error if creation failed.
+// Returns a pointer to the Processor or an error wrapping ErrProcessorNotFound if name
+// is not a known processor.
 func NewInternalProcessor(name string) (*Processor, error) {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
 
 	handle := C.pedalboard_create_internal_processor(cName)
 	if handle == nil {
-		return nil, fmt.Errorf("failed to create processor: %s", name)
+		return nil, fmt.Errorf("%w: %s", ErrProcessorNotFound, name)
+	}
+
+	p := wrapProcessor(handle)
+	p.recreate = func() (*Processor, error) { return NewInternalProcessor(name) }
+	return p, nil
+}
+
+// maxImpulseResponseSeconds is the longest impulse response NewConvolutionReverb accepts.
+const maxImpulseResponseSeconds = 10
+
+// NewConvolutionReverb creates a processor that convolves its input with an impulse
+// response, using partitioned FFT convolution with no added latency.
+// impulseResponse: The impulse response, up to 10 seconds long. It is copied, and
+// resampled if it is processed at a different sample rate than its own. Output channel
+// n uses impulse response channel n modulo the number of impulse response channels.
+// Parameter 0 is the dry/wet mix (default 1.0, fully wet).
+// Returns a pointer to the Processor or an error if creation failed.
+func NewConvolutionReverb(impulseResponse *AudioBuffer) (*Processor, error) {
+	if impulseResponse == nil || len(impulseResponse.Data) == 0 || len(impulseResponse.Data[0]) == 0 {
+		return nil, fmt.Errorf("%w: empty impulse response", ErrEmptyBuffer)
+	}
+	if impulseResponse.SampleRate <= 0 {
+		return nil, fmt.Errorf("%w: impulse response sample rate %f", ErrInvalidArgument, impulseResponse.SampleRate)
+	}
+	numChannels := len(impulseResponse.Data)
+	numSamples := len(impulseResponse.Data[0])
+	for ch, data := range impulseResponse.Data {
+		if len(data) != numSamples {
+			return nil, fmt.Errorf("%w: impulse response channel %d has %d samples, expected %d", ErrInvalidArgument, ch, len(data), numSamples)
+		}
+	}
+	if duration := float64(numSamples) / impulseResponse.SampleRate; duration > maxImpulseResponseSeconds {
+		return nil, fmt.Errorf("%w: impulse response of %.2fs (maximum %ds)", ErrOutOfRange, duration, maxImpulseResponseSeconds)
+	}
+
+	cPtrs := (**C.float)(C.malloc(C.size_t(numChannels) * C.size_t(unsafe.Sizeof((*C.float)(nil)))))
+	if cPtrs == nil {
+		return nil, fmt.Errorf("%w: failed to allocate impulse response", ErrOutOfMemory)
+	}
+	defer C.free(unsafe.Pointer(cPtrs))
+
+	cPtrsSlice := unsafe.Slice(cPtrs, numChannels)
+	for i := 0; i < numChannels; i++ {
+		cPtrsSlice[i] = (*C.float)(unsafe.Pointer(&impulseResponse.Data[i][0]))
 	}
 
-	return wrapProcessor(handle), nil
+	handle := C.pedalboard_create_convolution_reverb(cPtrs, C.int(numChannels), C.int(numSamples), C.double(impulseResponse.SampleRate))
+	if handle == nil {
+		return nil, fmt.Errorf("failed to create convolution reverb")
+	}
+	// Copy the impulse response for recreating the reverb, so later changes the caller
+	// makes to its buffer don't affect the copies
+	ir := &AudioBuffer{Data: make([][]float32, numChannels), SampleRate: impulseResponse.SampleRate}
+	for ch, data := range impulseResponse.Data {
+		ir.Data[ch] = append([]float32(nil), data...)
+	}
+	p := wrapProcessor(handle)
+	p.recreate = func() (*Processor, error) { return NewConvolutionReverb(ir) }
+	return p, nil
 }
 
-// LoadPlugin loads a VST3 or AU plugin from the specified file path.
-// path: The absolute path to the plugin file (e.g., .vst3 or .component).
-// Returns a pointer to the Processor or an error if loading failed.
+// LoadPlugin loads a VST3, AU or LV2 plugin from the specified file path.
+// path: The absolute path to the plugin file or bundle directory (e.g., .vst3,
+// .component or .lv2). An AU bundle must contain an executable in Contents/MacOS, and
+// an LV2 bundle a manifest.ttl; the first plugin the manifest lists is loaded. LV2 is
+// supported on Linux only.
+// Returns a pointer to the Processor or an error if loading failed: ErrPluginNotFound
+// if path does not exist, ErrInvalidPluginBundle if it is a directory without the
+// expected bundle layout, ErrUnsupportedFormat for an LV2 bundle on other platforms,
+// ErrPluginLoadFailed if the plugin could not be instantiated.
 func LoadPlugin(path string) (*Processor, error) {
-	cPath := C.CString(path)
+	if err := checkPluginPath(path); err != nil {
+		return nil, err
+	}
+	identifier, err := pluginIdentifier(path)
+	if err != nil {
+		return nil, err
+	}
+	cPath := C.CString(identifier)
 	defer C.free(unsafe.Pointer(cPath))
 
 	handle := C.pedalboard_load_plugin(cPath)
 	if handle == nil {
-		return nil, fmt.Errorf("failed to load plugin: %s", path)
+		return nil, fmt.Errorf("%w: %s", ErrPluginLoadFailed, path)
+	}
+
+	p := wrapProcessor(handle)
+	p.recreate = func() (*Processor, error) { return LoadPlugin(path) }
+	return p, nil
+}
+
+// PluginConfig is the channel layout to load a plugin with; see LoadPluginWithConfig.
+type PluginConfig struct {
+	// NumInputs is the number of channels of the main input bus. 0 disables the input,
+	// as for an instrument.
+	NumInputs int
+	// NumOutputs is the number of channels of the main output bus.
+	NumOutputs int
+}
+
+// LoadPluginWithConfig loads a plugin like LoadPlugin, with its main buses set to the
+// channel counts in cfg instead of the plugin's default layout. Counts up to 8 use the
+// usual layout for that many channels (mono, stereo, LCR, quad, 5.0, 5.1, 6.1, 7.1),
+// larger ones discrete channels.
+// Returns the errors of LoadPlugin, ErrInvalidArgument for negative counts or no
+// outputs, and ErrUnsupportedLayout if the plugin does not support the layout.
+func LoadPluginWithConfig(path string, cfg PluginConfig) (*Processor, error) {
+	if cfg.NumInputs < 0 || cfg.NumOutputs < 1 {
+		return nil, fmt.Errorf("%w: %d inputs and %d outputs", ErrInvalidArgument, cfg.NumInputs, cfg.NumOutputs)
+	}
+	if err := checkPluginPath(path); err != nil {
+		return nil, err
+	}
+	identifier, err := pluginIdentifier(path)
+	if err != nil {
+		return nil, err
+	}
+	cPath := C.CString(identifier)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var layoutSupported C.int
+	handle := C.pedalboard_load_plugin_with_layout(cPath, C.int(cfg.NumInputs), C.int(cfg.NumOutputs), &layoutSupported)
+	if layoutSupported == 0 {
+		return nil, fmt.Errorf("%w: %s with %d inputs and %d outputs", ErrUnsupportedLayout, path, cfg.NumInputs, cfg.NumOutputs)
+	}
+	if handle == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPluginLoadFailed, path)
 	}
 
-	return wrapProcessor(handle), nil
+	p := wrapProcessor(handle)
+	p.recreate = func() (*Processor, error) { return LoadPluginWithConfig(path, cfg) }
+	return p, nil
 }
 
 func wrapProcessor(handle C.PedalboardProcessor) *Processor {
@@ -62,27 +263,107 @@ func wrapProcessor(handle C.PedalboardProcessor) *Processor {
 	return p
 }
 
+// MarkerType identifies the purpose of a Marker.
+type MarkerType int
+
+const (
+	// MarkerBeat marks a beat position.
+	MarkerBeat MarkerType = iota
+	// MarkerCuePoint marks a generic cue point.
+	MarkerCuePoint
+	// MarkerLoopStart marks the start of a loop region.
+	MarkerLoopStart
+	// MarkerLoopEnd marks the end of a loop region.
+	MarkerLoopEnd
+)
+
+// Marker annotates a position in an AudioBuffer.
+type Marker struct {
+	// Name is the marker label.
+	Name string
+	// Position is the marker time relative to the start of the buffer.
+	Position time.Duration
+	// Color is an application-defined colour (e.g., "#ff0000").
+	Color string
+	// Type is the marker purpose.
+	Type MarkerType
+}
+
 // AudioBuffer represents a multi-channel audio buffer in memory.
 type AudioBuffer struct {
 	// Data holds the audio samples as [channel][sample].
-	Data       [][]float32
+	Data [][]float32
 	// SampleRate is the sample rate of the audio data in Hz.
 	SampleRate float64
+	// Markers holds annotations saved to and loaded from audio files.
+	// WAV files keep all fields; AIFF files keep only Name and Position.
+	Markers []Marker
+	// BitDepth is the bits per sample of the file the buffer was loaded from,
+	// or 0 for buffers created in memory. Saving does not use it.
+	BitDepth int
 }
 
 // LoadAudioFile loads an audio file from disk into an AudioBuffer.
 // path: The path to the audio file.
-// Returns an AudioBuffer or an error if loading failed.
+// Returns an AudioBuffer, or an error wrapping ErrUnsupportedFormat if the file exists
+// but could not be decoded.
 func LoadAudioFile(path string) (*AudioBuffer, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to load audio file: %w", err)
+	}
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
 
 	cBuffer := C.pedalboard_load_audio_file(cPath)
 	if cBuffer == nil {
-		return nil, fmt.Errorf("failed to load audio file: %s", path)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
 	}
 	defer C.pedalboard_audio_buffer_free(cBuffer)
+	return audioBufferFromC(cBuffer), nil
+}
+
+// LoadAudioFileFromReader decodes an audio file (WAV, AIFF, FLAC, ...) read in full from r.
+// The format is detected from the data.
+// Returns an AudioBuffer, or an error wrapping ErrUnsupportedFormat if the data could
+// not be decoded.
+func LoadAudioFileFromReader(r io.Reader) (*AudioBuffer, error) {
+	encoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio data: %w", err)
+	}
+	if len(encoded) == 0 {
+		return nil, fmt.Errorf("%w: no audio data", ErrUnsupportedFormat)
+	}
+
+	cBuffer := C.pedalboard_load_audio_data(unsafe.Pointer(&encoded[0]), C.size_t(len(encoded)))
+	if cBuffer == nil {
+		return nil, fmt.Errorf("%w: audio data not recognised", ErrUnsupportedFormat)
+	}
+	defer C.pedalboard_audio_buffer_free(cBuffer)
+	return audioBufferFromC(cBuffer), nil
+}
+
+// LoadAudioFileFromZip decodes the audio file stored as entryName in the zip archive at
+// zipPath, without extracting it to disk.
+// Returns an AudioBuffer, or an error wrapping os.ErrNotExist if the archive has no such
+// entry, or ErrUnsupportedFormat if the entry could not be decoded.
+func LoadAudioFileFromZip(zipPath, entryName string) (*AudioBuffer, error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer archive.Close()
+
+	entry, err := archive.Open(entryName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in %s: %w", entryName, zipPath, err)
+	}
+	defer entry.Close()
+	return LoadAudioFileFromReader(entry)
+}
 
+// audioBufferFromC copies a buffer returned by the C API into Go memory.
+func audioBufferFromC(cBuffer *C.PedalboardAudioBuffer) *AudioBuffer {
 	numChannels := int(cBuffer.num_channels)
 	numSamples := int(cBuffer.num_samples)
 	sampleRate := float64(cBuffer.sample_rate)
@@ -96,23 +377,54 @@ func LoadAudioFile(path string) (*AudioBuffer, error) {
 		copy(data[i], src)
 	}
 
+	var markers []Marker
+	numMarkers := int(cBuffer.num_markers)
+	if numMarkers > 0 && sampleRate > 0 {
+		markers = make([]Marker, numMarkers)
+		for i, cMarker := range unsafe.Slice(cBuffer.markers, numMarkers) {
+			markers[i] = Marker{
+				Name:     C.GoString(cMarker.name),
+				Position: time.Duration(math.Round(float64(cMarker.position) / sampleRate * float64(time.Second))),
+				Color:    C.GoString(cMarker.color),
+				Type:     MarkerType(cMarker._type),
+			}
+		}
+	}
+
 	return &AudioBuffer{
 		Data:       data,
 		SampleRate: sampleRate,
-	}, nil
+		Markers:    markers,
+		BitDepth:   int(cBuffer.bits_per_sample),
+	}
 }
 
-// SaveAudioFile saves an AudioBuffer to a file.
+// SaveAudioFile saves an AudioBuffer to a file as 16-bit audio.
 // path: The output file path. Format is determined by extension (e.g., .wav, .aiff).
 // buffer: The AudioBuffer to save.
 // Returns an error if saving failed.
 func SaveAudioFile(path string, buffer *AudioBuffer) error {
+	return SaveAudioFileWithBitDepth(path, buffer, 16)
+}
+
+// SaveAudioFileWithBitDepth saves an AudioBuffer to a file at the given bit depth.
+// path: The output file path. Format is determined by extension (e.g., .wav, .aiff).
+// buffer: The AudioBuffer to save.
+// bitDepth: Bits per sample: 8, 16, 24 or 32. Not every format supports every depth.
+// Returns an error if saving failed, wrapping ErrSaveFailed if the file could not be written.
+func SaveAudioFileWithBitDepth(path string, buffer *AudioBuffer, bitDepth int) error {
+	switch bitDepth {
+	case 8, 16, 24, 32:
+	default:
+		return fmt.Errorf("%w: bit depth %d", ErrUnsupportedFormat, bitDepth)
+	}
+
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
 
 	numChannels := len(buffer.Data)
 	if numChannels == 0 {
-		return fmt.Errorf("empty buffer")
+		return ErrEmptyBuffer
 	}
 	numSamples := len(buffer.Data[0])
 
@@ -125,59 +437,166 @@ func SaveAudioFile(path string, buffer *AudioBuffer) error {
 	// Allocate pointer array for C
 	cData := (**C.float)(C.malloc(C.size_t(numChannels) * C.size_t(unsafe.Sizeof((*C.float)(nil)))))
 	if cData == nil {
-		return fmt.Errorf("failed to allocate memory")
+		return ErrOutOfMemory
 	}
-	
+
 	cDataSlice := unsafe.Slice(cData, numChannels)
-	
+
 	for i := 0; i < numChannels; i++ {
 		cDataSlice[i] = (*C.float)(unsafe.Pointer(&buffer.Data[i][0]))
 	}
 	cBuffer.data = cData
 
-	C.pedalboard_save_audio_file(cPath, &cBuffer)
-	
+	if len(buffer.Markers) > 0 {
+		numMarkers := len(buffer.Markers)
+		cMarkers := (*C.PedalboardMarker)(C.calloc(C.size_t(numMarkers), C.size_t(unsafe.Sizeof(C.PedalboardMarker{}))))
+		if cMarkers == nil {
+			C.free(unsafe.Pointer(cData))
+			return ErrOutOfMemory
+		}
+		defer C.free(unsafe.Pointer(cMarkers))
+
+		for i, m := range buffer.Markers {
+			cMarker := &unsafe.Slice(cMarkers, numMarkers)[i]
+			cMarker.name = C.CString(m.Name)
+			cMarker.color = C.CString(m.Color)
+			cMarker.position = C.longlong(math.Round(m.Position.Seconds() * buffer.SampleRate))
+			cMarker._type = C.int(m.Type)
+			defer C.free(unsafe.Pointer(cMarker.name))
+			defer C.free(unsafe.Pointer(cMarker.color))
+		}
+		cBuffer.markers = cMarkers
+		cBuffer.num_markers = C.int(numMarkers)
+	}
+
+	ok := C.pedalboard_save_audio_file_with_depth(cPath, &cBuffer, C.int(bitDepth))
+
 	C.free(unsafe.Pointer(cData))
 
+	if ok == 0 {
+		return fmt.Errorf("%w: %s", ErrSaveFailed, path)
+	}
 	return nil
 }
 
+// processContextBlockSize is the largest block ProcessContext passes to the processor
+// at once when its context can be cancelled.
+const processContextBlockSize = 16384
+
 // Process processes a block of audio data through the processor.
 // buffer: The audio data to process (modified in-place).
 // sampleRate: The sample rate of the audio data.
+// Errors are ignored; use ProcessContext to receive them.
 func (p *Processor) Process(buffer [][]float32, sampleRate float64) {
+	p.ProcessContext(context.Background(), buffer, sampleRate)
+}
+
+// ProcessContext processes a block of audio data through the processor like Process,
+// reporting failures. If ctx can be cancelled, large buffers are processed in blocks of
+// up to 16384 samples and ctx is checked before each one; MIDI output (GetMIDIOutput)
+// then covers only the last block.
+// buffer: The audio data to process (modified in-place). All channels must have the
+// same length.
+// sampleRate: The sample rate of the audio data.
+// Returns ctx.Err() if cancelled (the buffer is then only partly processed), or an
+// error if the buffer is invalid or the processor rejects it, e.g. a plugin given
+// fewer channels than it needs.
+func (p *Processor) ProcessContext(ctx context.Context, buffer [][]float32, sampleRate float64) error {
 	numChannels := len(buffer)
 	if numChannels == 0 {
-		return
+		return ErrEmptyBuffer
 	}
 	numSamples := len(buffer[0])
+	for ch := range buffer {
+		if len(buffer[ch]) != numSamples {
+			return fmt.Errorf("%w: channel %d has %d samples, expected %d", ErrInvalidArgument, ch, len(buffer[ch]), numSamples)
+		}
+	}
+	if numSamples == 0 {
+		return nil
+	}
+	if p.sandbox != nil {
+		if p.Bypassed() {
+			return nil
+		}
+		return p.sandbox.process(ctx, buffer, sampleRate)
+	}
+
+	blockSize := numSamples
+	if ctx.Done() != nil {
+		blockSize = processContextBlockSize
+	}
 
 	// Allocate pointer array in C memory to avoid CGO pointer rules violation
 	// (Go pointer to Go pointer in a C call).
 	cPtrs := (**C.float)(C.malloc(C.size_t(numChannels) * C.size_t(unsafe.Sizeof((*C.float)(nil)))))
 	if cPtrs == nil {
-		return
+		return fmt.Errorf("%w: failed to allocate channel pointers", ErrOutOfMemory)
 	}
 	defer C.free(unsafe.Pointer(cPtrs))
-
 	cPtrsSlice := unsafe.Slice(cPtrs, numChannels)
-	for i := 0; i < numChannels; i++ {
-		cPtrsSlice[i] = (*C.float)(unsafe.Pointer(&buffer[i][0]))
+
+	for start := 0; start < numSamples; start += blockSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(blockSize, numSamples-start)
+		for i := 0; i < numChannels; i++ {
+			cPtrsSlice[i] = (*C.float)(unsafe.Pointer(&buffer[i][start]))
+		}
+		ok := C.pedalboard_processor_process(
+			p.handle,
+			cPtrs,
+			C.int(numChannels),
+			C.int(n),
+			C.double(sampleRate),
+		)
+		if ok == 0 {
+			return fmt.Errorf("%w: processor rejected a buffer of %d channels", ErrInvalidArgument, numChannels)
+		}
 	}
+	return nil
+}
 
-	C.pedalboard_processor_process(
-		p.handle,
-		cPtrs,
-		C.int(numChannels),
-		C.int(numSamples),
-		C.double(sampleRate),
-	)
+// SetBypassed bypasses the processor or runs it again. A bypassed processor passes audio
+// through unprocessed wherever it runs: in Process, as a chain stage or in a stream. If
+// the processor reports latency, the bypassed signal is delayed by the same amount so
+// toggling bypass does not shift it in time. The delay is sized when processing starts,
+// so a bypassed chain only compensates for stages it had by then. Sandboxed plugins are
+// bypassed without latency compensation.
+func (p *Processor) SetBypassed(bypassed bool) {
+	cBypassed := C.int(0)
+	if bypassed {
+		cBypassed = 1
+	}
+	C.pedalboard_processor_set_bypassed(p.handle, cBypassed)
 }
 
-// SetParameter sets a parameter value for the processor.
+// Bypassed reports whether the processor is bypassed; see SetBypassed.
+func (p *Processor) Bypassed() bool {
+	return C.pedalboard_processor_is_bypassed(p.handle) != 0
+}
+
+// LatencySamples returns how many samples the processor delays its output by, as it
+// reports it, or for a chain the total of its enabled stages. It can change with the
+// processor's parameters (e.g. PitchShifter reports its FFT size while shifting and 0
+// at 0 semitones). Sandboxed plugins report 0.
+func (p *Processor) LatencySamples() int {
+	return int(C.pedalboard_processor_get_latency_samples(p.handle))
+}
+
+// SetParameter sets a parameter value for the processor, cancelling any ramp pending
+// on it (see SetParameterRampTo).
 // index: The 0-based index of the parameter.
 // value: The new value (typically normalized 0.0 to 1.0).
 func (p *Processor) SetParameter(index int, value float32) {
+	if r := p.recorder.Load(); r != nil {
+		r.record(index, value)
+	}
+	if p.sandbox != nil {
+		p.sandbox.setParameter(index, value)
+		return
+	}
 	C.pedalboard_processor_set_parameter(p.handle, C.int(index), C.float(value))
 }
 
@@ -185,18 +604,383 @@ func (p *Processor) SetParameter(index int, value float32) {
 // index: The 0-based index of the parameter.
 // Returns the parameter value.
 func (p *Processor) GetParameter(index int) float32 {
+	if p.sandbox != nil {
+		return p.sandbox.getParameter(index)
+	}
 	return float32(C.pedalboard_processor_get_parameter(p.handle, C.int(index)))
 }
 
+// SetParameterRampTo schedules a linear ramp of a parameter from its current value to
+// targetValue over the next durationSamples processed samples. Internal processors get
+// a new value every sample; plugins are processed in sub-blocks of 32 samples while a
+// ramp runs, each with the value reached at its end. A ramp may span several Process
+// calls (or stream callbacks); once durationSamples samples have been processed the
+// parameter is exactly targetValue. Calling it again for the same index replaces the
+// pending ramp, starting from the value reached so far, and SetParameter or
+// SetParameterFromText cancels it. A durationSamples of 0 or less sets the value
+// immediately, as does any call on a sandboxed plugin.
+// index: The 0-based index of the parameter.
+// targetValue: The value to ramp to (typically normalized 0.0 to 1.0).
+// durationSamples: The ramp length in samples.
+func (p *Processor) SetParameterRampTo(index int, targetValue float32, durationSamples int) {
+	if p.sandbox != nil {
+		p.SetParameter(index, targetValue)
+		return
+	}
+	C.pedalboard_processor_ramp_parameter(p.handle, C.int(index), C.float(targetValue), C.int(durationSamples))
+}
+
+// SetParameterFromText sets a parameter from its text representation, the way a host
+// does when a user types a value. Plugins parse the text themselves (e.g., "-6.0 dB",
+// "440 Hz"); enumerated parameters accept one of their choice labels, ignoring case;
+// continuous parameters of internal processors accept a value in the range reported by
+// ParameterRange, optionally followed by the ParameterUnit (e.g., "-6 dB" or "-6").
+// Returns an error wrapping ErrInvalidParameter if index is out of range,
+// ErrParseFailure if text is not a valid value for the parameter, or ErrNotSupported
+// for a sandboxed plugin.
+func (p *Processor) SetParameterFromText(index int, text string) error {
+	if p.sandbox != nil {
+		return errSandboxed("SetParameterFromText")
+	}
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+
+	switch C.pedalboard_processor_set_parameter_text(p.handle, C.int(index), cText) {
+	case 1:
+		if r := p.recorder.Load(); r != nil {
+			r.record(index, p.GetParameter(index))
+		}
+		return nil
+	case -1:
+		return fmt.Errorf("%w: index %d (0-%d)", ErrParameterOutOfRange, index, p.NumParameters()-1)
+	default:
+		return fmt.Errorf("%w: %q for parameter %d", ErrParseFailure, text, index)
+	}
+}
+
+// ParameterText returns the text representation of a parameter's current value, as a
+// plugin would display it (e.g., "-6.0 dB"). Internal processors report the choice
+// label of enumerated parameters and otherwise the value in the range reported by
+// ParameterRange, with two decimals and the ParameterUnit (e.g., "440.00 Hz").
+// Returns an error wrapping ErrInvalidParameter if index is out of range, or
+// ErrNotSupported for a sandboxed plugin.
+func (p *Processor) ParameterText(index int) (string, error) {
+	if p.sandbox != nil {
+		return "", errSandboxed("ParameterText")
+	}
+	cText := C.pedalboard_processor_get_parameter_text(p.handle, C.int(index))
+	if cText == nil {
+		return "", fmt.Errorf("%w: index %d (0-%d)", ErrParameterOutOfRange, index, p.NumParameters()-1)
+	}
+	defer C.free(unsafe.Pointer(cText))
+	return C.GoString(cText), nil
+}
+
+// ParameterName returns the name of a parameter as the plugin reports it (e.g.,
+// "Cutoff"). Internal processors don't name their parameters and return "".
+// Returns an error wrapping ErrInvalidParameter if index is out of range.
+func (p *Processor) ParameterName(index int) (string, error) {
+	if p.sandbox != nil {
+		return "", errSandboxed("ParameterName")
+	}
+	cName := C.pedalboard_processor_get_parameter_name(p.handle, C.int(index))
+	if cName == nil {
+		return "", fmt.Errorf("%w: index %d (0-%d)", ErrParameterOutOfRange, index, p.NumParameters()-1)
+	}
+	defer C.free(unsafe.Pointer(cName))
+	return C.GoString(cName), nil
+}
+
+// ParameterRange returns the range of a parameter and its default value, in the units
+// the normalized value (0 to 1) set with SetParameter maps to (e.g., -60 to 0 for a
+// threshold in dB, 20 to 20000 for a cutoff in Hz); ParameterUnit names the unit.
+// Plugins report their NormalisableRange; hosts that only expose normalized values
+// report 0 to 1, as do internal parameters that are used as they are. The "Gain"
+// processor's parameter is a linear factor, from 0 to +Inf.
+// Returns an error wrapping ErrInvalidParameter if index is out of range.
+func (p *Processor) ParameterRange(index int) (min, max, defaultVal float32, err error) {
+	if p.sandbox != nil {
+		return 0, 0, 0, errSandboxed("ParameterRange")
+	}
+	var cMin, cMax, cDefault C.float
+	if C.pedalboard_processor_get_parameter_range(p.handle, C.int(index), &cMin, &cMax, &cDefault) == 0 {
+		return 0, 0, 0, fmt.Errorf("%w: index %d (0-%d)", ErrParameterOutOfRange, index, p.NumParameters()-1)
+	}
+	return float32(cMin), float32(cMax), float32(cDefault), nil
+}
+
+// ParameterUnit returns the unit of a parameter's range (see ParameterRange): "dB",
+// "Hz", "ms", "s", ":1" for ratios, "x" for factors, "cents", "semitones" or "bits" for
+// internal processors, and the plugin's label for plugin parameters. It is empty for
+// parameters without a unit.
+// Returns an error wrapping ErrInvalidParameter if index is out of range.
+func (p *Processor) ParameterUnit(index int) (string, error) {
+	if p.sandbox != nil {
+		return "", errSandboxed("ParameterUnit")
+	}
+	cUnit := C.pedalboard_processor_get_parameter_unit(p.handle, C.int(index))
+	if cUnit == nil {
+		return "", fmt.Errorf("%w: index %d (0-%d)", ErrParameterOutOfRange, index, p.NumParameters()-1)
+	}
+	defer C.free(unsafe.Pointer(cUnit))
+	return C.GoString(cUnit), nil
+}
+
+// SavePreset serializes the processor's full state, including state that parameters
+// don't cover, such as a plugin's proprietary state chunk or a ConvolutionReverb's
+// impulse response. Restore it with LoadPreset on a processor of the same type.
+func (p *Processor) SavePreset() ([]byte, error) {
+	if p.sandbox != nil {
+		return nil, errSandboxed("SavePreset")
+	}
+	var size C.size_t
+	cData := C.pedalboard_processor_get_state(p.handle, &size)
+	if cData == nil {
+		return nil, ErrNoState
+	}
+	defer C.free(cData)
+	return C.GoBytes(cData, C.int(size)), nil
+}
+
+// LoadPreset restores state saved by SavePreset. Internal processors validate the preset
+// and return ErrInvalidPreset, leaving their parameters unchanged, if data is corrupted
+// or was saved by a different processor. Plugins validate their own state and don't
+// report the result; ErrInvalidPreset is returned if a plugin's state is unchanged
+// afterwards although data differs from it, as when it ignores data it doesn't recognise.
+func (p *Processor) LoadPreset(data []byte) error {
+	if p.sandbox != nil {
+		return errSandboxed("LoadPreset")
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("%w: no data", ErrInvalidPreset)
+	}
+	if C.pedalboard_processor_set_state(p.handle, unsafe.Pointer(&data[0]), C.size_t(len(data))) == 0 {
+		return fmt.Errorf("%w: data is not a preset for this processor", ErrInvalidPreset)
+	}
+	return nil
+}
+
+// Reset clears the processor's internal state, such as delay lines, reverb tails and
+// envelopes, so the next Process call starts from silence. Parameters are unchanged.
+// Don't call it while an AudioStream is running the processor directly; resetting a
+// running ProcessorChain is safe.
+func (p *Processor) Reset() {
+	if p.sandbox != nil {
+		p.sandbox.reset()
+		return
+	}
+	C.pedalboard_processor_reset(p.handle)
+}
+
+// Trigger engages or releases the processor's momentary action. For "Freeze",
+// Trigger(true) captures the current spectrum and holds it until Trigger(false).
+// Returns an error wrapping ErrNotSupported if the processor has no triggerable action.
+func (p *Processor) Trigger(enable bool) error {
+	if p.sandbox != nil {
+		return errSandboxed("Trigger")
+	}
+	cEnable := C.int(0)
+	if enable {
+		cEnable = 1
+	}
+	if C.pedalboard_processor_trigger(p.handle, cEnable) == 0 {
+		return fmt.Errorf("%w: processor does not support Trigger", ErrNotSupported)
+	}
+	return nil
+}
+
+// GetEnvelopeLevel returns the current peak envelope (linear, 0 = silence) of channel for
+// processors that track one, such as "WaveformFollower". It is safe to call while a
+// stream is running the processor, e.g. to drive a level display.
+// Returns 0 if the processor has no envelope, as for a sandboxed plugin, or channel is
+// out of range.
+func (p *Processor) GetEnvelopeLevel(channel int) float32 {
+	if p.sandbox != nil {
+		return 0
+	}
+	return float32(C.pedalboard_processor_get_envelope_level(p.handle, C.int(channel)))
+}
+
 // NumParameters returns the total number of parameters available in the processor.
 func (p *Processor) NumParameters() int {
+	if p.sandbox != nil {
+		return p.sandbox.numParams
+	}
 	return int(C.pedalboard_processor_get_num_parameters(p.handle))
 }
 
+// GetParameterChoices returns the option labels of an enumerated parameter, in order of
+// increasing value, for example "Sine", "Triangle" and "Square" for the Tremolo shape.
+// Returns an empty slice for continuous parameters, or an error if index is out of range
+// or, wrapping ErrNotSupported, for a sandboxed plugin.
+func (p *Processor) GetParameterChoices(index int) ([]string, error) {
+	if p.sandbox != nil {
+		return nil, errSandboxed("GetParameterChoices")
+	}
+	list := C.pedalboard_processor_get_parameter_choices(p.handle, C.int(index))
+	if list == nil {
+		return nil, fmt.Errorf("%w: index %d (0-%d)", ErrParameterOutOfRange, index, p.NumParameters()-1)
+	}
+	defer C.pedalboard_string_list_free(list)
+
+	choices := make([]string, int(list.count))
+	if len(choices) > 0 {
+		for i, item := range unsafe.Slice(list.items, len(choices)) {
+			choices[i] = C.GoString(item)
+		}
+	}
+	return choices, nil
+}
+
+// SetParameterChoice sets an enumerated parameter by its option label, as returned by
+// GetParameterChoices. Choice i of n is set as the normalized value i/(n-1).
+// Returns an error if the parameter is not enumerated or choice is not one of its options.
+func (p *Processor) SetParameterChoice(index int, choice string) error {
+	choices, err := p.GetParameterChoices(index)
+	if err != nil {
+		return err
+	}
+	if len(choices) == 0 {
+		return fmt.Errorf("%w: parameter %d is not enumerated", ErrInvalidParameter, index)
+	}
+	for i, c := range choices {
+		if c == choice {
+			value := float32(0)
+			if len(choices) > 1 {
+				value = float32(i) / float32(len(choices)-1)
+			}
+			p.SetParameter(index, value)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: parameter %d has no choice %q (choices: %v)", ErrInvalidParameter, index, choice, choices)
+}
+
+// MIDIEvent is a raw MIDI message.
+type MIDIEvent struct {
+	// Data holds the raw MIDI bytes (e.g., {0x90, 60, 100} for a note-on).
+	Data []byte
+	// SampleOffset is the position of the event within a processing block, in samples.
+	SampleOffset int
+	// Timestamp is the arrival time of a live event relative to when MIDI input was opened.
+	Timestamp time.Duration
+	// Status is the status byte, including the channel nibble for channel messages.
+	Status byte
+	// Data1 is the first data byte (e.g., the note number), or 0 if absent.
+	Data1 byte
+	// Data2 is the second data byte (e.g., the velocity), or 0 if absent.
+	Data2 byte
+	// Channel is the MIDI channel (1-16) for channel messages, or 0 for system messages.
+	Channel int
+}
+
+// newMIDIEvent builds a MIDIEvent from raw bytes, filling in the decoded fields.
+func newMIDIEvent(data []byte, sampleOffset int, timestamp time.Duration) MIDIEvent {
+	ev := MIDIEvent{Data: data, SampleOffset: sampleOffset, Timestamp: timestamp}
+	if len(data) > 0 {
+		ev.Status = data[0]
+		if ev.Status >= 0x80 && ev.Status < 0xF0 {
+			ev.Channel = int(ev.Status&0x0F) + 1
+		}
+	}
+	if len(data) > 1 {
+		ev.Data1 = data[1]
+	}
+	if len(data) > 2 {
+		ev.Data2 = data[2]
+	}
+	return ev
+}
+
+// SendMIDI queues MIDI events to be delivered to the processor on the next Process call.
+// Sandboxed plugins don't receive MIDI; events sent to them are dropped.
+// events: The events to queue. Events with empty Data are ignored.
+func (p *Processor) SendMIDI(events []MIDIEvent) {
+	if p.sandbox != nil {
+		return
+	}
+	for _, ev := range events {
+		if len(ev.Data) == 0 {
+			continue
+		}
+		C.pedalboard_processor_add_midi_event(
+			p.handle,
+			(*C.uchar)(unsafe.Pointer(&ev.Data[0])),
+			C.int(len(ev.Data)),
+			C.int(ev.SampleOffset),
+		)
+	}
+}
+
+// GetMIDIOutput returns the MIDI events produced by the processor during the last Process call.
+// It returns nil for sandboxed plugins.
+func (p *Processor) GetMIDIOutput() []MIDIEvent {
+	if p.sandbox != nil {
+		return nil
+	}
+	cList := C.pedalboard_processor_get_midi_output(p.handle)
+	if cList == nil {
+		return nil
+	}
+	defer C.pedalboard_midi_event_list_free(cList)
+
+	numEvents := int(cList.num_events)
+	if numEvents == 0 {
+		return nil
+	}
+
+	events := make([]MIDIEvent, numEvents)
+	for i, cEv := range unsafe.Slice(cList.events, numEvents) {
+		events[i] = newMIDIEvent(C.GoBytes(unsafe.Pointer(cEv.data), cEv.size), int(cEv.sample_offset), 0)
+	}
+	return events
+}
+
 // AudioStream represents a live audio stream processing audio from default input to output.
 type AudioStream struct {
 	handle    C.PedalboardAudioStream
 	processor *Processor // Keep reference to prevent GC
+
+	midiInputID string
+	midiOnce    sync.Once
+	midiCh      chan MIDIEvent
+	midiDropped atomic.Int64
+
+	vuInterval time.Duration
+	vuOnce     sync.Once
+	vuCallback atomic.Pointer[func(inputLevels, outputLevels []float32)]
+
+	mu         sync.Mutex // Guards handle, xrunHandle and starting or stopping the stream
+	xrunHandle cgo.Handle
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup // Tracks goroutines that use handle
+}
+
+// AudioStreamConfig describes the devices and format used by an AudioStream.
+// Zero values select the system defaults.
+type AudioStreamConfig struct {
+	// InputDeviceID is the AudioDevice.ID of the input device. Empty selects the default input.
+	InputDeviceID string
+	// OutputDeviceID is the AudioDevice.ID of the output device. Empty selects the default output.
+	OutputDeviceID string
+	// SampleRate is the requested sample rate in Hz. Zero selects the device default.
+	SampleRate float64
+	// BufferSize is the requested block size in samples. Zero selects the device default.
+	BufferSize int
+	// NumInputChannels is the number of input channels to open. Zero opens two. A single
+	// input is copied to every output channel; otherwise extra outputs start silent.
+	NumInputChannels int
+	// NumOutputChannels is the number of output channels to open. Zero opens two.
+	NumOutputChannels int
+	// MIDIInputDeviceID is the MIDI input used by AudioStream.MIDIIn. Empty selects the default MIDI input.
+	MIDIInputDeviceID string
+	// VUMeterInterval is how often the callback set with AudioStream.SetVUMeterCallback
+	// is called. Zero selects 50 ms.
+	VUMeterInterval time.Duration
+	// Processor is the processor applied to the stream.
+	Processor *Processor
 }
 
 // NewAudioStream creates a new audio stream using the specified processor.
@@ -204,43 +988,291 @@ type AudioStream struct {
 // processor: The processor to apply to the audio stream.
 // Returns the AudioStream instance or an error.
 func NewAudioStream(processor *Processor) (*AudioStream, error) {
-	handle := C.pedalboard_create_audio_stream(processor.handle)
+	return NewAudioStreamWithConfig(AudioStreamConfig{Processor: processor})
+}
+
+// NewAudioStreamWithConfig creates a new audio stream with explicit device,
+// sample rate and buffer size selection.
+// cfg: The stream configuration. Device IDs come from EnumerateInputDevices and EnumerateOutputDevices.
+// Returns the AudioStream instance, ErrDeviceNotFound if a device ID does not exist, or an
+// error wrapping ErrDeviceFailed if the devices could not be opened.
+func NewAudioStreamWithConfig(cfg AudioStreamConfig) (*AudioStream, error) {
+	if cfg.Processor == nil {
+		return nil, fmt.Errorf("%w: audio stream requires a processor", ErrInvalidArgument)
+	}
+	if cfg.Processor.sandbox != nil {
+		return nil, fmt.Errorf("%w: a sandboxed plugin cannot run in an audio stream", ErrInvalidArgument)
+	}
+
+	var cConfig C.PedalboardAudioStreamConfig
+	if cfg.InputDeviceID != "" {
+		cConfig.input_device_id = C.CString(cfg.InputDeviceID)
+		defer C.free(unsafe.Pointer(cConfig.input_device_id))
+	}
+	if cfg.OutputDeviceID != "" {
+		cConfig.output_device_id = C.CString(cfg.OutputDeviceID)
+		defer C.free(unsafe.Pointer(cConfig.output_device_id))
+	}
+	cConfig.sample_rate = C.double(cfg.SampleRate)
+	cConfig.buffer_size = C.int(cfg.BufferSize)
+	cConfig.num_input_channels = C.int(cfg.NumInputChannels)
+	cConfig.num_output_channels = C.int(cfg.NumOutputChannels)
+
+	var cErr C.int
+	handle := C.pedalboard_create_audio_stream_with_config(cfg.Processor.handle, &cConfig, &cErr)
 	if handle == nil {
-		return nil, fmt.Errorf("failed to create audio stream")
+		if cErr == C.PEDALBOARD_ERR_DEVICE_NOT_FOUND {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, fmt.Errorf("%w: failed to create audio stream", ErrDeviceFailed)
 	}
-	return &AudioStream{handle: handle, processor: processor}, nil
+	return &AudioStream{
+		handle:      handle,
+		processor:   cfg.Processor,
+		midiInputID: cfg.MIDIInputDeviceID,
+		vuInterval:  cfg.VUMeterInterval,
+		done:        make(chan struct{}),
+	}, nil
 }
 
 // Start starts the audio processing on the stream.
-func (s *AudioStream) Start() {
-	C.pedalboard_audio_stream_start(s.handle)
+// Returns ErrStreamClosed if the stream has been closed, or ErrDeviceFailed if it has
+// no open device.
+func (s *AudioStream) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.startLocked()
+}
+
+// startLocked starts the stream. s.mu must be held.
+func (s *AudioStream) startLocked() error {
+	if s.handle == nil {
+		return ErrStreamClosed
+	}
+	if C.pedalboard_audio_stream_start(s.handle) != C.PEDALBOARD_OK {
+		return fmt.Errorf("%w: failed to start audio stream", ErrDeviceFailed)
+	}
+	return nil
 }
 
 // Stop stops the audio processing on the stream.
-func (s *AudioStream) Stop() {
+// Returns ErrStreamClosed if the stream has been closed.
+func (s *AudioStream) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle == nil {
+		return ErrStreamClosed
+	}
 	C.pedalboard_audio_stream_stop(s.handle)
+	return nil
+}
+
+// RunContext starts the stream and blocks until ctx is cancelled, then stops and
+// closes the stream. It pairs naturally with signal.NotifyContext for clean shutdown:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	err := stream.RunContext(ctx)
+//
+// Returns ErrAlreadyRunning if the stream has already been started, ErrStreamClosed if
+// it has been closed, or the error starting or stopping it.
+func (s *AudioStream) RunContext(ctx context.Context) error {
+	s.mu.Lock()
+	if s.handle != nil && C.pedalboard_audio_stream_is_running(s.handle) != 0 {
+		s.mu.Unlock()
+		return ErrAlreadyRunning
+	}
+	err := s.startLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	err = s.Stop()
+	s.Close()
+	return err
+}
+
+// SetBufferSize changes the block size of the audio callback, trading latency for stability.
+// The stream is stopped, the device reconfigured and the stream restarted, which causes
+// a brief audio dropout.
+// samples: The new block size in samples. Must be supported by the current device.
+// Returns an error wrapping ErrInvalidArgument if the size is not supported,
+// ErrDeviceFailed if the device could not be reconfigured, or ErrStreamClosed if the
+// stream has been closed.
+func (s *AudioStream) SetBufferSize(samples int) error {
+	if samples <= 0 {
+		return fmt.Errorf("%w: buffer size %d", ErrInvalidArgument, samples)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle == nil {
+		return ErrStreamClosed
+	}
+	switch C.pedalboard_audio_stream_set_buffer_size(s.handle, C.int(samples)) {
+	case C.PEDALBOARD_OK:
+		return nil
+	case C.PEDALBOARD_ERR_UNSUPPORTED:
+		return fmt.Errorf("%w: buffer size %d not supported by device", ErrInvalidArgument, samples)
+	default:
+		return fmt.Errorf("%w: failed to set buffer size to %d", ErrDeviceFailed, samples)
+	}
+}
+
+// Latency returns the round-trip input-to-output latency of the stream.
+// It is the sum of the input and output device latencies (including buffering)
+// and any latency reported by the processor. The value reflects the current
+// buffer size, so it changes after SetBufferSize.
+func (s *AudioStream) Latency() time.Duration {
+	seconds := float64(C.pedalboard_audio_stream_get_latency(s.handle))
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// PeakLevels returns the held absolute peak per input and output channel since
+// the stream started or the last ResetPeakHold call.
+// The audio callback updates the values lock-free after each block, so it is
+// safe to call from any goroutine while the stream is running.
+func (s *AudioStream) PeakLevels() (inputPeaks, outputPeaks []float32) {
+	var cIn, cOut [C.PEDALBOARD_MAX_METER_CHANNELS]C.float
+	var numIn, numOut C.int
+	C.pedalboard_audio_stream_get_peak_levels(s.handle, &cIn[0], &cOut[0], &numIn, &numOut)
+
+	inputPeaks = make([]float32, int(numIn))
+	for i := range inputPeaks {
+		inputPeaks[i] = float32(cIn[i])
+	}
+	outputPeaks = make([]float32, int(numOut))
+	for i := range outputPeaks {
+		outputPeaks[i] = float32(cOut[i])
+	}
+	return inputPeaks, outputPeaks
+}
+
+// ResetPeakHold resets the held peak values reported by PeakLevels.
+func (s *AudioStream) ResetPeakHold() {
+	C.pedalboard_audio_stream_reset_peak_hold(s.handle)
+}
+
+// closing reports whether Close has been called. The handle stays valid until the
+// goroutines tracked by wg have finished.
+func (s *AudioStream) closing() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // Close releases the audio stream resources.
 func (s *AudioStream) Close() {
-	C.pedalboard_audio_stream_free(s.handle)
+	s.closeOnce.Do(func() {
+		// Closed under the lock, so no goroutine is added to wg once Wait has begun
+		s.mu.Lock()
+		close(s.done)
+		s.mu.Unlock()
+		s.wg.Wait()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.setXrunCallbackLocked(nil)
+		C.pedalboard_audio_stream_free(s.handle)
+		s.handle = nil
+	})
+}
+
+// AudioDevice describes an audio input or output device available on the system.
+type AudioDevice struct {
+	// ID uniquely identifies the device, including its driver type (e.g., "CoreAudio:Built-in Microphone").
+	ID string
+	// Name is the human-readable device name.
+	Name string
+	// MaxChannels is the number of input or output channels the device provides.
+	MaxChannels int
+	// SupportedSampleRates lists the sample rates the device can run at, in Hz.
+	SupportedSampleRates []float64
+	// IsDefault reports whether this is the system default device.
+	IsDefault bool
+}
+
+// EnumerateInputDevices returns the audio input devices available on the system.
+// Returns an empty slice if no devices are found.
+func EnumerateInputDevices() ([]AudioDevice, error) {
+	return enumerateAudioDevices(true)
+}
+
+// EnumerateOutputDevices returns the audio output devices available on the system.
+// Returns an empty slice if no devices are found.
+func EnumerateOutputDevices() ([]AudioDevice, error) {
+	return enumerateAudioDevices(false)
 }
 
-// GetInputDevices returns a list of available input device names.
-// Currently returns a dummy list.
+func enumerateAudioDevices(input bool) ([]AudioDevice, error) {
+	isInput := C.int(0)
+	if input {
+		isInput = 1
+	}
+
+	cList := C.pedalboard_enumerate_audio_devices(isInput)
+	if cList == nil {
+		return nil, fmt.Errorf("%w: failed to enumerate audio devices", ErrDeviceFailed)
+	}
+	defer C.pedalboard_audio_device_list_free(cList)
+
+	numDevices := int(cList.num_devices)
+	devices := make([]AudioDevice, 0, numDevices)
+	if numDevices == 0 {
+		return devices, nil
+	}
+
+	for _, cDev := range unsafe.Slice(cList.devices, numDevices) {
+		dev := AudioDevice{
+			ID:          C.GoString(cDev.id),
+			Name:        C.GoString(cDev.name),
+			MaxChannels: int(cDev.max_channels),
+			IsDefault:   cDev.is_default != 0,
+		}
+		numRates := int(cDev.num_sample_rates)
+		if numRates > 0 {
+			dev.SupportedSampleRates = make([]float64, numRates)
+			for i, rate := range unsafe.Slice(cDev.sample_rates, numRates) {
+				dev.SupportedSampleRates[i] = float64(rate)
+			}
+		}
+		devices = append(devices, dev)
+	}
+	return devices, nil
+}
+
+// GetInputDevices returns the names of the audio input devices available on the system.
 func GetInputDevices() ([]string, error) {
-	return []string{"Default Input"}, nil
+	return audioDeviceNames(EnumerateInputDevices())
 }
 
-// GetOutputDevices returns a list of available output device names.
-// Currently returns a dummy list.
+// GetOutputDevices returns the names of the audio output devices available on the system.
 func GetOutputDevices() ([]string, error) {
-	return []string{"Default Output"}, nil
+	return audioDeviceNames(EnumerateOutputDevices())
+}
+
+func audioDeviceNames(devices []AudioDevice, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(devices))
+	for i, dev := range devices {
+		names[i] = dev.Name
+	}
+	return names, nil
 }
 
 // NewAudioStreamWithDevices creates a new audio stream using the specified processor and devices.
-// Currently ignores device names and uses defaults.
+// inputDevice, outputDevice: Device names as returned by GetInputDevices and
+// GetOutputDevices. An empty name selects the default device.
+// Returns ErrDeviceNotFound if a named device is not available.
 func NewAudioStreamWithDevices(processor *Processor, inputDevice, outputDevice string) (*AudioStream, error) {
-	// TODO: Implement device selection in C++ layer
-	return NewAudioStream(processor)
-}
\ No newline at end of file
+	cfg, err := AudioSettings{InputDeviceName: inputDevice, OutputDeviceName: outputDevice}.StreamConfig(processor)
+	if err != nil {
+		return nil, err
+	}
+	return NewAudioStreamWithConfig(cfg)
+}
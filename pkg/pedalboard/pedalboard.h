@@ -16,26 +16,192 @@ void pedalboard_init();
 
 // Processor management
 PedalboardProcessor pedalboard_create_internal_processor(const char* name);
+// Loads a plugin from a VST3 or AU path, or from an LV2 plugin URI.
 PedalboardProcessor pedalboard_load_plugin(const char* path);
+// Loads a plugin like pedalboard_load_plugin with a main input bus of num_inputs channels
+// (0 disables it) and a main output bus of num_outputs channels. Returns NULL and sets
+// *layout_supported to 0 if the plugin loaded but does not support that layout.
+PedalboardProcessor pedalboard_load_plugin_with_layout(const char* path, int num_inputs, int num_outputs, int* layout_supported);
 void pedalboard_processor_free(PedalboardProcessor processor);
 void pedalboard_processor_set_parameter(PedalboardProcessor processor, int index, float value);
 float pedalboard_processor_get_parameter(PedalboardProcessor processor, int index);
+// Schedules a linear ramp from the current value to target over duration_samples processed samples.
+// Replaces any pending ramp on the same parameter; duration_samples <= 0 sets the value immediately.
+void pedalboard_processor_ramp_parameter(PedalboardProcessor processor, int index, float target, int duration_samples);
 int pedalboard_processor_get_num_parameters(PedalboardProcessor processor);
+// Serializes the processor's full state, including state that is not exposed as
+// parameters, such as plugin state chunks. Returns a buffer of *size bytes to free with
+// free(), or NULL if the processor has no state.
+void* pedalboard_processor_get_state(PedalboardProcessor processor, size_t* size);
+// Restores state written by pedalboard_processor_get_state. Returns 1 on success, 0 if
+// data is not valid state for the processor. A plugin is taken to have rejected data if
+// its state is unchanged afterwards although data differs from it.
+int pedalboard_processor_set_state(PedalboardProcessor processor, const void* data, size_t size);
+// Reports the range of a parameter and its default value in the same units. Plugin
+// parameters report their NormalisableRange; internal processors report the range their
+// normalized values map to (e.g., 20 to 20000 Hz). Returns 0 if index is out of range.
+int pedalboard_processor_get_parameter_range(PedalboardProcessor processor, int index, float* min, float* max, float* default_value);
+// Returns the unit of a parameter's range, such as "dB" or "Hz"; empty if it has none.
+// Returns NULL if index is out of range. Free the result with free().
+char* pedalboard_processor_get_parameter_unit(PedalboardProcessor processor, int index);
+// Sets a parameter from its text representation, such as "-6.0 dB" or a choice label.
+// Returns 1 if set, 0 if text is not a valid value for the parameter, -1 if index is out of range.
+int pedalboard_processor_set_parameter_text(PedalboardProcessor processor, int index, const char* text);
+// Returns the name of a parameter as the plugin reports it (empty for internal processors),
+// or NULL if index is out of range. Free the result with free().
+char* pedalboard_processor_get_parameter_name(PedalboardProcessor processor, int index);
+// Returns the text representation of a parameter's current value, or NULL if index is
+// out of range. Free the result with free().
+char* pedalboard_processor_get_parameter_text(PedalboardProcessor processor, int index);
+// Clears internal state such as delay lines, reverb tails and envelopes. Parameters are
+// unchanged. For a chain, every stage is reset.
+void pedalboard_processor_reset(PedalboardProcessor processor);
+
+typedef struct {
+    char** items;
+    int count;
+} PedalboardStringList;
+
+// Returns the labels of an enumerated parameter (choice i of count is selected by the
+// value i / (count - 1)). The list is empty for continuous parameters. Returns NULL if
+// index is out of range. Free the result with pedalboard_string_list_free.
+PedalboardStringList* pedalboard_processor_get_parameter_choices(PedalboardProcessor processor, int index);
+
+// Frees a list returned by the pedalboard API.
+void pedalboard_string_list_free(PedalboardStringList* list);
+
+// Makes the LV2 bundles in dir known to the LV2 host so their plugin URIs can be loaded,
+// and returns the URIs of all plugins the host knows. Returns NULL if LV2 hosting is not
+// available in this build. Free the result with pedalboard_string_list_free.
+PedalboardStringList* pedalboard_lv2_search_path(const char* dir);
+
+typedef struct {
+    char* name;
+    char* vendor;
+    char* category;
+    char* unique_id;
+    char* version;
+} PedalboardPluginDescription;
+
+// Instantiates the first plugin found at path (or LV2 plugin URI) to verify it loads, then releases it and
+// returns its description. Returns NULL if no plugin could be loaded. Free the result
+// with pedalboard_plugin_description_free.
+PedalboardPluginDescription* pedalboard_describe_plugin(const char* path);
+void pedalboard_plugin_description_free(PedalboardPluginDescription* description);
+
+// Describes the first component an Audio Unit bundle lists without loading it. The
+// bundle's Info dictionary is read with CFBundle (XML or binary); the name and version
+// come from AudioComponentCopyName and AudioComponentGetVersion when the component is
+// registered, and from the dictionary otherwise. name is the full component name
+// ("Vendor: Name"), vendor is empty, category is the component type code and unique_id
+// is the ID the AU format gives the plugin. Sets *supported to 0 and returns NULL outside
+// macOS; returns NULL if the bundle lists no components. Free the result with
+// pedalboard_plugin_description_free.
+PedalboardPluginDescription* pedalboard_describe_audio_unit_bundle(const char* path, int* supported);
+
+// Engages (enable != 0) or releases a processor's momentary action, such as Freeze.
+// Returns 1 if the processor supports triggering, 0 otherwise.
+int pedalboard_processor_trigger(PedalboardProcessor processor, int enable);
+
+// Returns the current envelope level (linear peak) of channel for processors that track
+// one, such as WaveformFollower. Returns 0 if unsupported or channel is out of range.
+float pedalboard_processor_get_envelope_level(PedalboardProcessor processor, int channel);
+
+// Returns the number of samples the processor has processed since it was created, whether
+// directly, as a chain stage or in a stream. Safe to call while the processor is running.
+int64_t pedalboard_processor_get_processed_samples(PedalboardProcessor processor);
+
+// Returns the latency the processor reports in samples, or for a chain the total of its
+// enabled stages. It can change with the processor's parameters.
+int pedalboard_processor_get_latency_samples(PedalboardProcessor processor);
+
+// Bypasses the processor (bypassed != 0) or runs it again. A bypassed processor passes
+// audio through, delayed by the latency it reports, wherever it is processed. A bypassed
+// chain delays audio by the total latency of its enabled stages. The delay is sized when
+// the processor is prepared; latency beyond that passes through undelayed.
+void pedalboard_processor_set_bypassed(PedalboardProcessor processor, int bypassed);
+int pedalboard_processor_is_bypassed(PedalboardProcessor processor);
+
+// Creates a "ConvolutionReverb" processor from an impulse response of num_channels
+// arrays of num_samples, recorded at sample_rate. The data is copied.
+PedalboardProcessor pedalboard_create_convolution_reverb(float** impulse_response, int num_channels, int num_samples, double sample_rate);
+
+// Creates a processor that passes each block to goPedalboardProcessExternal with
+// context, for processors implemented outside the library.
+PedalboardProcessor pedalboard_create_external_processor(uintptr_t context);
+
+// Processor chains
+// A chain is itself a processor that runs its stages in series. Stages are not
+// owned by the chain and must outlive it. Changes are safe while a stream is running.
+PedalboardProcessor pedalboard_create_chain();
+
+// Inserts processor at index (clamped to the valid range). Returns 1 on success.
+int pedalboard_chain_insert(PedalboardProcessor chain, int index, PedalboardProcessor processor);
+
+// Removes the stage at index. Returns 1 on success, 0 if index is out of range.
+int pedalboard_chain_remove(PedalboardProcessor chain, int index);
+
+// Atomically replaces the stage at index. Returns 1 on success, 0 if index is out of range.
+int pedalboard_chain_replace(PedalboardProcessor chain, int index, PedalboardProcessor processor);
+
+// Enables (1) or disables (0) the stage at index; disabled stages are skipped.
+// Returns 1 on success, 0 if index is out of range.
+int pedalboard_chain_set_stage_enabled(PedalboardProcessor chain, int index, int enabled);
 
 // Audio processing
 // samples is a pointer to an array of float pointers (one per channel)
-void pedalboard_processor_process(PedalboardProcessor processor, float** samples, int num_channels, int num_samples, double sample_rate);
+// Returns 1 on success, 0 if the processor is NULL or the buffer has fewer channels
+// than a plugin's buses need.
+int pedalboard_processor_process(PedalboardProcessor processor, float** samples, int num_channels, int num_samples, double sample_rate);
+
+// MIDI
+typedef struct {
+    unsigned char* data;
+    int size;
+    int sample_offset;
+} PedalboardMidiEvent;
+
+typedef struct {
+    PedalboardMidiEvent* events;
+    int num_events;
+} PedalboardMidiEventList;
+
+// Queues a MIDI event to be delivered to the processor on the next process call.
+void pedalboard_processor_add_midi_event(PedalboardProcessor processor, const unsigned char* data, int size, int sample_offset);
+
+// Returns the MIDI events produced by the last process call.
+PedalboardMidiEventList* pedalboard_processor_get_midi_output(PedalboardProcessor processor);
+
+// Frees a list returned by pedalboard_processor_get_midi_output.
+void pedalboard_midi_event_list_free(PedalboardMidiEventList* list);
 
 // Audio File IO
+typedef struct {
+    char* name;
+    long long position; // offset in samples
+    int type;
+    char* color;
+} PedalboardMarker;
+
 typedef struct {
     float** data;
     int num_channels;
     int num_samples;
     double sample_rate;
+    PedalboardMarker* markers;
+    int num_markers;
+    int bits_per_sample; // of the decoded file; 0 for buffers not read from a file
 } PedalboardAudioBuffer;
 
 PedalboardAudioBuffer* pedalboard_load_audio_file(const char* path);
-void pedalboard_save_audio_file(const char* path, PedalboardAudioBuffer* buffer);
+// Decodes an audio file held in memory (size bytes at data). The data is only read
+// during the call. Returns NULL if the format is not recognised.
+PedalboardAudioBuffer* pedalboard_load_audio_data(const void* data, size_t size);
+// Writes buffer to path in the format its extension selects. Returns 1 on success, 0 if
+// the file could not be written.
+int pedalboard_save_audio_file(const char* path, PedalboardAudioBuffer* buffer);
+// Same as pedalboard_save_audio_file, writing bits_per_sample bits per sample (e.g., 16, 24, 32).
+int pedalboard_save_audio_file_with_depth(const char* path, PedalboardAudioBuffer* buffer, int bits_per_sample);
 void pedalboard_audio_buffer_free(PedalboardAudioBuffer* buffer);
 
 // Audio Stream (Live IO)
@@ -45,15 +211,128 @@ typedef void* PedalboardAudioStream;
 // through the given processor and sends it to the default output device.
 PedalboardAudioStream pedalboard_create_audio_stream(PedalboardProcessor processor);
 
+// Error codes reported by the audio stream functions.
+#define PEDALBOARD_OK 0
+#define PEDALBOARD_ERR_DEVICE_NOT_FOUND 1
+#define PEDALBOARD_ERR_DEVICE_OPEN 2
+#define PEDALBOARD_ERR_UNSUPPORTED 3
+
+typedef struct {
+    const char* input_device_id;   // NULL or empty for the default device
+    const char* output_device_id;  // NULL or empty for the default device
+    double sample_rate;            // 0 for the device default
+    int buffer_size;               // 0 for the device default
+    int num_input_channels;        // 0 for stereo
+    int num_output_channels;       // 0 for stereo
+} PedalboardAudioStreamConfig;
+
+typedef struct {
+    unsigned char data[3];
+    int size;
+    double timestamp; // seconds since the MIDI input was opened
+} PedalboardMidiInputEvent;
+
+// Creates an audio stream using the given device configuration.
+// On failure returns NULL and stores one of the PEDALBOARD_ERR_* codes in error.
+PedalboardAudioStream pedalboard_create_audio_stream_with_config(PedalboardProcessor processor, const PedalboardAudioStreamConfig* config, int* error);
+
 // Starts the audio stream.
-void pedalboard_audio_stream_start(PedalboardAudioStream stream);
+// Returns PEDALBOARD_OK, or PEDALBOARD_ERR_DEVICE_OPEN if the stream has no open device.
+int pedalboard_audio_stream_start(PedalboardAudioStream stream);
 
 // Stops the audio stream.
 void pedalboard_audio_stream_stop(PedalboardAudioStream stream);
 
+// Changes the device block size, restarting the stream if it was running.
+// Returns PEDALBOARD_OK or one of the PEDALBOARD_ERR_* codes.
+int pedalboard_audio_stream_set_buffer_size(PedalboardAudioStream stream, int samples);
+
+// Returns the round-trip input-to-output latency of the stream in seconds,
+// including any latency reported by the processor.
+double pedalboard_audio_stream_get_latency(PedalboardAudioStream stream);
+
+// Maximum number of channels per direction reported by the peak meters.
+#define PEDALBOARD_MAX_METER_CHANNELS 32
+
+// Copies the held absolute peak per channel into input_peaks and output_peaks
+// (each at least PEDALBOARD_MAX_METER_CHANNELS long) and stores the channel counts.
+// Safe to call while the stream is running.
+void pedalboard_audio_stream_get_peak_levels(PedalboardAudioStream stream, float* input_peaks, float* output_peaks,
+                                             int* num_inputs, int* num_outputs);
+
+// Copies the absolute peak per channel since the previous call, as
+// pedalboard_audio_stream_get_peak_levels does, and resets those peaks. The held peaks
+// are not affected. Safe to call while the stream is running.
+void pedalboard_audio_stream_take_meter_levels(PedalboardAudioStream stream, float* input_peaks, float* output_peaks,
+                                               int* num_inputs, int* num_outputs);
+
+// Resets the held peak values to zero.
+void pedalboard_audio_stream_reset_peak_hold(PedalboardAudioStream stream);
+
+// Returns 1 if the stream has been started and not stopped, 0 otherwise.
+int pedalboard_audio_stream_is_running(PedalboardAudioStream stream);
+
+// Returns the number of active input channels on the stream's device.
+int pedalboard_audio_stream_get_num_input_channels(PedalboardAudioStream stream);
+
+// Returns the current sample rate of the stream's device, or 0 if no device is open.
+double pedalboard_audio_stream_get_sample_rate(PedalboardAudioStream stream);
+
+// Starts copying input samples into a caller-allocated ring buffer of
+// num_channels x capacity floats. The buffer must stay valid until
+// pedalboard_audio_stream_end_capture returns.
+void pedalboard_audio_stream_begin_capture(PedalboardAudioStream stream, float** channels, int num_channels, int capacity);
+
+// Returns the total number of samples per channel written to the capture ring.
+long long pedalboard_audio_stream_get_captured_samples(PedalboardAudioStream stream);
+
+// Stops capturing. After this returns the audio thread no longer touches the ring.
+void pedalboard_audio_stream_end_capture(PedalboardAudioStream stream);
+
+// Opens a MIDI input device for the stream. identifier may be NULL for the default device.
+// Incoming short messages are queued until read with pedalboard_audio_stream_read_midi_input.
+// Returns PEDALBOARD_OK or one of the PEDALBOARD_ERR_* codes.
+int pedalboard_audio_stream_open_midi_input(PedalboardAudioStream stream, const char* identifier);
+
+// Copies up to max_events queued MIDI input events into events and returns the count.
+int pedalboard_audio_stream_read_midi_input(PedalboardAudioStream stream, PedalboardMidiInputEvent* events, int max_events);
+
+// Returns the number of MIDI input events dropped because the queue was full.
+long long pedalboard_audio_stream_get_dropped_midi_events(PedalboardAudioStream stream);
+
+// Xrun types passed to the xrun callback.
+#define PEDALBOARD_XRUN_UNDERRUN 0
+#define PEDALBOARD_XRUN_OVERRUN 1
+
+// Sets an opaque context passed back to the Go xrun handler from the audio thread
+// whenever an underrun or overrun is detected. A context of 0 disables reporting.
+// When this returns, no callback with the previous context is in flight.
+void pedalboard_audio_stream_set_xrun_callback(PedalboardAudioStream stream, uintptr_t context);
+
 // Frees the audio stream.
 void pedalboard_audio_stream_free(PedalboardAudioStream stream);
 
+// Audio Devices
+typedef struct {
+    char* id;
+    char* name;
+    int max_channels;
+    double* sample_rates;
+    int num_sample_rates;
+    int is_default;
+} PedalboardAudioDevice;
+
+typedef struct {
+    PedalboardAudioDevice* devices;
+    int num_devices;
+} PedalboardAudioDeviceList;
+
+// Enumerates the available input (is_input != 0) or output audio devices.
+PedalboardAudioDeviceList* pedalboard_enumerate_audio_devices(int is_input);
+
+// Frees a device list returned by pedalboard_enumerate_audio_devices.
+void pedalboard_audio_device_list_free(PedalboardAudioDeviceList* list);
+
 #ifdef __cplusplus
 }
 #endif
@@ -1,7 +1,16 @@
 package pedalboard
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewInternalProcessor(t *testing.T) {
@@ -30,15 +39,17 @@ func TestNewInternalProcessor(t *testing.T) {
 	}
 }
 
-func TestAllProcessors(t *testing.T) {
-	effects := []string{
-		"Gain", "Reverb", "Chorus", "Distortion", 
-		"Phaser", "Clipping", "Compressor", "Limiter",
-		"Delay", "LowPass", "HighPass", "LadderFilter",
-		"Bitcrush",
-	}
+// internalProcessors lists every name NewInternalProcessor accepts.
+var internalProcessors = []string{
+	"Gain", "Reverb", "Chorus", "Distortion", 
+	"Phaser", "Clipping", "Compressor", "Limiter", "NoiseGate",
+	"Delay", "LowPass", "HighPass", "LadderFilter",
+	"Bitcrush", "MIDIThru", "ParametricEQ",
+	"Tremolo", "Flanger", "Vibrato", "Freeze", "PitchShifter", "RingModulator", "TapeSaturation", "StereoWidener", "MidSideEncoder", "MidSideDecoder", "Panner", "AutoGainControl", "MultibandCompressor", "TransientShaper", "DCFilter", "TimeStretch", "WaveformFollower",
+}
 
-	for _, name := range effects {
+func TestAllProcessors(t *testing.T) {
+	for _, name := range internalProcessors {
 		p, err := NewInternalProcessor(name)
 		if err != nil {
 			t.Errorf("Failed to create %s: %v", name, err)
@@ -78,6 +89,261 @@ func TestProcessorParameters(t *testing.T) {
 	gain.SetParameter(0, initialVal)
 }
 
+func TestParameterChoices(t *testing.T) {
+	tremolo, _ := NewInternalProcessor("Tremolo")
+
+	choices, err := tremolo.GetParameterChoices(2)
+	if err != nil {
+		t.Fatalf("GetParameterChoices failed: %v", err)
+	}
+	expected := []string{"Sine", "Triangle", "Square"}
+	if len(choices) != len(expected) {
+		t.Fatalf("Expected choices %v, got %v", expected, choices)
+	}
+	for i := range expected {
+		if choices[i] != expected[i] {
+			t.Errorf("Choice %d: expected %q, got %q", i, expected[i], choices[i])
+		}
+	}
+
+	if err := tremolo.SetParameterChoice(2, "Triangle"); err != nil {
+		t.Fatalf("SetParameterChoice failed: %v", err)
+	}
+	if v := tremolo.GetParameter(2); v != 0.5 {
+		t.Errorf("Expected Triangle to set 0.5, got %f", v)
+	}
+	if err := tremolo.SetParameterChoice(2, "Sawtooth"); err == nil {
+		t.Error("Expected error for unknown choice")
+	}
+
+	// Rate is continuous
+	if choices, err := tremolo.GetParameterChoices(0); err != nil || len(choices) != 0 {
+		t.Errorf("Expected no choices for a continuous parameter, got %v (%v)", choices, err)
+	}
+	if err := tremolo.SetParameterChoice(0, "Sine"); err == nil {
+		t.Error("Expected error setting a choice on a continuous parameter")
+	}
+	if _, err := tremolo.GetParameterChoices(10); err == nil {
+		t.Error("Expected error for out-of-range index")
+	}
+}
+
+func TestParameterText(t *testing.T) {
+	tremolo, _ := NewInternalProcessor("Tremolo")
+
+	if err := tremolo.SetParameterFromText(2, "square"); err != nil {
+		t.Fatalf("SetParameterFromText failed: %v", err)
+	}
+	if text, err := tremolo.ParameterText(2); err != nil || text != "Square" {
+		t.Errorf("Expected Square, got %q (%v)", text, err)
+	}
+	// Continuous parameters of internal processors read and show their mapped value and unit:
+	// the rate runs logarithmically from 0.1 to 20 Hz
+	for _, text := range []string{" 5 Hz ", "5hz", "5"} {
+		if err := tremolo.SetParameterFromText(0, text); err != nil {
+			t.Fatalf("%q: SetParameterFromText failed: %v", text, err)
+		}
+		if v, want := tremolo.GetParameter(0), math.Log(50)/math.Log(200); math.Abs(float64(v)-want) > 1e-5 {
+			t.Errorf("%q: expected %f, got %f", text, want, v)
+		}
+	}
+	if text, _ := tremolo.ParameterText(0); text != "5.00 Hz" {
+		t.Errorf("Expected text 5.00 Hz, got %q", text)
+	}
+
+	compressor, _ := NewInternalProcessor("Compressor")
+	if err := compressor.SetParameterFromText(0, "-6 dB"); err != nil {
+		t.Fatalf("SetParameterFromText failed: %v", err)
+	}
+	if v := compressor.GetParameter(0); math.Abs(float64(v)-0.9) > 1e-5 {
+		t.Errorf("Expected threshold -6 dB to be 0.9, got %f", v)
+	}
+	if text, _ := compressor.ParameterText(0); text != "-6.00 dB" {
+		t.Errorf("Expected text -6.00 dB, got %q", text)
+	}
+	compressor.SetParameter(1, 0)
+	if text, _ := compressor.ParameterText(1); text != "1.00:1" {
+		t.Errorf("Expected ratio text 1.00:1, got %q", text)
+	}
+
+	for _, c := range []struct {
+		index int
+		text  string
+	}{{2, "Sawtooth"}, {0, "fast"}, {0, "50 Hz"}, {0, "5 dB"}} {
+		if err := tremolo.SetParameterFromText(c.index, c.text); !errors.Is(err, ErrParseFailure) {
+			t.Errorf("%q: expected ErrParseFailure, got %v", c.text, err)
+		}
+	}
+	if err := tremolo.SetParameterFromText(10, "0.5"); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for out-of-range index, got %v", err)
+	}
+	if _, err := tremolo.ParameterText(-1); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for out-of-range index, got %v", err)
+	}
+}
+
+func TestParameterRange(t *testing.T) {
+	for _, name := range internalProcessors {
+		p, err := NewInternalProcessor(name)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		for i := 0; i < p.NumParameters(); i++ {
+			min, max, def, err := p.ParameterRange(i)
+			if err != nil {
+				t.Errorf("%s parameter %d: %v", name, i, err)
+				continue
+			}
+			if math.IsNaN(float64(min)) || math.IsNaN(float64(max)) || math.IsNaN(float64(def)) {
+				t.Errorf("%s parameter %d: NaN in range %f-%f, default %f", name, i, min, max, def)
+			} else if min > def || def > max {
+				t.Errorf("%s parameter %d: default %f outside %f-%f", name, i, def, min, max)
+			}
+		}
+	}
+
+	// Internal parameters report what their normalized values map to
+	compressor, _ := NewInternalProcessor("Compressor")
+	for i, want := range []struct {
+		min, max float32
+		unit     string
+	}{{-60, 0, "dB"}, {1, 20, ":1"}, {1, 200, "ms"}, {20, 500, "ms"}, {0, 12, "dB"}} {
+		min, max, _, _ := compressor.ParameterRange(i)
+		unit, err := compressor.ParameterUnit(i)
+		if min != want.min || max != want.max || unit != want.unit || err != nil {
+			t.Errorf("Compressor parameter %d: expected %g-%g %s, got %g-%g %q (%v)", i, want.min, want.max, want.unit, min, max, unit, err)
+		}
+	}
+	lowPass, _ := NewInternalProcessor("LowPass")
+	if min, max, _, _ := lowPass.ParameterRange(0); min != 20 || max != 20000 {
+		t.Errorf("Expected a 20-20000 Hz cutoff, got %g-%g", min, max)
+	}
+	if _, _, def, _ := compressor.ParameterRange(0); math.Abs(float64(def-(-60+60*compressor.GetParameter(0)))) > 1e-4 {
+		t.Errorf("Expected the default threshold in dB, got %g", def)
+	}
+	if unit, err := lowPass.ParameterUnit(1); unit != "" || err != nil {
+		t.Errorf("Expected no unit for Q, got %q (%v)", unit, err)
+	}
+	if _, err := lowPass.ParameterUnit(2); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for out-of-range index, got %v", err)
+	}
+
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.9)
+	if _, _, def, _ := gain.ParameterRange(0); def == 0.9 {
+		t.Error("Expected default to be unaffected by SetParameter")
+	}
+	if _, _, _, err := gain.ParameterRange(gain.NumParameters()); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for out-of-range index, got %v", err)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, name := range internalProcessors {
+		p, _ := NewInternalProcessor(name)
+		for i := 0; i < p.NumParameters(); i++ {
+			p.SetParameter(i, float32(i%4)/3)
+		}
+		preset, err := p.SavePreset()
+		if err != nil {
+			t.Fatalf("%s: SavePreset failed: %v", name, err)
+		}
+
+		restored, _ := NewInternalProcessor(name)
+		if err := restored.LoadPreset(preset); err != nil {
+			t.Fatalf("%s: LoadPreset failed: %v", name, err)
+		}
+		for i := 0; i < p.NumParameters(); i++ {
+			if got, want := restored.GetParameter(i), p.GetParameter(i); got != want {
+				t.Errorf("%s parameter %d: expected %f, got %f", name, i, want, got)
+			}
+		}
+	}
+
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.5)
+	preset, _ := gain.SavePreset()
+	for _, data := range [][]byte{nil, []byte("not a preset"), preset[:len(preset)/2]} {
+		if err := gain.LoadPreset(data); !errors.Is(err, ErrInvalidPreset) {
+			t.Errorf("Expected ErrInvalidPreset for %d bytes, got %v", len(data), err)
+		}
+	}
+	if gain.GetParameter(0) != 0.5 {
+		t.Error("Expected a failed LoadPreset to leave parameters unchanged")
+	}
+
+	delay, _ := NewInternalProcessor("Delay")
+	if err := delay.LoadPreset(preset); !errors.Is(err, ErrInvalidPreset) {
+		t.Errorf("Expected ErrInvalidPreset for another processor's preset, got %v", err)
+	}
+}
+
+func TestBypass(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.1)
+	if gain.Bypassed() {
+		t.Fatal("Expected a new processor not to be bypassed")
+	}
+
+	gain.SetBypassed(true)
+	if !gain.Bypassed() {
+		t.Fatal("Expected the processor to be bypassed")
+	}
+	before := processedSamples(gain)
+	buffer := [][]float32{{0.1, 0.2, 0.3, 0.4}, {-0.1, -0.2, -0.3, -0.4}}
+	gain.Process(buffer, 44100)
+	if buffer[0][2] != 0.3 || buffer[1][3] != -0.4 {
+		t.Errorf("Expected a bypassed processor to pass audio through, got %v", buffer)
+	}
+	if got := processedSamples(gain) - before; got != 4 {
+		t.Errorf("Expected 4 samples to be consumed, got %d", got)
+	}
+
+	gain.SetBypassed(false)
+	buffer = [][]float32{{0.5, 0.5, 0.5, 0.5}, {0.5, 0.5, 0.5, 0.5}}
+	gain.Process(buffer, 44100)
+	if buffer[0][3] >= 0.5 {
+		t.Errorf("Expected the processor to run again after bypass, got %v", buffer[0])
+	}
+
+	// A bypassed processor or chain delays audio by the latency it reports
+	shifter, _ := NewInternalProcessor("PitchShifter")
+	shifter.SetParameter(0, 0.75) // +12 semitones, 2048 samples of latency
+	shifter.Process([][]float32{make([]float32, 512), make([]float32, 512)}, 44100)
+	shifter.SetBypassed(true)
+	expectDelayedImpulse(t, shifter, 2048)
+
+	stage, _ := NewInternalProcessor("PitchShifter")
+	stage.SetParameter(0, 0.75)
+	chain, _ := NewProcessorChain(stage)
+	chain.Processor().Process([][]float32{make([]float32, 512), make([]float32, 512)}, 44100)
+	chain.Processor().SetBypassed(true)
+	expectDelayedImpulse(t, chain.Processor(), 2048)
+}
+
+// expectDelayedImpulse processes an impulse through p in blocks and checks that it comes
+// out of both channels delay samples later, unchanged.
+func expectDelayedImpulse(t *testing.T, p *Processor, delay int) {
+	t.Helper()
+	const blockSize = 1500
+	for block := 0; block*blockSize <= delay; block++ {
+		buffer := [][]float32{make([]float32, blockSize), make([]float32, blockSize)}
+		if block == 0 {
+			buffer[0][0], buffer[1][0] = 1, -1
+		}
+		p.Process(buffer, 44100)
+		for i := range buffer[0] {
+			want := float32(0)
+			if block*blockSize+i == delay {
+				want = 1
+			}
+			if buffer[0][i] != want || buffer[1][i] != -want {
+				t.Fatalf("Sample %d: expected %v and %v, got %v and %v", block*blockSize+i, want, -want, buffer[0][i], buffer[1][i])
+			}
+		}
+	}
+}
+
 func TestProcess(t *testing.T) {
 	gain, _ := NewInternalProcessor("Gain")
 	gain.SetParameter(0, 0.5) // Half volume
@@ -108,6 +374,1023 @@ func TestProcess(t *testing.T) {
 	}
 }
 
+func TestErrorSentinels(t *testing.T) {
+	_, err := NewInternalProcessor("NoSuchProcessor")
+	if !errors.Is(err, ErrProcessorNotFound) {
+		t.Errorf("Expected ErrProcessorNotFound, got %v", err)
+	}
+
+	gain, _ := NewInternalProcessor("Gain")
+	if _, err := gain.GetParameterChoices(99); !errors.Is(err, ErrParameterOutOfRange) {
+		t.Errorf("Expected ErrParameterOutOfRange, got %v", err)
+	}
+	if err := gain.SetParameterChoice(0, "Loud"); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter, got %v", err)
+	}
+
+	empty := &AudioBuffer{SampleRate: 44100}
+	if _, err := empty.ToInt16(false); !errors.Is(err, ErrEmptyBuffer) {
+		t.Errorf("Expected ErrEmptyBuffer, got %v", err)
+	}
+	if err := SaveAudioFileWithBitDepth(filepath.Join(t.TempDir(), "out.wav"), sineBuffer(440, 0.5, 44100, 100), 12); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+	if _, err := LoadAudioFile(filepath.Join(t.TempDir(), "missing.wav")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist, got %v", err)
+	}
+	if err := sineBuffer(440, 0.5, 44100, 100).InvertPhaseChannel(3); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange, got %v", err)
+	}
+}
+
+func TestProcessContext(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.5)
+
+	// A cancellable context processes large buffers in blocks
+	buffer := [][]float32{make([]float32, 40000)}
+	for i := range buffer[0] {
+		buffer[0][i] = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := gain.ProcessContext(ctx, buffer, 44100.0); err != nil {
+		t.Fatalf("ProcessContext failed: %v", err)
+	}
+	if buffer[0][0] != 0.5 || buffer[0][39999] != 0.5 {
+		t.Errorf("Expected every block to be processed: %f, %f", buffer[0][0], buffer[0][39999])
+	}
+
+	cancel()
+	if err := gain.ProcessContext(ctx, buffer, 44100.0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if buffer[0][0] != 0.5 {
+		t.Error("Expected a cancelled call to leave the buffer untouched")
+	}
+
+	mismatched := [][]float32{make([]float32, 10), make([]float32, 5)}
+	if err := gain.ProcessContext(context.Background(), mismatched, 44100.0); err == nil {
+		t.Error("Expected error for channels of different lengths")
+	}
+	if err := gain.ProcessContext(context.Background(), nil, 44100.0); err == nil {
+		t.Error("Expected error for an empty buffer")
+	}
+}
+
+func TestSetParameterRampTo(t *testing.T) {
+	// StereoWidener applies its parameter without smoothing: with L=1, R=-1 the left
+	// output is sqrt(2*value)
+	widener, err := NewInternalProcessor("StereoWidener")
+	if err != nil {
+		t.Fatalf("Failed to create StereoWidener processor: %v", err)
+	}
+	widener.SetParameter(0, 0.0)
+	widener.SetParameterRampTo(0, 1.0, 100)
+	side := func(n int) [][]float32 {
+		buffer := [][]float32{make([]float32, n), make([]float32, n)}
+		for i := 0; i < n; i++ {
+			buffer[0][i], buffer[1][i] = 1, -1
+		}
+		return buffer
+	}
+
+	// The ramp spans two Process calls
+	buffer := side(60)
+	widener.Process(buffer, 44100.0)
+	for i, s := range buffer[0] {
+		expected := math.Sqrt(2 * float64(i+1) / 100)
+		if math.Abs(float64(s)-expected) > 1e-5 {
+			t.Fatalf("Sample %d: expected %f, got %f", i, expected, s)
+		}
+	}
+	if v := widener.GetParameter(0); math.Abs(float64(v-0.6)) > 1e-5 {
+		t.Errorf("Expected parameter 0.6 mid-ramp, got %f", v)
+	}
+
+	buffer = side(60)
+	widener.Process(buffer, 44100.0)
+	if v := widener.GetParameter(0); v != 1.0 {
+		t.Errorf("Expected parameter to snap to 1.0, got %f", v)
+	}
+	if math.Abs(float64(buffer[0][59])-math.Sqrt2) > 1e-5 {
+		t.Errorf("Expected output %f after the ramp, got %f", math.Sqrt2, buffer[0][59])
+	}
+
+	// A second ramp on the same parameter replaces the first
+	widener.SetParameterRampTo(0, 0.0, 1000)
+	widener.SetParameterRampTo(0, 0.5, 10)
+	widener.Process(side(20), 44100.0)
+	if v := widener.GetParameter(0); v != 0.5 {
+		t.Errorf("Expected the latest ramp to win, parameter is %f", v)
+	}
+
+	// Setting the parameter directly cancels the ramp
+	widener.SetParameterRampTo(0, 1.0, 1000)
+	widener.SetParameter(0, 0.25)
+	widener.Process(side(20), 44100.0)
+	if v := widener.GetParameter(0); v != 0.25 {
+		t.Errorf("Expected SetParameter to cancel the ramp, parameter is %f", v)
+	}
+}
+
+func TestMIDIThru(t *testing.T) {
+	thru, err := NewInternalProcessor("MIDIThru")
+	if err != nil {
+		t.Fatalf("Failed to create MIDIThru processor: %v", err)
+	}
+
+	buffer := [][]float32{
+		{0.1, 0.2, 0.3, 0.4},
+		{-0.1, -0.2, -0.3, -0.4},
+	}
+	noteOn := MIDIEvent{Data: []byte{0x90, 60, 100}, SampleOffset: 2}
+	thru.SendMIDI([]MIDIEvent{noteOn})
+	thru.Process(buffer, 44100.0)
+
+	if buffer[0][2] != 0.3 || buffer[1][3] != -0.4 {
+		t.Errorf("MIDIThru modified audio: %v", buffer)
+	}
+
+	out := thru.GetMIDIOutput()
+	if len(out) != 1 {
+		t.Fatalf("Expected 1 MIDI event, got %d", len(out))
+	}
+	if out[0].SampleOffset != noteOn.SampleOffset || string(out[0].Data) != string(noteOn.Data) {
+		t.Errorf("Expected %v, got %v", noteOn, out[0])
+	}
+
+	// Events are only reported for the most recent Process call
+	thru.Process(buffer, 44100.0)
+	if out := thru.GetMIDIOutput(); len(out) != 0 {
+		t.Errorf("Expected no MIDI events after second Process, got %d", len(out))
+	}
+}
+
+func TestParametricEQFlat(t *testing.T) {
+	eq, err := NewInternalProcessor("ParametricEQ")
+	if err != nil {
+		t.Fatalf("Failed to create ParametricEQ processor: %v", err)
+	}
+	if n := eq.NumParameters(); n < 15 {
+		t.Fatalf("Expected at least 15 parameters, got %d", n)
+	}
+
+	// Gain parameters are (band * 3) + 1; 0.5 maps to 0 dB
+	for band := 0; band < eq.NumParameters()/3; band++ {
+		eq.SetParameter(band*3+1, 0.5)
+	}
+
+	buffer := [][]float32{make([]float32, 512), make([]float32, 512)}
+	for c := range buffer {
+		for i := range buffer[c] {
+			buffer[c][i] = float32(i%64)/32.0 - 1.0
+		}
+	}
+	original := [][]float32{append([]float32(nil), buffer[0]...), append([]float32(nil), buffer[1]...)}
+
+	eq.Process(buffer, 44100.0)
+	for c := range buffer {
+		for i := range buffer[c] {
+			if buffer[c][i] != original[c][i] {
+				t.Fatalf("Channel %d sample %d: flat EQ changed %f to %f", c, i, original[c][i], buffer[c][i])
+			}
+		}
+	}
+}
+
+func TestNoiseGate(t *testing.T) {
+	const sampleRate = 48000.0
+	gate, err := NewInternalProcessor("NoiseGate")
+	if err != nil {
+		t.Fatalf("Failed to create NoiseGate processor: %v", err)
+	}
+	gate.SetParameter(0, 0.5) // -40 dBFS
+	gate.SetParameter(2, 0.0) // No hold
+	gate.SetParameter(3, 0.1) // 104.5 ms release
+	gate.SetParameter(4, 0.9) // 10:1
+
+	// Half a second of loud signal, then a noise floor at -66 dBFS
+	loud := sineBuffer(1000, 0.5, sampleRate, 24000)
+	floor := sineBuffer(1000, 0.0005, sampleRate, 24000)
+	input := [][]float32{append(append([]float32(nil), loud.Data[0]...), floor.Data[0]...)}
+	output := [][]float32{append([]float32(nil), input[0]...)}
+	gate.Process(output, sampleRate)
+
+	for i := 0; i < 24000; i++ {
+		if output[0][i] != input[0][i] {
+			t.Fatalf("Expected audio above the threshold to pass unmodified, sample %d: %f vs %f", i, output[0][i], input[0][i])
+		}
+	}
+
+	// Once the 10 ms detector window has emptied, the gate closes within the release time
+	gated := 24000 + int((0.010+0.1045)*sampleRate) + 1
+	var in, out float64
+	for i := gated; i < len(input[0]); i++ {
+		in += float64(input[0][i]) * float64(input[0][i])
+		out += float64(output[0][i]) * float64(output[0][i])
+	}
+	if gain := math.Sqrt(out / in); math.Abs(gain-0.1) > 1e-3 {
+		t.Errorf("Expected the floor to be attenuated to 0.1 within the release time, got %f", gain)
+	}
+}
+
+func TestTremolo(t *testing.T) {
+	newBuffer := func() [][]float32 {
+		buffer := [][]float32{make([]float32, 44100)}
+		for i := range buffer[0] {
+			buffer[0][i] = 1.0
+		}
+		return buffer
+	}
+
+	tremolo, err := NewInternalProcessor("Tremolo")
+	if err != nil {
+		t.Fatalf("Failed to create Tremolo processor: %v", err)
+	}
+
+	// Depth 0 is transparent
+	tremolo.SetParameter(1, 0.0)
+	buffer := newBuffer()
+	tremolo.Process(buffer, 44100.0)
+	for i, s := range buffer[0] {
+		if math.Abs(float64(s)-1.0) > 1e-6 {
+			t.Fatalf("Sample %d: expected 1.0 with zero depth, got %f", i, s)
+		}
+	}
+
+	// Depth 1 silences the signal at the LFO troughs
+	tremolo, _ = NewInternalProcessor("Tremolo")
+	tremolo.SetParameter(0, 0.5) // ~1.4 Hz, so one second covers a full cycle
+	tremolo.SetParameter(1, 1.0)
+	buffer = newBuffer()
+	tremolo.Process(buffer, 44100.0)
+	minimum := float32(1.0)
+	for _, s := range buffer[0] {
+		if s < minimum {
+			minimum = s
+		}
+	}
+	if minimum > 1e-3 {
+		t.Errorf("Expected amplitude to reach zero with full depth, minimum was %f", minimum)
+	}
+}
+
+func TestFlanger(t *testing.T) {
+	// Feedback 0, Mix 0 is transparent
+	flanger, err := NewInternalProcessor("Flanger")
+	if err != nil {
+		t.Fatalf("Failed to create Flanger processor: %v", err)
+	}
+	flanger.SetParameter(2, 0.0)
+	flanger.SetParameter(4, 0.0)
+
+	buffer := [][]float32{make([]float32, 1024)}
+	for i := range buffer[0] {
+		buffer[0][i] = float32(math.Sin(float64(i) * 0.05))
+	}
+	original := append([]float32(nil), buffer[0]...)
+	flanger.Process(buffer, 44100.0)
+	for i := range buffer[0] {
+		if math.Abs(float64(buffer[0][i]-original[i])) > 1e-6 {
+			t.Fatalf("Sample %d: expected %f, got %f", i, original[i], buffer[0][i])
+		}
+	}
+
+	// Higher feedback rings longer after an impulse
+	tailEnergy := func(feedback float32) float64 {
+		f, _ := NewInternalProcessor("Flanger")
+		f.SetParameter(2, feedback)
+		f.SetParameter(4, 0.5)
+		impulse := [][]float32{make([]float32, 8820)}
+		impulse[0][0] = 1.0
+		f.Process(impulse, 44100.0)
+		var energy float64
+		for _, s := range impulse[0][882:] { // after 20 ms
+			energy += float64(s) * float64(s)
+		}
+		return energy
+	}
+	if low, high := tailEnergy(0.0), tailEnergy(0.9); high <= low*10 {
+		t.Errorf("Expected feedback to add resonance: tail energy %g (fb=0) vs %g (fb=0.9)", low, high)
+	}
+}
+
+func TestVibrato(t *testing.T) {
+	const sampleRate = 44100.0
+	vibrato, err := NewInternalProcessor("Vibrato")
+	if err != nil {
+		t.Fatalf("Failed to create Vibrato processor: %v", err)
+	}
+	vibrato.SetParameter(0, 0.5) // 1 Hz
+	vibrato.SetParameter(1, 0.5) // 50 cents
+
+	buffer := [][]float32{make([]float32, 3*44100)}
+	for i := range buffer[0] {
+		buffer[0][i] = float32(math.Sin(2 * math.Pi * 440 * float64(i) / sampleRate))
+	}
+	vibrato.Process(buffer, sampleRate)
+
+	// Estimate the pitch of each cycle from interpolated rising zero crossings
+	var crossings []float64
+	data := buffer[0][4410:] // skip the delay line fill-up
+	for i := 1; i < len(data); i++ {
+		if data[i-1] < 0 && data[i] >= 0 {
+			frac := float64(-data[i-1]) / float64(data[i]-data[i-1])
+			crossings = append(crossings, float64(i-1)+frac)
+		}
+	}
+	minFreq, maxFreq := math.Inf(1), math.Inf(-1)
+	for i := 1; i < len(crossings); i++ {
+		freq := sampleRate / (crossings[i] - crossings[i-1])
+		minFreq = math.Min(minFreq, freq)
+		maxFreq = math.Max(maxFreq, freq)
+	}
+
+	// +/-50 cents around 440 Hz is roughly 427.5-452.9 Hz
+	if math.Abs(minFreq-427.5) > 3 || math.Abs(maxFreq-452.9) > 3 {
+		t.Errorf("Expected pitch to swing between ~427 and ~453 Hz, got %.1f-%.1f Hz", minFreq, maxFreq)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	const sampleRate = 44100.0
+	freeze, err := NewInternalProcessor("Freeze")
+	if err != nil {
+		t.Fatalf("Failed to create Freeze processor: %v", err)
+	}
+
+	tone := [][]float32{make([]float32, 44100)}
+	for i := range tone[0] {
+		tone[0][i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+	}
+	dry := append([]float32(nil), tone[0]...)
+
+	// A FreezeAmount of 0 (the default) passes the input through bit for bit
+	freeze.Process(tone, sampleRate)
+	for i := range dry {
+		if tone[0][i] != dry[i] {
+			t.Fatalf("Sample %d: expected %f unmodified at FreezeAmount 0, got %f", i, dry[i], tone[0][i])
+		}
+	}
+
+	freeze.SetParameter(1, 1.0) // frozen signal only
+	if err := freeze.Trigger(true); err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+
+	// With the input changing to silence and then noise, the output should hold steady
+	blockRMS := func(input []float32) float64 {
+		block := [][]float32{input}
+		freeze.Process(block, sampleRate)
+		return rms(block[0])
+	}
+	silent := blockRMS(make([]float32, 8192))
+	noise := make([]float32, 8192)
+	for i := range noise {
+		noise[i] = float32(math.Sin(float64(i)*1.7)) * 0.9
+	}
+	noisy := blockRMS(noise)
+
+	if silent < 0.05 {
+		t.Fatalf("Expected frozen output to keep sounding, RMS was %f", silent)
+	}
+	if math.Abs(silent-noisy)/silent > 0.1 {
+		t.Errorf("Frozen output changed with input: RMS %f vs %f", silent, noisy)
+	}
+
+	gain, _ := NewInternalProcessor("Gain")
+	if err := gain.Trigger(true); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported triggering a processor without a momentary action, got %v", err)
+	}
+}
+
+func TestPitchShifter(t *testing.T) {
+	const sampleRate = 44100.0
+	sine := func() [][]float32 {
+		buffer := [][]float32{make([]float32, 2*44100)}
+		for i := range buffer[0] {
+			buffer[0][i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+		}
+		return buffer
+	}
+
+	// 0 semitones (the default) leaves the signal untouched
+	shifter, err := NewInternalProcessor("PitchShifter")
+	if err != nil {
+		t.Fatalf("Failed to create PitchShifter processor: %v", err)
+	}
+	input, output := sine(), sine()
+	shifter.Process(output, sampleRate)
+	for i := range input[0] {
+		if math.Abs(float64(output[0][i]-input[0][i])) > 1e-6 {
+			t.Fatalf("Expected transparent output at 0 semitones, sample %d: %f vs %f", i, output[0][i], input[0][i])
+		}
+	}
+
+	// +12 semitones doubles the frequency
+	shifter.SetParameter(0, 0.75)
+	output = sine()
+	shifter.Process(output, sampleRate)
+
+	var first, last float64
+	count := 0
+	data := output[0][8192:] // skip the analysis latency
+	for i := 1; i < len(data); i++ {
+		if data[i-1] < 0 && data[i] >= 0 {
+			crossing := float64(i-1) + float64(-data[i-1])/float64(data[i]-data[i-1])
+			if count == 0 {
+				first = crossing
+			}
+			last = crossing
+			count++
+		}
+	}
+	if count < 2 {
+		t.Fatal("Expected a periodic output after shifting")
+	}
+	freq := sampleRate * float64(count-1) / (last - first)
+	if math.Abs(freq-880) > 5 {
+		t.Errorf("Expected ~880 Hz after shifting up an octave, got %.1f Hz", freq)
+	}
+}
+
+func TestRingModulator(t *testing.T) {
+	const sampleRate = 44100.0
+	ring, err := NewInternalProcessor("RingModulator")
+	if err != nil {
+		t.Fatalf("Failed to create RingModulator processor: %v", err)
+	}
+	// Carrier maps logarithmically over 20-5000 Hz
+	ring.SetParameter(0, float32(math.Log(100.0/20.0)/math.Log(5000.0/20.0)))
+
+	// Mix=0 leaves the input untouched
+	ring.SetParameter(1, 0.0)
+	input := sineBuffer(440, 0.5, sampleRate, 16384)
+	dry := sineBuffer(440, 0.5, sampleRate, 16384)
+	ring.Process(dry.Data, sampleRate)
+	for i := range input.Data[0] {
+		if dry.Data[0][i] != input.Data[0][i] {
+			t.Fatalf("Expected unmodified output at Mix=0, sample %d: %f vs %f", i, dry.Data[0][i], input.Data[0][i])
+		}
+	}
+
+	// Mix=1 replaces 440 Hz with 340 Hz and 540 Hz sidebands
+	ring.SetParameter(1, 1.0)
+	wet := sineBuffer(440, 0.5, sampleRate, 16384)
+	ring.Process(wet.Data, sampleRate)
+
+	window := blackmanHarris(len(wet.Data[0]))
+	power := powerSpectrum(wet.Data[0], window)
+	binHz := sampleRate / float64(len(window))
+	lower := bandPower(power, 340/binHz, 3)
+	upper := bandPower(power, 540/binHz, 3)
+	carrier := bandPower(power, 440/binHz, 3)
+	if carrier > 1e-4*lower || carrier > 1e-4*upper {
+		t.Errorf("Expected no 440 Hz component: 340 Hz %g, 440 Hz %g, 540 Hz %g", lower, carrier, upper)
+	}
+	if math.Abs(lower-upper)/upper > 0.1 {
+		t.Errorf("Expected equal sidebands: 340 Hz %g, 540 Hz %g", lower, upper)
+	}
+}
+
+func TestTapeSaturation(t *testing.T) {
+	const sampleRate = 48000.0
+	tape, err := NewInternalProcessor("TapeSaturation")
+	if err != nil {
+		t.Fatalf("Failed to create TapeSaturation processor: %v", err)
+	}
+
+	// Drive=0 leaves the input untouched
+	tape.SetParameter(0, 0.0)
+	input := sineBuffer(1000, 0.5, sampleRate, 16384)
+	output := sineBuffer(1000, 0.5, sampleRate, 16384)
+	tape.Process(output.Data, sampleRate)
+	for i := range input.Data[0] {
+		if output.Data[0][i] != input.Data[0][i] {
+			t.Fatalf("Expected unmodified output at Drive=0, sample %d: %f vs %f", i, output.Data[0][i], input.Data[0][i])
+		}
+	}
+
+	// More drive means more harmonic distortion
+	var previous float64
+	for _, drive := range []float32{0.2, 0.8} {
+		tape.SetParameter(0, drive)
+		buffer := sineBuffer(1000, 0.5, sampleRate, 16384)
+		tape.Process(buffer.Data, sampleRate)
+		thd, err := buffer.MeasureHarmonicDistortion(1000)
+		if err != nil {
+			t.Fatalf("MeasureHarmonicDistortion failed: %v", err)
+		}
+		if thd <= previous+0.5 {
+			t.Errorf("Drive %.1f: expected THD above %.2f%%, got %.2f%%", drive, previous+0.5, thd)
+		}
+		previous = thd
+	}
+}
+
+func TestStereoWidener(t *testing.T) {
+	const sampleRate = 44100.0
+	stereo := func() [][]float32 {
+		return [][]float32{
+			sineBuffer(440, 0.5, sampleRate, 4096).Data[0],
+			sineBuffer(660, 0.3, sampleRate, 4096).Data[0],
+		}
+	}
+	sideEnergy := func(buffer [][]float32) float64 {
+		var sum float64
+		for i := range buffer[0] {
+			side := float64(buffer[0][i]-buffer[1][i]) / 2
+			sum += side * side
+		}
+		return sum
+	}
+
+	widener, err := NewInternalProcessor("StereoWidener")
+	if err != nil {
+		t.Fatalf("Failed to create StereoWidener processor: %v", err)
+	}
+
+	// Width 0 (parameter 0.0) folds to mono
+	widener.SetParameter(0, 0.0)
+	mono := stereo()
+	widener.Process(mono, sampleRate)
+	for i := range mono[0] {
+		if mono[0][i] != mono[1][i] {
+			t.Fatalf("Expected identical channels at width 0, sample %d: %f vs %f", i, mono[0][i], mono[1][i])
+		}
+	}
+
+	// Width 1 (parameter 0.5) is bit-exact
+	widener.SetParameter(0, 0.5)
+	input, output := stereo(), stereo()
+	widener.Process(output, sampleRate)
+	for ch := range input {
+		for i := range input[ch] {
+			if output[ch][i] != input[ch][i] {
+				t.Fatalf("Expected pass-through at width 1, channel %d sample %d: %f vs %f", ch, i, output[ch][i], input[ch][i])
+			}
+		}
+	}
+
+	// Width 2 (parameter 1.0) doubles the side energy
+	widener.SetParameter(0, 1.0)
+	wide := stereo()
+	widener.Process(wide, sampleRate)
+	if ratio := sideEnergy(wide) / sideEnergy(input); math.Abs(ratio-2) > 0.01 {
+		t.Errorf("Expected side energy to double at width 2, got ratio %f", ratio)
+	}
+}
+
+func TestMidSide(t *testing.T) {
+	const sampleRate = 44100.0
+	encoder, err := NewInternalProcessor("MidSideEncoder")
+	if err != nil {
+		t.Fatalf("Failed to create MidSideEncoder processor: %v", err)
+	}
+	decoder, err := NewInternalProcessor("MidSideDecoder")
+	if err != nil {
+		t.Fatalf("Failed to create MidSideDecoder processor: %v", err)
+	}
+
+	left := sineBuffer(440, 0.5, sampleRate, 1024).Data[0]
+	right := sineBuffer(660, 0.3, sampleRate, 1024).Data[0]
+	buffer := [][]float32{append([]float32(nil), left...), append([]float32(nil), right...)}
+
+	encoder.Process(buffer, sampleRate)
+	for i := range left {
+		mid, side := (left[i]+right[i])/2, (left[i]-right[i])/2
+		if math.Abs(float64(buffer[0][i]-mid)) > 1e-6 || math.Abs(float64(buffer[1][i]-side)) > 1e-6 {
+			t.Fatalf("Sample %d: expected M/S %f/%f, got %f/%f", i, mid, side, buffer[0][i], buffer[1][i])
+		}
+	}
+
+	decoder.Process(buffer, sampleRate)
+	for i := range left {
+		if math.Abs(float64(buffer[0][i]-left[i])) > 1e-6 || math.Abs(float64(buffer[1][i]-right[i])) > 1e-6 {
+			t.Fatalf("Sample %d: expected L/R %f/%f after round trip, got %f/%f", i, left[i], right[i], buffer[0][i], buffer[1][i])
+		}
+	}
+}
+
+func TestPanner(t *testing.T) {
+	const sampleRate = 44100.0
+	tone := sineBuffer(440, 0.5, sampleRate, 4096).Data[0]
+	monoSource := func() [][]float32 {
+		return [][]float32{append([]float32(nil), tone...), append([]float32(nil), tone...)}
+	}
+
+	panner, err := NewInternalProcessor("Panner")
+	if err != nil {
+		t.Fatalf("Failed to create Panner processor: %v", err)
+	}
+
+	// Centre is transparent
+	buffer := monoSource()
+	panner.Process(buffer, sampleRate)
+	for ch := range buffer {
+		for i, s := range buffer[ch] {
+			if math.Abs(float64(s-tone[i])) > 1e-6 {
+				t.Fatalf("Expected transparent centre pan, channel %d sample %d: %f vs %f", ch, i, s, tone[i])
+			}
+		}
+	}
+
+	// Hard right silences the left channel and leaves the right at full amplitude
+	panner.SetParameter(0, 1.0)
+	buffer = monoSource()
+	panner.Process(buffer, sampleRate)
+	if level := rms(buffer[0]); level > 1e-6 {
+		t.Errorf("Expected silent left channel, RMS %f", level)
+	}
+	for i, s := range buffer[1] {
+		if math.Abs(float64(s-tone[i])) > 1e-6 {
+			t.Fatalf("Expected unity gain on the right, sample %d: %f vs %f", i, s, tone[i])
+		}
+	}
+
+	// Halfway right keeps the right at unity and lowers the left along sqrt(2) * cos
+	panner.SetParameter(0, 0.75)
+	buffer = monoSource()
+	panner.Process(buffer, sampleRate)
+	wantLeft := math.Sqrt2 * math.Cos(0.75*math.Pi/2)
+	if gain := rms(buffer[0]) / rms(tone); math.Abs(gain-wantLeft) > 1e-4 {
+		t.Errorf("Expected left gain %f, got %f", wantLeft, gain)
+	}
+	if gain := rms(buffer[1]) / rms(tone); math.Abs(gain-1) > 1e-4 {
+		t.Errorf("Expected right gain 1, got %f", gain)
+	}
+
+	// A single-channel buffer passes through
+	single := [][]float32{append([]float32(nil), tone...)}
+	panner.Process(single, sampleRate)
+	if single[0][100] != tone[100] {
+		t.Errorf("Expected mono buffer to pass through, got %f vs %f", single[0][100], tone[100])
+	}
+}
+
+func TestAutoGainControl(t *testing.T) {
+	const sampleRate = 44100.0
+	agc, err := NewInternalProcessor("AutoGainControl")
+	if err != nil {
+		t.Fatalf("Failed to create AutoGainControl processor: %v", err)
+	}
+	agc.SetParameter(0, 0.55) // -18 dBFS
+	agc.SetParameter(1, 0.02) // ~11 ms attack
+	agc.SetParameter(2, 0.05) // ~110 ms release
+
+	// 5 seconds at -30 dBFS RMS
+	amplitude := math.Pow(10, -30.0/20) * math.Sqrt2
+	buffer := sineBuffer(440, amplitude, sampleRate, 5*44100)
+	agc.Process(buffer.Data, sampleRate)
+
+	level := 20 * math.Log10(rms(buffer.Data[0][4*44100:]))
+	if math.Abs(level-(-18)) > 1 {
+		t.Errorf("Expected output near -18 dBFS, got %.2f dBFS", level)
+	}
+}
+
+func TestMultibandCompressor(t *testing.T) {
+	const sampleRate = 44100.0
+	mixture := func() [][]float32 {
+		low := sineBuffer(80, 0.4, sampleRate, 16384).Data[0]
+		mid := sineBuffer(400, 0.3, sampleRate, 16384).Data[0]
+		high := sineBuffer(9000, 0.2, sampleRate, 16384).Data[0]
+		for i := range low {
+			low[i] += mid[i] + high[i]
+		}
+		return [][]float32{low}
+	}
+
+	mbc, err := NewInternalProcessor("MultibandCompressor")
+	if err != nil {
+		t.Fatalf("Failed to create MultibandCompressor processor: %v", err)
+	}
+	if n := mbc.NumParameters(); n != 20 {
+		t.Fatalf("Expected 20 parameters, got %d", n)
+	}
+
+	// Ratio 1, threshold 0 dB and no makeup on every band reconstructs the input
+	for band := 0; band < 4; band++ {
+		mbc.SetParameter(band*5+0, 1.0)
+		mbc.SetParameter(band*5+1, 0.0)
+		mbc.SetParameter(band*5+4, 0.0)
+	}
+	input, output := mixture(), mixture()
+	mbc.Process(output, sampleRate)
+	for i := range input[0] {
+		if math.Abs(float64(output[0][i]-input[0][i])) > 1e-5 {
+			t.Fatalf("Expected bypass-equivalent output, sample %d: %f vs %f", i, output[0][i], input[0][i])
+		}
+	}
+
+	// Compressing only the lowest band reduces the 80 Hz component
+	mbc.SetParameter(0, 0.0) // -60 dB threshold
+	mbc.SetParameter(1, 1.0) // 20:1
+	compressed := [][]float32{sineBuffer(80, 0.4, sampleRate, 16384).Data[0]}
+	mbc.Process(compressed, sampleRate)
+	if level := rms(compressed[0][8192:]); level > 0.1 {
+		t.Errorf("Expected the low band to be compressed, RMS %f", level)
+	}
+}
+
+func TestTransientShaper(t *testing.T) {
+	const sampleRate = 44100.0
+	// A percussive hit: silence, then a decaying 1 kHz tone with an instant onset
+	hit := func() [][]float32 {
+		data := make([]float32, 22050)
+		for i := 1000; i < len(data); i++ {
+			n := float64(i - 1000)
+			data[i] = float32(0.5 * math.Exp(-n/(0.2*sampleRate)) * math.Sin(2*math.Pi*1000*n/sampleRate))
+		}
+		return [][]float32{data}
+	}
+	// Samples from the onset until the 1 ms peak envelope first reaches 90% of its maximum
+	riseTime := func(data []float32) int {
+		const window = 44
+		env := make([]float64, len(data))
+		var peak float64
+		for i := range data {
+			for j := i; j > i-window && j >= 0; j-- {
+				env[i] = math.Max(env[i], math.Abs(float64(data[j])))
+			}
+			peak = math.Max(peak, env[i])
+		}
+		for i, e := range env {
+			if e >= 0.9*peak {
+				return i - 1000
+			}
+		}
+		return len(data)
+	}
+
+	shaper, err := NewInternalProcessor("TransientShaper")
+	if err != nil {
+		t.Fatalf("Failed to create TransientShaper processor: %v", err)
+	}
+
+	// Attack=0, Sustain=0 is transparent
+	input, output := hit(), hit()
+	shaper.Process(output, sampleRate)
+	for i := range input[0] {
+		if output[0][i] != input[0][i] {
+			t.Fatalf("Expected transparent output, sample %d: %f vs %f", i, output[0][i], input[0][i])
+		}
+	}
+
+	// Attack=-1 softens the onset
+	shaper.SetParameter(0, 0.0)
+	softened := hit()
+	shaper.Process(softened, sampleRate)
+	original, slower := riseTime(input[0]), riseTime(softened[0])
+	if slower < original+220 { // at least 5 ms slower
+		t.Errorf("Expected a slower rise with Attack=-1: %d samples vs %d", slower, original)
+	}
+}
+
+func TestCompressorKnee(t *testing.T) {
+	const sampleRate = 44100.0
+	// A constant level exactly at the -12 dB threshold
+	level := float32(math.Pow(10, -12.0/20))
+	compress := func(knee float32) float32 {
+		comp, err := NewInternalProcessor("Compressor")
+		if err != nil {
+			t.Fatalf("Failed to create Compressor processor: %v", err)
+		}
+		comp.SetParameter(CompressorParamThreshold, 0.8)
+		comp.SetParameter(CompressorParamRatio, 3.0/19.0) // 4:1
+		comp.SetParameter(CompressorParamKnee, knee)
+		buffer := [][]float32{make([]float32, 22050)}
+		for i := range buffer[0] {
+			buffer[0][i] = level
+		}
+		comp.Process(buffer, sampleRate)
+		return buffer[0][len(buffer[0])-1]
+	}
+
+	// A hard knee leaves the threshold level untouched
+	if out := compress(0); math.Abs(float64(out-level)) > 1e-4 {
+		t.Errorf("Expected no gain reduction at the threshold with a hard knee, got %f from %f", out, level)
+	}
+
+	// A 12 dB soft knee already reduces it by (1/4 - 1) * 6^2 / 24 = -1.125 dB
+	out := compress(1)
+	if reduction := 20 * math.Log10(float64(out/level)); math.Abs(reduction+1.125) > 0.05 {
+		t.Errorf("Expected -1.125 dB at the threshold with a soft knee, got %.3f dB", reduction)
+	}
+}
+
+func TestTimeStretch(t *testing.T) {
+	const sampleRate = 44100.0
+	sine := func(numSamples int) [][]float32 {
+		buffer := [][]float32{make([]float32, numSamples)}
+		for i := 0; i < 44100; i++ {
+			buffer[0][i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+		}
+		return buffer
+	}
+
+	// Ratio=1.0 (the default) leaves the signal untouched
+	stretch, err := NewInternalProcessor("TimeStretch")
+	if err != nil {
+		t.Fatalf("Failed to create TimeStretch processor: %v", err)
+	}
+	input, output := sine(44100), sine(44100)
+	stretch.Process(output, sampleRate)
+	for i := range input[0] {
+		if output[0][i] != input[0][i] {
+			t.Fatalf("Expected transparent output at 1x, sample %d: %f vs %f", i, output[0][i], input[0][i])
+		}
+	}
+	if n := stretch.LatencySamples(); n != 0 {
+		t.Errorf("Expected no latency at 1x, got %d samples", n)
+	}
+
+	// Half speed doubles the duration and keeps the pitch
+	stretch.SetParameter(0, 0.0)
+	output = sine(2*44100 + 4096)
+	stretch.Process(output, sampleRate)
+	latency := stretch.LatencySamples()
+	if latency != 1536*2 {
+		t.Errorf("Expected a latency of %d samples at half speed, got %d", 1536*2, latency)
+	}
+	// The stretched signal has started once the latency has passed
+	if start := rms(output[0][latency : latency+2048]); start < 0.1 {
+		t.Errorf("Expected the stretched signal after the latency, got RMS %f", start)
+	}
+
+	var first, last float64
+	count := 0
+	data := output[0][latency+2048 : latency+2*44100-2048]
+	for i := 1; i < len(data); i++ {
+		if data[i-1] < 0 && data[i] >= 0 {
+			crossing := float64(i-1) + float64(-data[i-1])/float64(data[i]-data[i-1])
+			if count == 0 {
+				first = crossing
+			}
+			last = crossing
+			count++
+		}
+	}
+	if count < 2 {
+		t.Fatal("Expected a periodic output after stretching")
+	}
+	if freq := sampleRate * float64(count-1) / (last - first); math.Abs(freq-440) > 2 {
+		t.Errorf("Expected pitch near 440 Hz, got %.1f", freq)
+	}
+	if tail := rms(output[0][latency+2*44100+1024:]); tail > 0.01 {
+		t.Errorf("Expected silence after the stretched signal, got RMS %f", tail)
+	}
+}
+
+func TestWaveformFollower(t *testing.T) {
+	const sampleRate = 44100.0
+	follower, err := NewInternalProcessor("WaveformFollower")
+	if err != nil {
+		t.Fatalf("Failed to create WaveformFollower processor: %v", err)
+	}
+
+	buffer := sineBuffer(1000, 0.5, sampleRate, 44100)
+	buffer.Data = append(buffer.Data, make([]float32, 44100))
+	input := append([]float32(nil), buffer.Data[0]...)
+	follower.Process(buffer.Data, sampleRate)
+
+	for i, s := range buffer.Data[0] {
+		if s != input[i] {
+			t.Fatalf("Expected audio to pass unchanged, sample %d is %f instead of %f", i, s, input[i])
+		}
+	}
+	if level := follower.GetEnvelopeLevel(0); math.Abs(float64(level)-0.5) > 0.05 {
+		t.Errorf("Expected envelope near 0.5 on channel 0, got %f", level)
+	}
+	if level := follower.GetEnvelopeLevel(1); level != 0 {
+		t.Errorf("Expected zero envelope on the silent channel, got %f", level)
+	}
+
+	// The envelope decays after the signal stops
+	silence := [][]float32{make([]float32, 44100), make([]float32, 44100)}
+	follower.Process(silence, sampleRate)
+	if level := follower.GetEnvelopeLevel(0); level > 0.01 {
+		t.Errorf("Expected envelope to decay after silence, got %f", level)
+	}
+
+	if level := follower.GetEnvelopeLevel(-1); level != 0 {
+		t.Errorf("Expected 0 for out of range channel, got %f", level)
+	}
+	gain, _ := NewInternalProcessor("Gain")
+	if level := gain.GetEnvelopeLevel(0); level != 0 {
+		t.Errorf("Expected 0 for a processor without an envelope, got %f", level)
+	}
+}
+
+func TestDCFilter(t *testing.T) {
+	const sampleRate = 44100.0
+	buffer := sineBuffer(1000, 0.5, sampleRate, 2*44100)
+	for i := range buffer.Data[0] {
+		buffer.Data[0][i] += 0.5
+	}
+
+	filter, err := NewInternalProcessor("DCFilter")
+	if err != nil {
+		t.Fatalf("Failed to create DCFilter processor: %v", err)
+	}
+	if n := filter.NumParameters(); n != 0 {
+		t.Errorf("Expected no parameters, got %d", n)
+	}
+	filter.Process(buffer.Data, sampleRate)
+
+	// Skip the first second while the filter settles
+	settled := buffer.Data[0][44100:]
+	var mean float64
+	for _, s := range settled {
+		mean += float64(s)
+	}
+	mean /= float64(len(settled))
+	if math.Abs(mean) > 1e-3 {
+		t.Errorf("Expected no DC after filtering, got %f", mean)
+	}
+	if level := rms(settled); math.Abs(level-0.5/math.Sqrt2) > 0.005 {
+		t.Errorf("Expected the sine to pass at RMS %f, got %f", 0.5/math.Sqrt2, level)
+	}
+}
+
+func TestConvolutionReverb(t *testing.T) {
+	const sampleRate = 44100.0
+	if _, err := NewConvolutionReverb(&AudioBuffer{SampleRate: sampleRate}); err == nil {
+		t.Error("Expected error for empty impulse response")
+	}
+	if _, err := NewConvolutionReverb(&AudioBuffer{Data: [][]float32{make([]float32, 11*44100)}, SampleRate: sampleRate}); err == nil {
+		t.Error("Expected error for impulse response longer than 10 s")
+	}
+
+	// Decaying noise spanning several partitions
+	rng := rand.New(rand.NewSource(1))
+	ir := make([]float32, 3000)
+	for i := range ir {
+		ir[i] = float32((rng.Float64()*2 - 1) * math.Exp(-float64(i)/800))
+	}
+	reverb, err := NewConvolutionReverb(&AudioBuffer{Data: [][]float32{ir}, SampleRate: sampleRate})
+	if err != nil {
+		t.Fatalf("Failed to create ConvolutionReverb: %v", err)
+	}
+
+	// A Dirac fed in uneven blocks should come out as the impulse response
+	output := make([]float32, 6000)
+	output[0] = 1
+	for start := 0; start < len(output); start += 700 {
+		end := start + 700
+		if end > len(output) {
+			end = len(output)
+		}
+		reverb.Process([][]float32{output[start:end]}, sampleRate)
+	}
+	for i, got := range output {
+		want := float32(0)
+		if i < len(ir) {
+			want = ir[i]
+		}
+		if math.Abs(float64(got-want)) > 1e-4 {
+			t.Fatalf("Sample %d: expected %f, got %f", i, want, got)
+		}
+	}
+
+	// A preset carries the impulse response to a reverb created with another one
+	preset, err := reverb.SavePreset()
+	if err != nil {
+		t.Fatalf("SavePreset failed: %v", err)
+	}
+	restored, _ := NewConvolutionReverb(&AudioBuffer{Data: [][]float32{{0.5}}, SampleRate: 48000})
+	if err := restored.LoadPreset(preset); err != nil {
+		t.Fatalf("LoadPreset failed: %v", err)
+	}
+	output = make([]float32, 4000)
+	output[0] = 1
+	restored.Process([][]float32{output}, sampleRate)
+	for i, want := range ir {
+		if math.Abs(float64(output[i]-want)) > 1e-4 {
+			t.Fatalf("Restored sample %d: expected %f, got %f", i, want, output[i])
+		}
+	}
+
+	// Copies keep the impulse response the reverb was created with
+	source := &AudioBuffer{Data: [][]float32{append([]float32(nil), ir...)}, SampleRate: sampleRate}
+	original, _ := NewConvolutionReverb(source)
+	for i := range source.Data[0] {
+		source.Data[0][i] = 0
+	}
+	copied, err := original.recreate()
+	if err != nil {
+		t.Fatalf("Failed to recreate ConvolutionReverb: %v", err)
+	}
+	output = make([]float32, 4000)
+	output[0] = 1
+	copied.Process([][]float32{output}, sampleRate)
+	for i, want := range ir {
+		if math.Abs(float64(output[i]-want)) > 1e-4 {
+			t.Fatalf("Copied sample %d: expected %f, got %f", i, want, output[i])
+		}
+	}
+}
+
 func TestAudioStreamCreation(t *testing.T) {
 	// We might not be able to start/stop the stream in a CI environment without audio hardware,
 	// but we can at least test creation and closing.
@@ -127,6 +1410,52 @@ func TestAudioStreamCreation(t *testing.T) {
 	stream.Close()
 }
 
+func TestEnumerateAudioDevices(t *testing.T) {
+	for name, enumerate := range map[string]func() ([]AudioDevice, error){
+		"input":  EnumerateInputDevices,
+		"output": EnumerateOutputDevices,
+	} {
+		devices, err := enumerate()
+		if err != nil {
+			// Headless environments may have no audio driver at all
+			t.Logf("Enumerating %s devices failed (expected in some environments): %v", name, err)
+			continue
+		}
+		if devices == nil {
+			t.Errorf("Expected a non-nil %s device list", name)
+		}
+		for i, dev := range devices {
+			if dev.ID == "" || dev.Name == "" {
+				t.Errorf("%s device %d: expected a non-empty ID and name, got %+v", name, i, dev)
+			}
+		}
+	}
+}
+
+func TestAudioStreamAfterClose(t *testing.T) {
+	// A closed stream has no handle
+	s := &AudioStream{done: make(chan struct{})}
+	close(s.done)
+	if err := s.Start(); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("Start: expected ErrStreamClosed, got %v", err)
+	}
+	if err := s.Stop(); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("Stop: expected ErrStreamClosed, got %v", err)
+	}
+	if err := s.RunContext(context.Background()); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("RunContext: expected ErrStreamClosed, got %v", err)
+	}
+	if err := s.SetBufferSize(256); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("SetBufferSize: expected ErrStreamClosed, got %v", err)
+	}
+	if err := s.SetXrunCallback(nil); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("SetXrunCallback: expected ErrStreamClosed, got %v", err)
+	}
+	if _, err := s.Record(time.Second); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("Record: expected ErrStreamClosed, got %v", err)
+	}
+}
+
 func TestFileIO(t *testing.T) {
 	// Create a dummy buffer
 	original := &AudioBuffer{
@@ -143,6 +1472,9 @@ func TestFileIO(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to save audio file: %v", err)
 	}
+	if err := SaveAudioFile(tmpDir+"/missing/test_output.wav", original); !errors.Is(err, ErrSaveFailed) {
+		t.Errorf("Expected ErrSaveFailed saving into a missing directory, got %v", err)
+	}
 
 	loaded, err := LoadAudioFile(tmpFile)
 	if err != nil {
@@ -171,3 +1503,92 @@ func TestFileIO(t *testing.T) {
 		}
 	}
 }
+
+func TestFileIOMarkers(t *testing.T) {
+	original := &AudioBuffer{
+		Data: [][]float32{
+			make([]float32, 44100),
+		},
+		SampleRate: 44100.0,
+		Markers: []Marker{
+			{Name: "Intro", Position: 0, Color: "#ff0000", Type: MarkerCuePoint},
+			{Name: "Loop", Position: 500 * time.Millisecond, Color: "#00ff00", Type: MarkerLoopStart},
+			// Sample 3 is 68027.2 ns in, so its nanosecond position lies just before the sample
+			{Name: "Hit", Position: 68027 * time.Nanosecond, Color: "#0000ff", Type: MarkerCuePoint},
+		},
+	}
+
+	tmpFile := t.TempDir() + "/markers.wav"
+	if err := SaveAudioFile(tmpFile, original); err != nil {
+		t.Fatalf("Failed to save audio file: %v", err)
+	}
+
+	loaded, err := LoadAudioFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to load audio file: %v", err)
+	}
+
+	if len(loaded.Markers) != len(original.Markers) {
+		t.Fatalf("Expected %d markers, got %d", len(original.Markers), len(loaded.Markers))
+	}
+	for i, m := range original.Markers {
+		if loaded.Markers[i] != m {
+			t.Errorf("Marker %d: expected %+v, got %+v", i, m, loaded.Markers[i])
+		}
+	}
+}
+
+func TestLoadAudioFileFromZip(t *testing.T) {
+	original := sineBuffer(440, 0.5, 44100, 4410)
+	dir := t.TempDir()
+	wavPath := filepath.Join(dir, "tone.wav")
+	if err := SaveAudioFile(wavPath, original); err != nil {
+		t.Fatalf("Failed to save audio file: %v", err)
+	}
+	encoded, err := os.ReadFile(wavPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath := filepath.Join(dir, "assets.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("sounds/tone.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(encoded)
+	w, _ = zw.Create("readme.txt")
+	w.Write([]byte("not audio"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	loaded, err := LoadAudioFileFromZip(zipPath, "sounds/tone.wav")
+	if err != nil {
+		t.Fatalf("LoadAudioFileFromZip failed: %v", err)
+	}
+	if len(loaded.Data) != 1 || len(loaded.Data[0]) != len(original.Data[0]) || loaded.SampleRate != 44100 {
+		t.Fatalf("Expected 1 channel of %d samples at 44100 Hz, got %d channels at %f Hz", len(original.Data[0]), len(loaded.Data), loaded.SampleRate)
+	}
+	for i, s := range original.Data[0] {
+		if math.Abs(float64(s-loaded.Data[0][i])) > 0.001 {
+			t.Fatalf("Data mismatch at sample %d: original %f, loaded %f", i, s, loaded.Data[0][i])
+		}
+	}
+
+	if _, err := LoadAudioFileFromZip(zipPath, "sounds/missing.wav"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist for missing entry, got %v", err)
+	}
+	if _, err := LoadAudioFileFromZip(zipPath, "readme.txt"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat for non-audio entry, got %v", err)
+	}
+	if _, err := LoadAudioFileFromReader(bytes.NewReader(nil)); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat for empty data, got %v", err)
+	}
+}
+
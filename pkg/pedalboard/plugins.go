@@ -0,0 +1,268 @@
+package pedalboard
+
+/*
+#include <stdlib.h>
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// PluginFormat identifies the plugin standard a plugin is built for.
+type PluginFormat int
+
+const (
+	// PluginFormatUnknown is used when the format could not be determined.
+	PluginFormatUnknown PluginFormat = iota
+	// PluginFormatVST3 is a VST3 plugin bundle (.vst3).
+	PluginFormatVST3
+	// PluginFormatAU is an Audio Unit component (.component).
+	PluginFormatAU
+	// PluginFormatLV2 is an LV2 plugin bundle (.lv2), supported on Linux.
+	PluginFormatLV2
+)
+
+// String returns the conventional name of the plugin format.
+func (f PluginFormat) String() string {
+	switch f {
+	case PluginFormatVST3:
+		return "VST3"
+	case PluginFormatAU:
+		return "AudioUnit"
+	case PluginFormatLV2:
+		return "LV2"
+	default:
+		return "Unknown"
+	}
+}
+
+// PluginInfo describes an installed plugin.
+type PluginInfo struct {
+	// Path is the absolute path to the plugin file or bundle.
+	Path string
+	// Name is the plugin's display name.
+	Name string
+	// Vendor is the plugin manufacturer.
+	Vendor string
+	// Category is the plugin category (e.g., "Fx|Reverb").
+	Category string
+	// UniqueID identifies the plugin independently of its path.
+	UniqueID string
+	// Version is the plugin version string, if the plugin reports one.
+	Version string
+	// Format is the plugin standard.
+	Format PluginFormat
+}
+
+// NewProcessorFromPlugin loads the plugin described by info.
+// info: Plugin information, typically obtained from a plugin scan.
+// Returns a pointer to the Processor or an error if loading failed.
+func NewProcessorFromPlugin(info PluginInfo) (*Processor, error) {
+	if info.Path == "" {
+		return nil, fmt.Errorf("%w: plugin info has no path: %s", ErrInvalidArgument, info.Name)
+	}
+	return LoadPlugin(info.Path)
+}
+
+// ScanOptions configures ScanPlugins.
+type ScanOptions struct {
+	// Timeout is how long to wait for a single plugin to load; 0 waits indefinitely.
+	// A plugin that times out is reported to OnError and left loading in the background,
+	// since a hung plugin cannot be interrupted; see ScanPlugins.
+	Timeout time.Duration
+	// MaxDepth limits how many directory levels below dir are searched; 0 means no limit.
+	// Plugins directly inside dir are at depth 1.
+	MaxDepth int
+	// OnError, if set, is called for each plugin that could not be loaded and each
+	// directory that could not be read.
+	OnError func(path string, err error)
+	// Cache lists plugins known to load, typically from LoadScanCache. Plugins found at
+	// one of these paths are returned as cached without being loaded again.
+	Cache []PluginInfo
+}
+
+// ScanPlugins walks dir recursively for VST3 plugins (.vst3), Audio Units (.component)
+// and LV2 bundles (.lv2), loads each one to check that it works, and returns the description of
+// every plugin that loaded. Plugins are tried one at a time, except that a load that
+// exceeds opts.Timeout cannot be stopped: the scan moves on while it carries on in the
+// background, so it can overlap later loads and keeps running after ScanPlugins
+// returns. Plugins in opts.Cache are not loaded again.
+// Failures are reported to opts.OnError and do not stop the scan.
+// Returns an error only if dir itself cannot be read.
+func ScanPlugins(dir string, opts ScanOptions) ([]PluginInfo, error) {
+	if _, err := os.ReadDir(dir); err != nil {
+		return nil, fmt.Errorf("failed to scan plugins: %w", err)
+	}
+	report := func(path string, err error) {
+		if opts.OnError != nil {
+			opts.OnError(path, err)
+		}
+	}
+
+	cached := make(map[string]PluginInfo, len(opts.Cache))
+	for _, info := range opts.Cache {
+		cached[info.Path] = info
+	}
+
+	var plugins []PluginInfo
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			report(path, err)
+			return nil
+		}
+		format := pluginFormatForPath(path)
+		if format == PluginFormatUnknown {
+			if d.IsDir() && opts.MaxDepth > 0 && path != dir && pathDepth(dir, path) >= opts.MaxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Bundles are directories; don't search inside them
+		absPath, _ := filepath.Abs(path)
+		if info, ok := cached[absPath]; ok {
+			plugins = append(plugins, info)
+		} else if info, err := scanPlugin(path, format, opts.Timeout); err != nil {
+			report(path, err)
+		} else {
+			plugins = append(plugins, info)
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return plugins, nil
+}
+
+// pluginFormatForPath returns the plugin format implied by the extension of path.
+func pluginFormatForPath(path string) PluginFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vst3":
+		return PluginFormatVST3
+	case ".component":
+		return PluginFormatAU
+	case ".lv2":
+		return PluginFormatLV2
+	default:
+		return PluginFormatUnknown
+	}
+}
+
+// pathDepth returns how many levels path is below root.
+func pathDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// scanPlugin loads the plugin at path and returns its description, giving up after
+// timeout if it is positive.
+func scanPlugin(path string, format PluginFormat, timeout time.Duration) (PluginInfo, error) {
+	if err := checkPluginPath(path); err != nil {
+		return PluginInfo{}, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return PluginInfo{}, err
+	}
+
+	type result struct {
+		info PluginInfo
+		ok   bool
+	}
+	// Buffered so a plugin that finishes after the timeout doesn't block forever
+	done := make(chan result, 1)
+	identifier, err := pluginIdentifier(absPath)
+	if err != nil {
+		return PluginInfo{}, err
+	}
+	go func() {
+		cPath := C.CString(identifier)
+		defer C.free(unsafe.Pointer(cPath))
+
+		desc := C.pedalboard_describe_plugin(cPath)
+		if desc == nil {
+			done <- result{}
+			return
+		}
+		defer C.pedalboard_plugin_description_free(desc)
+		done <- result{info: PluginInfo{
+			Path:     absPath,
+			Name:     C.GoString(desc.name),
+			Vendor:   C.GoString(desc.vendor),
+			Category: C.GoString(desc.category),
+			UniqueID: C.GoString(desc.unique_id),
+			Version:  C.GoString(desc.version),
+			Format:   format,
+		}, ok: true}
+	}()
+
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	select {
+	case r := <-done:
+		if !r.ok {
+			return PluginInfo{}, fmt.Errorf("%w: %s", ErrPluginLoadFailed, path)
+		}
+		return r.info, nil
+	case <-timedOut:
+		return PluginInfo{}, fmt.Errorf("%w: %s did not load within %v", ErrPluginLoadFailed, path, timeout)
+	}
+}
+
+// checkPluginPath verifies that path exists and, if it is a directory, that it has the
+// layout of a plugin bundle: Contents/MacOS with an executable for an AU .component,
+// a Contents directory for a VST3 bundle, and a manifest.ttl for an LV2 bundle.
+func checkPluginPath(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrPluginNotFound, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to access plugin: %w", err)
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".component":
+		entries, err := os.ReadDir(filepath.Join(path, "Contents", "MacOS"))
+		if err != nil {
+			return fmt.Errorf("%w: %s has no Contents/MacOS directory", ErrInvalidPluginBundle, path)
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s has no executable in Contents/MacOS", ErrInvalidPluginBundle, path)
+	case ".vst3":
+		if info, err := os.Stat(filepath.Join(path, "Contents")); err != nil || !info.IsDir() {
+			return fmt.Errorf("%w: %s has no Contents directory", ErrInvalidPluginBundle, path)
+		}
+		return nil
+	case ".lv2":
+		if info, err := os.Stat(filepath.Join(path, "manifest.ttl")); err != nil || !info.Mode().IsRegular() {
+			return fmt.Errorf("%w: %s has no manifest.ttl", ErrInvalidPluginBundle, path)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s is a directory without a .component, .vst3 or .lv2 extension", ErrInvalidPluginBundle, path)
+	}
+}
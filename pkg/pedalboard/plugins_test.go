@@ -0,0 +1,104 @@
+package pedalboard
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPluginPathErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := LoadPlugin(filepath.Join(dir, "Missing.component")); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("Expected ErrPluginNotFound, got %v", err)
+	}
+
+	// A .component directory without an executable is not a bundle
+	empty := filepath.Join(dir, "Empty.component")
+	if err := os.MkdirAll(filepath.Join(empty, "Contents", "MacOS"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPlugin(empty); !errors.Is(err, ErrInvalidPluginBundle) {
+		t.Errorf("Expected ErrInvalidPluginBundle for an empty bundle, got %v", err)
+	}
+	if _, err := LoadPlugin(dir); !errors.Is(err, ErrInvalidPluginBundle) {
+		t.Errorf("Expected ErrInvalidPluginBundle for a plain directory, got %v", err)
+	}
+
+	valid := filepath.Join(dir, "Valid.component")
+	if err := os.MkdirAll(filepath.Join(valid, "Contents", "MacOS"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(valid, "Contents", "MacOS", "Valid"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPluginPath(valid); err != nil {
+		t.Errorf("Expected a complete bundle to pass, got %v", err)
+	}
+}
+
+func TestNewProcessorFromPlugin(t *testing.T) {
+	if _, err := NewProcessorFromPlugin(PluginInfo{Name: "Echo"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument without a path, got %v", err)
+	}
+	missing := PluginInfo{Name: "Echo", Path: filepath.Join(t.TempDir(), "Echo.vst3"), Format: PluginFormatVST3}
+	if _, err := NewProcessorFromPlugin(missing); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("Expected ErrPluginNotFound for a plugin that has been removed, got %v", err)
+	}
+}
+
+func TestScanPlugins(t *testing.T) {
+	if _, err := ScanPlugins(filepath.Join(t.TempDir(), "missing"), ScanOptions{}); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+
+	// Bundles without an executable fail before any plugin code runs
+	dir := t.TempDir()
+	for _, bundle := range []string{"Top.component", filepath.Join("Vendor", "Nested.component"), filepath.Join("a", "b", "Deep.component")} {
+		if err := os.MkdirAll(filepath.Join(dir, bundle, "Contents", "MacOS"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := map[string]error{}
+	plugins, err := ScanPlugins(dir, ScanOptions{
+		MaxDepth: 2,
+		OnError:  func(path string, err error) { failed[filepath.Base(path)] = err },
+	})
+	if err != nil {
+		t.Fatalf("ScanPlugins failed: %v", err)
+	}
+	if len(plugins) != 0 {
+		t.Errorf("Expected no plugins, got %v", plugins)
+	}
+	if len(failed) != 2 {
+		t.Errorf("Expected failures for the two bundles within depth 2, got %v", failed)
+	}
+	for _, name := range []string{"Top.component", "Nested.component"} {
+		if !errors.Is(failed[name], ErrInvalidPluginBundle) {
+			t.Errorf("%s: expected ErrInvalidPluginBundle, got %v", name, failed[name])
+		}
+	}
+
+	failed = map[string]error{}
+	ScanPlugins(dir, ScanOptions{OnError: func(path string, err error) { failed[filepath.Base(path)] = err }})
+	if _, ok := failed["Deep.component"]; !ok || len(failed) != 3 {
+		t.Errorf("Expected all three bundles to be tried without a depth limit, got %v", failed)
+	}
+}
+
+func TestLoadPluginWithConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, cfg := range []PluginConfig{{NumInputs: -1, NumOutputs: 2}, {NumInputs: 2, NumOutputs: 0}} {
+		if _, err := LoadPluginWithConfig(dir, cfg); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("Expected ErrInvalidArgument for %+v, got %v", cfg, err)
+		}
+	}
+	if _, err := LoadPluginWithConfig(filepath.Join(dir, "Missing.vst3"), PluginConfig{NumInputs: 6, NumOutputs: 6}); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("Expected ErrPluginNotFound, got %v", err)
+	}
+}
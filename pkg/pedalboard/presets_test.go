@@ -0,0 +1,61 @@
+package pedalboard
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMorphValues(t *testing.T) {
+	from := []float32{0.0, 1.0, 0.5}
+	to := []float32{1.0, 0.0}
+
+	var frames [][]float32
+	for values := range morphValues(from, to, 40*time.Millisecond, 10*time.Millisecond) {
+		frames = append(frames, values)
+	}
+
+	if len(frames) != 5 {
+		t.Fatalf("Expected 5 frames, got %d", len(frames))
+	}
+	if len(frames[0]) != 2 {
+		t.Fatalf("Expected 2 common parameters, got %d", len(frames[0]))
+	}
+	if frames[0][0] != 0.0 || frames[0][1] != 1.0 {
+		t.Errorf("First frame should match from values: %v", frames[0])
+	}
+	if frames[2][0] != 0.5 || frames[2][1] != 0.5 {
+		t.Errorf("Middle frame should be halfway: %v", frames[2])
+	}
+	last := frames[len(frames)-1]
+	if last[0] != 1.0 || last[1] != 0.0 {
+		t.Errorf("Last frame should match to values: %v", last)
+	}
+}
+
+func TestComparePresets(t *testing.T) {
+	delay, _ := NewInternalProcessor("Delay")
+	a, _ := delay.SavePreset()
+	delay.SetParameter(0, 0.8)
+	delay.SetParameter(2, 0.1)
+	b, _ := delay.SavePreset()
+	delay.SetParameter(0, 0.3)
+
+	diffs, err := ComparePresets(delay, a, b)
+	if err != nil {
+		t.Fatalf("ComparePresets failed: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].Index != 0 || diffs[0].ValueB != 0.8 || diffs[1].Index != 2 || diffs[1].ValueB != 0.1 {
+		t.Errorf("Expected parameters 0 and 2 to differ, got %+v", diffs)
+	}
+	if delay.GetParameter(0) != 0.3 {
+		t.Error("Expected the processor's own state to be restored")
+	}
+
+	if diffs, err := ComparePresets(delay, a, a); err != nil || len(diffs) != 0 {
+		t.Errorf("Expected no differences between identical presets, got %+v (%v)", diffs, err)
+	}
+	if _, err := ComparePresets(delay, a, []byte("garbage")); !errors.Is(err, ErrInvalidPreset) {
+		t.Errorf("Expected ErrInvalidPreset, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	"unsafe"
+)
+
+const (
+	// recordRingSeconds is the length of the capture ring buffer shared with the audio thread.
+	recordRingSeconds = 2
+	// recordPollInterval is how often captured samples are drained from the ring buffer.
+	recordPollInterval = 10 * time.Millisecond
+)
+
+// Record captures live input for the given duration and returns it as an AudioBuffer.
+// The stream is started if it is not already running, and stopped again afterwards.
+// duration: The length of audio to capture.
+// Returns the captured audio or an error if capture failed.
+func (s *AudioStream) Record(duration time.Duration) (*AudioBuffer, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("%w: record duration %v", ErrInvalidArgument, duration)
+	}
+	return s.record(context.Background(), duration)
+}
+
+// RecordContext captures live input until ctx is cancelled and returns everything recorded.
+// The stream is started if it is not already running, and stopped again afterwards.
+// Returns the captured audio or an error if capture failed.
+func (s *AudioStream) RecordContext(ctx context.Context) (*AudioBuffer, error) {
+	return s.record(ctx, 0)
+}
+
+// record drains input from a ring buffer filled by the audio thread. A duration
+// of zero records until ctx is done. Returns ErrStreamClosed if the stream is closed
+// before or while recording.
+func (s *AudioStream) record(ctx context.Context, duration time.Duration) (*AudioBuffer, error) {
+	// Keep Close from freeing the stream until recording has finished
+	s.mu.Lock()
+	if s.closing() {
+		s.mu.Unlock()
+		return nil, ErrStreamClosed
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	numChannels := int(C.pedalboard_audio_stream_get_num_input_channels(s.handle))
+	sampleRate := float64(C.pedalboard_audio_stream_get_sample_rate(s.handle))
+	if numChannels <= 0 || sampleRate <= 0 {
+		return nil, fmt.Errorf("%w: stream has no active input device", ErrDeviceNotFound)
+	}
+
+	target := int64(0)
+	if duration > 0 {
+		target = int64(math.Round(duration.Seconds() * sampleRate))
+	}
+
+	// The ring is allocated here, in C memory, so the audio thread never allocates.
+	capacity := int(sampleRate) * recordRingSeconds
+	cChannels := (**C.float)(C.malloc(C.size_t(numChannels) * C.size_t(unsafe.Sizeof((*C.float)(nil)))))
+	if cChannels == nil {
+		return nil, ErrOutOfMemory
+	}
+	defer C.free(unsafe.Pointer(cChannels))
+
+	ring := make([][]float32, numChannels)
+	cChannelSlice := unsafe.Slice(cChannels, numChannels)
+	for ch := range cChannelSlice {
+		cChannelSlice[ch] = (*C.float)(C.malloc(C.size_t(capacity) * C.size_t(unsafe.Sizeof(C.float(0)))))
+		if cChannelSlice[ch] == nil {
+			for i := 0; i < ch; i++ {
+				C.free(unsafe.Pointer(cChannelSlice[i]))
+			}
+			return nil, ErrOutOfMemory
+		}
+		defer C.free(unsafe.Pointer(cChannelSlice[ch]))
+		ring[ch] = unsafe.Slice((*float32)(unsafe.Pointer(cChannelSlice[ch])), capacity)
+	}
+
+	C.pedalboard_audio_stream_begin_capture(s.handle, cChannels, C.int(numChannels), C.int(capacity))
+	defer C.pedalboard_audio_stream_end_capture(s.handle)
+
+	s.mu.Lock()
+	started := false
+	if C.pedalboard_audio_stream_is_running(s.handle) == 0 {
+		if err := s.startLocked(); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		started = true
+	}
+	s.mu.Unlock()
+	if started {
+		defer s.Stop()
+	}
+
+	data := make([][]float32, numChannels)
+	if target > 0 {
+		for ch := range data {
+			data[ch] = make([]float32, 0, target)
+		}
+	}
+
+	var read int64
+	drain := func() error {
+		written := int64(C.pedalboard_audio_stream_get_captured_samples(s.handle))
+		if written-read > int64(capacity) {
+			return fmt.Errorf("record overrun: %d samples lost", written-read-int64(capacity))
+		}
+		if target > 0 && written > target {
+			written = target
+		}
+		for ; read < written; read++ {
+			idx := int(read % int64(capacity))
+			for ch := range data {
+				data[ch] = append(data[ch], ring[ch][idx])
+			}
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(recordPollInterval)
+	defer ticker.Stop()
+	for {
+		if err := drain(); err != nil {
+			return nil, err
+		}
+		if target > 0 && read >= target {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			if target > 0 {
+				return nil, ctx.Err()
+			}
+			if err := drain(); err != nil {
+				return nil, err
+			}
+			return &AudioBuffer{Data: data, SampleRate: sampleRate}, nil
+		case <-s.done:
+			return nil, ErrStreamClosed
+		case <-ticker.C:
+		}
+	}
+
+	return &AudioBuffer{Data: data, SampleRate: sampleRate}, nil
+}
@@ -0,0 +1,177 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// AutoRestartOptions configures ProcessorChain.EnableAutoRestart.
+type AutoRestartOptions struct {
+	// MaxRestarts is the total number of restarts allowed across all stages; 0 means no limit.
+	MaxRestarts int
+	// CooldownPeriod is the minimum time between two restarts of the same stage. A stage
+	// that fails again sooner is not restarted.
+	CooldownPeriod time.Duration
+	// OnFailure, if set, is called from Process when a stage fails and is not restarted,
+	// with the stage's index and an error describing the failure. A sandbox failure is
+	// wrapped, so errors.Is(err, ErrSandboxFailed) reports it. If nil, the failure is logged.
+	OnFailure func(stage int, err error)
+}
+
+// autoRestart tracks the restarts performed for a chain.
+type autoRestart struct {
+	ctx      context.Context
+	opts     AutoRestartOptions
+	mu       sync.Mutex
+	restarts int
+	last     map[*Processor]time.Time
+	failed   map[*Processor]bool // Stages that failed and were not restarted
+}
+
+// EnableAutoRestart makes Process recover from a failing stage by restarting it and
+// continuing with the rest of the chain; the failing stage's block passes through
+// unprocessed. Each restart is logged. A stage fails when processing it raises a Go
+// panic or, for a sandboxed plugin (NewSandboxedPlugin), when its host process crashes
+// or hangs. A crash inside a plugin loaded in-process ends the program and cannot be
+// recovered, so load plugins that may crash with NewSandboxedPlugin.
+// A restarted stage is recreated (reloading the plugin or rebuilding the internal
+// processor) with its full state and parameter values, and the stage's Processor refers
+// to the new instance from then on. A sandboxed plugin gets a new host process with the
+// parameter values set through the Processor; other state is lost with the old host.
+// While enabled, Process runs the stages one at a time so a failing stage can be
+// identified, and MIDI is not passed between stages. A stage that cannot be restarted
+// (the limits in opts are reached, or the stage cannot be recreated) is reported to
+// opts.OnFailure and skipped from then on, passing its audio through.
+// Auto-restart stays enabled until ctx is done; calling EnableAutoRestart again
+// replaces the options and resets the restart count and the skipped stages.
+func (c *ProcessorChain) EnableAutoRestart(ctx context.Context, opts AutoRestartOptions) {
+	c.restartLock.Lock()
+	defer c.restartLock.Unlock()
+	c.restart = &autoRestart{ctx: ctx, opts: opts, last: make(map[*Processor]time.Time), failed: make(map[*Processor]bool)}
+}
+
+// activeRestart returns the auto-restart state, or nil if it is disabled or expired.
+func (c *ProcessorChain) activeRestart() *autoRestart {
+	c.restartLock.Lock()
+	defer c.restartLock.Unlock()
+	if c.restart != nil && c.restart.ctx.Err() != nil {
+		c.restart = nil
+	}
+	return c.restart
+}
+
+// processWithRestart runs each stage in turn, restarting stages that fail.
+func (c *ProcessorChain) processWithRestart(r *autoRestart, buffer [][]float32, sampleRate float64) {
+	stages := c.runningStages()
+	for i := 0; i < len(stages); i++ {
+		if c.disabled[i] {
+			continue
+		}
+		stage := stages[i]
+		if r.hasFailed(stage) {
+			continue
+		}
+		failure := runStage(stage, buffer, sampleRate)
+		if failure == nil {
+			continue
+		}
+		if err := c.restartStage(r, i, stage); err != nil {
+			r.fail(i, stage, failure, err)
+			continue
+		}
+		log.Printf("pedalboard: restarted chain stage %d after failure: %v", i, failure)
+	}
+}
+
+// hasFailed reports whether stage failed earlier and was not restarted.
+func (r *autoRestart) hasFailed(stage *Processor) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failed[stage]
+}
+
+// fail marks the stage at index as failed, so it is skipped from now on, and reports
+// why it was not restarted.
+func (r *autoRestart) fail(index int, stage *Processor, failure any, restartErr error) {
+	r.mu.Lock()
+	r.failed[stage] = true
+	r.mu.Unlock()
+
+	cause, ok := failure.(error)
+	if !ok {
+		cause = fmt.Errorf("panic: %v", failure)
+	}
+	err := fmt.Errorf("chain stage %d not restarted (%v): %w", index, restartErr, cause)
+	if r.opts.OnFailure != nil {
+		r.opts.OnFailure(index, err)
+		return
+	}
+	log.Printf("pedalboard: %v; the stage is skipped", err)
+}
+
+// runStage processes one stage and returns the value of any panic it raised or the
+// error of a failed sandbox host.
+func runStage(stage *Processor, buffer [][]float32, sampleRate float64) (failure any) {
+	defer func() {
+		if v := recover(); v != nil {
+			failure = v
+		}
+	}()
+	if err := stage.ProcessContext(context.Background(), buffer, sampleRate); errors.Is(err, ErrSandboxFailed) {
+		return err
+	}
+	return nil
+}
+
+// restartStage gives the stage at index a fresh instance carrying the same state, if
+// the restart limits allow it.
+func (c *ProcessorChain) restartStage(r *autoRestart, index int, stage *Processor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.opts.MaxRestarts > 0 && r.restarts >= r.opts.MaxRestarts {
+		return fmt.Errorf("restart limit of %d reached", r.opts.MaxRestarts)
+	}
+	if last, ok := r.last[stage]; ok && time.Since(last) < r.opts.CooldownPeriod {
+		return fmt.Errorf("failed again within the %v cooldown", r.opts.CooldownPeriod)
+	}
+
+	var err error
+	if stage.sandbox != nil {
+		err = stage.sandbox.restart()
+	} else {
+		err = c.reloadStage(index, stage)
+	}
+	if err != nil {
+		return err
+	}
+	r.restarts++
+	r.last[stage] = time.Now()
+	return nil
+}
+
+// reloadStage replaces the native processor behind the stage at index with a snapshot
+// of it, moves the snapshot into stage so callers holding stage keep a valid handle, and
+// frees the old processor.
+func (c *ProcessorChain) reloadStage(index int, stage *Processor) error {
+	fresh, err := snapshotProcessor(stage)
+	if err != nil {
+		return err
+	}
+	if C.pedalboard_chain_replace(c.proc.handle, C.int(index), fresh.handle) == 0 {
+		return fmt.Errorf("failed to replace stage %d", index)
+	}
+	old := stage.handle
+	stage.handle, fresh.handle = fresh.handle, nil
+	runtime.SetFinalizer(fresh, nil)
+	C.pedalboard_processor_free(old)
+	return nil
+}
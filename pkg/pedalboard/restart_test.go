@@ -0,0 +1,107 @@
+//go:build linux || darwin
+
+package pedalboard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnableAutoRestart(t *testing.T) {
+	half := newTestSandbox(t, "Gain")
+	half.SetParameter(0, 0.5)
+	chain, err := NewProcessorChain(half)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var failures []error
+	chain.EnableAutoRestart(ctx, AutoRestartOptions{
+		MaxRestarts:    1,
+		CooldownPeriod: time.Hour,
+		OnFailure: func(stage int, err error) {
+			if stage != 0 {
+				t.Errorf("Expected stage 0 to fail, got %d", stage)
+			}
+			failures = append(failures, err)
+		},
+	})
+
+	buffer := [][]float32{{1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][1] != 0.5 {
+		t.Fatalf("Expected 0.5 from the sandboxed stage, got %f", buffer[0][1])
+	}
+
+	// The block the host crashes on passes through, and the stage gets a new host
+	crashSandbox(t, half)
+	buffer = [][]float32{{1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][1] != 1 {
+		t.Errorf("Expected the failed block to pass through, got %f", buffer[0][1])
+	}
+	if stage, _ := chain.GetStage(0); stage != half {
+		t.Error("Expected the restarted stage to keep its Processor")
+	}
+	if v := half.GetParameter(0); v != 0.5 {
+		t.Errorf("Expected the restarted stage to keep its parameter, got %f", v)
+	}
+	buffer = [][]float32{{1, 1}}
+	chain.Process(buffer, 44100.0)
+	if buffer[0][1] != 0.5 {
+		t.Errorf("Expected 0.5 from the restarted stage, got %f", buffer[0][1])
+	}
+
+	// Past MaxRestarts the failure is reported and the stage is skipped from then on
+	crashSandbox(t, half)
+	for i := 0; i < 2; i++ {
+		buffer = [][]float32{{1, 1}}
+		chain.Process(buffer, 44100.0)
+		if buffer[0][1] != 1 {
+			t.Errorf("Block %d: expected the failed stage to pass audio through, got %f", i, buffer[0][1])
+		}
+	}
+	if len(failures) != 1 || !errors.Is(failures[0], ErrSandboxFailed) {
+		t.Errorf("Expected one ErrSandboxFailed failure, got %v", failures)
+	}
+
+	// Once the context is done, the chain runs as a whole again
+	cancel()
+	if chain.activeRestart() != nil {
+		t.Error("Expected auto-restart to end with its context")
+	}
+}
+
+func TestSyncedChainAutoRestart(t *testing.T) {
+	half := newTestSandbox(t, "Gain")
+	half.SetParameter(0, 0.5)
+	chain, err := NewProcessorChain(half)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	chain.EnableAutoRestart(context.Background(), AutoRestartOptions{})
+	synced := NewSyncedChain(chain)
+
+	// Concurrent Process calls must not race with the restart they trigger (go test -race)
+	crashSandbox(t, half)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				synced.Process([][]float32{{1, 1}}, 44100.0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	buffer := [][]float32{{1, 1}}
+	synced.Process(buffer, 44100.0)
+	if buffer[0][1] != 0.5 {
+		t.Errorf("Expected 0.5 from the restarted stage, got %f", buffer[0][1])
+	}
+}
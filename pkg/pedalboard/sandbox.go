@@ -0,0 +1,466 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/cgo"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// ProcessorSandboxConfig configures NewSandboxedPlugin.
+type ProcessorSandboxConfig struct {
+	// TimeoutPerBlock is how long the plugin may take to process one block (or answer a
+	// parameter request) before its host process is killed; 0 waits indefinitely.
+	TimeoutPerBlock time.Duration
+	// MaxMemoryMB limits the address space of the host process (RLIMIT_AS); 0 means no
+	// limit. The host itself (Go runtime and JUCE) needs some of it, so allow a few
+	// hundred MB more than the plugin uses. macOS may refuse the limit, in which case
+	// the plugin fails to load.
+	MaxMemoryMB int
+	// AllowNetworkAccess lets the plugin use the network. When false the host runs in a
+	// network namespace of its own on Linux (which needs unprivileged user namespaces)
+	// and under sandbox-exec on macOS.
+	AllowNetworkAccess bool
+	// HostPath is the executable that hosts the plugin; empty looks up the pedalboard
+	// command on PATH. Any program that calls RunSandboxHost for the "sandbox-host"
+	// command will do.
+	HostPath string
+}
+
+const (
+	// sandboxSharedSamples is the capacity, in float32 samples across all channels, of
+	// the memory shared with a sandbox host. Larger buffers are sent in several blocks.
+	sandboxSharedSamples = 1 << 20
+	// sandboxStartTimeout bounds how long a host may take to load its plugin.
+	sandboxStartTimeout = 30 * time.Second
+
+	// Requests sent to the host. Each is answered before the next is sent.
+	sandboxOpProcess  byte = 1 // int32 channels, int32 samples, float64 rate -> byte status
+	sandboxOpSetParam byte = 2 // int32 index, float32 value -> byte status
+	sandboxOpGetParam byte = 3 // int32 index -> float32 value
+	sandboxOpReset    byte = 4 // -> byte status
+)
+
+// sandboxClient talks to the host process of a sandboxed plugin. Requests go over a
+// pipe; audio is exchanged through shared memory, channel after channel.
+type sandboxClient struct {
+	host string
+	path string
+	cfg  ProcessorSandboxConfig
+
+	mu        sync.Mutex
+	params    map[int]float32 // Values set through setParameter, replayed by restart
+	cmd       *exec.Cmd
+	requests  *os.File
+	replies   *os.File
+	shared    []byte
+	samples   []float32 // Aliases shared
+	timeout   time.Duration
+	numParams int
+	err       error // Set once the host has failed
+}
+
+// errSandboxed returns the error of a Processor method that sandboxed plugins don't support.
+func errSandboxed(method string) error {
+	return fmt.Errorf("%w for sandboxed plugins: %s", ErrNotSupported, method)
+}
+
+// NewSandboxedPlugin loads the VST3 or AU plugin at path in a separate host process, so
+// a plugin that crashes, hangs or leaks takes down only that process. Audio is passed
+// through shared memory, so each Process call costs two context switches rather than a
+// copy through a pipe.
+// The returned Processor supports Process, ProcessContext, parameters, Reset and
+// bypass, and can be a ProcessorChain stage; it cannot be run by an AudioStream, and
+// MIDI is not forwarded. Parameter ramps are applied at once, and methods that need
+// the plugin itself (parameter text, names, ranges, units and choices, presets and
+// Trigger) return an error wrapping ErrNotSupported. After a failure, ProcessContext returns an error wrapping
+// ErrSandboxFailed and leaves the buffer unprocessed (a chain stage passes its audio
+// through); create a new processor to continue, or let ProcessorChain.EnableAutoRestart
+// restart the host.
+// Returns the Processor, or an error if the host could not be started or could not load
+// the plugin.
+func NewSandboxedPlugin(path string, cfg ProcessorSandboxConfig) (*Processor, error) {
+	if err := checkPluginPath(path); err != nil {
+		return nil, err
+	}
+	host := cfg.HostPath
+	if host == "" {
+		var err error
+		if host, err = exec.LookPath("pedalboard"); err != nil {
+			return nil, fmt.Errorf("%w: no sandbox host: %w", ErrSandboxFailed, err)
+		}
+	}
+
+	client := &sandboxClient{host: host, path: path, cfg: cfg, params: make(map[int]float32)}
+	if err := client.start(); err != nil {
+		return nil, err
+	}
+	external := cgo.NewHandle(client)
+	p := &Processor{handle: C.pedalboard_create_external_processor(C.uintptr_t(external)), sandbox: client}
+	runtime.SetFinalizer(p, func(obj *Processor) {
+		C.pedalboard_processor_free(obj.handle)
+		external.Delete()
+		obj.sandbox.close()
+	})
+	p.recreate = func() (*Processor, error) { return NewSandboxedPlugin(path, cfg) }
+	return p, nil
+}
+
+// start launches the host with the plugin and waits until it has loaded. c.mu must be
+// held unless c is new.
+func (c *sandboxClient) start() error {
+	shm, err := os.CreateTemp("", "pedalboard-sandbox-*")
+	if err != nil {
+		return fmt.Errorf("failed to create shared memory: %w", err)
+	}
+	// The host inherits the open file, so the name is not needed
+	os.Remove(shm.Name())
+	defer shm.Close()
+	if err := shm.Truncate(sandboxSharedSamples * 4); err != nil {
+		return fmt.Errorf("failed to create shared memory: %w", err)
+	}
+	shared, err := mapSharedFile(shm, sandboxSharedSamples*4)
+	if err != nil {
+		return fmt.Errorf("failed to map shared memory: %w", err)
+	}
+
+	requestsRead, requestsWrite, err := os.Pipe()
+	if err != nil {
+		unmapSharedFile(shared)
+		return err
+	}
+	repliesRead, repliesWrite, err := os.Pipe()
+	if err != nil {
+		unmapSharedFile(shared)
+		requestsRead.Close()
+		requestsWrite.Close()
+		return err
+	}
+
+	// The host finds the request pipe, reply pipe and shared memory at fds 3, 4 and 5
+	cmd := exec.Command(c.host, "sandbox-host", "-max-memory", strconv.Itoa(c.cfg.MaxMemoryMB), c.path)
+	cmd.ExtraFiles = []*os.File{requestsRead, repliesWrite, shm}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if !c.cfg.AllowNetworkAccess {
+		err = isolateNetwork(cmd)
+	}
+	if err == nil {
+		err = cmd.Start()
+	}
+	requestsRead.Close()
+	repliesWrite.Close()
+	c.cmd = cmd
+	c.requests = requestsWrite
+	c.replies = repliesRead
+	c.shared = shared
+	c.samples = unsafe.Slice((*float32)(unsafe.Pointer(&shared[0])), sandboxSharedSamples)
+	c.timeout = c.cfg.TimeoutPerBlock
+	c.err = nil
+	if err != nil {
+		c.stop()
+		return fmt.Errorf("%w: failed to start host: %w", ErrSandboxFailed, err)
+	}
+
+	// The host answers with the parameter count, or -1 and a message
+	var numParams int32
+	if err := c.read(&numParams, sandboxStartTimeout); err != nil {
+		c.stop()
+		return fmt.Errorf("%w: host did not start: %w", ErrSandboxFailed, err)
+	}
+	if numParams < 0 {
+		var message [256]byte
+		n, _ := c.replies.Read(message[:])
+		c.stop()
+		return fmt.Errorf("%w: %s", ErrPluginLoadFailed, message[:n])
+	}
+	c.numParams = int(numParams)
+	return nil
+}
+
+// read reads a fixed-size reply, giving up after timeout if it is positive.
+func (c *sandboxClient) read(data any, timeout time.Duration) error {
+	deadline := time.Time{}
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	c.replies.SetReadDeadline(deadline)
+	return binary.Read(c.replies, binary.LittleEndian, data)
+}
+
+// request sends op and its arguments and reads the reply into reply. Any failure kills
+// the host and is returned for every later request.
+func (c *sandboxClient) request(reply any, op byte, args ...any) error {
+	if c.err != nil {
+		return c.err
+	}
+	message := []byte{op}
+	for _, arg := range args {
+		message, _ = binary.Append(message, binary.LittleEndian, arg)
+	}
+	if _, err := c.requests.Write(message); err != nil {
+		return c.fail(err)
+	}
+	if err := c.read(reply, c.timeout); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			err = fmt.Errorf("no reply within %v", c.timeout)
+		}
+		return c.fail(err)
+	}
+	return nil
+}
+
+// fail records err as the host's failure and stops the host.
+func (c *sandboxClient) fail(err error) error {
+	c.err = fmt.Errorf("%w: %w", ErrSandboxFailed, err)
+	c.cmd.Process.Kill()
+	return c.err
+}
+
+// process runs buffer through the plugin in blocks that fit the shared memory.
+func (c *sandboxClient) process(ctx context.Context, buffer [][]float32, sampleRate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	numChannels := len(buffer)
+	numSamples := len(buffer[0])
+	blockSize := sandboxSharedSamples / numChannels
+	if blockSize == 0 {
+		return fmt.Errorf("%w: %d channels", ErrInvalidArgument, numChannels)
+	}
+	for start := 0; start < numSamples; start += blockSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(blockSize, numSamples-start)
+		for ch := range buffer {
+			copy(c.samples[ch*n:(ch+1)*n], buffer[ch][start:start+n])
+		}
+		var status byte
+		if err := c.request(&status, sandboxOpProcess, int32(numChannels), int32(n), sampleRate); err != nil {
+			return err
+		}
+		if status != 0 {
+			return fmt.Errorf("%w: processor rejected a buffer of %d channels", ErrInvalidArgument, numChannels)
+		}
+		for ch := range buffer {
+			copy(buffer[ch][start:start+n], c.samples[ch*n:(ch+1)*n])
+		}
+	}
+	return nil
+}
+
+func (c *sandboxClient) setParameter(index int, value float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.params[index] = value
+	var status byte
+	c.request(&status, sandboxOpSetParam, int32(index), value)
+}
+
+func (c *sandboxClient) getParameter(index int) float32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var value float32
+	c.request(&value, sandboxOpGetParam, int32(index))
+	return value
+}
+
+func (c *sandboxClient) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var status byte
+	c.request(&status, sandboxOpReset)
+}
+
+// restart replaces a failed host with a new one and sets the parameter values set so
+// far on it. Other plugin state is lost with the old host.
+func (c *sandboxClient) restart() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop()
+	if err := c.start(); err != nil {
+		c.err = fmt.Errorf("%w: restart failed: %w", ErrSandboxFailed, err)
+		return err
+	}
+	for index, value := range c.params {
+		var status byte
+		if err := c.request(&status, sandboxOpSetParam, int32(index), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close stops the host and releases the shared memory.
+func (c *sandboxClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop()
+	if c.err == nil {
+		c.err = fmt.Errorf("%w: closed", ErrSandboxFailed)
+	}
+}
+
+// stop stops the host, if running, and releases the shared memory. c.mu must be held.
+func (c *sandboxClient) stop() {
+	if c.shared == nil {
+		return
+	}
+	// Closing the request pipe makes a healthy host exit
+	c.requests.Close()
+	if c.cmd.Process != nil {
+		done := make(chan struct{})
+		go func() {
+			c.cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			c.cmd.Process.Kill()
+			<-done
+		}
+	}
+	c.replies.Close()
+	unmapSharedFile(c.shared)
+	c.shared, c.samples = nil, nil
+}
+
+//export goPedalboardProcessExternal
+func goPedalboardProcessExternal(external C.uintptr_t, samples **C.float, numChannels, numSamples C.int, sampleRate C.double) {
+	client, ok := cgo.Handle(external).Value().(*sandboxClient)
+	if !ok || numChannels <= 0 || numSamples <= 0 {
+		return
+	}
+	buffer := make([][]float32, int(numChannels))
+	for ch, ptr := range unsafe.Slice(samples, int(numChannels)) {
+		buffer[ch] = unsafe.Slice((*float32)(unsafe.Pointer(ptr)), int(numSamples))
+	}
+	// A failure leaves the block unprocessed; ProcessContext reports it
+	client.process(context.Background(), buffer, float64(sampleRate))
+}
+
+// RunSandboxHost is the host side of NewSandboxedPlugin. A program named by
+// ProcessorSandboxConfig.HostPath must call it with its arguments when its first
+// argument is "sandbox-host" (the pedalboard command does this). args are the remaining
+// arguments. It serves requests until the parent closes the connection.
+func RunSandboxHost(args []string) error {
+	return runSandboxHost(args, LoadPlugin)
+}
+
+// runSandboxHost is RunSandboxHost with the plugin loaded by load.
+func runSandboxHost(args []string, load func(path string) (*Processor, error)) error {
+	flags := flag.NewFlagSet("sandbox-host", flag.ContinueOnError)
+	maxMemory := flags.Int("max-memory", 0, "address space limit in MB (0 = none)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: sandbox-host [-max-memory mb] plugin")
+	}
+	requests := os.NewFile(3, "requests")
+	replies := os.NewFile(4, "replies")
+	shm := os.NewFile(5, "shared")
+	if requests == nil || replies == nil || shm == nil {
+		return fmt.Errorf("sandbox-host must be started by NewSandboxedPlugin")
+	}
+
+	shared, err := mapSharedFile(shm, sandboxSharedSamples*4)
+	if err != nil {
+		return fmt.Errorf("failed to map shared memory: %w", err)
+	}
+	samples := unsafe.Slice((*float32)(unsafe.Pointer(&shared[0])), sandboxSharedSamples)
+
+	write := func(data any) error { return binary.Write(replies, binary.LittleEndian, data) }
+	var plugin *Processor
+	if *maxMemory > 0 {
+		err = limitMemory(*maxMemory)
+	}
+	if err == nil {
+		plugin, err = load(flags.Arg(0))
+	}
+	if err != nil {
+		write(int32(-1))
+		replies.Write([]byte(err.Error()))
+		return err
+	}
+	if err := write(int32(plugin.NumParameters())); err != nil {
+		return err
+	}
+
+	for {
+		var op byte
+		if err := binary.Read(requests, binary.LittleEndian, &op); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		switch op {
+		case sandboxOpProcess:
+			var header struct {
+				Channels, Samples int32
+				SampleRate        float64
+			}
+			if err := binary.Read(requests, binary.LittleEndian, &header); err != nil {
+				return err
+			}
+			numChannels, n := int(header.Channels), int(header.Samples)
+			status := byte(1)
+			if numChannels > 0 && n >= 0 && numChannels*n <= len(samples) {
+				buffer := make([][]float32, numChannels)
+				for ch := range buffer {
+					buffer[ch] = samples[ch*n : (ch+1)*n]
+				}
+				if plugin.ProcessContext(context.Background(), buffer, header.SampleRate) == nil {
+					status = 0
+				}
+			}
+			err = write(status)
+		case sandboxOpSetParam:
+			var req struct {
+				Index int32
+				Value float32
+			}
+			if err := binary.Read(requests, binary.LittleEndian, &req); err != nil {
+				return err
+			}
+			plugin.SetParameter(int(req.Index), req.Value)
+			err = write(byte(0))
+		case sandboxOpGetParam:
+			var index int32
+			if err := binary.Read(requests, binary.LittleEndian, &index); err != nil {
+				return err
+			}
+			value := plugin.GetParameter(int(index))
+			if math.IsNaN(float64(value)) {
+				value = 0
+			}
+			err = write(value)
+		case sandboxOpReset:
+			plugin.Reset()
+			err = write(byte(0))
+		default:
+			return fmt.Errorf("unknown sandbox request %d", op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
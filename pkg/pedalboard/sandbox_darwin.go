@@ -0,0 +1,17 @@
+package pedalboard
+
+import "os/exec"
+
+// sandboxProfile allows everything except networking.
+const sandboxProfile = "(version 1) (allow default) (deny network*)"
+
+// isolateNetwork makes cmd run under sandbox-exec with networking denied.
+func isolateNetwork(cmd *exec.Cmd) error {
+	path, err := exec.LookPath("sandbox-exec")
+	if err != nil {
+		return err
+	}
+	cmd.Args = append([]string{"sandbox-exec", "-p", sandboxProfile, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = path
+	return nil
+}
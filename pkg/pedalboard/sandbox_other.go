@@ -0,0 +1,27 @@
+//go:build !linux && !darwin
+
+package pedalboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Sandboxed plugins need shared memory and process isolation, which are only
+// implemented for Linux and macOS.
+
+func mapSharedFile(*os.File, int) ([]byte, error) {
+	return nil, fmt.Errorf("%w: sandboxing is not supported on %s", ErrSandboxFailed, runtime.GOOS)
+}
+
+func unmapSharedFile([]byte) {}
+
+func limitMemory(int) error {
+	return fmt.Errorf("%w: memory limits are not supported on %s", ErrSandboxFailed, runtime.GOOS)
+}
+
+func isolateNetwork(*exec.Cmd) error {
+	return fmt.Errorf("%w: network isolation is not supported on %s", ErrSandboxFailed, runtime.GOOS)
+}
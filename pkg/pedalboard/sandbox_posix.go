@@ -0,0 +1,23 @@
+//go:build linux || darwin
+
+package pedalboard
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapSharedFile maps size bytes of f into memory shared with other processes.
+func mapSharedFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func unmapSharedFile(data []byte) {
+	syscall.Munmap(data)
+}
+
+// limitMemory caps the address space of the current process at mb megabytes.
+func limitMemory(mb int) error {
+	limit := uint64(mb) << 20
+	return syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: limit, Max: limit})
+}
@@ -0,0 +1,170 @@
+//go:build linux || darwin
+
+package pedalboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary serve as a sandbox host. Its plugins are internal
+// processors: the host for Gain.vst3 runs a "Gain" processor.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == "sandbox-host" {
+		err := runSandboxHost(os.Args[2:], func(path string) (*Processor, error) {
+			return NewInternalProcessor(strings.TrimSuffix(filepath.Base(path), ".vst3"))
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestSandbox returns the internal processor name hosted by the test binary.
+func newTestSandbox(t *testing.T, name string) *Processor {
+	t.Helper()
+	bundle := filepath.Join(t.TempDir(), name+".vst3")
+	if err := os.MkdirAll(filepath.Join(bundle, "Contents"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	host, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := ProcessorSandboxConfig{TimeoutPerBlock: 5 * time.Second, AllowNetworkAccess: true, HostPath: host}
+	p, err := NewSandboxedPlugin(bundle, cfg)
+	if err != nil {
+		t.Fatalf("NewSandboxedPlugin failed: %v", err)
+	}
+	return p
+}
+
+// crashSandbox kills the host process of a sandboxed processor.
+func crashSandbox(t *testing.T, p *Processor) {
+	t.Helper()
+	if err := p.sandbox.cmd.Process.Kill(); err != nil {
+		t.Fatalf("Failed to kill the sandbox host: %v", err)
+	}
+}
+
+func TestNewSandboxedPluginErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewSandboxedPlugin(filepath.Join(dir, "Missing.vst3"), ProcessorSandboxConfig{}); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("Expected ErrPluginNotFound, got %v", err)
+	}
+
+	plugin := filepath.Join(dir, "Fake.vst3")
+	if err := os.MkdirAll(filepath.Join(plugin, "Contents"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := ProcessorSandboxConfig{AllowNetworkAccess: true, HostPath: filepath.Join(dir, "no-such-host")}
+	if _, err := NewSandboxedPlugin(plugin, cfg); !errors.Is(err, ErrSandboxFailed) {
+		t.Errorf("Expected ErrSandboxFailed for a missing host, got %v", err)
+	}
+
+	// A host that exits without answering
+	cfg.HostPath = "/usr/bin/false"
+	if _, err := os.Stat(cfg.HostPath); err != nil {
+		cfg.HostPath = "/bin/false"
+	}
+	if _, err := NewSandboxedPlugin(plugin, cfg); !errors.Is(err, ErrSandboxFailed) {
+		t.Errorf("Expected ErrSandboxFailed for a host that exits, got %v", err)
+	}
+}
+
+func TestSandboxedPluginProcess(t *testing.T) {
+	gain := newTestSandbox(t, "Gain")
+	if n := gain.NumParameters(); n != 1 {
+		t.Fatalf("Expected 1 parameter from the host, got %d", n)
+	}
+	gain.SetParameter(0, 0.5)
+	if v := gain.GetParameter(0); v != 0.5 {
+		t.Errorf("Expected the host to report 0.5, got %f", v)
+	}
+
+	// More samples than fit in shared memory at once, so the buffer crosses in several blocks
+	numSamples := sandboxSharedSamples
+	buffer := [][]float32{make([]float32, numSamples), make([]float32, numSamples)}
+	for ch := range buffer {
+		for i := range buffer[ch] {
+			buffer[ch][i] = float32(ch+1) * float32(i%100) / 100
+		}
+	}
+	if err := gain.ProcessContext(context.Background(), buffer, 44100.0); err != nil {
+		t.Fatalf("ProcessContext failed: %v", err)
+	}
+	for ch := range buffer {
+		for i, got := range buffer[ch] {
+			if want := 0.5 * float32(ch+1) * float32(i%100) / 100; got != want {
+				t.Fatalf("Channel %d sample %d: expected %f, got %f", ch, i, want, got)
+			}
+		}
+	}
+
+	// A ramp on a sandboxed plugin jumps to its target
+	gain.SetParameterRampTo(0, 0.25, 1000)
+	if v := gain.GetParameter(0); v != 0.25 {
+		t.Errorf("Expected the ramp target 0.25, got %f", v)
+	}
+
+	if err := gain.SetParameterFromText(0, "0.5"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("SetParameterFromText: expected ErrNotSupported, got %v", err)
+	}
+	if _, err := gain.ParameterText(0); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("ParameterText: expected ErrNotSupported, got %v", err)
+	}
+	if _, err := gain.GetParameterChoices(0); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("GetParameterChoices: expected ErrNotSupported, got %v", err)
+	}
+	if err := gain.Trigger(true); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Trigger: expected ErrNotSupported, got %v", err)
+	}
+	if _, err := gain.SavePreset(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("SavePreset: expected ErrNotSupported, got %v", err)
+	}
+	if level := gain.GetEnvelopeLevel(0); level != 0 {
+		t.Errorf("Expected no envelope level, got %f", level)
+	}
+	gain.SendMIDI([]MIDIEvent{{Data: []byte{0x90, 60, 100}}})
+	if out := gain.GetMIDIOutput(); out != nil {
+		t.Errorf("Expected no MIDI output, got %v", out)
+	}
+}
+
+func TestSandboxedPluginCrash(t *testing.T) {
+	crashing := newTestSandbox(t, "Gain")
+	healthy := newTestSandbox(t, "Gain")
+	crashing.SetParameter(0, 0.5)
+	healthy.SetParameter(0, 0.5)
+
+	// The crash is confined to the host: this process keeps running, the buffer is left
+	// as it was, and the failure is reported for every later call
+	crashSandbox(t, crashing)
+	for i := 0; i < 2; i++ {
+		buffer := [][]float32{{1, 1}}
+		if err := crashing.ProcessContext(context.Background(), buffer, 44100.0); !errors.Is(err, ErrSandboxFailed) {
+			t.Errorf("Call %d: expected ErrSandboxFailed, got %v", i, err)
+		}
+		if buffer[0][0] != 1 || buffer[0][1] != 1 {
+			t.Errorf("Call %d: expected the buffer unprocessed, got %v", i, buffer[0])
+		}
+	}
+
+	// Other sandboxed plugins are unaffected
+	buffer := [][]float32{{1, 1}}
+	if err := healthy.ProcessContext(context.Background(), buffer, 44100.0); err != nil {
+		t.Fatalf("ProcessContext failed on the healthy host: %v", err)
+	}
+	if buffer[0][1] != 0.5 {
+		t.Errorf("Expected 0.5 from the healthy host, got %f", buffer[0][1])
+	}
+}
@@ -0,0 +1,166 @@
+package pedalboard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// scanCacheEntry is a plugin in a scan cache file, with what identifies the plugin
+// files it was scanned from.
+type scanCacheEntry struct {
+	Path     string       `json:"path"`
+	Name     string       `json:"name"`
+	Vendor   string       `json:"vendor"`
+	Category string       `json:"category"`
+	UniqueID string       `json:"uniqueId"`
+	Version  string       `json:"version"`
+	Format   PluginFormat `json:"format"`
+	// ModTime is the latest modification time of the plugin file or of any file in the bundle.
+	ModTime time.Time `json:"mtime"`
+	// SHA256 is the hex digest of pluginHash.
+	SHA256 string `json:"sha256"`
+}
+
+// SaveScanCache writes plugins, typically the result of ScanPlugins, to a JSON file
+// together with the modification time and SHA-256 hash of each plugin, so the next
+// launch can skip loading plugins that haven't changed. See LoadScanCache.
+// Returns an error if a plugin cannot be read or the file cannot be written.
+func SaveScanCache(plugins []PluginInfo, path string) error {
+	entries := make([]scanCacheEntry, len(plugins))
+	for i, p := range plugins {
+		modTime, err := pluginModTime(p.Path)
+		if err != nil {
+			return fmt.Errorf("failed to save scan cache: %w", err)
+		}
+		hash, err := pluginHash(p.Path)
+		if err != nil {
+			return fmt.Errorf("failed to save scan cache: %w", err)
+		}
+		entries[i] = scanCacheEntry{
+			Path:     p.Path,
+			Name:     p.Name,
+			Vendor:   p.Vendor,
+			Category: p.Category,
+			UniqueID: p.UniqueID,
+			Version:  p.Version,
+			Format:   p.Format,
+			ModTime:  modTime,
+			SHA256:   hash,
+		}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scan cache: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save scan cache: %w", err)
+	}
+	return nil
+}
+
+// LoadScanCache reads plugins saved by SaveScanCache. Entries whose plugin has been
+// removed, or whose modification time or SHA-256 hash no longer matches, are dropped
+// silently; pass the result as ScanOptions.Cache so ScanPlugins only loads plugins that
+// are new or changed.
+// Returns an error if the file cannot be read or decoded.
+func LoadScanCache(path string) ([]PluginInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan cache: %w", err)
+	}
+	var entries []scanCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode scan cache: %w", err)
+	}
+
+	var plugins []PluginInfo
+	for _, e := range entries {
+		// The hash is only computed when the cheaper modification time check passes
+		if modTime, err := pluginModTime(e.Path); err != nil || !modTime.Equal(e.ModTime) {
+			continue
+		}
+		if hash, err := pluginHash(e.Path); err != nil || hash != e.SHA256 {
+			continue
+		}
+		plugins = append(plugins, PluginInfo{
+			Path:     e.Path,
+			Name:     e.Name,
+			Vendor:   e.Vendor,
+			Category: e.Category,
+			UniqueID: e.UniqueID,
+			Version:  e.Version,
+			Format:   e.Format,
+		})
+	}
+	return plugins, nil
+}
+
+// pluginModTime returns the modification time of the plugin file at path or, for a
+// bundle, the latest modification time of the bundle and everything inside it.
+func pluginModTime(path string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// pluginHash returns the hex SHA-256 digest of the plugin file at path or, for a bundle,
+// of the relative path and contents of every file and symlink inside it in lexical order.
+func pluginHash(path string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00link\x00%s\x00", filepath.ToSlash(rel), target)
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00file\x00%d\x00", filepath.ToSlash(rel), info.Size())
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,73 @@
+package pedalboard
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanCache(t *testing.T) {
+	dir := t.TempDir()
+	writeBundle := func(name, content string) PluginInfo {
+		binary := filepath.Join(dir, name+".vst3", "Contents", "x86_64-linux", name+".so")
+		if err := os.MkdirAll(filepath.Dir(binary), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(binary, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return PluginInfo{Path: filepath.Join(dir, name+".vst3"), Name: name, Vendor: "Test", Format: PluginFormatVST3}
+	}
+	plugins := []PluginInfo{writeBundle("Kept", "kept"), writeBundle("Edited", "edited"), writeBundle("Touched", "touched"), writeBundle("Removed", "removed")}
+
+	cachePath := filepath.Join(t.TempDir(), "plugins.json")
+	if err := SaveScanCache(plugins, cachePath); err != nil {
+		t.Fatalf("SaveScanCache failed: %v", err)
+	}
+
+	cached, err := LoadScanCache(cachePath)
+	if err != nil {
+		t.Fatalf("LoadScanCache failed: %v", err)
+	}
+	if len(cached) != 4 || cached[0] != plugins[0] {
+		t.Fatalf("Expected the saved plugins back, got %v", cached)
+	}
+
+	// A warm cache means no plugin is loaded
+	start := time.Now()
+	scanned, err := ScanPlugins(dir, ScanOptions{Cache: cached})
+	if err != nil {
+		t.Fatalf("ScanPlugins failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("Expected a cached scan within 10ms, took %v", elapsed)
+	}
+	if len(scanned) != 4 {
+		t.Errorf("Expected the 4 cached plugins, got %v", scanned)
+	}
+
+	// Same size and modification time, different contents
+	edited := filepath.Join(plugins[1].Path, "Contents", "x86_64-linux", "Edited.so")
+	info, _ := os.Stat(edited)
+	os.WriteFile(edited, []byte("EDITED"), 0o644)
+	os.Chtimes(edited, info.ModTime(), info.ModTime())
+	// Same contents, newer modification time
+	touched := filepath.Join(plugins[2].Path, "Contents", "x86_64-linux", "Touched.so")
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(touched, later, later)
+	os.RemoveAll(plugins[3].Path)
+
+	cached, err = LoadScanCache(cachePath)
+	if err != nil {
+		t.Fatalf("LoadScanCache failed: %v", err)
+	}
+	if len(cached) != 1 || cached[0].Name != "Kept" {
+		t.Errorf("Expected only the unchanged plugin, got %v", cached)
+	}
+
+	if _, err := LoadScanCache(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a not-exist error for a missing cache, got %v", err)
+	}
+}
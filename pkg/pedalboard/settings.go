@@ -0,0 +1,97 @@
+package pedalboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// AudioSettings holds the user's preferred audio device configuration.
+// It can be persisted between sessions with SaveAudioSettings and LoadAudioSettings.
+type AudioSettings struct {
+	// InputDeviceName is the name of the preferred input device. Empty means the default device.
+	InputDeviceName string `json:"inputDeviceName"`
+	// OutputDeviceName is the name of the preferred output device. Empty means the default device.
+	OutputDeviceName string `json:"outputDeviceName"`
+	// SampleRate is the preferred sample rate in Hz. Zero means the device default.
+	SampleRate float64 `json:"sampleRate"`
+	// BufferSize is the preferred block size in samples. Zero means the device default.
+	BufferSize int `json:"bufferSize"`
+}
+
+// SaveAudioSettings writes the settings to a JSON file.
+// path: The output file path.
+// s: The settings to save.
+// Returns an error if encoding or writing failed.
+func SaveAudioSettings(path string, s AudioSettings) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode audio settings: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save audio settings: %w", err)
+	}
+	return nil
+}
+
+// LoadAudioSettings reads settings previously written by SaveAudioSettings.
+// path: The path to the JSON settings file.
+// Returns the settings or an error if reading or decoding failed.
+func LoadAudioSettings(path string) (AudioSettings, error) {
+	var s AudioSettings
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("failed to load audio settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to decode audio settings: %w", err)
+	}
+	return s, nil
+}
+
+// StreamConfig resolves the saved device names to device IDs and returns an
+// AudioStreamConfig suitable for NewAudioStreamWithConfig.
+// processor: The processor to apply to the stream.
+// Returns the config or ErrDeviceNotFound if a saved device is no longer available.
+func (s AudioSettings) StreamConfig(processor *Processor) (AudioStreamConfig, error) {
+	cfg := AudioStreamConfig{
+		SampleRate: s.SampleRate,
+		BufferSize: s.BufferSize,
+		Processor:  processor,
+	}
+
+	if s.InputDeviceName != "" {
+		devices, err := EnumerateInputDevices()
+		if err != nil {
+			return cfg, err
+		}
+		id, ok := findDeviceID(devices, s.InputDeviceName)
+		if !ok {
+			return cfg, ErrDeviceNotFound
+		}
+		cfg.InputDeviceID = id
+	}
+
+	if s.OutputDeviceName != "" {
+		devices, err := EnumerateOutputDevices()
+		if err != nil {
+			return cfg, err
+		}
+		id, ok := findDeviceID(devices, s.OutputDeviceName)
+		if !ok {
+			return cfg, ErrDeviceNotFound
+		}
+		cfg.OutputDeviceID = id
+	}
+
+	return cfg, nil
+}
+
+func findDeviceID(devices []AudioDevice, name string) (string, bool) {
+	for _, dev := range devices {
+		if dev.Name == name {
+			return dev.ID, true
+		}
+	}
+	return "", false
+}
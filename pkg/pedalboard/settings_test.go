@@ -0,0 +1,43 @@
+package pedalboard
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAudioSettingsRoundTrip(t *testing.T) {
+	original := AudioSettings{
+		InputDeviceName:  "USB Interface",
+		OutputDeviceName: "Built-in Output",
+		SampleRate:       48000.0,
+		BufferSize:       256,
+	}
+
+	tmpFile := t.TempDir() + "/settings.json"
+	if err := SaveAudioSettings(tmpFile, original); err != nil {
+		t.Fatalf("Failed to save settings: %v", err)
+	}
+
+	loaded, err := LoadAudioSettings(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+	if loaded != original {
+		t.Errorf("Expected %+v, got %+v", original, loaded)
+	}
+}
+
+func TestLoadAudioSettingsErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	if _, err := LoadAudioSettings(tmpDir + "/missing.json"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	badFile := tmpDir + "/bad.json"
+	if err := os.WriteFile(badFile, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAudioSettings(badFile); err == nil {
+		t.Error("Expected error for malformed file")
+	}
+}
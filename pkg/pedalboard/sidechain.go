@@ -0,0 +1,98 @@
+package pedalboard
+
+import (
+	"fmt"
+	"math"
+)
+
+// SidechainCompressor compresses one signal by the level of another, as for ducking
+// music under a voice-over (the voice is the sidechain) or de-essing (a filtered copy
+// of the vocal is the sidechain). The level is the RMS of the sidechain channels
+// together, so every channel of the main signal gets the same gain reduction.
+// It is not safe for concurrent use.
+type SidechainCompressor struct {
+	threshold float64 // dB
+	ratio     float64
+	attackMs  float64
+	releaseMs float64
+
+	meanSquare float64 // Smoothed mean square of the sidechain
+}
+
+// NewSidechainCompressor creates a sidechain compressor.
+// threshold: The sidechain RMS level, in dBFS, above which the main signal is compressed.
+// ratio: The compression ratio, 1 or more (4 reduces 4 dB over the threshold to 1 dB).
+// attackMs: How fast the detector follows a rising sidechain level, in milliseconds.
+// releaseMs: How fast the detector follows a falling sidechain level, in milliseconds.
+// Returns an error wrapping ErrInvalidArgument if threshold is above 0, ratio is below
+// 1, or a time is not positive.
+func NewSidechainCompressor(threshold, ratio, attackMs, releaseMs float32) (*SidechainCompressor, error) {
+	if threshold > 0 || ratio < 1 || attackMs <= 0 || releaseMs <= 0 {
+		return nil, fmt.Errorf("%w: threshold %g dB, ratio %g, attack %g ms, release %g ms", ErrInvalidArgument, threshold, ratio, attackMs, releaseMs)
+	}
+	return &SidechainCompressor{
+		threshold: float64(threshold),
+		ratio:     float64(ratio),
+		attackMs:  float64(attackMs),
+		releaseMs: float64(releaseMs),
+	}, nil
+}
+
+// ProcessWithSidechain compresses main by the level of sidechain. The detector carries
+// over from one call to the next, so a signal can be processed block by block; see Reset.
+// A silent sidechain leaves main unmodified.
+// main: The audio to compress (modified in-place).
+// sidechain: The audio whose level drives the compression, with any number of channels
+// of the same length as main. It is not modified.
+// sampleRate: The sample rate of both signals.
+// Returns an error if either buffer is empty, their lengths differ, or sampleRate is not positive.
+func (s *SidechainCompressor) ProcessWithSidechain(main, sidechain [][]float32, sampleRate float64) error {
+	if len(main) == 0 || len(sidechain) == 0 {
+		return ErrEmptyBuffer
+	}
+	if sampleRate <= 0 {
+		return fmt.Errorf("%w: sample rate %g", ErrInvalidArgument, sampleRate)
+	}
+	numSamples := len(main[0])
+	for ch, samples := range main {
+		if len(samples) != numSamples {
+			return fmt.Errorf("%w: main channel %d has %d samples, expected %d", ErrInvalidArgument, ch, len(samples), numSamples)
+		}
+	}
+	for ch, samples := range sidechain {
+		if len(samples) != numSamples {
+			return fmt.Errorf("%w: sidechain channel %d has %d samples, expected %d", ErrInvalidArgument, ch, len(samples), numSamples)
+		}
+	}
+
+	attack := 1 - math.Exp(-1000/(s.attackMs*sampleRate))
+	release := 1 - math.Exp(-1000/(s.releaseMs*sampleRate))
+	for i := 0; i < numSamples; i++ {
+		var sum float64
+		for _, samples := range sidechain {
+			x := float64(samples[i])
+			sum += x * x
+		}
+		power := sum / float64(len(sidechain))
+		if power > s.meanSquare {
+			s.meanSquare += attack * (power - s.meanSquare)
+		} else {
+			s.meanSquare += release * (power - s.meanSquare)
+		}
+
+		over := 10*math.Log10(s.meanSquare) - s.threshold
+		if s.meanSquare == 0 || over <= 0 {
+			continue
+		}
+		gain := float32(math.Pow(10, (1/s.ratio-1)*over/20))
+		for _, samples := range main {
+			samples[i] *= gain
+		}
+	}
+	return nil
+}
+
+// Reset clears the level detector, e.g. before processing an unrelated signal.
+func (s *SidechainCompressor) Reset() {
+	s.meanSquare = 0
+}
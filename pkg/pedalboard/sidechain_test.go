@@ -0,0 +1,68 @@
+package pedalboard
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSidechainCompressor(t *testing.T) {
+	const sampleRate = 48000.0
+	comp, err := NewSidechainCompressor(-20, 4, 5, 50)
+	if err != nil {
+		t.Fatalf("NewSidechainCompressor failed: %v", err)
+	}
+
+	newMain := func() [][]float32 {
+		main := [][]float32{make([]float32, 4800), make([]float32, 4800)}
+		for i := range main[0] {
+			main[0][i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+			main[1][i] = -main[0][i]
+		}
+		return main
+	}
+
+	// A silent sidechain leaves the main signal untouched
+	main, dry := newMain(), newMain()
+	if err := comp.ProcessWithSidechain(main, [][]float32{make([]float32, 4800)}, sampleRate); err != nil {
+		t.Fatalf("ProcessWithSidechain failed: %v", err)
+	}
+	for ch := range main {
+		for i := range main[ch] {
+			if main[ch][i] != dry[ch][i] {
+				t.Fatalf("Channel %d sample %d: expected %f unmodified, got %f", ch, i, dry[ch][i], main[ch][i])
+			}
+		}
+	}
+
+	// A 0 dBFS sidechain is 20 dB over the threshold; 4:1 reduces the main signal by 15 dB
+	loud := make([]float32, 4800)
+	for i := range loud {
+		loud[i] = 1
+	}
+	main = newMain()
+	if err := comp.ProcessWithSidechain(main, [][]float32{loud, loud}, sampleRate); err != nil {
+		t.Fatalf("ProcessWithSidechain failed: %v", err)
+	}
+	want := math.Pow(10, -15.0/20)
+	for _, i := range []int{4000, 4700} {
+		if got := float64(main[0][i] / dry[0][i]); math.Abs(got-want) > 1e-3 {
+			t.Errorf("Sample %d: expected gain %f, got %f", i, want, got)
+		}
+		if main[1][i] != -main[0][i] {
+			t.Errorf("Sample %d: expected both channels to get the same gain", i)
+		}
+	}
+
+	if err := comp.ProcessWithSidechain(newMain(), [][]float32{loud[:100]}, sampleRate); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for a shorter sidechain, got %v", err)
+	}
+	if err := comp.ProcessWithSidechain(newMain(), nil, sampleRate); !errors.Is(err, ErrEmptyBuffer) {
+		t.Errorf("Expected ErrEmptyBuffer for no sidechain, got %v", err)
+	}
+	for _, args := range [][4]float32{{6, 4, 5, 50}, {-20, 0.5, 5, 50}, {-20, 4, 0, 50}, {-20, 4, 5, -1}} {
+		if _, err := NewSidechainCompressor(args[0], args[1], args[2], args[3]); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("Expected ErrInvalidArgument for %v, got %v", args, err)
+		}
+	}
+}
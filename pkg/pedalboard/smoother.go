@@ -0,0 +1,59 @@
+package pedalboard
+
+import "sync"
+
+// ParameterSmoother ramps a parameter towards a target value one block at a time, so
+// changes made between blocks do not jump and cause zipper noise. Call Advance at the
+// start of each block, before the processor runs, e.g. for every stage in a loop that
+// processes the stages of a chain. SetTarget may be called from another goroutine,
+// such as a UI. For ramps applied sample by sample inside the processor, see
+// Processor.SetParameterRampTo.
+type ParameterSmoother struct {
+	p           *Processor
+	index       int
+	rampSamples int
+
+	mu        sync.Mutex
+	current   float32
+	target    float32
+	remaining int // Samples left in the ramp
+}
+
+// NewParameterSmoother creates a smoother for parameter index of processor, starting
+// from its current value.
+// rampSamples: The number of samples a change takes to reach its target; 0 or less
+// applies changes at the next Advance.
+func NewParameterSmoother(processor *Processor, index int, rampSamples int) *ParameterSmoother {
+	value := processor.GetParameter(index)
+	return &ParameterSmoother{p: processor, index: index, rampSamples: rampSamples, current: value, target: value}
+}
+
+// SetTarget starts a ramp from the value reached so far to value, replacing any ramp
+// in progress.
+func (s *ParameterSmoother) SetTarget(value float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.target = value
+	s.remaining = max(s.rampSamples, 1)
+}
+
+// Advance moves the parameter numSamples further along the ramp and sets the value
+// reached on the processor; it does nothing once the target has been reached.
+// numSamples: The length of the block about to be processed.
+func (s *ParameterSmoother) Advance(numSamples int) {
+	s.mu.Lock()
+	if s.remaining == 0 || numSamples <= 0 {
+		s.mu.Unlock()
+		return
+	}
+	if numSamples >= s.remaining {
+		s.current = s.target
+		s.remaining = 0
+	} else {
+		s.current += (s.target - s.current) * float32(numSamples) / float32(s.remaining)
+		s.remaining -= numSamples
+	}
+	value := s.current
+	s.mu.Unlock()
+	s.p.SetParameter(s.index, value)
+}
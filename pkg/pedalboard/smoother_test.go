@@ -0,0 +1,41 @@
+package pedalboard
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParameterSmoother(t *testing.T) {
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.2)
+	s := NewParameterSmoother(gain, 0, 400)
+
+	s.Advance(100)
+	if got := gain.GetParameter(0); got != 0.2 {
+		t.Errorf("Expected no change without a target, got %f", got)
+	}
+
+	s.SetTarget(1.0)
+	for i, want := range []float32{0.4, 0.6, 0.8, 1.0, 1.0} {
+		s.Advance(100)
+		if got := gain.GetParameter(0); math.Abs(float64(got-want)) > 1e-6 {
+			t.Errorf("Block %d: expected %f, got %f", i, want, got)
+		}
+	}
+
+	// A new target ramps from the value reached so far
+	s.SetTarget(0.0)
+	s.Advance(200)
+	s.SetTarget(1.0)
+	s.Advance(200)
+	if got := gain.GetParameter(0); math.Abs(float64(got-0.75)) > 1e-6 {
+		t.Errorf("Expected the ramp to restart from 0.5, got %f", got)
+	}
+
+	instant := NewParameterSmoother(gain, 0, 0)
+	instant.SetTarget(0.1)
+	instant.Advance(1)
+	if got := gain.GetParameter(0); math.Abs(float64(got-0.1)) > 1e-6 {
+		t.Errorf("Expected a zero-length ramp to jump to the target, got %f", got)
+	}
+}
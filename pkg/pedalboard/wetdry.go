@@ -0,0 +1,114 @@
+package pedalboard
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// WetDryProcessor blends the output of a processor with its unprocessed input, like the
+// mix knob of an effect pedal. The inner processor runs on every block whatever the mix,
+// so delay and reverb tails carry on when the mix is raised. The dry signal is delayed
+// by the latency the inner processor reports, so the two stay in time.
+// Process must not be called from several goroutines at once; SetMix and Mix may be
+// called at any time, e.g. from a UI goroutine while audio is processed.
+type WetDryProcessor struct {
+	inner *Processor
+	mix   atomic.Uint32 // math.Float32bits of the mix
+	dry   [][]float32   // Reused copy of the input
+	delay delayLine     // Delays dry by the inner processor's latency
+}
+
+// NewWetDryProcessor wraps inner with a wet/dry mix.
+// mix: The proportion of processed signal, from 0 (dry only) to 1 (wet only).
+func NewWetDryProcessor(inner *Processor, mix float32) *WetDryProcessor {
+	w := &WetDryProcessor{inner: inner}
+	w.SetMix(mix)
+	return w
+}
+
+// SetMix sets the proportion of processed signal, clamped to 0 to 1. The new mix
+// applies from the next Process call.
+func (w *WetDryProcessor) SetMix(mix float32) {
+	w.mix.Store(math.Float32bits(max(0, min(mix, 1))))
+}
+
+// Mix returns the current proportion of processed signal.
+func (w *WetDryProcessor) Mix() float32 {
+	return math.Float32frombits(w.mix.Load())
+}
+
+// Inner returns the wrapped processor.
+func (w *WetDryProcessor) Inner() *Processor {
+	return w.inner
+}
+
+// Process runs the inner processor on buffer and replaces each sample with
+// (1-mix)*dry + mix*wet, where dry is the input delayed by the inner processor's
+// latency. A mix of 0 leaves buffer bit-exact to the delayed input and a mix of 1
+// leaves the inner processor's output untouched. A change of latency restarts the dry
+// delay from silence.
+// buffer: The audio data to process (modified in-place).
+// sampleRate: The sample rate of the audio data.
+func (w *WetDryProcessor) Process(buffer [][]float32, sampleRate float64) {
+	if len(w.dry) < len(buffer) {
+		w.dry = append(w.dry, make([][]float32, len(buffer)-len(w.dry))...)
+	}
+	for ch, samples := range buffer {
+		w.dry[ch] = append(w.dry[ch][:0], samples...)
+	}
+	w.delay.process(w.dry[:len(buffer)], w.inner.LatencySamples())
+
+	mix := w.Mix()
+	w.inner.Process(buffer, sampleRate)
+	switch mix {
+	case 1:
+		return
+	case 0:
+		for ch, samples := range buffer {
+			copy(samples, w.dry[ch])
+		}
+		return
+	}
+	for ch, samples := range buffer {
+		dry := w.dry[ch]
+		for i := range samples {
+			samples[i] = (1-mix)*dry[i] + mix*samples[i]
+		}
+	}
+}
+
+// delayLine delays audio by a number of samples that can change between blocks.
+// The zero value is ready to use.
+type delayLine struct {
+	rings [][]float32 // Per-channel rings of the last delay input samples
+	pos   int         // Position of the oldest sample in every ring
+}
+
+// process delays buffer in place by delay samples, continuing from the previous call.
+// A change of delay or a new channel restarts the delay from silence.
+func (d *delayLine) process(buffer [][]float32, delay int) {
+	if len(buffer) == 0 {
+		return
+	}
+	if delay <= 0 {
+		d.rings = nil
+		return
+	}
+	if len(d.rings) < len(buffer) || len(d.rings[0]) != delay {
+		d.rings = make([][]float32, len(buffer))
+		for ch := range d.rings {
+			d.rings[ch] = make([]float32, delay)
+		}
+		d.pos = 0
+	}
+	for ch, samples := range buffer {
+		ring, pos := d.rings[ch], d.pos
+		for i, x := range samples {
+			samples[i], ring[pos] = ring[pos], x
+			if pos++; pos == delay {
+				pos = 0
+			}
+		}
+	}
+	d.pos = (d.pos + len(buffer[0])) % delay
+}
@@ -0,0 +1,76 @@
+package pedalboard
+
+import (
+	"testing"
+)
+
+func TestWetDryProcessor(t *testing.T) {
+	input := func() [][]float32 {
+		return [][]float32{{0.1, -0.2, 0.3, -0.4}, {0.5, 0.25, -0.125, 1}}
+	}
+
+	gain, _ := NewInternalProcessor("Gain")
+	gain.SetParameter(0, 0.5)
+	w := NewWetDryProcessor(gain, 0)
+	buffer := input()
+	w.Process(buffer, 44100.0)
+	for ch, samples := range input() {
+		for i, s := range samples {
+			if buffer[ch][i] != s {
+				t.Fatalf("mix=0: expected %v, got %v at [%d][%d]", s, buffer[ch][i], ch, i)
+			}
+		}
+	}
+
+	direct, _ := NewInternalProcessor("Distortion")
+	wrapped, _ := NewInternalProcessor("Distortion")
+	want := input()
+	direct.Process(want, 44100.0)
+	buffer = input()
+	NewWetDryProcessor(wrapped, 1).Process(buffer, 44100.0)
+	for ch := range want {
+		for i := range want[ch] {
+			if buffer[ch][i] != want[ch][i] {
+				t.Fatalf("mix=1: expected %v, got %v at [%d][%d]", want[ch][i], buffer[ch][i], ch, i)
+			}
+		}
+	}
+
+	// Halfway between the input and half of it
+	w.SetMix(0.5)
+	buffer = input()
+	w.Process(buffer, 44100.0)
+	if v := buffer[0][0]; v < 0.0749 || v > 0.0751 {
+		t.Errorf("mix=0.5: expected 0.075, got %v", v)
+	}
+
+	// The dry signal is delayed to line up with a processor that reports latency
+	shifter, _ := NewInternalProcessor("PitchShifter")
+	shifter.SetParameter(0, 0.75) // +12 semitones
+	latency := shifter.LatencySamples()
+	if latency != 2048 {
+		t.Fatalf("Expected PitchShifter to report 2048 samples of latency, got %d", latency)
+	}
+	delayed := NewWetDryProcessor(shifter, 0)
+	for block := 0; block < 2; block++ {
+		impulse := [][]float32{make([]float32, 1500)}
+		if block == 0 {
+			impulse[0][0] = 1
+		}
+		delayed.Process(impulse, 44100.0)
+		for i, s := range impulse[0] {
+			want := float32(0)
+			if block*1500+i == latency {
+				want = 1
+			}
+			if s != want {
+				t.Fatalf("Block %d sample %d: expected %v, got %v", block, i, want, s)
+			}
+		}
+	}
+
+	w.SetMix(2)
+	if w.Mix() != 1 {
+		t.Errorf("Expected the mix to be clamped to 1, got %v", w.Mix())
+	}
+}
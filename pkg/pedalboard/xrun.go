@@ -0,0 +1,80 @@
+package pedalboard
+
+/*
+#include "pedalboard.h"
+*/
+import "C"
+import (
+	"runtime/cgo"
+	"time"
+)
+
+// XrunType identifies the kind of audio dropout reported to an xrun callback.
+type XrunType int
+
+const (
+	// XrunUnderrun means the output device ran out of audio, as reported by the driver.
+	XrunUnderrun XrunType = C.PEDALBOARD_XRUN_UNDERRUN
+	// XrunOverrun means processing a block took longer than the block's duration,
+	// so input arrived faster than it was consumed.
+	XrunOverrun XrunType = C.PEDALBOARD_XRUN_OVERRUN
+)
+
+// String returns the name of the xrun type.
+func (t XrunType) String() string {
+	switch t {
+	case XrunUnderrun:
+		return "Underrun"
+	case XrunOverrun:
+		return "Overrun"
+	default:
+		return "Unknown"
+	}
+}
+
+// SetXrunCallback registers fn to be notified of underruns and overruns.
+// Passing nil removes the callback.
+//
+// fn is invoked synchronously on the audio thread. It must be real-time safe:
+// it must not allocate, block, take contended locks or perform I/O. A typical
+// callback increments an atomic counter or does a non-blocking send on a
+// buffered channel, leaving any reporting to another goroutine.
+// Returns ErrStreamClosed if the stream has been closed.
+func (s *AudioStream) SetXrunCallback(fn func(XrunType, time.Time)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle == nil {
+		return ErrStreamClosed
+	}
+	s.setXrunCallbackLocked(fn)
+	return nil
+}
+
+// setXrunCallbackLocked replaces the xrun callback. s.mu must be held.
+func (s *AudioStream) setXrunCallbackLocked(fn func(XrunType, time.Time)) {
+	var context C.uintptr_t
+	var handle cgo.Handle
+	if fn != nil {
+		handle = cgo.NewHandle(fn)
+		context = C.uintptr_t(handle)
+	}
+	C.pedalboard_audio_stream_set_xrun_callback(s.handle, context)
+
+	// The C layer guarantees the old handle is no longer in use
+	if s.xrunHandle != 0 {
+		s.xrunHandle.Delete()
+	}
+	s.xrunHandle = handle
+}
+
+//export goPedalboardXrun
+func goPedalboardXrun(context C.uintptr_t, xrunType C.int, unixNanos C.longlong) {
+	dispatchXrun(cgo.Handle(context), XrunType(xrunType), time.Unix(0, int64(unixNanos)))
+}
+
+// dispatchXrun invokes the callback stored in h.
+func dispatchXrun(h cgo.Handle, t XrunType, when time.Time) {
+	if fn, ok := h.Value().(func(XrunType, time.Time)); ok {
+		fn(t, when)
+	}
+}
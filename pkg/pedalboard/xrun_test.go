@@ -0,0 +1,62 @@
+//go:build linux || darwin
+
+package pedalboard
+
+import (
+	"runtime/cgo"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestXrunCallback(t *testing.T) {
+	var got []XrunType
+	fn := func(xt XrunType, when time.Time) {
+		got = append(got, xt)
+	}
+	h := cgo.NewHandle(fn)
+	defer h.Delete()
+
+	dispatchXrun(h, XrunUnderrun, time.Now())
+	dispatchXrun(h, XrunOverrun, time.Now())
+	if len(got) != 2 || got[0] != XrunUnderrun || got[1] != XrunOverrun {
+		t.Errorf("Unexpected xrun events: %v", got)
+	}
+
+	// A stage whose host is paused holds up the audio callback far longer than a block
+	slow := newTestSandbox(t, "Gain")
+	chain, err := NewProcessorChain(slow)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	stream, err := NewAudioStream(chain.Processor())
+	if err != nil {
+		t.Logf("Audio stream creation failed (expected in some environments): %v", err)
+		return
+	}
+	defer stream.Close()
+
+	var overruns atomic.Int64
+	stream.SetXrunCallback(func(xt XrunType, _ time.Time) {
+		if xt == XrunOverrun {
+			overruns.Add(1)
+		}
+	})
+	if err := stream.Start(); err != nil {
+		t.Fatalf("Failed to start the stream: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := slow.sandbox.cmd.Process.Signal(syscall.SIGSTOP); err != nil {
+		t.Fatalf("Failed to pause the sandbox host: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	slow.sandbox.cmd.Process.Signal(syscall.SIGCONT)
+	time.Sleep(50 * time.Millisecond)
+	stream.Stop()
+	stream.SetXrunCallback(nil)
+
+	if overruns.Load() == 0 {
+		t.Error("Expected an overrun while the stage was paused")
+	}
+}